@@ -1,22 +1,63 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
+	"sort"
 
 	"github.com/labstack/echo/v4"
 
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types/helpers"
 	"data-pipelines-worker/types/interfaces"
 )
 
 // @Summary Get all blocks
-// @Description Returns a JSON array of all blocks in the registry.
+// @Description Returns a JSON object of the blocks in the registry by id. Every block is its default version
+// @Description with the `versions` registered side by side and their deprecation flags.
 // @Tags blocks
 // @Accept json
 // @Produce json
-// @Success 200 {array} interfaces.Block
+// @Success 200 {object} map[string]interface{}
 // @Router /blocks [get]
 func BlocksHandler(registry interfaces.BlockRegistry) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		return c.JSON(http.StatusOK, registry.GetAll())
+		response := make(map[string]map[string]interface{})
+		for id, block := range registry.GetAll() {
+			blockResponse, err := getBlockResponse(block, registry.GetVersions(id))
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, err.Error())
+			}
+			response[id] = blockResponse
+		}
+
+		return c.JSON(http.StatusOK, response)
+	}
+}
+
+// getBlockResponse returns the fields of the block with its versions ordered from the oldest
+func getBlockResponse(block interfaces.Block, versions map[string]interfaces.Block) (map[string]interface{}, error) {
+	blockJSON, err := json.Marshal(block)
+	if err != nil {
+		return nil, err
 	}
+	blockResponse := make(map[string]interface{})
+	if err := json.Unmarshal(blockJSON, &blockResponse); err != nil {
+		return nil, err
+	}
+
+	blockVersions := make([]schemas.BlockVersionSchema, 0, len(versions))
+	for version, versionBlock := range versions {
+		blockVersions = append(blockVersions, schemas.BlockVersionSchema{
+			Version:    version,
+			Deprecated: versionBlock.IsDeprecated(),
+			Available:  versionBlock.IsAvailable(),
+		})
+	}
+	sort.Slice(blockVersions, func(i, j int) bool {
+		return helpers.CompareVersions(blockVersions[i].Version, blockVersions[j].Version) < 0
+	})
+	blockResponse["versions"] = blockVersions
+
+	return blockResponse, nil
 }
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -10,6 +11,7 @@ import (
 
 	"data-pipelines-worker/api/schemas"
 	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/registries"
 )
 
 // @Summary Get all pipelines
@@ -27,16 +29,18 @@ func PipelinesHandler(registry interfaces.PipelineRegistry) echo.HandlerFunc {
 
 // @Summary Get a pipeline
 // @Description Returns a JSON object of the pipeline with the given slug.
+// @Description The default version is returned unless the version is set.
 // @Tags pipelines
 // @Accept json
 // @Produce json
 // @Param slug path string true "Pipeline slug"
+// @Param version query string false "Pipeline version"
 // @Success 200 {object} dataclasses.PipelineData
 // @Failure 404 {string} string "Pipeline not found"
 // @Router /pipelines/{slug} [get]
 func PipelineHandler(registry interfaces.PipelineRegistry) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		pipeline := registry.Get(c.Param("slug"))
+		pipeline := registry.GetVersion(c.Param("slug"), c.QueryParam("version"))
 		if pipeline == nil {
 			return c.JSON(http.StatusNotFound, "Pipeline not found")
 		}
@@ -45,13 +49,148 @@ func PipelineHandler(registry interfaces.PipelineRegistry) echo.HandlerFunc {
 	}
 }
 
+func getPipelineVersions(registry interfaces.PipelineRegistry, pipeline interfaces.Pipeline) schemas.PipelineVersionsSchema {
+	pipelineVersions := schemas.PipelineVersionsSchema{
+		Slug:           pipeline.GetSlug(),
+		DefaultVersion: pipeline.GetVersion(),
+		Versions:       make([]string, 0),
+	}
+	for _, version := range registry.GetVersions(pipeline.GetSlug()) {
+		pipelineVersions.Versions = append(pipelineVersions.Versions, version.GetVersion())
+	}
+
+	return pipelineVersions
+}
+
+// @Summary Get pipeline versions
+// @Description Returns the registered versions of the pipeline and the version new processings are started with.
+// @Tags pipelines
+// @Accept json
+// @Produce json
+// @Param slug path string true "Pipeline slug"
+// @Success 200 {object} schemas.PipelineVersionsSchema
+// @Failure 404 {string} string "Pipeline not found"
+// @Router /pipelines/{slug}/versions [get]
+func PipelineVersionsHandler(registry interfaces.PipelineRegistry) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		pipeline := registry.Get(c.Param("slug"))
+		if pipeline == nil {
+			return c.JSON(http.StatusNotFound, "Pipeline not found")
+		}
+
+		return c.JSON(http.StatusOK, getPipelineVersions(registry, pipeline))
+	}
+}
+
+// @Summary Set the pipeline default version
+// @Description Sets the version new processings of the pipeline are started with.
+// @Description Processings already started keep their version.
+// @Tags pipelines
+// @Accept json
+// @Produce json
+// @Param slug path string true "Pipeline slug"
+// @Param input body schemas.PipelineDefaultVersionInputSchema true "Default version"
+// @Success 200 {object} schemas.PipelineVersionsSchema
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Pipeline or version not found"
+// @Router /pipelines/{slug}/versions/default [put]
+func PipelineDefaultVersionHandler(registry interfaces.PipelineRegistry) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		pipeline := registry.Get(c.Param("slug"))
+		if pipeline == nil {
+			return c.JSON(http.StatusNotFound, "Pipeline not found")
+		}
+
+		var inputData schemas.PipelineDefaultVersionInputSchema
+		if err := c.Bind(&inputData); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		if inputData.Version == "" {
+			return c.JSON(http.StatusBadRequest, "version is required")
+		}
+
+		err := registry.SetDefaultVersion(pipeline.GetSlug(), inputData.Version)
+		if errors.Is(err, registries.ErrPipelineVersionNotFound) {
+			return c.JSON(http.StatusNotFound, "Pipeline version not found")
+		}
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+
+		return c.JSON(http.StatusOK, getPipelineVersions(registry, registry.Get(pipeline.GetSlug())))
+	}
+}
+
+// @Summary Get pipeline Triggers
+// @Description Returns a JSON array of the pipeline Triggers with their last and next run.
+// @Tags pipelines
+// @Accept json
+// @Produce json
+// @Param slug path string true "Pipeline slug"
+// @Success 200 {array} registries.PipelineTriggerState
+// @Failure 404 {string} string "Pipeline not found"
+// @Router /pipelines/{slug}/triggers [get]
+func PipelineTriggersHandler(
+	registry interfaces.PipelineRegistry,
+	triggerRegistry interfaces.TriggerRegistry,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		pipeline := registry.Get(c.Param("slug"))
+		if pipeline == nil {
+			return c.JSON(http.StatusNotFound, "Pipeline not found")
+		}
+
+		return c.JSON(http.StatusOK, triggerRegistry.GetPipelineTriggers(pipeline.GetSlug()))
+	}
+}
+
+// @Summary Reload the pipelines catalogue
+// @Description Loads the catalogue files and swaps the registered pipelines at once.
+// @Description Invalid files are reported with their errors and previous definitions of their pipelines are kept.
+// @Tags pipelines
+// @Accept json
+// @Produce json
+// @Success 200 {object} schemas.PipelineCatalogueReloadSchema
+// @Failure 500 {string} string "Catalogue loading failed"
+// @Router /pipelines/reload [post]
+func PipelinesReloadHandler(registry interfaces.PipelineRegistry) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		report, err := registry.ReloadCatalogue()
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(http.StatusOK, report)
+	}
+}
+
+// @Summary Get the pipelines catalogue status
+// @Description Returns the result of the last catalogue reload including errors of the invalid files.
+// @Tags pipelines
+// @Accept json
+// @Produce json
+// @Success 200 {object} schemas.PipelineCatalogueReloadSchema
+// @Router /catalogue [get]
+func PipelinesCatalogueHandler(registry interfaces.PipelineRegistry) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, registry.GetCatalogueReload())
+	}
+}
+
 // @Summary Get pipeline Processings info
-// @Description Returns a JSON object of the pipeline Processings.
+// @Description Returns a page of the pipeline Processings statuses.
 // @Tags pipelines
 // @Accept json
 // @Produce json
 // @Param slug path string true "Pipeline slug"
-// @Success 200 {object} map[uuid.UUID][]dataclasses.PipelineProcessingStatus
+// @Param status query string false "Processing status: completed, stopped, failed or incomplete"
+// @Param from query string false "Processings finished at or after the date ( RFC3339 )"
+// @Param to query string false "Processings finished at or before the date ( RFC3339 )"
+// @Param sort query string false "Sorting: date_finished or -date_finished ( default )"
+// @Param offset query int false "Number of processings to skip"
+// @Param limit query int false "Maximum number of processings ( 50 by default )"
+// @Success 200 {object} schemas.PipelineProcessingsListSchema{processings=[]dataclasses.PipelineProcessingStatus}
+// @Failure 400 {string} string "Bad request"
 // @Failure 404 {string} string "Pipeline not found"
 // @Router /pipelines/{slug}/processings/ [get]
 func PipelineProcessingsStatusHandler(registry interfaces.PipelineRegistry) echo.HandlerFunc {
@@ -61,7 +200,85 @@ func PipelineProcessingsStatusHandler(registry interfaces.PipelineRegistry) echo
 			return c.JSON(http.StatusNotFound, "Pipeline not found")
 		}
 
-		return c.JSON(http.StatusOK, registry.GetProcessingsStatus(pipeline))
+		query := schemas.NewPipelineProcessingsQuerySchema()
+		if err := query.ParseQuery(c.QueryParams()); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+
+		processingsStatus, total := registry.QueryProcessingsStatus(pipeline, query)
+
+		return c.JSON(
+			http.StatusOK,
+			schemas.PipelineProcessingsListSchema{
+				Total:       total,
+				Offset:      query.Offset,
+				Limit:       query.Limit,
+				Processings: processingsStatus,
+			},
+		)
+	}
+}
+
+// @Summary Get pipeline Processings usage
+// @Description Returns the usage and the cost of the pipeline Processings matching the query.
+// @Tags pipelines
+// @Accept json
+// @Produce json
+// @Param slug path string true "Pipeline slug"
+// @Param status query string false "Processing status: completed, stopped, failed or incomplete"
+// @Param from query string false "Processings finished at or after the date ( RFC3339 )"
+// @Param to query string false "Processings finished at or before the date ( RFC3339 )"
+// @Success 200 {object} schemas.PipelineUsageSchema
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Pipeline not found"
+// @Router /pipelines/{slug}/usage [get]
+func PipelineUsageHandler(registry interfaces.PipelineRegistry) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		pipeline := registry.Get(c.Param("slug"))
+		if pipeline == nil {
+			return c.JSON(http.StatusNotFound, "Pipeline not found")
+		}
+
+		query := schemas.NewPipelineProcessingsQuerySchema()
+		if err := query.ParseQuery(c.QueryParams()); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+
+		return c.JSON(
+			http.StatusOK,
+			registry.QueryProcessingsUsage(pipeline, query),
+		)
+	}
+}
+
+// @Summary Reindex pipeline Processings
+// @Description Rebuilds the index of the pipeline Processings logs and statuses from the storages.
+// @Tags pipelines
+// @Accept json
+// @Produce json
+// @Param slug path string true "Pipeline slug"
+// @Success 200 {object} schemas.PipelineProcessingsReindexOutputSchema
+// @Failure 404 {string} string "Pipeline not found"
+// @Failure 500 {string} string "Reindexing failed"
+// @Router /pipelines/{slug}/processings/reindex [post]
+func PipelineProcessingsReindexHandler(registry interfaces.PipelineRegistry) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		pipeline := registry.Get(c.Param("slug"))
+		if pipeline == nil {
+			return c.JSON(http.StatusNotFound, "Pipeline not found")
+		}
+
+		indexed, err := registry.ReindexProcessings(pipeline)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(
+			http.StatusOK,
+			schemas.PipelineProcessingsReindexOutputSchema{
+				Indexed: indexed,
+			},
+		)
 	}
 }
 
@@ -99,6 +316,51 @@ func PipelineProcessingDetailsByLogIdHandler(registry interfaces.PipelineRegistr
 	}
 }
 
+// @Summary Delete pipeline Processing
+// @Description Removes all artifacts ( outputs, logs and statuses ) of the processing in every storage.
+// @Tags pipelines
+// @Accept json
+// @Produce json
+// @Param slug path string true "Pipeline slug"
+// @Param id path string true "Processing ID"
+// @Success 200 {object} schemas.PipelineProcessingDeleteOutputSchema
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Pipeline or Processing not found"
+// @Failure 409 {string} string "Processing is not finished"
+// @Failure 500 {string} string "Deletion failed"
+// @Router /pipelines/{slug}/processings/{id} [delete]
+func PipelineProcessingDeleteHandler(registry interfaces.PipelineRegistry) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		pipeline := registry.Get(c.Param("slug"))
+		if pipeline == nil {
+			return c.JSON(http.StatusNotFound, "Pipeline not found")
+		}
+		processingId, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, "Invalid processing ID")
+		}
+
+		deletedObjects, err := registry.DeleteProcessing(pipeline.GetSlug(), processingId)
+		if errors.Is(err, registries.ErrProcessingNotFound) {
+			return c.JSON(http.StatusNotFound, "Processing not found")
+		}
+		if errors.Is(err, registries.ErrProcessingNotFinished) {
+			return c.JSON(http.StatusConflict, err.Error())
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(
+			http.StatusOK,
+			schemas.PipelineProcessingDeleteOutputSchema{
+				ProcessingID:   processingId,
+				DeletedObjects: deletedObjects,
+			},
+		)
+	}
+}
+
 // @Summary Get pipeline Processing Details
 // @Description Returns a JSON object of the pipeline Processing Details.
 // @Tags pipelines
@@ -169,6 +431,9 @@ func PipelineStartHandler(registry interfaces.PipelineRegistry) echo.HandlerFunc
 		inputData.Pipeline.Slug = c.Param("slug")
 
 		processingId, err := registry.StartPipeline(inputData)
+		if errors.Is(err, registries.ErrBudgetExceeded) {
+			return c.JSON(http.StatusTooManyRequests, err.Error())
+		}
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, err.Error())
 		}
@@ -182,6 +447,151 @@ func PipelineStartHandler(registry interfaces.PipelineRegistry) echo.HandlerFunc
 	}
 }
 
+// @Summary Fork a pipeline Processing
+// @Description Starts a new processing from the given block with overridden input.
+// @Description Outputs of the previous blocks are copied from the source processing.
+// @Tags pipelines
+// @Accept json, multipart/form-data
+// @Produce json
+// @Param slug path string true "Pipeline slug"
+// @Param id path string true "Source Processing ID"
+// @Param input body schemas.PipelineForkInputSchema true "Block to start from and its input"
+// @Success 200 {object} schemas.PipelineForkOutputSchema
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Pipeline or Processing not found"
+// @Router /pipelines/{slug}/processings/{id}/fork [post]
+func PipelineProcessingForkHandler(registry interfaces.PipelineRegistry) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		pipeline := registry.Get(c.Param("slug"))
+		if pipeline == nil {
+			return c.JSON(http.StatusNotFound, "Pipeline not found")
+		}
+		sourceProcessingId, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, "Invalid processing ID")
+		}
+
+		var forkData schemas.PipelineForkInputSchema
+
+		// Check the Content-Type to determine how to bind the request
+		contentType := c.Request().Header.Get("Content-Type")
+		switch {
+		case contentType == "application/json":
+			if err := c.Bind(&forkData); err != nil {
+				return c.JSON(http.StatusBadRequest, err.Error())
+			}
+		case strings.HasPrefix(contentType, "multipart/form-data"):
+			if err := c.Request().ParseMultipartForm(10 << 20); err != nil {
+				return c.JSON(http.StatusBadRequest, "Unable to parse multipart form")
+			}
+
+			if err := forkData.ParseForm(c.Request()); err != nil {
+				return c.JSON(http.StatusBadRequest, fmt.Sprintf("Error parsing pipeline data: %v", err))
+			}
+		default:
+			// Unsupported content type
+			return c.JSON(http.StatusBadRequest, "Unsupported Content-Type")
+		}
+
+		processingId, err := registry.ForkPipeline(
+			sourceProcessingId,
+			schemas.PipelineStartInputSchema{
+				Pipeline: schemas.PipelineInputSchema{
+					Slug: pipeline.GetSlug(),
+				},
+				Block:    forkData.Block,
+				Callback: forkData.Callback,
+			},
+		)
+		if errors.Is(err, registries.ErrProcessingNotFound) {
+			return c.JSON(http.StatusNotFound, "Processing not found")
+		}
+		if errors.Is(err, registries.ErrBudgetExceeded) {
+			return c.JSON(http.StatusTooManyRequests, err.Error())
+		}
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+
+		return c.JSON(
+			http.StatusOK,
+			schemas.PipelineForkOutputSchema{
+				ProcessingID:       processingId,
+				ParentProcessingID: sourceProcessingId,
+			},
+		)
+	}
+}
+
+// @Summary Retry a failed pipeline Processing
+// @Description Resumes the processing from the first failed or incomplete block with its original input.
+// @Description Optionally only the failed indexes of the block are retried.
+// @Tags pipelines
+// @Accept json, multipart/form-data
+// @Produce json
+// @Param slug path string true "Pipeline slug"
+// @Param id path string true "Processing ID"
+// @Param input body schemas.PipelineRetryInputSchema false "Retry options"
+// @Success 200 {object} schemas.PipelineRetryOutputSchema
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Pipeline or Processing not found"
+// @Router /pipelines/{slug}/processings/{id}/retry [post]
+func PipelineProcessingRetryHandler(registry interfaces.PipelineRegistry) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		pipeline := registry.Get(c.Param("slug"))
+		if pipeline == nil {
+			return c.JSON(http.StatusNotFound, "Pipeline not found")
+		}
+		processingId, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, "Invalid processing ID")
+		}
+
+		var retryData schemas.PipelineRetryInputSchema
+
+		// Check the Content-Type to determine how to bind the request
+		contentType := c.Request().Header.Get("Content-Type")
+		switch {
+		case contentType == "":
+			// Retry with default options
+		case contentType == "application/json":
+			if err := c.Bind(&retryData); err != nil {
+				return c.JSON(http.StatusBadRequest, err.Error())
+			}
+		case strings.HasPrefix(contentType, "multipart/form-data"):
+			if err := c.Request().ParseMultipartForm(10 << 20); err != nil {
+				return c.JSON(http.StatusBadRequest, "Unable to parse multipart form")
+			}
+
+			if err := retryData.ParseForm(c.Request()); err != nil {
+				return c.JSON(http.StatusBadRequest, fmt.Sprintf("Error parsing retry data: %v", err))
+			}
+		default:
+			// Unsupported content type
+			return c.JSON(http.StatusBadRequest, "Unsupported Content-Type")
+		}
+
+		if retryData.Callback != nil {
+			if err := retryData.Callback.Validate(); err != nil {
+				return c.JSON(http.StatusBadRequest, err.Error())
+			}
+		}
+
+		result, err := registry.RetryPipeline(pipeline.GetSlug(), processingId, retryData)
+		if errors.Is(err, registries.ErrProcessingNotFound) {
+			return c.JSON(http.StatusNotFound, "Processing not found")
+		}
+		if errors.Is(err, registries.ErrBudgetExceeded) {
+			return c.JSON(http.StatusTooManyRequests, err.Error())
+		}
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
 // @Summary Resume a paused pipeline
 // @Description Resumes a paused pipeline with the given input data and returns the processing ID.
 // @Tags pipelines
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"data-pipelines-worker/types/interfaces"
+)
+
+// @Summary Apply the retention policy
+// @Description Deletes expired processing artifacts according to the retention policy and returns the report.
+// @Description Nothing is deleted unless dry_run is false.
+// @Tags retention
+// @Accept json
+// @Produce json
+// @Param dry_run query bool false "Only report the artifacts to delete" default(true)
+// @Success 200 {object} schemas.RetentionReportSchema
+// @Failure 400 {string} string "Bad request"
+// @Router /retention [post]
+func RetentionHandler(manager interfaces.RetentionManager) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		dryRun := true
+		if value := c.QueryParam("dry_run"); value != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(value); err != nil {
+				return c.JSON(http.StatusBadRequest, "Invalid dry_run")
+			}
+		}
+
+		return c.JSON(http.StatusOK, manager.Apply(time.Now(), dryRun))
+	}
+}
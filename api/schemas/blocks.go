@@ -0,0 +1,8 @@
+package schemas
+
+// BlockVersionSchema is a registered version of the block
+type BlockVersionSchema struct {
+	Version    string `json:"version" example:"1"`
+	Deprecated bool   `json:"deprecated" example:"false"`
+	Available  bool   `json:"available" example:"true"`
+}
@@ -0,0 +1,59 @@
+package schemas
+
+// OutputMetadataSchema represents what is known of a block output besides its content.
+// It is saved next to the output and is available to the `input_config` of the next blocks.
+//
+// swagger:model
+type OutputMetadataSchema struct {
+	// MIME type of the content
+	// example: "audio/mpeg"
+	ContentType string `json:"content_type,omitempty"`
+
+	// Name of the file the content is known by
+	// example: "segment001.mp3"
+	FileName string `json:"file_name,omitempty"`
+
+	// Duration of the audio or video in seconds
+	// example: 600
+	Duration float64 `json:"duration,omitempty"`
+
+	// Width of the image or video in pixels
+	// example: 1024
+	Width int `json:"width,omitempty"`
+
+	// Height of the image or video in pixels
+	// example: 768
+	Height int `json:"height,omitempty"`
+
+	// Other values of the output by name
+	// example: {"language": "en"}
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Merge fills the values missing in the metadata with the values of the other metadata
+func (m OutputMetadataSchema) Merge(other OutputMetadataSchema) OutputMetadataSchema {
+	if m.ContentType == "" {
+		m.ContentType = other.ContentType
+	}
+	if m.FileName == "" {
+		m.FileName = other.FileName
+	}
+	if m.Duration == 0 {
+		m.Duration = other.Duration
+	}
+	if m.Width == 0 && m.Height == 0 {
+		m.Width, m.Height = other.Width, other.Height
+	}
+	if len(other.Metadata) > 0 {
+		metadata := make(map[string]interface{}, len(m.Metadata)+len(other.Metadata))
+		for key, value := range other.Metadata {
+			metadata[key] = value
+		}
+		for key, value := range m.Metadata {
+			metadata[key] = value
+		}
+		m.Metadata = metadata
+	}
+
+	return m
+}
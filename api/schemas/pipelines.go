@@ -6,8 +6,11 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -25,9 +28,22 @@ type PipelineInputSchema struct {
 	// The unique processing ID associated with this pipeline
 	// example: "d9b2d63d5f23e4d76b7f3f2f25d93a7a"
 	ProcessingID uuid.UUID `json:"processing_id,omitempty"`
+
+	// The processing ID this processing was forked from. Set by the fork endpoint
+	// example: "6c2d6978-7364-441e-bb0f-aa7c3efd4ad2"
+	ParentProcessingID uuid.UUID `json:"parent_processing_id,omitempty"`
+
+	// The version of the pipeline (optional)
+	// New processings use the default version, resumed ones the version they were started with
+	// example: "2"
+	Version string `json:"version,omitempty"`
+
+	// The input parameters declared by the input schema of the pipeline (optional)
+	// example: {"topic": "October 24", "duration": 30}
+	Input map[string]interface{} `json:"input,omitempty"`
 }
 
-func (p *PipelineInputSchema) ParseForm(form map[string][]string) error {
+func (p *PipelineInputSchema) ParseForm(form map[string][]string, files map[string]*multipart.FileHeader) error {
 	// Check for the `pipeline.slug` field and set it
 	if slug, exists := form["pipeline.slug"]; exists && len(slug) > 0 {
 		p.Slug = slug[0]
@@ -46,6 +62,50 @@ func (p *PipelineInputSchema) ParseForm(form map[string][]string) error {
 		p.ProcessingID = parsedID
 	}
 
+	if version, exists := form["pipeline.version"]; exists && len(version) > 0 {
+		p.Version = version[0]
+	}
+
+	// Form values are cast to the declared types of the parameters when the pipeline is started
+	for key, value := range form {
+		if !strings.HasPrefix(key, "pipeline.input.") {
+			continue
+		}
+		if p.Input == nil {
+			p.Input = make(map[string]interface{})
+		}
+
+		fieldName := strings.TrimPrefix(key, "pipeline.input.")
+		if strings.HasSuffix(fieldName, "[]") {
+			p.Input[strings.TrimSuffix(fieldName, "[]")] = append([]string{}, value...)
+		} else {
+			p.Input[fieldName] = value[0]
+		}
+	}
+
+	// Uploaded files are mapped onto the file parameters
+	for key, fileHeader := range files {
+		if !strings.HasPrefix(key, "pipeline.input.") {
+			continue
+		}
+		if p.Input == nil {
+			p.Input = make(map[string]interface{})
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %v", key, err)
+		}
+		defer file.Close()
+
+		fileBytes, err := io.ReadAll(file)
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %v", key, err)
+		}
+
+		p.Input[strings.TrimPrefix(key, "pipeline.input.")] = fileBytes
+	}
+
 	return nil
 }
 
@@ -70,6 +130,11 @@ type BlockInputSchema struct {
 	// example: 5
 	TargetIndex int `json:"target_index,omitempty,string"`
 
+	// The target indexes for the block (optional)
+	// Processed along with the target index
+	// example: [1, 3]
+	TargetIndexes []int `json:"target_indexes,omitempty"`
+
 	// The destination slug for the block (optional)
 	// example: "destination-block"
 	DestinationSlug string `json:"destination_slug,omitempty"`
@@ -97,6 +162,17 @@ func (b *BlockInputSchema) ParseForm(form map[string][]string, files map[string]
 		b.TargetIndex = -1
 	}
 
+	// Parse the target indexes
+	if targetIndexes, exists := form["block.target_indexes[]"]; exists {
+		for _, targetIndex := range targetIndexes {
+			value, err := strconv.Atoi(targetIndex)
+			if err != nil {
+				return fmt.Errorf("invalid block.target_indexes: %v", err)
+			}
+			b.TargetIndexes = append(b.TargetIndexes, value)
+		}
+	}
+
 	// Parse the destination slug
 	if destinationSlug, exists := form["block.destination_slug"]; exists && len(destinationSlug) > 0 {
 		b.DestinationSlug = destinationSlug[0]
@@ -153,6 +229,16 @@ func (b *BlockInputSchema) ParseForm(form map[string][]string, files map[string]
 	return nil
 }
 
+// HasTargetIndex reports whether only the target indexes of the block must be processed
+func (b *BlockInputSchema) HasTargetIndex() bool {
+	return b.TargetIndex >= 0 || len(b.TargetIndexes) > 0
+}
+
+// IsTargetIndex reports whether the block input index must be processed
+func (b *BlockInputSchema) IsTargetIndex(index int) bool {
+	return index == b.TargetIndex || slices.Contains(b.TargetIndexes, index)
+}
+
 // UnmarshalJSON for BlockInputSchema to handle empty string for TargetIndex
 func (b *BlockInputSchema) UnmarshalJSON(data []byte) error {
 	type Alias BlockInputSchema
@@ -182,6 +268,79 @@ func (b *BlockInputSchema) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+const (
+	PIPELINE_EVENT_COMPLETED       = "completed"
+	PIPELINE_EVENT_STOPPED         = "stopped"
+	PIPELINE_EVENT_FAILED          = "failed"
+	PIPELINE_EVENT_MODERATION_WAIT = "moderation_wait"
+)
+
+var PIPELINE_EVENTS = []string{
+	PIPELINE_EVENT_COMPLETED,
+	PIPELINE_EVENT_STOPPED,
+	PIPELINE_EVENT_FAILED,
+	PIPELINE_EVENT_MODERATION_WAIT,
+}
+
+// PipelineCallbackSchema represents the webhook which is notified about the processing events.
+// Payloads are signed with HMAC-SHA256 of the Secret if it is set.
+//
+// swagger:model
+type PipelineCallbackSchema struct {
+	// The URL to POST the event payload to
+	// required: true
+	// example: "https://example.com/hooks/pipelines"
+	URL string `json:"url"`
+
+	// The events to receive. All events are sent if omitted
+	// example: ["completed", "failed"]
+	Events []string `json:"events,omitempty"`
+
+	// The secret used to sign the payload
+	// example: "my-webhook-secret"
+	Secret string `json:"secret,omitempty"`
+}
+
+func (c *PipelineCallbackSchema) ParseForm(form map[string][]string) error {
+	if callbackUrl, exists := form["callback.url"]; exists && len(callbackUrl) > 0 {
+		c.URL = callbackUrl[0]
+	}
+	if events, exists := form["callback.events[]"]; exists {
+		c.Events = append(c.Events, events...)
+	}
+	if secret, exists := form["callback.secret"]; exists && len(secret) > 0 {
+		c.Secret = secret[0]
+	}
+
+	return c.Validate()
+}
+
+// Validate checks the callback URL and the events
+func (c *PipelineCallbackSchema) Validate() error {
+	parsedUrl, err := url.Parse(c.URL)
+	if err != nil || parsedUrl.Host == "" ||
+		(parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") {
+		return fmt.Errorf("invalid callback url %s", c.URL)
+	}
+
+	for _, event := range c.Events {
+		if !slices.Contains(PIPELINE_EVENTS, event) {
+			return fmt.Errorf(
+				"invalid callback event %s. Allowed events: %s",
+				event,
+				strings.Join(PIPELINE_EVENTS, ", "),
+			)
+		}
+	}
+
+	return nil
+}
+
+// IsSubscribed checks if the callback should receive the event
+func (c *PipelineCallbackSchema) IsSubscribed(event string) bool {
+	return len(c.Events) == 0 || slices.Contains(c.Events, event)
+}
+
 // PipelineStartInputSchema represents the structure of the entire JSON payload.
 // It contains the `Pipeline` and `Block` data, which are used to start the pipeline process.
 //
@@ -191,9 +350,12 @@ type PipelineStartInputSchema struct {
 	// required: true
 	Pipeline PipelineInputSchema `json:"pipeline"`
 
-	// The block information, represented by the BlockInputSchema model
-	// required: true
+	// The block information, represented by the BlockInputSchema model.
+	// Pipelines started with the input parameters only are processed from the first block
 	Block BlockInputSchema `json:"block"`
+
+	// The webhook to notify about the processing events (optional)
+	Callback *PipelineCallbackSchema `json:"callback,omitempty"`
 }
 
 func (p *PipelineStartInputSchema) ParseForm(r *http.Request) error {
@@ -215,11 +377,21 @@ func (p *PipelineStartInputSchema) ParseForm(r *http.Request) error {
 	}
 
 	// Parse the pipeline and block using their respective methods
-	if err := p.Pipeline.ParseForm(r.Form); err != nil {
+	if err := p.Pipeline.ParseForm(r.Form, files); err != nil {
 		return fmt.Errorf("error parsing pipeline: %v", err)
 	}
-	if err := p.Block.ParseForm(r.Form, files); err != nil {
-		return fmt.Errorf("error parsing block: %v", err)
+	if _, exists := r.Form["block.slug"]; exists || len(p.Pipeline.Input) == 0 {
+		if err := p.Block.ParseForm(r.Form, files); err != nil {
+			return fmt.Errorf("error parsing block: %v", err)
+		}
+	} else {
+		p.Block.TargetIndex = -1
+	}
+	if callbackUrl, exists := r.Form["callback.url"]; exists && len(callbackUrl) > 0 {
+		p.Callback = &PipelineCallbackSchema{}
+		if err := p.Callback.ParseForm(r.Form); err != nil {
+			return fmt.Errorf("error parsing callback: %v", err)
+		}
 	}
 	return nil
 }
@@ -253,6 +425,62 @@ type PipelineStartOutputSchema struct {
 	ProcessingID uuid.UUID `json:"processing_id"`
 }
 
+// PipelineForkInputSchema represents the structure of the JSON payload to fork a processing.
+// The Block is the first block to rerun, outputs of all previous blocks are copied
+// from the source processing.
+//
+// swagger:model
+type PipelineForkInputSchema struct {
+	// The block information, represented by the BlockInputSchema model
+	// required: true
+	Block BlockInputSchema `json:"block"`
+
+	// The webhook to notify about the processing events (optional)
+	Callback *PipelineCallbackSchema `json:"callback,omitempty"`
+}
+
+func (p *PipelineForkInputSchema) ParseForm(r *http.Request) error {
+	if r.MultipartForm == nil {
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			return fmt.Errorf("unable to parse multipart form: %v", err)
+		}
+	}
+
+	files := make(map[string]*multipart.FileHeader)
+	for key, headers := range r.MultipartForm.File {
+		if len(headers) > 0 {
+			files[key] = headers[0]
+		}
+	}
+
+	if err := p.Block.ParseForm(r.Form, files); err != nil {
+		return fmt.Errorf("error parsing block: %v", err)
+	}
+	if callbackUrl, exists := r.Form["callback.url"]; exists && len(callbackUrl) > 0 {
+		p.Callback = &PipelineCallbackSchema{}
+		if err := p.Callback.ParseForm(r.Form); err != nil {
+			return fmt.Errorf("error parsing callback: %v", err)
+		}
+	}
+	return nil
+}
+
+// PipelineForkOutputSchema represents the structure of the output JSON
+// when a processing is forked.
+//
+// swagger:model
+type PipelineForkOutputSchema struct {
+	// The unique processing ID of the new processing
+	// required: true
+	// example: "d9b2d63d-5f23-e4d7-6b7f-3f2f25d93a7a"
+	ProcessingID uuid.UUID `json:"processing_id"`
+
+	// The processing ID of the source processing
+	// required: true
+	// example: "6c2d6978-7364-441e-bb0f-aa7c3efd4ad2"
+	ParentProcessingID uuid.UUID `json:"parent_processing_id"`
+}
+
 // PipelineResumeOutputSchema represents the structure of the output JSON
 // when resuming a pipeline. It includes the processing ID associated
 // with the pipeline that was previously started.
@@ -264,3 +492,339 @@ type PipelineResumeOutputSchema struct {
 	// example: "d9b2d63d-5f23-e4d7-6b7f-3f2f25d93a7a"
 	ProcessingID uuid.UUID `json:"processing_id"`
 }
+
+// PipelineRetryInputSchema represents the structure of the JSON payload to retry a processing.
+// The processing is resumed from the first failed or incomplete block.
+//
+// swagger:model
+type PipelineRetryInputSchema struct {
+	// Retry only the failed indexes of the block (optional)
+	// example: true
+	FailedIndexesOnly bool `json:"failed_indexes_only"`
+
+	// The webhook to notify about the processing events (optional)
+	Callback *PipelineCallbackSchema `json:"callback,omitempty"`
+}
+
+func (p *PipelineRetryInputSchema) ParseForm(r *http.Request) error {
+	if r.MultipartForm == nil {
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			return fmt.Errorf("unable to parse multipart form: %v", err)
+		}
+	}
+
+	if failedIndexesOnly, exists := r.Form["failed_indexes_only"]; exists && len(failedIndexesOnly) > 0 {
+		value, err := strconv.ParseBool(failedIndexesOnly[0])
+		if err != nil {
+			return fmt.Errorf("invalid failed_indexes_only: %v", err)
+		}
+		p.FailedIndexesOnly = value
+	}
+	if callbackUrl, exists := r.Form["callback.url"]; exists && len(callbackUrl) > 0 {
+		p.Callback = &PipelineCallbackSchema{}
+		if err := p.Callback.ParseForm(r.Form); err != nil {
+			return fmt.Errorf("error parsing callback: %v", err)
+		}
+	}
+	return nil
+}
+
+// PipelineRetryOutputSchema represents the structure of the output JSON
+// when a processing is retried.
+//
+// swagger:model
+type PipelineRetryOutputSchema struct {
+	// The unique processing ID of the retried processing
+	// required: true
+	// example: "d9b2d63d-5f23-e4d7-6b7f-3f2f25d93a7a"
+	ProcessingID uuid.UUID `json:"processing_id"`
+
+	// The slug of the block the processing is resumed from
+	// required: true
+	// example: "example-block"
+	BlockSlug string `json:"block_slug"`
+
+	// The block input indexes to retry, empty when all indexes are retried
+	// example: [1, 3]
+	TargetIndexes []int `json:"target_indexes,omitempty"`
+}
+
+// PipelineProcessingDeleteOutputSchema represents the structure of the output JSON
+// when processing artifacts are deleted.
+//
+// swagger:model
+type PipelineProcessingDeleteOutputSchema struct {
+	// The unique processing ID of the deleted processing
+	// required: true
+	// example: "d9b2d63d-5f23-e4d7-6b7f-3f2f25d93a7a"
+	ProcessingID uuid.UUID `json:"processing_id"`
+
+	// The deleted objects prefixed with the storage name
+	// example: ["local:example-pipeline/d9b2d63d-5f23-e4d7-6b7f-3f2f25d93a7a/log_1727000000.json"]
+	DeletedObjects []string `json:"deleted_objects"`
+}
+
+const (
+	RETENTION_ACTION_PURGE              = "purge"
+	RETENTION_ACTION_PURGE_INTERMEDIATE = "purge_intermediate"
+)
+
+// RetentionReportProcessingSchema represents the retention action applied to a processing
+//
+// swagger:model
+type RetentionReportProcessingSchema struct {
+	// example: "example-pipeline"
+	PipelineSlug string `json:"pipeline_slug"`
+
+	// example: "d9b2d63d-5f23-e4d7-6b7f-3f2f25d93a7a"
+	ProcessingID uuid.UUID `json:"processing_id"`
+
+	// The time of the last log or status of the processing
+	DateFinished time.Time `json:"date_finished"`
+
+	// The action applied to the processing: purge or purge_intermediate
+	// example: "purge_intermediate"
+	Action string `json:"action"`
+
+	// The objects deleted ( or to be deleted in dry run ) prefixed with the storage name
+	Objects []string `json:"objects"`
+}
+
+// RetentionReportSchema represents the result of the retention policy run
+//
+// swagger:model
+type RetentionReportSchema struct {
+	// Nothing is deleted in dry run
+	DryRun bool `json:"dry_run"`
+
+	// The time the retention policy is applied at
+	Date time.Time `json:"date"`
+
+	Processings []RetentionReportProcessingSchema `json:"processings"`
+
+	// Errors of the deletion
+	Errors []string `json:"errors,omitempty"`
+}
+
+const (
+	PROCESSING_STATUS_COMPLETED  = "completed"
+	PROCESSING_STATUS_STOPPED    = "stopped"
+	PROCESSING_STATUS_FAILED     = "failed"
+	PROCESSING_STATUS_INCOMPLETE = "incomplete"
+
+	PROCESSINGS_SORT_DATE_FINISHED      = "date_finished"
+	PROCESSINGS_SORT_DATE_FINISHED_DESC = "-date_finished"
+
+	PROCESSINGS_DEFAULT_LIMIT = 50
+	PROCESSINGS_MAX_LIMIT     = 1000
+)
+
+var PROCESSING_STATUSES = []string{
+	PROCESSING_STATUS_COMPLETED,
+	PROCESSING_STATUS_STOPPED,
+	PROCESSING_STATUS_FAILED,
+	PROCESSING_STATUS_INCOMPLETE,
+}
+
+// PipelineProcessingsQuerySchema represents the query of the Pipeline processings list.
+//
+// swagger:model
+type PipelineProcessingsQuerySchema struct {
+	// Status of the processings: completed, stopped, failed or incomplete (optional)
+	// example: "failed"
+	Status string `json:"status,omitempty"`
+
+	// Processings finished at or after the date, RFC3339 (optional)
+	// example: "2024-09-01T00:00:00Z"
+	From *time.Time `json:"from,omitempty"`
+
+	// Processings finished at or before the date, RFC3339 (optional)
+	// example: "2024-09-30T23:59:59Z"
+	To *time.Time `json:"to,omitempty"`
+
+	// Sorting by the finish date: date_finished or -date_finished (default)
+	// example: "-date_finished"
+	Sort string `json:"sort,omitempty"`
+
+	// Number of processings to skip
+	// example: 0
+	Offset int `json:"offset"`
+
+	// Maximum number of processings to return, 50 by default and 1000 at most
+	// example: 50
+	Limit int `json:"limit"`
+}
+
+func NewPipelineProcessingsQuerySchema() PipelineProcessingsQuerySchema {
+	return PipelineProcessingsQuerySchema{
+		Sort:  PROCESSINGS_SORT_DATE_FINISHED_DESC,
+		Limit: PROCESSINGS_DEFAULT_LIMIT,
+	}
+}
+
+func (q *PipelineProcessingsQuerySchema) ParseQuery(query url.Values) error {
+	if status := query.Get("status"); status != "" {
+		q.Status = status
+	}
+	for _, dateParam := range []struct {
+		name  string
+		value **time.Time
+	}{
+		{"from", &q.From},
+		{"to", &q.To},
+	} {
+		if rawDate := query.Get(dateParam.name); rawDate != "" {
+			date, err := time.Parse(time.RFC3339, rawDate)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %v", dateParam.name, err)
+			}
+			*dateParam.value = &date
+		}
+	}
+	if sort := query.Get("sort"); sort != "" {
+		q.Sort = sort
+	}
+	for _, intParam := range []struct {
+		name  string
+		value *int
+	}{
+		{"offset", &q.Offset},
+		{"limit", &q.Limit},
+	} {
+		if rawValue := query.Get(intParam.name); rawValue != "" {
+			value, err := strconv.Atoi(rawValue)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %v", intParam.name, err)
+			}
+			*intParam.value = value
+		}
+	}
+
+	return q.Validate()
+}
+
+func (q *PipelineProcessingsQuerySchema) Validate() error {
+	if q.Status != "" && !slices.Contains(PROCESSING_STATUSES, q.Status) {
+		return fmt.Errorf("invalid status %s, expected one of %s", q.Status, strings.Join(PROCESSING_STATUSES, ", "))
+	}
+	if q.Sort != PROCESSINGS_SORT_DATE_FINISHED && q.Sort != PROCESSINGS_SORT_DATE_FINISHED_DESC {
+		return fmt.Errorf(
+			"invalid sort %s, expected %s or %s",
+			q.Sort,
+			PROCESSINGS_SORT_DATE_FINISHED,
+			PROCESSINGS_SORT_DATE_FINISHED_DESC,
+		)
+	}
+	if q.From != nil && q.To != nil && q.From.After(*q.To) {
+		return fmt.Errorf("from must not be after to")
+	}
+	if q.Offset < 0 {
+		return fmt.Errorf("offset must not be negative")
+	}
+	if q.Limit <= 0 || q.Limit > PROCESSINGS_MAX_LIMIT {
+		return fmt.Errorf("limit must be between 1 and %d", PROCESSINGS_MAX_LIMIT)
+	}
+
+	return nil
+}
+
+// PipelineProcessingsListSchema represents a page of the Pipeline processings.
+//
+// swagger:model
+type PipelineProcessingsListSchema struct {
+	// Number of processings matching the query
+	// example: 120
+	Total int `json:"total"`
+
+	// example: 0
+	Offset int `json:"offset"`
+
+	// example: 50
+	Limit int `json:"limit"`
+
+	// Statuses of the processings
+	Processings interface{} `json:"processings" swaggertype:"array,object"`
+}
+
+// PipelineProcessingsReindexOutputSchema represents the result of the Pipeline processings reindexing.
+//
+// swagger:model
+type PipelineProcessingsReindexOutputSchema struct {
+	// Number of indexed processing logs
+	// example: 120
+	Indexed int `json:"indexed"`
+}
+
+// PipelineCatalogueErrorSchema represents an invalid file of the Pipelines catalogue.
+//
+// swagger:model
+type PipelineCatalogueErrorSchema struct {
+	// The catalogue file
+	// example: "config/pipelines/openai-podcast-summary.json"
+	File string `json:"file"`
+
+	// The slug of the Pipeline defined in the file if it could be read
+	// example: "openai-podcast-summary"
+	Slug string `json:"slug,omitempty"`
+
+	// The version of the Pipeline defined in the file if it could be read
+	// example: "2"
+	Version string `json:"version,omitempty"`
+
+	// example: "Pipeline schema is invalid for pipeline: openai-podcast-summary"
+	Error string `json:"error"`
+}
+
+// PipelineCatalogueReloadSchema represents the result of the Pipelines catalogue reload.
+//
+// swagger:model
+type PipelineCatalogueReloadSchema struct {
+	// The time the catalogue is reloaded at
+	Date time.Time `json:"date"`
+
+	// The version of the loaded catalogue files
+	// example: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	Version string `json:"version"`
+
+	// Slugs of the registered Pipelines
+	// example: ["openai-podcast-summary"]
+	Pipelines []string `json:"pipelines"`
+
+	// Slugs of the Pipelines removed from the catalogue
+	// example: ["openai-yt-short-generation"]
+	Removed []string `json:"removed"`
+
+	// Invalid catalogue files. Previous definitions of their Pipelines are kept
+	Errors []PipelineCatalogueErrorSchema `json:"errors"`
+}
+
+// PipelineVersionsSchema represents the versions of the Pipeline.
+//
+// swagger:model
+type PipelineVersionsSchema struct {
+	// The slug of the Pipeline
+	// required: true
+	// example: "openai-podcast-summary"
+	Slug string `json:"slug"`
+
+	// The version new processings are started with
+	// required: true
+	// example: "2"
+	DefaultVersion string `json:"default_version"`
+
+	// The registered versions ordered from the oldest
+	// required: true
+	// example: ["1", "2"]
+	Versions []string `json:"versions"`
+}
+
+// PipelineDefaultVersionInputSchema represents the structure of the JSON payload
+// to set the default version of the Pipeline.
+//
+// swagger:model
+type PipelineDefaultVersionInputSchema struct {
+	// The version new processings are started with
+	// required: true
+	// example: "2"
+	Version string `json:"version"`
+}
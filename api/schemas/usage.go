@@ -0,0 +1,107 @@
+package schemas
+
+import (
+	"time"
+)
+
+// UsageSchema represents the usage and the cost of the paid API calls.
+//
+// swagger:model
+type UsageSchema struct {
+	// Tokens of the chat completion prompts
+	// example: 1200
+	PromptTokens int `json:"prompt_tokens"`
+
+	// Tokens of the chat completion answers
+	// example: 300
+	CompletionTokens int `json:"completion_tokens"`
+
+	// Characters of the speech synthesis
+	// example: 1500
+	Characters int `json:"characters"`
+
+	// Seconds of the transcribed audio
+	// example: 62.5
+	Seconds float64 `json:"seconds"`
+
+	// Generated images
+	// example: 1
+	Images int `json:"images"`
+
+	// Cost by the price table
+	// example: 0.0645
+	Cost float64 `json:"cost"`
+}
+
+func (u *UsageSchema) Add(other UsageSchema) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.Characters += other.Characters
+	u.Seconds += other.Seconds
+	u.Images += other.Images
+	u.Cost += other.Cost
+}
+
+func (u UsageSchema) IsZero() bool {
+	return u == UsageSchema{}
+}
+
+// ProcessingUsageSchema represents the usage of a processing in total and by model.
+//
+// swagger:model
+type ProcessingUsageSchema struct {
+	UsageSchema
+
+	// Currency of the cost
+	// example: "USD"
+	Currency string `json:"currency,omitempty"`
+
+	// Usage by model
+	Models map[string]UsageSchema `json:"models,omitempty"`
+}
+
+// AddModelUsage adds the usage of a model call
+func (u *ProcessingUsageSchema) AddModelUsage(model string, usage UsageSchema) {
+	if u.Models == nil {
+		u.Models = make(map[string]UsageSchema)
+	}
+
+	modelUsage := u.Models[model]
+	modelUsage.Add(usage)
+	u.Models[model] = modelUsage
+	u.UsageSchema.Add(usage)
+}
+
+func (u *ProcessingUsageSchema) Add(other ProcessingUsageSchema) {
+	if u.Currency == "" {
+		u.Currency = other.Currency
+	}
+	for model, usage := range other.Models {
+		u.AddModelUsage(model, usage)
+	}
+}
+
+func (u ProcessingUsageSchema) IsZero() bool {
+	return len(u.Models) == 0 && u.UsageSchema.IsZero()
+}
+
+// PipelineUsageSchema represents the usage of the Pipeline processings matching the query.
+//
+// swagger:model
+type PipelineUsageSchema struct {
+	// example: "openai-yt-short-generation"
+	PipelineSlug string `json:"pipeline_slug"`
+
+	// Processings finished at or after the date
+	From *time.Time `json:"from,omitempty"`
+
+	// Processings finished at or before the date
+	To *time.Time `json:"to,omitempty"`
+
+	// Number of the processing logs with the usage
+	// example: 12
+	Processings int `json:"processings"`
+
+	// Total usage of the processings
+	Usage ProcessingUsageSchema `json:"usage"`
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	echoSwagger "github.com/swaggo/echo-swagger"
 
 	"data-pipelines-worker/api/handlers"
@@ -39,6 +40,11 @@ type Server struct {
 	pipelineRegistry   interfaces.PipelineRegistry
 	blockRegistry      interfaces.BlockRegistry
 	processingRegistry interfaces.ProcessingRegistry
+	triggerRegistry    interfaces.TriggerRegistry
+	retentionManager   interfaces.RetentionManager
+	indexRefresher     interfaces.ProcessingIndexRefresher
+	catalogueWatcher   interfaces.PipelineCatalogueWatcher
+	wasmWatcher        interfaces.WasmPluginsWatcher
 }
 
 func NewServer(_config config.Config) *Server {
@@ -50,7 +56,7 @@ func NewServer(_config config.Config) *Server {
 		workerRegistry,
 		blockRegistry,
 		processingRegistry,
-		dataclasses.NewPipelineCatalogueLoader(),
+		NewPipelineCatalogueLoader(_config),
 	)
 	if err != nil {
 		panic(err)
@@ -64,6 +70,12 @@ func NewServer(_config config.Config) *Server {
 		},
 	)
 
+	triggerRegistry := registries.NewTriggerRegistry(pipelineRegistry)
+	retentionManager := registries.NewRetentionManager(pipelineRegistry)
+	indexRefresher := registries.NewProcessingIndexRefresher(pipelineRegistry)
+	catalogueWatcher := registries.NewPipelineCatalogueWatcher(pipelineRegistry)
+	wasmWatcher := registries.NewWasmPluginsWatcher(blockRegistry)
+
 	_echo := echo.New()
 	_echo.HideBanner = true
 
@@ -79,6 +91,11 @@ func NewServer(_config config.Config) *Server {
 		pipelineRegistry:   pipelineRegistry,
 		blockRegistry:      blockRegistry,
 		processingRegistry: processingRegistry,
+		triggerRegistry:    triggerRegistry,
+		retentionManager:   retentionManager,
+		indexRefresher:     indexRefresher,
+		catalogueWatcher:   catalogueWatcher,
+		wasmWatcher:        wasmWatcher,
 		Ready:              make(chan struct{}, 1),
 	}
 	worker.echo.Use(middleware.Logger())
@@ -88,6 +105,23 @@ func NewServer(_config config.Config) *Server {
 	return worker
 }
 
+// NewPipelineCatalogueLoader returns the catalogue loader reading the configured catalogue storage
+func NewPipelineCatalogueLoader(_config config.Config) *dataclasses.PipelineCatalogueLoader {
+	catalogueLoader := dataclasses.NewPipelineCatalogueLoader()
+
+	switch _config.Pipeline.CatalogueStorage {
+	case "":
+	case "minio":
+		catalogueLoader.SetStorage(types.NewMINIOStorage())
+	case "local":
+		catalogueLoader.SetStorage(types.NewLocalStorage(_config.Storage.Local.RootPath))
+	default:
+		panic(fmt.Sprintf("unknown pipeline catalogue storage %s", _config.Pipeline.CatalogueStorage))
+	}
+
+	return catalogueLoader
+}
+
 func (s *Server) AddMiddleware(middleware ...echo.MiddlewareFunc) {
 	s.Lock()
 	defer s.Unlock()
@@ -114,6 +148,18 @@ func (s *Server) Start(ctx context.Context) {
 	s.mdns.Announce()
 	s.mdns.DiscoverWorkers()
 
+	if s.GetConfig().Triggers.Enabled {
+		s.GetTriggerRegistry().Start()
+	}
+	if s.GetConfig().Retention.Enabled {
+		s.GetRetentionManager().Start()
+	}
+	if s.GetConfig().ProcessingIndex.Enabled {
+		s.GetProcessingIndexRefresher().Start()
+	}
+	s.GetCatalogueWatcher().Start()
+	s.GetWasmPluginsWatcher().Start()
+
 	// Start server
 	go func() {
 		s.Ready <- struct{}{}
@@ -137,8 +183,14 @@ func (s *Server) Shutdown(timeout time.Duration) {
 
 	shutdownCalls := []func(context.Context) error{
 		s.mdns.Shutdown,
+		s.triggerRegistry.Shutdown,
+		s.retentionManager.Shutdown,
+		s.indexRefresher.Shutdown,
+		s.catalogueWatcher.Shutdown,
+		s.wasmWatcher.Shutdown,
 		s.blockRegistry.Shutdown,
 		s.pipelineRegistry.Shutdown,
+		dataclasses.WaitPipelineWebhookDeliveries,
 	}
 
 	for _, shutdownCall := range shutdownCalls {
@@ -239,6 +291,41 @@ func (s *Server) GetProcessingRegistry() interfaces.ProcessingRegistry {
 	return s.processingRegistry
 }
 
+func (s *Server) GetTriggerRegistry() interfaces.TriggerRegistry {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.triggerRegistry
+}
+
+func (s *Server) GetRetentionManager() interfaces.RetentionManager {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.retentionManager
+}
+
+func (s *Server) GetProcessingIndexRefresher() interfaces.ProcessingIndexRefresher {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.indexRefresher
+}
+
+func (s *Server) GetCatalogueWatcher() interfaces.PipelineCatalogueWatcher {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.catalogueWatcher
+}
+
+func (s *Server) GetWasmPluginsWatcher() interfaces.WasmPluginsWatcher {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.wasmWatcher
+}
+
 func (s *Server) SetAPIMiddlewares() {
 	s.AddMiddleware(
 		middleware.Logger(),
@@ -260,6 +347,7 @@ func (s *Server) SetAPIMiddlewares() {
 
 func (s *Server) SetAPIHandlers() {
 	s.AddHTTPAPIRoute("GET", "/health", handlers.HealthHandler)
+	s.AddHTTPAPIRoute("GET", "/metrics", echo.WrapHandler(promhttp.Handler()))
 	s.AddHTTPAPIRoute("GET", "/blocks", handlers.BlocksHandler(
 		s.GetBlockRegistry(),
 	))
@@ -269,24 +357,64 @@ func (s *Server) SetAPIHandlers() {
 	s.AddHTTPAPIRoute("GET", "/pipelines", handlers.PipelinesHandler(
 		s.GetPipelineRegistry(),
 	))
+	s.AddHTTPAPIRoute("POST", "/pipelines/reload", handlers.PipelinesReloadHandler(
+		s.GetPipelineRegistry(),
+	))
+	s.AddHTTPAPIRoute("GET", "/catalogue", handlers.PipelinesCatalogueHandler(
+		s.GetPipelineRegistry(),
+	))
 	s.AddHTTPAPIRoute("GET", "/pipelines/:slug", handlers.PipelineHandler(
 		s.GetPipelineRegistry(),
 	))
+	s.AddHTTPAPIRoute("GET", "/pipelines/:slug/versions", handlers.PipelineVersionsHandler(
+		s.GetPipelineRegistry(),
+	))
+	s.AddHTTPAPIRoute("PUT", "/pipelines/:slug/versions/default", handlers.PipelineDefaultVersionHandler(
+		s.GetPipelineRegistry(),
+	))
+	s.AddHTTPAPIRoute("GET", "/pipelines/:slug/triggers", handlers.PipelineTriggersHandler(
+		s.GetPipelineRegistry(),
+		s.GetTriggerRegistry(),
+	))
 	s.AddHTTPAPIRoute("GET", "/pipelines/:slug/processings/:id/:log-id", handlers.PipelineProcessingDetailsByLogIdHandler(
 		s.GetPipelineRegistry(),
 	))
 	s.AddHTTPAPIRoute("GET", "/pipelines/:slug/processings/:id", handlers.PipelineProcessingDetailsHandler(
 		s.GetPipelineRegistry(),
 	))
+	s.AddHTTPAPIRoute("DELETE", "/pipelines/:slug/processings/:id", handlers.PipelineProcessingDeleteHandler(
+		s.GetPipelineRegistry(),
+	))
 	s.AddHTTPAPIRoute("GET", "/pipelines/:slug/processings", handlers.PipelineProcessingsStatusHandler(
 		s.GetPipelineRegistry(),
 	))
+	s.AddHTTPAPIRoute("GET", "/pipelines/:slug/usage", handlers.PipelineUsageHandler(
+		s.GetPipelineRegistry(),
+	))
 	s.AddHTTPAPIRoute(
 		"POST", "/pipelines/:slug/start",
 		handlers.PipelineStartHandler(
 			s.GetPipelineRegistry(),
 		),
 	)
+	s.AddHTTPAPIRoute(
+		"POST", "/pipelines/:slug/processings/reindex",
+		handlers.PipelineProcessingsReindexHandler(
+			s.GetPipelineRegistry(),
+		),
+	)
+	s.AddHTTPAPIRoute(
+		"POST", "/pipelines/:slug/processings/:id/fork",
+		handlers.PipelineProcessingForkHandler(
+			s.GetPipelineRegistry(),
+		),
+	)
+	s.AddHTTPAPIRoute(
+		"POST", "/pipelines/:slug/processings/:id/retry",
+		handlers.PipelineProcessingRetryHandler(
+			s.GetPipelineRegistry(),
+		),
+	)
 	s.AddHTTPAPIRoute(
 		"POST", "/pipelines/:slug/resume",
 		handlers.PipelineResumeHandler(
@@ -294,6 +422,13 @@ func (s *Server) SetAPIHandlers() {
 		),
 	)
 
+	s.AddHTTPAPIRoute(
+		"POST", "/retention",
+		handlers.RetentionHandler(
+			s.GetRetentionManager(),
+		),
+	)
+
 	if s.GetConfig().Swagger {
 		s.AddHTTPAPIRoute(
 			"GET", "/swagger/*",
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"data-pipelines-worker/types/config"
+)
+
+// Encrypts the JSON object of secrets read from stdin into the encrypted secrets file
+// written to stdout. The passphrase is read from the environment variable
+func main() {
+	keyEnvVar := flag.String("key-env-var", config.DEFAULT_SECRETS_ENCRYPTION_KEY_ENV_VAR, "Environment variable with the passphrase")
+	flag.Parse()
+
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read secrets: %s\n", err)
+		os.Exit(1)
+	}
+
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(input, &secrets); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse secrets: %s\n", err)
+		os.Exit(1)
+	}
+
+	content, err := config.EncryptSecrets(secrets, os.Getenv(*keyEnvVar))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to encrypt secrets: %s\n", err)
+		os.Exit(1)
+	}
+
+	os.Stdout.Write(content)
+}
@@ -39,6 +39,29 @@ const docTemplate = `{
                 }
             }
         },
+        "/catalogue": {
+            "get": {
+                "description": "Returns the result of the last catalogue reload including errors of the invalid files.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pipelines"
+                ],
+                "summary": "Get the pipelines catalogue status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.PipelineCatalogueReloadSchema"
+                        }
+                    }
+                }
+            }
+        },
         "/health": {
             "get": {
                 "description": "Responds with a simple \"OK\" message to indicate that the service is healthy.",
@@ -88,9 +111,38 @@ const docTemplate = `{
                 }
             }
         },
+        "/pipelines/reload": {
+            "post": {
+                "description": "Loads the catalogue files and swaps the registered pipelines at once.\nInvalid files are reported with their errors and previous definitions of their pipelines are kept.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pipelines"
+                ],
+                "summary": "Reload the pipelines catalogue",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.PipelineCatalogueReloadSchema"
+                        }
+                    },
+                    "500": {
+                        "description": "Catalogue loading failed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/pipelines/{slug}": {
             "get": {
-                "description": "Returns a JSON object of the pipeline with the given slug.",
+                "description": "Returns a JSON object of the pipeline with the given slug.\nThe default version is returned unless the version is set.",
                 "consumes": [
                     "application/json"
                 ],
@@ -108,6 +160,12 @@ const docTemplate = `{
                         "name": "slug",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pipeline version",
+                        "name": "version",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -128,7 +186,7 @@ const docTemplate = `{
         },
         "/pipelines/{slug}/processings/": {
             "get": {
-                "description": "Returns a JSON object of the pipeline Processings.",
+                "description": "Returns a page of the pipeline Processings statuses.",
                 "consumes": [
                     "application/json"
                 ],
@@ -146,19 +204,108 @@ const docTemplate = `{
                         "name": "slug",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Processing status: completed, stopped, failed or incomplete",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Processings finished at or after the date ( RFC3339 )",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Processings finished at or before the date ( RFC3339 )",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sorting: date_finished or -date_finished ( default )",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of processings to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of processings ( 50 by default )",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "array",
-                                "items": {
-                                    "$ref": "#/definitions/dataclasses.PipelineProcessingStatus"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/schemas.PipelineProcessingsListSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "processings": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/dataclasses.PipelineProcessingStatus"
+                                            }
+                                        }
+                                    }
                                 }
-                            }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Pipeline not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pipelines/{slug}/processings/reindex": {
+            "post": {
+                "description": "Rebuilds the index of the pipeline Processings logs and statuses from the storages.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pipelines"
+                ],
+                "summary": "Reindex pipeline Processings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Pipeline slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.PipelineProcessingsReindexOutputSchema"
                         }
                     },
                     "404": {
@@ -166,6 +313,12 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "500": {
+                        "description": "Reindexing failed",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
@@ -216,11 +369,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/pipelines/{slug}/processings/{id}/{log-id}": {
-            "get": {
-                "description": "Returns a JSON object of the pipeline Processing Details.",
+            },
+            "delete": {
+                "description": "Removes all artifacts ( outputs, logs and statuses ) of the processing in every storage.",
                 "consumes": [
                     "application/json"
                 ],
@@ -230,7 +381,7 @@ const docTemplate = `{
                 "tags": [
                     "pipelines"
                 ],
-                "summary": "Get pipeline Processing Details by Log Id",
+                "summary": "Delete pipeline Processing",
                 "parameters": [
                     {
                         "type": "string",
@@ -245,24 +396,35 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Log ID",
-                        "name": "log-id",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dataclasses.PipelineProcessingDetails"
+                            "$ref": "#/definitions/schemas.PipelineProcessingDeleteOutputSchema"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "404": {
-                        "description": "Pipeline not found",
+                        "description": "Pipeline or Processing not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Processing is not finished",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Deletion failed",
                         "schema": {
                             "type": "string"
                         }
@@ -270,9 +432,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/pipelines/{slug}/resume": {
+        "/pipelines/{slug}/processings/{id}/fork": {
             "post": {
-                "description": "Resumes a paused pipeline with the given input data and returns the processing ID.",
+                "description": "Starts a new processing from the given block with overridden input.\nOutputs of the previous blocks are copied from the source processing.",
                 "consumes": [
                     "application/json",
                     " multipart/form-data"
@@ -283,7 +445,7 @@ const docTemplate = `{
                 "tags": [
                     "pipelines"
                 ],
-                "summary": "Resume a paused pipeline",
+                "summary": "Fork a pipeline Processing",
                 "parameters": [
                     {
                         "type": "string",
@@ -293,12 +455,19 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Input data to resume the pipeline",
+                        "type": "string",
+                        "description": "Source Processing ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Block to start from and its input",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/schemas.PipelineStartInputSchema"
+                            "$ref": "#/definitions/schemas.PipelineForkInputSchema"
                         }
                     }
                 ],
@@ -306,7 +475,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/schemas.PipelineResumeOutputSchema"
+                            "$ref": "#/definitions/schemas.PipelineForkOutputSchema"
                         }
                     },
                     "400": {
@@ -314,13 +483,19 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "404": {
+                        "description": "Pipeline or Processing not found",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/pipelines/{slug}/start": {
+        "/pipelines/{slug}/processings/{id}/retry": {
             "post": {
-                "description": "Starts the pipeline with the given input data and returns the processing ID.",
+                "description": "Resumes the processing from the first failed or incomplete block with its original input.\nOptionally only the failed indexes of the block are retried.",
                 "consumes": [
                     "application/json",
                     " multipart/form-data"
@@ -331,7 +506,7 @@ const docTemplate = `{
                 "tags": [
                     "pipelines"
                 ],
-                "summary": "Start a pipeline",
+                "summary": "Retry a failed pipeline Processing",
                 "parameters": [
                     {
                         "type": "string",
@@ -341,12 +516,18 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Input data to start the pipeline",
+                        "type": "string",
+                        "description": "Processing ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Retry options",
                         "name": "input",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/schemas.PipelineStartInputSchema"
+                            "$ref": "#/definitions/schemas.PipelineRetryInputSchema"
                         }
                     }
                 ],
@@ -354,7 +535,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/schemas.PipelineStartOutputSchema"
+                            "$ref": "#/definitions/schemas.PipelineRetryOutputSchema"
                         }
                     },
                     "400": {
@@ -362,13 +543,19 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "404": {
+                        "description": "Pipeline or Processing not found",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/workers": {
+        "/pipelines/{slug}/processings/{id}/{log-id}": {
             "get": {
-                "description": "Returns a JSON array of all discovered workers in the mDNS instance.",
+                "description": "Returns a JSON object of the pipeline Processing Details.",
                 "consumes": [
                     "application/json"
                 ],
@@ -376,172 +563,798 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "workers"
+                    "pipelines"
+                ],
+                "summary": "Get pipeline Processing Details by Log Id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Pipeline slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Processing ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Log ID",
+                        "name": "log-id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get all discovered workers",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/dataclasses.Worker"
-                            }
+                            "$ref": "#/definitions/dataclasses.PipelineProcessingDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Pipeline not found",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "dataclasses.PipelineData": {
-            "type": "object",
-            "properties": {
-                "blocks": {
-                    "description": "A list of blocks that make up the pipeline\nrequired: true",
-                    "type": "array",
-                    "items": {}
-                },
-                "description": {
-                    "description": "The description of the pipeline\nexample: \"This pipeline processes data in a series of blocks.\"",
-                    "type": "string"
-                },
-                "slug": {
-                    "description": "The unique slug identifier for the pipeline\nrequired: true\nexample: \"pipeline-abc123\"",
-                    "type": "string"
-                },
-                "title": {
-                    "description": "The title of the pipeline\nrequired: true\nexample: \"Example Pipeline\"",
-                    "type": "string"
-                }
-            }
         },
-        "dataclasses.PipelineProcessingDetails": {
-            "type": "object",
-            "properties": {
-                "date_finished": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "is_completed": {
-                    "type": "boolean"
-                },
-                "is_error": {
-                    "type": "boolean"
-                },
-                "is_stopped": {
-                    "type": "boolean"
-                },
-                "log_data": {
+        "/pipelines/{slug}/resume": {
+            "post": {
+                "description": "Resumes a paused pipeline with the given input data and returns the processing ID.",
+                "consumes": [
+                    "application/json",
+                    " multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pipelines"
+                ],
+                "summary": "Resume a paused pipeline",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Pipeline slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Input data to resume the pipeline",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.PipelineStartInputSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.PipelineResumeOutputSchema"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pipelines/{slug}/start": {
+            "post": {
+                "description": "Starts the pipeline with the given input data and returns the processing ID.",
+                "consumes": [
+                    "application/json",
+                    " multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pipelines"
+                ],
+                "summary": "Start a pipeline",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Pipeline slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Input data to start the pipeline",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.PipelineStartInputSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.PipelineStartOutputSchema"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pipelines/{slug}/triggers": {
+            "get": {
+                "description": "Returns a JSON array of the pipeline Triggers with their last and next run.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pipelines"
+                ],
+                "summary": "Get pipeline Triggers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Pipeline slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/registries.PipelineTriggerState"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Pipeline not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pipelines/{slug}/usage": {
+            "get": {
+                "description": "Returns the usage and the cost of the pipeline Processings matching the query.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pipelines"
+                ],
+                "summary": "Get pipeline Processings usage",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Pipeline slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Processing status: completed, stopped, failed or incomplete",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Processings finished at or after the date ( RFC3339 )",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Processings finished at or before the date ( RFC3339 )",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.PipelineUsageSchema"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Pipeline not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pipelines/{slug}/versions": {
+            "get": {
+                "description": "Returns the registered versions of the pipeline and the version new processings are started with.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pipelines"
+                ],
+                "summary": "Get pipeline versions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Pipeline slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.PipelineVersionsSchema"
+                        }
+                    },
+                    "404": {
+                        "description": "Pipeline not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pipelines/{slug}/versions/default": {
+            "put": {
+                "description": "Sets the version new processings of the pipeline are started with.\nProcessings already started keep their version.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pipelines"
+                ],
+                "summary": "Set the pipeline default version",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Pipeline slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Default version",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.PipelineDefaultVersionInputSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.PipelineVersionsSchema"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Pipeline or version not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/retention": {
+            "post": {
+                "description": "Deletes expired processing artifacts according to the retention policy and returns the report.\nNothing is deleted unless dry_run is false.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "retention"
+                ],
+                "summary": "Apply the retention policy",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "default": true,
+                        "description": "Only report the artifacts to delete",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.RetentionReportSchema"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/workers": {
+            "get": {
+                "description": "Returns a JSON array of all discovered workers in the mDNS instance.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workers"
+                ],
+                "summary": "Get all discovered workers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dataclasses.Worker"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dataclasses.PipelineData": {
+            "type": "object",
+            "properties": {
+                "blocks": {
+                    "description": "A list of blocks that make up the pipeline\nrequired: true",
+                    "type": "array",
+                    "items": {}
+                },
+                "description": {
+                    "description": "The description of the pipeline\nexample: \"This pipeline processes data in a series of blocks.\"",
+                    "type": "string"
+                },
+                "input_schema": {
+                    "description": "The JSON schema of the named input parameters the pipeline is started with.\nBlocks reference the parameters with the ` + "`" + `$pipeline.input` + "`" + ` origin",
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "slug": {
+                    "description": "The unique slug identifier for the pipeline\nrequired: true\nexample: \"pipeline-abc123\"",
+                    "type": "string"
+                },
+                "title": {
+                    "description": "The title of the pipeline\nrequired: true\nexample: \"Example Pipeline\"",
+                    "type": "string"
+                },
+                "triggers": {
+                    "description": "A list of schedules which start the pipeline automatically",
+                    "type": "array",
+                    "items": {}
+                },
+                "version": {
+                    "description": "The version of the pipeline. Processings are pinned to the version they are started with\nexample: \"2\"",
+                    "type": "string"
+                }
+            }
+        },
+        "dataclasses.PipelineProcessingDetails": {
+            "type": "object",
+            "properties": {
+                "date_finished": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_completed": {
+                    "type": "boolean"
+                },
+                "is_error": {
+                    "type": "boolean"
+                },
+                "is_stopped": {
+                    "type": "boolean"
+                },
+                "log_data": {
+                    "type": "array",
+                    "items": {
+                        "type": "object",
+                        "additionalProperties": true
+                    }
+                },
+                "log_id": {
+                    "type": "string"
+                },
+                "parent_block_slug": {
+                    "type": "string"
+                },
+                "parent_processing_id": {
+                    "type": "string"
+                },
+                "pipeline_slug": {
+                    "type": "string"
+                },
+                "pipeline_version": {
+                    "type": "string"
+                },
+                "storage": {
+                    "type": "string"
+                },
+                "usage": {
+                    "$ref": "#/definitions/schemas.ProcessingUsageSchema"
+                }
+            }
+        },
+        "dataclasses.PipelineProcessingStatus": {
+            "type": "object",
+            "properties": {
+                "date_finished": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_completed": {
+                    "type": "boolean"
+                },
+                "is_error": {
+                    "type": "boolean"
+                },
+                "is_stopped": {
+                    "type": "boolean"
+                },
+                "log_id": {
+                    "type": "string"
+                },
+                "parent_block_slug": {
+                    "type": "string"
+                },
+                "parent_processing_id": {
+                    "type": "string"
+                },
+                "pipeline_slug": {
+                    "type": "string"
+                },
+                "pipeline_version": {
+                    "type": "string"
+                },
+                "storage": {
+                    "type": "string"
+                },
+                "usage": {
+                    "$ref": "#/definitions/schemas.ProcessingUsageSchema"
+                }
+            }
+        },
+        "dataclasses.Worker": {
+            "type": "object",
+            "properties": {
+                "host": {
+                    "description": "The hostname of the worker\nexample: \"worker-hostname.local\"",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "The unique identifier for the worker\nexample: \"d9b2d63d-5f23-e4d7-6b7f-3f2f25d93a7a\"",
+                    "type": "string"
+                },
+                "ipv4": {
+                    "description": "The IPv4 address of the worker\nexample: \"192.168.1.10\"",
+                    "type": "string"
+                },
+                "ipv6": {
+                    "description": "The IPv6 address of the worker\nexample: \"fe80::a00:27ff:fe9e:3c28\"",
+                    "type": "string"
+                },
+                "port": {
+                    "description": "The port on which the worker is running\nexample: 8080",
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "The current status of the worker\nexample: {\"load\": 0.5, \"available\": true, \"version\": \"1.0.0\"}"
+                }
+            }
+        },
+        "registries.PipelineTriggerState": {
+            "type": "object",
+            "properties": {
+                "last_error": {
+                    "type": "string"
+                },
+                "last_processing_id": {
+                    "type": "string"
+                },
+                "last_run": {
+                    "type": "string"
+                },
+                "next_run": {
+                    "type": "string"
+                },
+                "pipeline_slug": {
+                    "type": "string"
+                },
+                "trigger": {}
+            }
+        },
+        "schemas.BlockInputSchema": {
+            "type": "object",
+            "properties": {
+                "destination_slug": {
+                    "description": "The destination slug for the block (optional)\nexample: \"destination-block\"",
+                    "type": "string"
+                },
+                "input": {
+                    "description": "The input data for the block, represented as a map of key-value pairs\nrequired: true\nexample: {\"key1\": \"value1\", \"key2\": 42}",
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "slug": {
+                    "description": "The slug of the block\nrequired: true\nexample: \"example-block\"",
+                    "type": "string"
+                },
+                "target_index": {
+                    "description": "The target index for the block (optional)\nIf omitted or empty, it defaults to -1\nexample: 5",
+                    "type": "string",
+                    "example": "0"
+                },
+                "target_indexes": {
+                    "description": "The target indexes for the block (optional)\nProcessed along with the target index\nexample: [1, 3]",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "schemas.PipelineCallbackSchema": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "description": "The events to receive. All events are sent if omitted\nexample: [\"completed\", \"failed\"]",
                     "type": "array",
                     "items": {
-                        "type": "object",
-                        "additionalProperties": true
+                        "type": "string"
                     }
                 },
-                "log_id": {
-                    "type": "string"
-                },
-                "pipeline_slug": {
+                "secret": {
+                    "description": "The secret used to sign the payload\nexample: \"my-webhook-secret\"",
                     "type": "string"
                 },
-                "storage": {
+                "url": {
+                    "description": "The URL to POST the event payload to\nrequired: true\nexample: \"https://example.com/hooks/pipelines\"",
                     "type": "string"
                 }
             }
         },
-        "dataclasses.PipelineProcessingStatus": {
+        "schemas.PipelineCatalogueErrorSchema": {
             "type": "object",
             "properties": {
-                "date_finished": {
-                    "type": "string"
-                },
-                "id": {
+                "error": {
+                    "description": "example: \"Pipeline schema is invalid for pipeline: openai-podcast-summary\"",
                     "type": "string"
                 },
-                "is_completed": {
-                    "type": "boolean"
-                },
-                "is_error": {
-                    "type": "boolean"
-                },
-                "is_stopped": {
-                    "type": "boolean"
-                },
-                "log_id": {
+                "file": {
+                    "description": "The catalogue file\nexample: \"config/pipelines/openai-podcast-summary.json\"",
                     "type": "string"
                 },
-                "pipeline_slug": {
+                "slug": {
+                    "description": "The slug of the Pipeline defined in the file if it could be read\nexample: \"openai-podcast-summary\"",
                     "type": "string"
                 },
-                "storage": {
+                "version": {
+                    "description": "The version of the Pipeline defined in the file if it could be read\nexample: \"2\"",
                     "type": "string"
                 }
             }
         },
-        "dataclasses.Worker": {
+        "schemas.PipelineCatalogueReloadSchema": {
             "type": "object",
             "properties": {
-                "host": {
-                    "description": "The hostname of the worker\nexample: \"worker-hostname.local\"",
+                "date": {
+                    "description": "The time the catalogue is reloaded at",
                     "type": "string"
                 },
-                "id": {
-                    "description": "The unique identifier for the worker\nexample: \"d9b2d63d-5f23-e4d7-6b7f-3f2f25d93a7a\"",
-                    "type": "string"
+                "errors": {
+                    "description": "Invalid catalogue files. Previous definitions of their Pipelines are kept",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.PipelineCatalogueErrorSchema"
+                    }
                 },
-                "ipv4": {
-                    "description": "The IPv4 address of the worker\nexample: \"192.168.1.10\"",
-                    "type": "string"
+                "pipelines": {
+                    "description": "Slugs of the registered Pipelines\nexample: [\"openai-podcast-summary\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "ipv6": {
-                    "description": "The IPv6 address of the worker\nexample: \"fe80::a00:27ff:fe9e:3c28\"",
-                    "type": "string"
+                "removed": {
+                    "description": "Slugs of the Pipelines removed from the catalogue\nexample: [\"openai-yt-short-generation\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "port": {
-                    "description": "The port on which the worker is running\nexample: 8080",
-                    "type": "integer"
+                "version": {
+                    "description": "The version of the loaded catalogue files\nexample: \"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08\"",
+                    "type": "string"
+                }
+            }
+        },
+        "schemas.PipelineDefaultVersionInputSchema": {
+            "type": "object",
+            "properties": {
+                "version": {
+                    "description": "The version new processings are started with\nrequired: true\nexample: \"2\"",
+                    "type": "string"
+                }
+            }
+        },
+        "schemas.PipelineForkInputSchema": {
+            "type": "object",
+            "properties": {
+                "block": {
+                    "description": "The block information, represented by the BlockInputSchema model\nrequired: true",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/schemas.BlockInputSchema"
+                        }
+                    ]
                 },
-                "status": {
-                    "description": "The current status of the worker\nexample: {\"load\": 0.5, \"available\": true, \"version\": \"1.0.0\"}"
+                "callback": {
+                    "description": "The webhook to notify about the processing events (optional)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/schemas.PipelineCallbackSchema"
+                        }
+                    ]
                 }
             }
         },
-        "schemas.BlockInputSchema": {
+        "schemas.PipelineForkOutputSchema": {
             "type": "object",
             "properties": {
-                "destination_slug": {
-                    "description": "The destination slug for the block (optional)\nexample: \"destination-block\"",
+                "parent_processing_id": {
+                    "description": "The processing ID of the source processing\nrequired: true\nexample: \"6c2d6978-7364-441e-bb0f-aa7c3efd4ad2\"",
                     "type": "string"
                 },
+                "processing_id": {
+                    "description": "The unique processing ID of the new processing\nrequired: true\nexample: \"d9b2d63d-5f23-e4d7-6b7f-3f2f25d93a7a\"",
+                    "type": "string"
+                }
+            }
+        },
+        "schemas.PipelineInputSchema": {
+            "type": "object",
+            "properties": {
                 "input": {
-                    "description": "The input data for the block, represented as a map of key-value pairs\nrequired: true\nexample: {\"key1\": \"value1\", \"key2\": 42}",
+                    "description": "The input parameters declared by the input schema of the pipeline (optional)\nexample: {\"topic\": \"October 24\", \"duration\": 30}",
                     "type": "object",
                     "additionalProperties": true
                 },
+                "parent_processing_id": {
+                    "description": "The processing ID this processing was forked from. Set by the fork endpoint\nexample: \"6c2d6978-7364-441e-bb0f-aa7c3efd4ad2\"",
+                    "type": "string"
+                },
+                "processing_id": {
+                    "description": "The unique processing ID associated with this pipeline\nexample: \"d9b2d63d5f23e4d76b7f3f2f25d93a7a\"",
+                    "type": "string"
+                },
                 "slug": {
-                    "description": "The slug of the block\nrequired: true\nexample: \"example-block\"",
+                    "description": "The slug of the pipeline\nrequired: true\nexample: \"example-slug\"",
                     "type": "string"
                 },
-                "target_index": {
-                    "description": "The target index for the block (optional)\nIf omitted or empty, it defaults to -1\nexample: 5",
-                    "type": "string",
-                    "example": "0"
+                "version": {
+                    "description": "The version of the pipeline (optional)\nNew processings use the default version, resumed ones the version they were started with\nexample: \"2\"",
+                    "type": "string"
                 }
             }
         },
-        "schemas.PipelineInputSchema": {
+        "schemas.PipelineProcessingDeleteOutputSchema": {
             "type": "object",
             "properties": {
+                "deleted_objects": {
+                    "description": "The deleted objects prefixed with the storage name\nexample: [\"local:example-pipeline/d9b2d63d-5f23-e4d7-6b7f-3f2f25d93a7a/log_1727000000.json\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "processing_id": {
-                    "description": "The unique processing ID associated with this pipeline\nexample: \"d9b2d63d5f23e4d76b7f3f2f25d93a7a\"",
+                    "description": "The unique processing ID of the deleted processing\nrequired: true\nexample: \"d9b2d63d-5f23-e4d7-6b7f-3f2f25d93a7a\"",
                     "type": "string"
+                }
+            }
+        },
+        "schemas.PipelineProcessingsListSchema": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "description": "example: 50",
+                    "type": "integer"
                 },
-                "slug": {
-                    "description": "The slug of the pipeline\nrequired: true\nexample: \"example-slug\"",
-                    "type": "string"
+                "offset": {
+                    "description": "example: 0",
+                    "type": "integer"
+                },
+                "processings": {
+                    "description": "Statuses of the processings",
+                    "type": "array",
+                    "items": {
+                        "type": "object"
+                    }
+                },
+                "total": {
+                    "description": "Number of processings matching the query\nexample: 120",
+                    "type": "integer"
+                }
+            }
+        },
+        "schemas.PipelineProcessingsReindexOutputSchema": {
+            "type": "object",
+            "properties": {
+                "indexed": {
+                    "description": "Number of indexed processing logs\nexample: 120",
+                    "type": "integer"
                 }
             }
         },
@@ -554,17 +1367,62 @@ const docTemplate = `{
                 }
             }
         },
+        "schemas.PipelineRetryInputSchema": {
+            "type": "object",
+            "properties": {
+                "callback": {
+                    "description": "The webhook to notify about the processing events (optional)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/schemas.PipelineCallbackSchema"
+                        }
+                    ]
+                },
+                "failed_indexes_only": {
+                    "description": "Retry only the failed indexes of the block (optional)\nexample: true",
+                    "type": "boolean"
+                }
+            }
+        },
+        "schemas.PipelineRetryOutputSchema": {
+            "type": "object",
+            "properties": {
+                "block_slug": {
+                    "description": "The slug of the block the processing is resumed from\nrequired: true\nexample: \"example-block\"",
+                    "type": "string"
+                },
+                "processing_id": {
+                    "description": "The unique processing ID of the retried processing\nrequired: true\nexample: \"d9b2d63d-5f23-e4d7-6b7f-3f2f25d93a7a\"",
+                    "type": "string"
+                },
+                "target_indexes": {
+                    "description": "The block input indexes to retry, empty when all indexes are retried\nexample: [1, 3]",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
         "schemas.PipelineStartInputSchema": {
             "type": "object",
             "properties": {
                 "block": {
-                    "description": "The block information, represented by the BlockInputSchema model\nrequired: true",
+                    "description": "The block information, represented by the BlockInputSchema model.\nPipelines started with the input parameters only are processed from the first block",
                     "allOf": [
                         {
                             "$ref": "#/definitions/schemas.BlockInputSchema"
                         }
                     ]
                 },
+                "callback": {
+                    "description": "The webhook to notify about the processing events (optional)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/schemas.PipelineCallbackSchema"
+                        }
+                    ]
+                },
                 "pipeline": {
                     "description": "The pipeline information, represented by the PipelineInputSchema model\nrequired: true",
                     "allOf": [
@@ -583,6 +1441,178 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "schemas.PipelineUsageSchema": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "description": "Processings finished at or after the date",
+                    "type": "string"
+                },
+                "pipeline_slug": {
+                    "description": "example: \"openai-yt-short-generation\"",
+                    "type": "string"
+                },
+                "processings": {
+                    "description": "Number of the processing logs with the usage\nexample: 12",
+                    "type": "integer"
+                },
+                "to": {
+                    "description": "Processings finished at or before the date",
+                    "type": "string"
+                },
+                "usage": {
+                    "description": "Total usage of the processings",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/schemas.ProcessingUsageSchema"
+                        }
+                    ]
+                }
+            }
+        },
+        "schemas.PipelineVersionsSchema": {
+            "type": "object",
+            "properties": {
+                "default_version": {
+                    "description": "The version new processings are started with\nrequired: true\nexample: \"2\"",
+                    "type": "string"
+                },
+                "slug": {
+                    "description": "The slug of the Pipeline\nrequired: true\nexample: \"openai-podcast-summary\"",
+                    "type": "string"
+                },
+                "versions": {
+                    "description": "The registered versions ordered from the oldest\nrequired: true\nexample: [\"1\", \"2\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "schemas.ProcessingUsageSchema": {
+            "type": "object",
+            "properties": {
+                "characters": {
+                    "description": "Characters of the speech synthesis\nexample: 1500",
+                    "type": "integer"
+                },
+                "completion_tokens": {
+                    "description": "Tokens of the chat completion answers\nexample: 300",
+                    "type": "integer"
+                },
+                "cost": {
+                    "description": "Cost by the price table\nexample: 0.0645",
+                    "type": "number"
+                },
+                "currency": {
+                    "description": "Currency of the cost\nexample: \"USD\"",
+                    "type": "string"
+                },
+                "images": {
+                    "description": "Generated images\nexample: 1",
+                    "type": "integer"
+                },
+                "models": {
+                    "description": "Usage by model",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/schemas.UsageSchema"
+                    }
+                },
+                "prompt_tokens": {
+                    "description": "Tokens of the chat completion prompts\nexample: 1200",
+                    "type": "integer"
+                },
+                "seconds": {
+                    "description": "Seconds of the transcribed audio\nexample: 62.5",
+                    "type": "number"
+                }
+            }
+        },
+        "schemas.RetentionReportProcessingSchema": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "The action applied to the processing: purge or purge_intermediate\nexample: \"purge_intermediate\"",
+                    "type": "string"
+                },
+                "date_finished": {
+                    "description": "The time of the last log or status of the processing",
+                    "type": "string"
+                },
+                "objects": {
+                    "description": "The objects deleted ( or to be deleted in dry run ) prefixed with the storage name",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "pipeline_slug": {
+                    "description": "example: \"example-pipeline\"",
+                    "type": "string"
+                },
+                "processing_id": {
+                    "description": "example: \"d9b2d63d-5f23-e4d7-6b7f-3f2f25d93a7a\"",
+                    "type": "string"
+                }
+            }
+        },
+        "schemas.RetentionReportSchema": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "description": "The time the retention policy is applied at",
+                    "type": "string"
+                },
+                "dry_run": {
+                    "description": "Nothing is deleted in dry run",
+                    "type": "boolean"
+                },
+                "errors": {
+                    "description": "Errors of the deletion",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "processings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.RetentionReportProcessingSchema"
+                    }
+                }
+            }
+        },
+        "schemas.UsageSchema": {
+            "type": "object",
+            "properties": {
+                "characters": {
+                    "description": "Characters of the speech synthesis\nexample: 1500",
+                    "type": "integer"
+                },
+                "completion_tokens": {
+                    "description": "Tokens of the chat completion answers\nexample: 300",
+                    "type": "integer"
+                },
+                "cost": {
+                    "description": "Cost by the price table\nexample: 0.0645",
+                    "type": "number"
+                },
+                "images": {
+                    "description": "Generated images\nexample: 1",
+                    "type": "integer"
+                },
+                "prompt_tokens": {
+                    "description": "Tokens of the chat completion prompts\nexample: 1200",
+                    "type": "integer"
+                },
+                "seconds": {
+                    "description": "Seconds of the transcribed audio\nexample: 62.5",
+                    "type": "number"
+                }
+            }
         }
     }
 }`
@@ -27,8 +27,10 @@ import (
 	"data-pipelines-worker/types"
 	"data-pipelines-worker/types/config"
 	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/egress"
 	"data-pipelines-worker/types/helpers"
 	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/limiter"
 )
 
 type APIServer struct {
@@ -70,6 +72,11 @@ func (suite *FunctionalTestSuite) SetupTest() {
 	_config := config.GetConfig()
 	_config.Storage.Local.RootPath = os.TempDir()
 
+	// Mock HTTP servers listen on the loopback denied by the egress policy
+	_config.Egress.AllowCIDRs = []string{"127.0.0.0/8", "::1"}
+	_, err := egress.ReloadPolicy()
+	suite.Nil(err)
+
 	// OpenAI
 	openAIModelsList := `{
 		"data": [
@@ -108,6 +115,9 @@ func (suite *FunctionalTestSuite) SetupTest() {
 			suite._config.Blocks[blockId].Detector.Conditions["url"] = successUrl
 		}
 	}
+
+	// Circuit breakers of the previous tests must not fail fast the providers
+	limiter.GetLimiterRegistry(true)
 }
 
 func (suite *FunctionalTestSuite) GetMockHTTPServer(
@@ -386,8 +396,8 @@ func (suite *FunctionalTestSuite) GetPipelineProcessingsStatus(
 	server *api.Server,
 	pipelineSlug string,
 	httpClient *http.Client,
-) (map[string][]interface{}, int, string, error) {
-	result := make(map[string][]interface{})
+) (schemas.PipelineProcessingsListSchema, int, string, error) {
+	result := schemas.PipelineProcessingsListSchema{}
 
 	if httpClient == nil {
 		httpClient = &http.Client{}
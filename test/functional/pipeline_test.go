@@ -80,7 +80,7 @@ func (suite *FunctionalTestSuite) TestPipelinesListHandler() {
 	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%s", api_path), nil)
 
 	c := server.GetEcho().NewContext(req, rec)
-	server.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks(""))
+	suite.Nil(server.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks("")))
 
 	// When
 	handlers.PipelinesHandler(server.GetPipelineRegistry())(c)
@@ -113,7 +113,7 @@ func (suite *FunctionalTestSuite) TestPipelineProcessingsStatus() {
 	mockedSecondBlockResponse := fmt.Sprintf("Hello, world! Mocked value is %s", uuid.NewString())
 	secondBlockInput := suite.GetMockHTTPServerURL(mockedSecondBlockResponse, http.StatusOK, 0)
 	firstBlockInput := suite.GetMockHTTPServerURL(secondBlockInput, http.StatusOK, 0)
-	server.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks(firstBlockInput))
+	suite.Nil(server.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks(firstBlockInput)))
 
 	testPipelineSlug, _ := "test-two-http-blocks", "http_request"
 	inputData := schemas.PipelineStartInputSchema{
@@ -160,15 +160,12 @@ func (suite *FunctionalTestSuite) TestPipelineProcessingsStatus() {
 	suite.Empty(errorResponse)
 	suite.Nil(err, errorResponse)
 	suite.Equal(http.StatusOK, statusCode, errorResponse)
-	suite.NotEmpty(processingsStatus)
-	suite.Contains(
-		processingsStatus,
-		processingResponse.ProcessingID.String(),
-	)
+	suite.Equal(1, processingsStatus.Total)
 
-	processingsStatusMap, ok := processingsStatus[processingResponse.ProcessingID.String()]
+	processingsStatusList, ok := processingsStatus.Processings.([]interface{})
 	suite.True(ok)
-	processingsStatusMap0, ok := processingsStatusMap[0].(map[string]interface{})
+	suite.Len(processingsStatusList, 1)
+	processingsStatusMap0, ok := processingsStatusList[0].(map[string]interface{})
 	suite.True(ok)
 	suite.Equal(processingResponse.ProcessingID.String(), processingsStatusMap0["id"].(string))
 	suite.True(processingsStatusMap0["is_completed"].(bool))
@@ -198,7 +195,7 @@ func (suite *FunctionalTestSuite) TestPipelineProcessingDetails() {
 	mockedSecondBlockResponse := fmt.Sprintf("Hello, world! Mocked value is %s", uuid.NewString())
 	secondBlockInput := suite.GetMockHTTPServerURL(mockedSecondBlockResponse, http.StatusOK, 0)
 	firstBlockInput := suite.GetMockHTTPServerURL(secondBlockInput, http.StatusOK, 0)
-	server.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks(firstBlockInput))
+	suite.Nil(server.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks(firstBlockInput)))
 
 	testPipelineSlug, _ := "test-two-http-blocks", "http_request"
 	inputData := schemas.PipelineStartInputSchema{
@@ -277,7 +274,7 @@ func (suite *FunctionalTestSuite) TestPipelineStartHandlerTwoBlocks() {
 	mockedSecondBlockResponse := fmt.Sprintf("Hello, world! Mocked value is %s", uuid.NewString())
 	secondBlockInput := suite.GetMockHTTPServerURL(mockedSecondBlockResponse, http.StatusOK, 0)
 	firstBlockInput := suite.GetMockHTTPServerURL(secondBlockInput, http.StatusOK, 0)
-	server.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks(firstBlockInput))
+	suite.Nil(server.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks(firstBlockInput)))
 
 	testPipelineSlug, _ := "test-two-http-blocks", "http_request"
 	inputData := schemas.PipelineStartInputSchema{
@@ -485,7 +482,7 @@ func (suite *FunctionalTestSuite) TestPipelineArrayFromJSONPathStart() {
 	server, _, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
 
-	server.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server.GetPipelineRegistry().Add(pipeline))
 	notificationChannel := make(chan interfaces.Processing)
 	processingRegistry := server.GetProcessingRegistry()
 	processingRegistry.SetNotificationChannel(notificationChannel)
@@ -691,7 +688,7 @@ func (suite *FunctionalTestSuite) TestPipelineArrayFromJSONPathResume() {
 	server, _, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
 
-	server.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server.GetPipelineRegistry().Add(pipeline))
 	notificationChannel := make(chan interfaces.Processing)
 
 	pipelineRegistry := server.GetPipelineRegistry()
@@ -972,7 +969,7 @@ func (suite *FunctionalTestSuite) TestPipelineArrayFromJSONPathReplaceStart() {
 	server, _, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
 
-	server.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server.GetPipelineRegistry().Add(pipeline))
 	notificationChannel := make(chan interfaces.Processing)
 	processingRegistry := server.GetProcessingRegistry()
 	processingRegistry.SetNotificationChannel(notificationChannel)
@@ -1228,7 +1225,7 @@ func (suite *FunctionalTestSuite) TestPipelineResumeArrayTargetIndex() {
 	server, _, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
 
-	server.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server.GetPipelineRegistry().Add(pipeline))
 	notificationChannel := make(chan interfaces.Processing)
 
 	pipelineRegistry := server.GetPipelineRegistry()
@@ -1521,7 +1518,7 @@ func (suite *FunctionalTestSuite) TestPipelineArrayFromJSONPathStartParallel() {
 	server, _, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
 
-	server.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server.GetPipelineRegistry().Add(pipeline))
 	notificationChannel := make(chan interfaces.Processing, 100)
 	processingRegistry := server.GetProcessingRegistry()
 	processingRegistry.SetNotificationChannel(notificationChannel)
@@ -1769,7 +1766,7 @@ func (suite *FunctionalTestSuite) TestPipelineArrayFromJSONPathStartParallelFail
 	server, _, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
 
-	server.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server.GetPipelineRegistry().Add(pipeline))
 	notificationChannel := make(chan interfaces.Processing, 100)
 	processingRegistry := server.GetProcessingRegistry()
 	processingRegistry.SetNotificationChannel(notificationChannel)
@@ -2167,7 +2164,7 @@ func (suite *FunctionalTestSuite) TestPipelineArrayModerationApproveAll() {
 	server, _, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
 
-	server.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server.GetPipelineRegistry().Add(pipeline))
 	notificationChannel := make(chan interfaces.Processing, 100)
 	processingRegistry := server.GetProcessingRegistry()
 	processingRegistry.SetNotificationChannel(notificationChannel)
@@ -2572,7 +2569,7 @@ func (suite *FunctionalTestSuite) TestPipelineArrayModerationDeclineThird() {
 	server, _, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
 
-	server.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server.GetPipelineRegistry().Add(pipeline))
 	notificationChannel := make(chan interfaces.Processing, 100)
 	processingRegistry := server.GetProcessingRegistry()
 	processingRegistry.SetNotificationChannel(notificationChannel)
@@ -3002,7 +2999,7 @@ func (suite *FunctionalTestSuite) TestPipelineArrayModerationRegenerateThird() {
 	server, _, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
 
-	server.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server.GetPipelineRegistry().Add(pipeline))
 	notificationChannel := make(chan interfaces.Processing, 100)
 	processingRegistry := server.GetProcessingRegistry()
 	processingRegistry.SetNotificationChannel(notificationChannel)
@@ -3204,7 +3201,7 @@ func (suite *FunctionalTestSuite) TestPipelineMultipartFormDataFile() {
 	server, _, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
 
-	server.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server.GetPipelineRegistry().Add(pipeline))
 	notificationChannel := make(chan interfaces.Processing, 100)
 	processingRegistry := server.GetProcessingRegistry()
 	processingRegistry.SetNotificationChannel(notificationChannel)
@@ -3343,7 +3340,7 @@ func (suite *FunctionalTestSuite) TestPipelineAudioChunkOutput() {
 	server, _, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
 
-	server.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server.GetPipelineRegistry().Add(pipeline))
 	notificationChannel := make(chan interfaces.Processing, 100)
 	processingRegistry := server.GetProcessingRegistry()
 	processingRegistry.SetNotificationChannel(notificationChannel)
@@ -64,11 +64,11 @@ func (suite *FunctionalTestSuite) TestTwoWorkersAPIDiscoveryCommunication() {
 	testPipelineSlug, testBlockId := "test-two-http-blocks", "http_request"
 	server1, worker1, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
-	server1.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks(""))
+	suite.Nil(server1.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks("")))
 
 	server2, worker2, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
-	server2.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks(""))
+	suite.Nil(server2.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks("")))
 
 	workerRegistry1 := server1.GetWorkerRegistry()
 	workerRegistry2 := server2.GetWorkerRegistry()
@@ -92,6 +92,12 @@ func (suite *FunctionalTestSuite) TestTwoWorkersAPIDiscoveryCommunication() {
 	suite.Equal(workersWithBlocks1[worker2.GetId()], worker2)
 	suite.Equal(workersWithBlocks2[worker1.GetId()], worker1)
 
+	// Pinned versions require a compatible version at the worker
+	suite.Len(workerRegistry1.GetWorkersWithBlocksAvailable(availableWorkers1, testBlockId+"@1"), 1)
+	suite.Len(workerRegistry1.GetWorkersWithBlocksAvailable(availableWorkers1, testBlockId+"@2"), 0)
+	suite.Len(workerRegistry1.GetWorkersWithBlocksAvailable(availableWorkers1, testBlockId+"@^1"), 1)
+	suite.Len(workerRegistry1.GetWorkersWithBlocksAvailable(availableWorkers1, testBlockId+"@1.1"), 0)
+
 	validWorkers1 := workerRegistry1.GetValidWorkers(testPipelineSlug, testBlockId)
 	validWorkers2 := workerRegistry2.GetValidWorkers(testPipelineSlug, testBlockId)
 	suite.Equal(len(validWorkers1), 1)
@@ -105,10 +111,10 @@ func (suite *FunctionalTestSuite) TestTwoWorkersPipelineProcessingRequiredBlocks
 	testPipelineSlug, testBlockId := "test-two-http-blocks", "http_request"
 	server1, worker1, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
-	server1.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks(""))
+	suite.Nil(server1.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks("")))
 	server2, worker2, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
-	server2.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks(""))
+	suite.Nil(server2.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks("")))
 
 	notificationChannel := make(chan interfaces.Processing)
 
@@ -172,10 +178,10 @@ func (suite *FunctionalTestSuite) TestTwoWorkersPipelineProcessingRequiredBlocks
 	testPipelineSlug, testBlockId := "test-two-http-blocks", "http_request"
 	server1, worker1, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
-	server1.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks(""))
+	suite.Nil(server1.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks("")))
 	server2, worker2, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
-	server2.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks(""))
+	suite.Nil(server2.GetPipelineRegistry().Add(suite.GetTestPipelineTwoBlocks("")))
 
 	workerRegistry1 := server1.GetWorkerRegistry()
 	workerRegistry2 := server2.GetWorkerRegistry()
@@ -312,10 +318,10 @@ func (suite *FunctionalTestSuite) TestTwoWorkersPipelineProcessingWorker1HasNoSe
 
 	server1, worker1, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
-	server1.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server1.GetPipelineRegistry().Add(pipeline))
 	server2, worker2, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
-	server2.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server2.GetPipelineRegistry().Add(pipeline))
 
 	workerRegistry1 := server1.GetWorkerRegistry()
 	workerRegistry2 := server2.GetWorkerRegistry()
@@ -449,10 +455,10 @@ func (suite *FunctionalTestSuite) TestTwoWorkersPipelineProcessingWorker1HasNoSe
 
 	server1, worker1, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
-	server1.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server1.GetPipelineRegistry().Add(pipeline))
 	server2, worker2, err := suite.NewWorkerServerWithHandlers(true, suite._config)
 	suite.Nil(err)
-	server2.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server2.GetPipelineRegistry().Add(pipeline))
 
 	workerRegistry1 := server1.GetWorkerRegistry()
 	workerRegistry2 := server2.GetWorkerRegistry()
@@ -617,11 +623,11 @@ func (suite *FunctionalTestSuite) TestTwoWorkersResumeProcessing2Times() {
 
 	server1, worker1, err := suite.NewWorkerServerWithHandlers(true, config.GetConfig())
 	suite.Nil(err)
-	server1.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server1.GetPipelineRegistry().Add(pipeline))
 
 	server2, worker2, err := suite.NewWorkerServerWithHandlers(true, config.GetConfig())
 	suite.Nil(err)
-	server2.GetPipelineRegistry().Add(pipeline)
+	suite.Nil(server2.GetPipelineRegistry().Add(pipeline))
 
 	workerRegistry1 := server1.GetWorkerRegistry()
 	workerRegistry2 := server2.GetWorkerRegistry()
@@ -1,6 +1,7 @@
 package functional_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -48,6 +49,15 @@ func (suite *FunctionalTestSuite) TestBlocksHandler() {
 	suite.Equal(http.StatusOK, rec.Code)
 	suite.NotNil(rec.Body.String())
 	suite.Contains(rec.Body.String(), "http_request")
+
+	blocks := make(map[string]map[string]interface{})
+	suite.Nil(json.Unmarshal(rec.Body.Bytes(), &blocks))
+	suite.Equal("1", blocks["http_request"]["version"])
+	suite.Equal(false, blocks["http_request"]["deprecated"])
+	suite.Equal(
+		[]interface{}{map[string]interface{}{"version": "1", "deprecated": false, "available": true}},
+		blocks["http_request"]["versions"],
+	)
 }
 
 func (suite *FunctionalTestSuite) TestWorkersHandler() {
@@ -2,12 +2,20 @@ package unit_test
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"data-pipelines-worker/types"
 	"data-pipelines-worker/types/blocks"
+	"data-pipelines-worker/types/config"
 	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/interfaces"
 	"data-pipelines-worker/types/validators"
 )
 
@@ -78,6 +86,7 @@ func (suite *UnitTestSuite) TestBlockHTTPProcessSuccess() {
 			"url": successUrl,
 		},
 	}
+	data.SetBlock(block)
 
 	// Process the block
 	result, stop, _, _, _, err := block.Process(
@@ -105,6 +114,7 @@ func (suite *UnitTestSuite) TestBlockHTTPProcessCancel() {
 		},
 	}
 	block := blocks.NewBlockHTTP()
+	data.SetBlock(block)
 
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -145,6 +155,7 @@ func (suite *UnitTestSuite) TestBlockHTTPProcessError() {
 			"url": failUrl,
 		},
 	}
+	data.SetBlock(block)
 
 	// Process the block
 	result, stop, _, _, _, err := block.Process(
@@ -161,3 +172,253 @@ func (suite *UnitTestSuite) TestBlockHTTPProcessError() {
 		"HTTP request failed with status code: 500",
 	)
 }
+
+func (suite *UnitTestSuite) TestBlockHTTPProcessRequest() {
+	testCases := []struct {
+		name          string
+		input         map[string]interface{}
+		method        string
+		query         string
+		contentType   string
+		body          string
+		authorization string
+	}{
+		{
+			name: "json body with bearer auth",
+			input: map[string]interface{}{
+				"method":  "POST",
+				"query":   map[string]interface{}{"page": "2"},
+				"headers": map[string]interface{}{"X-Request-Id": "42"},
+				"body":    map[string]interface{}{"name": "test"},
+				"auth":    map[string]interface{}{"token": "secret-token"},
+			},
+			method:        http.MethodPost,
+			query:         "limit=10&page=2",
+			contentType:   "application/json",
+			body:          `{"name":"test"}`,
+			authorization: "Bearer secret-token",
+		},
+		{
+			name: "form body with basic auth",
+			input: map[string]interface{}{
+				"method":      "PUT",
+				"body":        map[string]interface{}{"name": "test", "tags": []interface{}{"a", "b"}},
+				"body_format": "form",
+				"auth":        map[string]interface{}{"type": "basic", "username": "user", "password": "pass"},
+			},
+			method:        http.MethodPut,
+			query:         "limit=10",
+			contentType:   "application/x-www-form-urlencoded",
+			body:          "name=test&tags=a&tags=b",
+			authorization: "Basic dXNlcjpwYXNz",
+		},
+		{
+			name: "raw string body",
+			input: map[string]interface{}{
+				"method":  "PATCH",
+				"headers": map[string]interface{}{"Content-Type": "text/plain"},
+				"body":    "plain text",
+			},
+			method:      http.MethodPatch,
+			query:       "limit=10",
+			contentType: "text/plain",
+			body:        "plain text",
+		},
+	}
+
+	for _, testCase := range testCases {
+		suite.Run(testCase.name, func() {
+			// Given
+			var request *http.Request
+			var requestBody []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				request = r
+				requestBody, _ = io.ReadAll(r.Body)
+				w.Write([]byte("ok"))
+			}))
+			defer server.Close()
+
+			input := map[string]interface{}{"url": server.URL + "/items?limit=10"}
+			for key, value := range testCase.input {
+				input[key] = value
+			}
+			block := blocks.NewBlockHTTP()
+			data := &dataclasses.BlockData{
+				Id:    "http_request",
+				Slug:  "http-request",
+				Input: input,
+			}
+			data.SetBlock(block)
+
+			// When
+			result, _, _, _, _, err := block.Process(
+				suite.GetContextWithcancel(),
+				blocks.NewProcessorHTTP(),
+				data,
+			)
+
+			// Then
+			suite.Nil(err)
+			suite.Equal("ok", result[0].String())
+			suite.Equal(testCase.method, request.Method)
+			suite.Equal(testCase.query, request.URL.RawQuery)
+			suite.Equal(testCase.contentType, request.Header.Get("Content-Type"))
+			suite.Equal(testCase.body, string(requestBody))
+			suite.Equal(testCase.authorization, request.Header.Get("Authorization"))
+			if headers, ok := testCase.input["headers"].(map[string]interface{}); ok {
+				for name, value := range headers {
+					suite.Equal(value, request.Header.Get(name))
+				}
+			}
+		})
+	}
+}
+
+func (suite *UnitTestSuite) TestBlockHTTPProcessResponse() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/json", http.StatusFound)
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Date", "Mon, 01 Jan 2024 00:00:00 GMT")
+			w.Write([]byte(`{"items": [{"id": 1}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		}
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name   string
+		input  map[string]interface{}
+		output string
+		err    string
+	}{
+		{
+			name:   "envelope of the followed redirect",
+			input:  map[string]interface{}{"url": server.URL + "/redirect", "output_envelope": true},
+			output: `{"status":200,"headers":{"Content-Length":"22","Content-Type":"application/json","Date":"Mon, 01 Jan 2024 00:00:00 GMT"},"body":{"items":[{"id":1}]}}`,
+		},
+		{
+			name:   "redirect is not followed",
+			input:  map[string]interface{}{"url": server.URL + "/redirect", "follow_redirects": false, "accepted_status_codes": []interface{}{302}},
+			output: "",
+		},
+		{
+			name:   "accepted status code",
+			input:  map[string]interface{}{"url": server.URL + "/missing", "accepted_status_codes": []interface{}{404}},
+			output: "not found",
+		},
+		{
+			name:   "status code is not accepted",
+			input:  map[string]interface{}{"url": server.URL + "/missing"},
+			output: "not found",
+			err:    "HTTP request failed with status code: 404",
+		},
+		{
+			name:  "response exceeds the limit",
+			input: map[string]interface{}{"url": server.URL + "/json", "max_response_size": 10},
+			err:   "HTTP response exceeds the max_response_size of 10 bytes",
+		},
+	}
+
+	for _, testCase := range testCases {
+		suite.Run(testCase.name, func() {
+			// Given
+			block := blocks.NewBlockHTTP()
+			data := &dataclasses.BlockData{
+				Id:    "http_request",
+				Slug:  "http-request",
+				Input: testCase.input,
+			}
+			data.SetBlock(block)
+
+			// When
+			result, _, _, _, _, err := block.Process(
+				suite.GetContextWithcancel(),
+				blocks.NewProcessorHTTP(),
+				data,
+			)
+
+			// Then
+			if testCase.err != "" {
+				suite.NotNil(err)
+				suite.Contains(err.Error(), testCase.err)
+			} else {
+				suite.Nil(err)
+			}
+			if testCase.output != "" {
+				suite.Equal(testCase.output, strings.TrimSpace(result[0].String()))
+			}
+		})
+	}
+}
+
+func (suite *UnitTestSuite) TestBlockHTTPMaskInput() {
+	// Given
+	block := blocks.NewBlockHTTP()
+	input := map[string]interface{}{
+		"url":     "https://example.com",
+		"auth":    map[string]interface{}{"type": "basic", "username": "user", "password": "password-value"},
+		"headers": map[string]interface{}{"authorization": "Bearer header-token", "Accept": "application/json"},
+	}
+
+	// When
+	maskedInput := block.MaskInput(input)
+
+	// Then
+	suite.Equal("https://example.com", maskedInput["url"])
+	suite.Equal(
+		map[string]interface{}{"type": "basic", "username": "user", "password": config.SECRET_REDACTED},
+		maskedInput["auth"],
+	)
+	suite.Equal(
+		map[string]interface{}{"authorization": config.SECRET_REDACTED, "Accept": "application/json"},
+		maskedInput["headers"],
+	)
+	// Input passed to the block is not changed
+	suite.Equal("password-value", input["auth"].(map[string]interface{})["password"])
+
+	bearerInput := block.MaskInput(map[string]interface{}{"auth": map[string]interface{}{"token": "token-value"}})
+	suite.Equal(map[string]interface{}{"token": config.SECRET_REDACTED}, bearerInput["auth"])
+}
+
+func (suite *UnitTestSuite) TestPipelineProcessMasksLoggedHTTPInput() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineOneBlock(successUrl),
+		"test-pipeline-slug",
+		"test-block-slug",
+		map[string]interface{}{
+			"url":     successUrl,
+			"auth":    map[string]interface{}{"token": "token-value"},
+			"headers": map[string]interface{}{"Authorization": "Bearer header-token"},
+		},
+	)
+	storageDirectory := suite.T().TempDir()
+	pipelineRegistry.SetPipelineResultStorages(
+		[]interfaces.Storage{
+			types.NewLocalStorage(storageDirectory),
+		},
+	)
+
+	// When
+	processingId, err := pipelineRegistry.StartPipeline(processingData)
+
+	// Then
+	suite.Nil(err)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), processingId)
+
+	logs, _ := filepath.Glob(filepath.Join(storageDirectory, pipeline.GetSlug(), processingId.String(), "log_*"))
+	suite.NotEmpty(logs)
+	for _, logFile := range logs {
+		logContent, err := os.ReadFile(logFile)
+		suite.Nil(err)
+		suite.Contains(string(logContent), "Processing input for block test-block-slug")
+		suite.NotContains(string(logContent), "token-value")
+		suite.NotContains(string(logContent), "header-token")
+	}
+}
@@ -3,7 +3,9 @@ package unit_test
 import (
 	"data-pipelines-worker/types/blocks"
 	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/llm"
 	"data-pipelines-worker/types/validators"
+	"encoding/json"
 	"net/http"
 
 	"github.com/sashabaranov/go-openai"
@@ -162,3 +164,86 @@ func (suite *UnitTestSuite) TestBlockOpenAIRequestCompletionProcessSuccess() {
 	suite.False(stop)
 	suite.Nil(err)
 }
+
+func (suite *UnitTestSuite) TestBlockOpenAIRequestCompletionProcessRichRequest() {
+	// Given
+	block := blocks.NewBlockOpenAIRequestCompletion()
+	data := &dataclasses.BlockData{
+		Id:   "openai_chat_completion",
+		Slug: "request-openai-chat-completion",
+		Input: map[string]interface{}{
+			"credentials": "offline",
+			"messages": []interface{}{
+				map[string]interface{}{"role": "user", "content": "Describe the image"},
+			},
+			"image":           []byte("\x89PNG\r\n\x1a\n"),
+			"temperature":     0.2,
+			"max_tokens":      100,
+			"seed":            42,
+			"n":               2,
+			"response_format": "json_schema",
+			"json_schema": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"title": map[string]interface{}{"type": "string"}},
+				"required":   []interface{}{"title"},
+			},
+			"output_envelope": true,
+		},
+	}
+	data.SetBlock(block)
+
+	provider := &stubLLMProvider{
+		response: openai.ChatCompletionResponse{
+			Model: "gpt-4o",
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Content: `{"title": "First"}`}, FinishReason: openai.FinishReasonStop},
+				{Message: openai.ChatCompletionMessage{Content: `{"title": "Second"}`}, FinishReason: openai.FinishReasonLength},
+			},
+			Usage: openai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+		},
+	}
+	llm.GetProviderRegistry().Set("offline", provider)
+	defer llm.GetProviderRegistry().Delete("offline")
+
+	// When
+	result, _, _, _, _, err := block.Process(
+		suite.GetContextWithcancel(),
+		blocks.NewProcessorOpenAIRequestCompletion(),
+		data,
+	)
+
+	// Then
+	suite.Nil(err)
+	suite.Len(result, 2)
+
+	envelope := blocks.ChatCompletionOutputEnvelope{}
+	suite.Nil(json.Unmarshal(result[1].Bytes(), &envelope))
+	suite.Equal(`{"title": "Second"}`, envelope.Content)
+	suite.Equal("length", envelope.FinishReason)
+	suite.Equal(15, envelope.Usage.TotalTokens)
+
+	request := provider.requests[0]
+	suite.Equal(float32(0.2), request.Temperature)
+	suite.Equal(100, request.MaxTokens)
+	suite.Equal(42, *request.Seed)
+	suite.Equal(2, request.N)
+	suite.Equal(openai.ChatCompletionResponseFormatTypeJSONSchema, request.ResponseFormat.Type)
+
+	// System prompt of the config, the messages with the image attached to the last user message
+	suite.Len(request.Messages, 2)
+	suite.Equal(openai.ChatMessageRoleSystem, request.Messages[0].Role)
+	suite.Len(request.Messages[1].MultiContent, 2)
+	suite.Equal("Describe the image", request.Messages[1].MultiContent[0].Text)
+	suite.Contains(request.Messages[1].MultiContent[1].ImageURL.URL, "data:image/png;base64,")
+
+	// Output not matching the JSON schema fails the block
+	provider.response.Choices[1].Message.Content = `{"name": "Second"}`
+	result, _, _, _, _, err = block.Process(
+		suite.GetContextWithcancel(),
+		blocks.NewProcessorOpenAIRequestCompletion(),
+		data,
+	)
+	suite.Empty(result)
+	suite.NotNil(err)
+	suite.Contains(err.Error(), "title is required")
+}
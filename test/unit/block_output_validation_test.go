@@ -0,0 +1,365 @@
+package unit_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/test/factories"
+	"data-pipelines-worker/types/blocks"
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/interfaces"
+)
+
+type stubOutputProcessor struct {
+	output   []*bytes.Buffer
+	metadata map[int]schemas.OutputMetadataSchema
+}
+
+func (p *stubOutputProcessor) GetRetryCount(_ interfaces.Block) int {
+	return 0
+}
+
+func (p *stubOutputProcessor) GetRetryInterval(_ interfaces.Block) time.Duration {
+	return 0
+}
+
+func (p *stubOutputProcessor) Process(
+	ctx context.Context,
+	_ interfaces.Block,
+	_ interfaces.ProcessableBlockData,
+) ([]*bytes.Buffer, bool, bool, string, int, error) {
+	for outputIndex, metadata := range p.metadata {
+		blocks.RecordOutputMetadata(ctx, outputIndex, metadata)
+	}
+
+	return p.output, false, false, "", -1, nil
+}
+
+func (suite *UnitTestSuite) TestBlockValidateOutputFile() {
+	// Given
+	block := blocks.NewBlockImageBlur()
+	imageBuffer := factories.GetPNGImageBuffer(10, 10)
+	// The MP4 file type box is enough to detect the video
+	videoBuffer := bytes.NewBuffer(
+		append([]byte{0, 0, 0, 0x18}, []byte("ftypisom\x00\x00\x02\x00isomiso2")...),
+	)
+
+	// Then
+	suite.Nil(block.ValidateOutput([]*bytes.Buffer{&imageBuffer}))
+	suite.NotNil(block.ValidateOutput([]*bytes.Buffer{bytes.NewBufferString("not an image")}))
+	suite.NotNil(block.ValidateOutput([]*bytes.Buffer{videoBuffer}))
+	suite.NotNil(block.ValidateOutput([]*bytes.Buffer{{}}))
+	suite.Nil(blocks.NewBlockJoinVideos().ValidateOutput([]*bytes.Buffer{videoBuffer}))
+}
+
+func (suite *UnitTestSuite) TestBlockValidateOutputJSON() {
+	// Given
+	block := &blocks.BlockParent{
+		Id: "json_block",
+		SchemaString: `{
+			"type": "object",
+			"properties": {
+				"output": {
+					"type": "array",
+					"items": {
+						"type": "string",
+						"contentMediaType": "application/json",
+						"contentSchema": {
+							"type": "object",
+							"properties": {
+								"text": {"type": "string"}
+							},
+							"required": ["text"]
+						}
+					}
+				}
+			}
+		}`,
+	}
+
+	// Then
+	suite.Nil(block.ValidateOutput([]*bytes.Buffer{bytes.NewBufferString(`{"text": "hello"}`)}))
+	suite.NotNil(block.ValidateOutput([]*bytes.Buffer{bytes.NewBufferString(`{"text": 1}`)}))
+	suite.NotNil(block.ValidateOutput([]*bytes.Buffer{bytes.NewBufferString(`{}`)}))
+	suite.NotNil(block.ValidateOutput([]*bytes.Buffer{bytes.NewBufferString(`not json`)}))
+}
+
+func (suite *UnitTestSuite) TestBlockValidateOutputNullable() {
+	// Given
+	block := &blocks.BlockParent{
+		Id:           "nullable_block",
+		SchemaString: `{"properties": {"output": {"type": ["string", "null"]}}}`,
+	}
+	requiredBlock := &blocks.BlockParent{
+		Id:           "required_block",
+		SchemaString: `{"properties": {"output": {"type": "string"}}}`,
+	}
+
+	// Then
+	suite.Nil(block.ValidateOutput([]*bytes.Buffer{{}}))
+	suite.Nil(requiredBlock.ValidateOutput([]*bytes.Buffer{bytes.NewBufferString("text")}))
+	suite.NotNil(requiredBlock.ValidateOutput([]*bytes.Buffer{{}}))
+}
+
+type sequenceOutputProcessor struct {
+	stubOutputProcessor
+	outputs [][]*bytes.Buffer
+	calls   int
+}
+
+func (p *sequenceOutputProcessor) Process(
+	ctx context.Context,
+	block interfaces.Block,
+	data interfaces.ProcessableBlockData,
+) ([]*bytes.Buffer, bool, bool, string, int, error) {
+	p.output = p.outputs[min(p.calls, len(p.outputs)-1)]
+	p.calls++
+
+	return p.stubOutputProcessor.Process(ctx, block, data)
+}
+
+func (suite *UnitTestSuite) getOutputValidationProcessing(
+	processor interfaces.BlockProcessor,
+) *dataclasses.Processing {
+	block := blocks.NewBlockOpenAIRequestImage()
+	block.SetProcessor(processor)
+	data := &dataclasses.BlockData{
+		Id:   "openai_image_request",
+		Slug: "openai-image-request",
+		Input: map[string]interface{}{
+			"prompt": "A cat sitting on the window",
+		},
+	}
+	data.SetBlock(block)
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	return dataclasses.NewProcessing(ctx, ctxCancel, uuid.New(), nil, block, data)
+}
+
+func (suite *UnitTestSuite) TestProcessingOutputValidationRetries() {
+	// Given
+	_config := config.GetConfig()
+	blockConfig := _config.Blocks["openai_image_request"]
+	defer func() {
+		_config.Blocks["openai_image_request"] = blockConfig
+	}()
+
+	enabled, retries := true, 2
+	validatedBlockConfig := blockConfig
+	validatedBlockConfig.ValidateOutput = &enabled
+	validatedBlockConfig.OutputValidationRetries = &retries
+	_config.Blocks["openai_image_request"] = validatedBlockConfig
+
+	imageBuffer := factories.GetPNGImageBuffer(10, 10)
+	processor := &sequenceOutputProcessor{
+		outputs: [][]*bytes.Buffer{
+			{bytes.NewBufferString("not an image")},
+			{&imageBuffer},
+		},
+	}
+	processing := suite.getOutputValidationProcessing(processor)
+
+	// When
+	output := processing.Start()
+
+	// Then
+	// Invalid outputs are retried though the processor has no retries
+	suite.Nil(output.GetError())
+	suite.Equal(interfaces.ProcessingStatusCompleted, processing.GetStatus())
+	suite.Len(output.GetValue(), 1)
+	suite.Equal(2, processor.calls)
+
+	// Given
+	processor = &sequenceOutputProcessor{
+		outputs: [][]*bytes.Buffer{{bytes.NewBufferString("not an image")}},
+	}
+	processing = suite.getOutputValidationProcessing(processor)
+
+	// When
+	output = processing.Start()
+
+	// Then
+	// The validation error is kept when the retries are exhausted
+	suite.Equal(interfaces.ProcessingStatusRetryFailed, processing.GetStatus())
+	suite.True(errors.Is(output.GetError(), blocks.ErrInvalidOutput))
+	suite.Contains(output.GetError().Error(), "failed after exhausting all 2 retry attempts")
+	suite.Equal(retries+1, processor.calls)
+}
+
+func (suite *UnitTestSuite) TestBlockProcessOutputValidation() {
+	// Given
+	_config := config.GetConfig()
+	blockConfig := _config.Blocks["image_blur"]
+	defer func() {
+		_config.Blocks["image_blur"] = blockConfig
+	}()
+
+	enabled, disabled := true, false
+	validatedBlockConfig := blockConfig
+
+	imageBuffer := factories.GetPNGImageBuffer(10, 10)
+	block := blocks.NewBlockImageBlur()
+	data := &dataclasses.BlockData{
+		Id:   "image_blur",
+		Slug: "image-blur",
+		Input: map[string]interface{}{
+			"image": imageBuffer.Bytes(),
+		},
+	}
+	data.SetBlock(block)
+	processor := &stubOutputProcessor{
+		output: []*bytes.Buffer{bytes.NewBufferString("not an image")},
+	}
+
+	// When
+	validatedBlockConfig.ValidateOutput = &disabled
+	_config.Blocks["image_blur"] = validatedBlockConfig
+	result, _, retry, _, _, err := block.Process(suite.GetContextWithcancel(), processor, data)
+
+	// Then
+	suite.Nil(err)
+	suite.False(retry)
+	suite.Len(result, 1)
+
+	// When
+	validatedBlockConfig.ValidateOutput = &enabled
+	_config.Blocks["image_blur"] = validatedBlockConfig
+	result, _, retry, _, _, err = block.Process(suite.GetContextWithcancel(), processor, data)
+
+	// Then
+	// Deterministic blocks fail without the retry
+	suite.NotNil(err)
+	suite.False(retry)
+	suite.Empty(result)
+
+	// When
+	processor.metadata = map[int]schemas.OutputMetadataSchema{0: {ContentType: "image/png"}}
+	result, _, retry, _, _, err = block.Process(suite.GetContextWithcancel(), processor, data)
+
+	// Then
+	// Content type is detected without the metadata recorder of the processing
+	suite.NotNil(err)
+	suite.False(retry)
+
+	// When
+	ctx, _ := blocks.WithOutputMetadataRecorder(suite.GetContextWithcancel())
+	result, _, retry, _, _, err = block.Process(ctx, processor, data)
+
+	// Then
+	// Content type declared in the output metadata is not detected again
+	suite.Nil(err)
+	suite.False(retry)
+	suite.Len(result, 1)
+
+	// When
+	processor.metadata = nil
+	processor.output = []*bytes.Buffer{&imageBuffer}
+	result, _, retry, _, _, err = block.Process(suite.GetContextWithcancel(), processor, data)
+
+	// Then
+	suite.Nil(err)
+	suite.False(retry)
+	suite.Len(result, 1)
+}
+
+func (suite *UnitTestSuite) TestBlockProcessOutputValidationNonDeterministic() {
+	// Given
+	_config := config.GetConfig()
+	blockConfig := _config.Blocks["openai_image_request"]
+	defer func() {
+		_config.Blocks["openai_image_request"] = blockConfig
+	}()
+
+	enabled := true
+	validatedBlockConfig := blockConfig
+	validatedBlockConfig.ValidateOutput = &enabled
+	_config.Blocks["openai_image_request"] = validatedBlockConfig
+
+	block := blocks.NewBlockOpenAIRequestImage()
+	data := &dataclasses.BlockData{
+		Id:   "openai_image_request",
+		Slug: "openai-image-request",
+		Input: map[string]interface{}{
+			"prompt": "A cat sitting on the window",
+		},
+	}
+	data.SetBlock(block)
+	processor := &stubOutputProcessor{
+		output: []*bytes.Buffer{bytes.NewBufferString("not an image")},
+	}
+
+	// When
+	result, _, retry, _, _, err := block.Process(suite.GetContextWithcancel(), processor, data)
+
+	// Then
+	suite.True(errors.Is(err, blocks.ErrInvalidOutput))
+	suite.True(retry)
+	suite.Empty(result)
+}
+
+func (suite *UnitTestSuite) TestBlockValidateOutputDeclaredContentType() {
+	// Given
+	block := blocks.NewBlockImageBlur()
+	imageBuffer := factories.GetPNGImageBuffer(10, 10)
+	textBuffer := bytes.NewBufferString("not an image")
+
+	// Then
+	suite.Nil(block.ValidateOutput(
+		[]*bytes.Buffer{textBuffer},
+		[]schemas.OutputMetadataSchema{{ContentType: "image/png"}},
+	))
+	suite.Nil(block.ValidateOutput(
+		[]*bytes.Buffer{textBuffer},
+		[]schemas.OutputMetadataSchema{{ContentType: "image/x-custom; quality=high"}},
+	))
+	suite.NotNil(block.ValidateOutput(
+		[]*bytes.Buffer{&imageBuffer},
+		[]schemas.OutputMetadataSchema{{ContentType: "video/mp4"}},
+	))
+	// Outputs without the declared content type are detected
+	suite.Nil(block.ValidateOutput([]*bytes.Buffer{&imageBuffer}, []schemas.OutputMetadataSchema{{}}))
+	suite.NotNil(block.ValidateOutput([]*bytes.Buffer{textBuffer}, []schemas.OutputMetadataSchema{}))
+}
+
+func (suite *UnitTestSuite) TestGetOutputValidationRetryCount() {
+	// Given
+	retries := 5
+	_config := config.Config{
+		Pipeline: config.PipelineConfig{OutputValidationRetries: 2},
+		Blocks: map[string]config.BlockConfig{
+			"retried_block": {OutputValidationRetries: &retries},
+		},
+	}
+
+	// Then
+	suite.Equal(5, blocks.GetOutputValidationRetryCount(_config, "retried_block"))
+	suite.Equal(2, blocks.GetOutputValidationRetryCount(_config, "other_block"))
+}
+
+func (suite *UnitTestSuite) TestIsOutputValidationEnabled() {
+	// Given
+	enabled := true
+	disabled := false
+	_config := config.Config{
+		Pipeline: config.PipelineConfig{ValidateBlockOutputs: true},
+		Blocks: map[string]config.BlockConfig{
+			"enabled_block":  {ValidateOutput: &enabled},
+			"disabled_block": {ValidateOutput: &disabled},
+		},
+	}
+
+	// Then
+	suite.True(blocks.IsOutputValidationEnabled(_config, "enabled_block"))
+	suite.False(blocks.IsOutputValidationEnabled(_config, "disabled_block"))
+	suite.True(blocks.IsOutputValidationEnabled(_config, "other_block"))
+
+	_config.Pipeline.ValidateBlockOutputs = false
+	suite.True(blocks.IsOutputValidationEnabled(_config, "enabled_block"))
+	suite.False(blocks.IsOutputValidationEnabled(_config, "other_block"))
+}
@@ -0,0 +1,376 @@
+package unit_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"data-pipelines-worker/types/blocks"
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/plugins"
+	"data-pipelines-worker/types/registries"
+)
+
+const PLUGIN_HELPER_PROCESS_ENV = "DATA_PIPELINES_PLUGIN_HELPER_PROCESS"
+
+// servePlugin is the test plugin: it uppercases the `text` and reverses the `file` of the input
+func servePlugin(method string, r io.Reader, w io.Writer) {
+	reader := bufio.NewReader(r)
+	request := &plugins.Request{}
+	if err := plugins.ReadHeader(reader, request); err != nil {
+		plugins.WriteMessage(w, plugins.ProcessResult{Error: err.Error()}, nil)
+		return
+	}
+
+	switch method {
+	case plugins.METHOD_DESCRIBE:
+		plugins.WriteMessage(w, plugins.Description{
+			Id:          "test_plugin",
+			Name:        "Test Plugin",
+			Description: "Uppercase the text and reverse the file",
+			Version:     "2",
+			Schema: []byte(`{
+				"type": "object",
+				"properties": {
+					"input": {
+						"type": "object",
+						"properties": {
+							"text": {"type": "string"},
+							"file": {"type": "string", "format": "file"},
+							"sleep": {"type": "integer"},
+							"fail": {"type": "boolean"},
+							"env": {"type": "string"}
+						},
+						"required": ["text"]
+					},
+					"output": {"type": "string", "format": "file"}
+				}
+			}`),
+		}, nil)
+	case plugins.METHOD_DETECT:
+		plugins.WriteMessage(w, plugins.DetectResult{Available: true}, nil)
+	case plugins.METHOD_PROCESS:
+		files, err := plugins.ReadFiles(reader, request.Files, 0)
+		if err != nil {
+			plugins.WriteMessage(w, plugins.ProcessResult{Error: err.Error()}, nil)
+			return
+		}
+		if sleep, ok := request.Input["sleep"].(float64); ok {
+			time.Sleep(time.Duration(sleep) * time.Millisecond)
+		}
+		if fail, ok := request.Input["fail"].(bool); ok && fail {
+			plugins.WriteMessage(w, plugins.ProcessResult{Error: "fail is requested", Retry: true}, nil)
+			return
+		}
+
+		text := []byte(strings.ToUpper(request.Input["text"].(string)))
+		if env, ok := request.Input["env"].(string); ok {
+			text = []byte(os.Getenv(env))
+		}
+		outputs := []io.Reader{bytes.NewReader(text)}
+		result := plugins.ProcessResult{
+			Outputs: []plugins.File{{Name: "text", Size: int64(len(text))}},
+		}
+		for i, file := range files {
+			reversed := file.Bytes()
+			for left, right := 0, len(reversed)-1; left < right; left, right = left+1, right-1 {
+				reversed[left], reversed[right] = reversed[right], reversed[left]
+			}
+			outputs = append(outputs, bytes.NewReader(reversed))
+			result.Outputs = append(result.Outputs, plugins.File{Name: request.Files[i].Name, Size: int64(len(reversed))})
+		}
+		plugins.WriteMessage(w, result, outputs)
+	}
+}
+
+// TestPluginHelperProcess is the executable of the exec plugin, it runs only as the subprocess of the tests
+func TestPluginHelperProcess(t *testing.T) {
+	if os.Getenv(PLUGIN_HELPER_PROCESS_ENV) != "1" {
+		return
+	}
+
+	servePlugin(os.Args[len(os.Args)-1], os.Stdin, os.Stdout)
+	os.Exit(0)
+}
+
+func (suite *UnitTestSuite) GetPluginHTTPServerURL() string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		servePlugin(strings.TrimPrefix(r.URL.Path, "/"), r.Body, w)
+	}))
+	suite.httpTestServers = append(suite.httpTestServers, server)
+
+	return server.URL
+}
+
+func (suite *UnitTestSuite) GetPluginExecConfig() config.ExternalPluginConfig {
+	return config.ExternalPluginConfig{
+		Name:    "test-exec-plugin",
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestPluginHelperProcess$", "--"},
+		Env:     map[string]string{PLUGIN_HELPER_PROCESS_ENV: "1"},
+	}
+}
+
+func (suite *UnitTestSuite) TestNewBlockPlugin() {
+	testCases := map[string]config.ExternalPluginConfig{
+		"http": {Name: "test-http-plugin", URL: suite.GetPluginHTTPServerURL()},
+		"exec": suite.GetPluginExecConfig(),
+	}
+
+	for name, pluginConfig := range testCases {
+		suite.Run(name, func() {
+			// When
+			block, err := blocks.NewBlockExternalPlugin(pluginConfig)
+
+			// Then
+			suite.Nil(err)
+			suite.Equal("test_plugin", block.GetId())
+			suite.Equal("Test Plugin", block.GetName())
+			suite.Equal("Uppercase the text and reverse the file", block.GetDescription())
+			suite.Equal("2", block.GetVersion())
+			suite.NotNil(block.GetSchema())
+			suite.True(blocks.NewDetectorPlugin(block.Plugin, block.GetDetectorConfig()).Detect())
+		})
+	}
+}
+
+func (suite *UnitTestSuite) TestNewBlockPluginInvalidConfig() {
+	_, err := blocks.NewBlockExternalPlugin(config.ExternalPluginConfig{Name: "nothing"})
+	suite.NotNil(err)
+	suite.Equal("plugin nothing has neither command nor url", err.Error())
+
+	_, err = blocks.NewBlockExternalPlugin(config.ExternalPluginConfig{URL: "http://127.0.0.1:1"})
+	suite.NotNil(err)
+}
+
+func (suite *UnitTestSuite) TestBlockPluginProcessSuccess() {
+	testCases := map[string]config.ExternalPluginConfig{
+		"http": {Name: "test-http-plugin", URL: suite.GetPluginHTTPServerURL()},
+		"exec": suite.GetPluginExecConfig(),
+	}
+
+	for name, pluginConfig := range testCases {
+		suite.Run(name, func() {
+			// Given
+			block, err := blocks.NewBlockExternalPlugin(pluginConfig)
+			suite.Nil(err)
+
+			data := &dataclasses.BlockData{
+				Id:   "test_plugin",
+				Slug: "test-plugin",
+				Input: map[string]interface{}{
+					"text": "hello",
+					"file": []byte("0123456789"),
+				},
+			}
+			data.SetBlock(block)
+
+			// When
+			result, stop, retry, _, _, err := block.Process(
+				suite.GetContextWithcancel(),
+				blocks.NewProcessorPlugin(),
+				data,
+			)
+
+			// Then
+			suite.Nil(err)
+			suite.False(stop)
+			suite.False(retry)
+			suite.Len(result, 2)
+			suite.Equal("HELLO", result[0].String())
+			suite.Equal("9876543210", result[1].String())
+		})
+	}
+}
+
+func (suite *UnitTestSuite) TestBlockPluginProcessEnv() {
+	// Given
+	suite.T().Setenv("SECRET_TEST_TOKEN", "worker-secret")
+	block, err := blocks.NewBlockExternalPlugin(suite.GetPluginExecConfig())
+	suite.Nil(err)
+
+	testCases := map[string]string{
+		"SECRET_TEST_TOKEN":       "",
+		PLUGIN_HELPER_PROCESS_ENV: "1",
+		"PATH":                    os.Getenv("PATH"),
+	}
+
+	for env, expected := range testCases {
+		suite.Run(env, func() {
+			data := &dataclasses.BlockData{
+				Id:    "test_plugin",
+				Slug:  "test-plugin",
+				Input: map[string]interface{}{"text": "hello", "env": env},
+			}
+			data.SetBlock(block)
+
+			// When
+			result, _, _, _, _, err := block.Process(
+				suite.GetContextWithcancel(),
+				blocks.NewProcessorPlugin(),
+				data,
+			)
+
+			// Then
+			suite.Nil(err)
+			suite.Len(result, 1)
+			suite.Equal(expected, result[0].String())
+		})
+	}
+}
+
+func (suite *UnitTestSuite) TestBlockPluginProcessError() {
+	// Given
+	block, err := blocks.NewBlockExternalPlugin(suite.GetPluginExecConfig())
+	suite.Nil(err)
+
+	data := &dataclasses.BlockData{
+		Id:    "test_plugin",
+		Slug:  "test-plugin",
+		Input: map[string]interface{}{"text": "hello", "fail": true},
+	}
+	data.SetBlock(block)
+
+	// When
+	result, _, retry, _, _, err := block.Process(
+		suite.GetContextWithcancel(),
+		blocks.NewProcessorPlugin(),
+		data,
+	)
+
+	// Then
+	suite.Empty(result)
+	suite.True(retry)
+	suite.NotNil(err)
+	suite.Equal("plugin test-exec-plugin failed to process: fail is requested", err.Error())
+}
+
+func (suite *UnitTestSuite) TestBlockPluginProcessTimeout() {
+	// Given
+	pluginConfig := suite.GetPluginExecConfig()
+	pluginConfig.Timeout = 200 * time.Millisecond
+
+	block, err := blocks.NewBlockExternalPlugin(pluginConfig)
+	suite.Nil(err)
+
+	data := &dataclasses.BlockData{
+		Id:    "test_plugin",
+		Slug:  "test-plugin",
+		Input: map[string]interface{}{"text": "hello", "sleep": 5000},
+	}
+	data.SetBlock(block)
+
+	// When
+	startedAt := time.Now()
+	_, _, _, _, _, err = block.Process(
+		suite.GetContextWithcancel(),
+		blocks.NewProcessorPlugin(),
+		data,
+	)
+
+	// Then
+	suite.NotNil(err)
+	suite.Equal("plugin test-exec-plugin timed out after 200ms", err.Error())
+	suite.Less(time.Since(startedAt), 5*time.Second)
+}
+
+func (suite *UnitTestSuite) TestBlockPluginProcessCancel() {
+	// Given
+	block, err := blocks.NewBlockExternalPlugin(suite.GetPluginExecConfig())
+	suite.Nil(err)
+
+	data := &dataclasses.BlockData{
+		Id:    "test_plugin",
+		Slug:  "test-plugin",
+		Input: map[string]interface{}{"text": "hello", "sleep": 5000},
+	}
+	data.SetBlock(block)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	// When
+	_, _, _, _, _, err = block.Process(ctx, blocks.NewProcessorPlugin(), data)
+
+	// Then
+	suite.Equal(context.Canceled, err)
+}
+
+func (suite *UnitTestSuite) TestBlockRegistryDetectPluginBlocks() {
+	// Given
+	_config := config.GetConfig()
+	_config.Plugins.External = []config.ExternalPluginConfig{
+		{Name: "test-http-plugin", URL: suite.GetPluginHTTPServerURL()},
+		// Plugins with the ids of the registered blocks are skipped
+		suite.GetPluginExecConfig(),
+		{Name: "unreachable", URL: "http://127.0.0.1:1"},
+	}
+	defer func() {
+		_config.Plugins.External = nil
+	}()
+
+	// When
+	blockRegistry := registries.NewBlockRegistry()
+	defer blockRegistry.Shutdown(suite.GetShutDownContext(time.Second))
+
+	// Then
+	block := blockRegistry.Get("test_plugin")
+	suite.NotNil(block)
+	suite.IsType(&blocks.BlockPlugin{}, block)
+	suite.Equal("test-http-plugin", block.(*blocks.BlockPlugin).Plugin.GetName())
+	suite.True(blockRegistry.IsAvailable(block))
+	suite.NotNil(blockRegistry.Get("http_request"))
+}
+
+func (suite *UnitTestSuite) TestBlockRegistryDetectSlowPluginBlocks() {
+	// Given
+	describeStarted := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := strings.TrimPrefix(r.URL.Path, "/")
+		if method == plugins.METHOD_DESCRIBE {
+			select {
+			case describeStarted <- struct{}{}:
+			default:
+			}
+			time.Sleep(time.Second)
+		}
+		servePlugin(method, r.Body, w)
+	}))
+	suite.httpTestServers = append(suite.httpTestServers, server)
+
+	blockRegistry := registries.NewBlockRegistry()
+	defer blockRegistry.Shutdown(suite.GetShutDownContext(time.Second))
+
+	_config := config.GetConfig()
+	_config.Plugins.External = []config.ExternalPluginConfig{
+		{Name: "slow-http-plugin", URL: server.URL},
+	}
+	defer func() {
+		_config.Plugins.External = nil
+	}()
+
+	// When
+	detected := make(chan struct{})
+	go func() {
+		blockRegistry.DetectBlocks()
+		close(detected)
+	}()
+	<-describeStarted
+
+	// Then
+	// Registered blocks are served while the plugins are described
+	lookupStarted := time.Now()
+	suite.NotNil(blockRegistry.Get("http_request"))
+	suite.Less(time.Since(lookupStarted), 500*time.Millisecond)
+
+	<-detected
+	suite.NotNil(blockRegistry.Get("test_plugin"))
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"data-pipelines-worker/types/blocks"
 	"data-pipelines-worker/types/config"
 	"data-pipelines-worker/types/registries"
 )
@@ -130,3 +131,141 @@ func (suite *UnitTestSuite) TestBlockRegistryShutdown() {
 		suite.False(block.IsAvailable())
 	}
 }
+
+func (suite *UnitTestSuite) TestBlockRegistryDisabledBlocks() {
+	// Given
+	_config := config.GetConfig()
+	blockConfig := _config.Blocks["text_replace"]
+	defer func() {
+		_config.Blocks["text_replace"] = blockConfig
+	}()
+
+	enabled := false
+	disabledBlockConfig := blockConfig
+	disabledBlockConfig.Enabled = &enabled
+	_config.Blocks["text_replace"] = disabledBlockConfig
+
+	// When
+	blockRegistry := registries.NewBlockRegistry()
+	defer blockRegistry.Shutdown(suite.GetShutDownContext(time.Second))
+
+	// Then
+	suite.Nil(blockRegistry.Get("text_replace"))
+	suite.NotNil(blockRegistry.Get("join_strings"))
+	suite.Len(blockRegistry.GetAll(), len(blocks.GetBlockRegistrations())-1)
+}
+
+func (suite *UnitTestSuite) TestBlockRegistryBlockAliases() {
+	// Given
+	_config := config.GetConfig()
+	blockConfig := _config.Blocks["join_strings"]
+	defer func() {
+		_config.Blocks["join_strings"] = blockConfig
+	}()
+
+	aliasedBlockConfig := blockConfig
+	aliasedBlockConfig.Aliases = []string{"concat_strings"}
+	_config.Blocks["join_strings"] = aliasedBlockConfig
+
+	// When
+	blockRegistry := registries.NewBlockRegistry()
+	defer blockRegistry.Shutdown(suite.GetShutDownContext(time.Second))
+
+	// Then
+	suite.Same(blockRegistry.Get("join_strings"), blockRegistry.Get("concat_strings"))
+	suite.Same(blockRegistry.Get("wrap_text"), blockRegistry.Get("text_add_prefix_or_suffix"))
+	suite.NotNil(blockRegistry.Get("wrap_text"))
+	suite.Nil(blockRegistry.Get("unknown_block"))
+
+	_, ok := blockRegistry.GetAll()["concat_strings"]
+	suite.False(ok)
+}
+
+func (suite *UnitTestSuite) TestBlockRegistryBlocksWithoutConfig() {
+	// Given
+	_config := config.GetConfig()
+	blockConfig := _config.Blocks["join_strings"]
+	httpBlockConfig := _config.Blocks["http_request"]
+	delete(_config.Blocks, "join_strings")
+	delete(_config.Blocks, "http_request")
+	defer func() {
+		_config.Blocks["join_strings"] = blockConfig
+		_config.Blocks["http_request"] = httpBlockConfig
+	}()
+
+	// When
+	blockRegistry := registries.NewBlockRegistry()
+	defer blockRegistry.Shutdown(suite.GetShutDownContext(time.Second))
+
+	// Then
+	block := blockRegistry.Get("join_strings")
+	suite.NotNil(block)
+	suite.True(blockRegistry.IsAvailable(block))
+
+	// Detector without the url condition is registered
+	suite.NotNil(blockRegistry.Get("http_request"))
+
+	detector := blocks.NewDetectorJoinStrings(config.BlockConfigDetector{})
+	suite.Equal(config.DEFAULT_BLOCK_CHECK_INTERVAL, detector.Config.CheckInterval)
+
+	defaultBlockConfig := config.BlockConfig{}.WithDefaults()
+	suite.True(defaultBlockConfig.IsEnabled())
+	suite.Equal(config.DEFAULT_BLOCK_CHECK_INTERVAL, defaultBlockConfig.Detector.CheckInterval)
+	suite.NotNil(defaultBlockConfig.Detector.Conditions)
+	suite.Equal(config.DEFAULT_BLOCK_RELIABILITY_POLICY, defaultBlockConfig.Reliability.Policy)
+	suite.NotNil(defaultBlockConfig.Config)
+}
+
+func (suite *UnitTestSuite) TestBlockRegistryBlockVersions() {
+	// Given
+	blockRegistry := registries.NewBlockRegistry()
+	defer blockRegistry.Shutdown(suite.GetShutDownContext(time.Second))
+
+	blockV1 := blockRegistry.Get("join_strings")
+	blockV1_1 := blocks.NewBlockJoinStrings()
+	blockV1_1.Version = "1.1"
+	blockV1_1.SetAvailable(true)
+	blockV2 := blocks.NewBlockJoinStrings()
+	blockV2.Version = "2"
+	blockV2.SetAvailable(true)
+	blockV3 := blocks.NewBlockJoinStrings()
+	blockV3.Version = "3"
+	blockV3.Deprecated = true
+
+	// When
+	blockRegistry.Add(blockV1_1)
+	blockRegistry.Add(blockV2)
+	blockRegistry.Add(blockV3)
+
+	// Then
+	suite.Equal("1", blockV1.GetVersion())
+	suite.Len(blockRegistry.GetVersions("join_strings"), 4)
+
+	// Deprecated versions are not the default
+	suite.Same(blockV2, blockRegistry.Get("join_strings"))
+	suite.Same(blockV2, blockRegistry.GetAll()["join_strings"])
+
+	// Pinned versions resolve to the same version
+	suite.Same(blockV1, blockRegistry.Get("join_strings@1"))
+	suite.Same(blockV1, blockRegistry.Get("join_strings@1.0"))
+	suite.Same(blockV1_1, blockRegistry.Get("join_strings@1.1"))
+	suite.Same(blockV2, blockRegistry.Get("join_strings@2"))
+	suite.Same(blockV3, blockRegistry.Get("join_strings@3"))
+	suite.Nil(blockRegistry.Get("join_strings@4"))
+	suite.Nil(blockRegistry.Get("join_strings@1.2"))
+
+	// Ranges resolve to the latest compatible version
+	suite.Same(blockV1_1, blockRegistry.Get("join_strings@^1"))
+	suite.Same(blockV1_1, blockRegistry.Get("join_strings@^1.1"))
+	suite.Nil(blockRegistry.Get("join_strings@^1.2"))
+
+	// Versions are available on their own
+	suite.True(blockRegistry.IsAvailable(blockV1))
+	suite.True(blockRegistry.IsAvailable(blockV2))
+	suite.False(blockRegistry.IsAvailable(blockV3))
+
+	blockRegistry.Delete("join_strings")
+	suite.Nil(blockRegistry.Get("join_strings"))
+	suite.Nil(blockRegistry.Get("join_strings@1"))
+	suite.Empty(blockRegistry.GetVersions("join_strings"))
+}
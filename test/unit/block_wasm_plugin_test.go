@@ -0,0 +1,308 @@
+package unit_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"data-pipelines-worker/types/blocks"
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/plugins"
+	"data-pipelines-worker/types/registries"
+)
+
+var (
+	wasmPluginOnce    sync.Once
+	wasmPluginContent []byte
+	wasmPluginError   error
+)
+
+// GetWasmPluginContent builds the test WebAssembly plugin of the testdata once
+func (suite *UnitTestSuite) GetWasmPluginContent() []byte {
+	wasmPluginOnce.Do(func() {
+		outputPath := filepath.Join(suite.T().TempDir(), "plugin.wasm")
+
+		cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", outputPath, ".")
+		cmd.Dir = filepath.Join("testdata", "wasm_plugin")
+		cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm", "GOWORK=off")
+
+		if output, err := cmd.CombinedOutput(); err != nil {
+			wasmPluginError = err
+			suite.T().Logf("Failed to build the WebAssembly plugin: %s", output)
+			return
+		}
+		wasmPluginContent, wasmPluginError = os.ReadFile(outputPath)
+	})
+	if wasmPluginError != nil {
+		suite.T().Skipf("WebAssembly plugin is not built: %s", wasmPluginError)
+	}
+
+	return wasmPluginContent
+}
+
+func (suite *UnitTestSuite) GetWasmPluginBlock(wasmConfig config.WasmPluginsConfig) *blocks.BlockPlugin {
+	module, err := plugins.LoadWasmModule(
+		context.Background(),
+		"test-wasm-plugin.wasm",
+		suite.GetWasmPluginContent(),
+		wasmConfig,
+	)
+	suite.Require().Nil(err)
+	suite.T().Cleanup(func() {
+		module.Close(context.Background())
+	})
+
+	block, err := blocks.NewBlockPlugin(module)
+	suite.Require().Nil(err)
+
+	return block
+}
+
+func (suite *UnitTestSuite) TestNewBlockWasmPlugin() {
+	// When
+	block := suite.GetWasmPluginBlock(config.WasmPluginsConfig{})
+
+	// Then
+	suite.Equal("test_wasm_plugin", block.GetId())
+	suite.Equal("Test WebAssembly Plugin", block.GetName())
+	suite.Equal("1", block.GetVersion())
+	suite.Equal("test-wasm-plugin", block.Plugin.GetName())
+	suite.NotNil(block.GetSchema())
+	suite.True(blocks.NewDetectorPlugin(block.Plugin, block.GetDetectorConfig()).Detect())
+}
+
+func (suite *UnitTestSuite) TestLoadWasmModuleInvalid() {
+	_, err := plugins.LoadWasmModule(context.Background(), "invalid.wasm", []byte("not a module"), config.WasmPluginsConfig{})
+	suite.NotNil(err)
+}
+
+func (suite *UnitTestSuite) TestBlockWasmPluginProcess() {
+	// Given
+	block := suite.GetWasmPluginBlock(config.WasmPluginsConfig{})
+
+	testCases := []struct {
+		name    string
+		input   map[string]interface{}
+		outputs []string
+		retry   bool
+		err     string
+	}{
+		{
+			name:    "success",
+			input:   map[string]interface{}{"text": "hello", "file": []byte("0123456789")},
+			outputs: []string{"HELLO", "9876543210"},
+		},
+		{
+			name:  "error",
+			input: map[string]interface{}{"text": "hello", "fail": true},
+			retry: true,
+			err:   "plugin test-wasm-plugin failed to process: fail is requested",
+		},
+	}
+
+	for _, testCase := range testCases {
+		suite.Run(testCase.name, func() {
+			data := &dataclasses.BlockData{
+				Id:    "test_wasm_plugin",
+				Slug:  "test-wasm-plugin",
+				Input: testCase.input,
+			}
+			data.SetBlock(block)
+
+			// When
+			result, _, retry, _, _, err := block.Process(
+				suite.GetContextWithcancel(),
+				blocks.NewProcessorPlugin(),
+				data,
+			)
+
+			// Then
+			suite.Equal(testCase.retry, retry)
+			if testCase.err != "" {
+				suite.NotNil(err)
+				suite.Equal(testCase.err, err.Error())
+				return
+			}
+			suite.Nil(err)
+			suite.Len(result, len(testCase.outputs))
+			for i, output := range testCase.outputs {
+				suite.Equal(output, result[i].String())
+			}
+		})
+	}
+}
+
+func (suite *UnitTestSuite) TestBlockWasmPluginProcessTimeout() {
+	// Given
+	block := suite.GetWasmPluginBlock(config.WasmPluginsConfig{Timeout: 200 * time.Millisecond})
+
+	data := &dataclasses.BlockData{
+		Id:    "test_wasm_plugin",
+		Slug:  "test-wasm-plugin",
+		Input: map[string]interface{}{"text": "hello", "loop": true},
+	}
+	data.SetBlock(block)
+
+	// When
+	startedAt := time.Now()
+	_, _, _, _, _, err := block.Process(suite.GetContextWithcancel(), blocks.NewProcessorPlugin(), data)
+
+	// Then
+	suite.NotNil(err)
+	suite.Equal("plugin test-wasm-plugin timed out after 200ms", err.Error())
+	suite.Less(time.Since(startedAt), 5*time.Second)
+}
+
+func (suite *UnitTestSuite) TestBlockWasmPluginProcessCancel() {
+	// Given
+	block := suite.GetWasmPluginBlock(config.WasmPluginsConfig{})
+
+	data := &dataclasses.BlockData{
+		Id:    "test_wasm_plugin",
+		Slug:  "test-wasm-plugin",
+		Input: map[string]interface{}{"text": "hello", "loop": true},
+	}
+	data.SetBlock(block)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	// When
+	_, _, _, _, _, err := block.Process(ctx, blocks.NewProcessorPlugin(), data)
+
+	// Then
+	suite.Equal(context.Canceled, err)
+}
+
+func (suite *UnitTestSuite) TestBlockWasmPluginProcessMemoryLimit() {
+	// Given
+	block := suite.GetWasmPluginBlock(config.WasmPluginsConfig{MaxMemoryMB: 64})
+
+	data := &dataclasses.BlockData{
+		Id:    "test_wasm_plugin",
+		Slug:  "test-wasm-plugin",
+		Input: map[string]interface{}{"text": "hello", "allocate": 128 << 20},
+	}
+	data.SetBlock(block)
+
+	// When
+	_, _, _, _, _, err := block.Process(suite.GetContextWithcancel(), blocks.NewProcessorPlugin(), data)
+
+	// Then
+	suite.NotNil(err)
+	suite.Contains(err.Error(), "plugin test-wasm-plugin failed")
+}
+
+func (suite *UnitTestSuite) TestBlockWasmPluginProcessClosed() {
+	// Given
+	module, err := plugins.LoadWasmModule(
+		context.Background(),
+		"test-wasm-plugin.wasm",
+		suite.GetWasmPluginContent(),
+		config.WasmPluginsConfig{},
+	)
+	suite.Require().Nil(err)
+	block, err := blocks.NewBlockPlugin(module)
+	suite.Require().Nil(err)
+
+	data := &dataclasses.BlockData{
+		Id:    "test_wasm_plugin",
+		Slug:  "test-wasm-plugin",
+		Input: map[string]interface{}{"text": "hello"},
+	}
+	data.SetBlock(block)
+
+	// When
+	suite.Nil(module.Close(context.Background()))
+	_, _, _, _, _, err = block.Process(suite.GetContextWithcancel(), blocks.NewProcessorPlugin(), data)
+
+	// Then
+	suite.True(errors.Is(err, plugins.ErrPluginClosed))
+}
+
+func (suite *UnitTestSuite) TestBlockRegistryReloadWasmPlugins() {
+	// Given
+	content := suite.GetWasmPluginContent()
+	directory := suite.T().TempDir()
+	pluginPath := filepath.Join(directory, "plugin.wasm")
+	suite.Nil(os.WriteFile(pluginPath, content, 0644))
+
+	_config := config.GetConfig()
+	_config.Plugins.Wasm = config.WasmPluginsConfig{Directory: directory}
+	defer func() {
+		_config.Plugins.Wasm = config.WasmPluginsConfig{}
+	}()
+
+	// When
+	blockRegistry := registries.NewBlockRegistry()
+	defer blockRegistry.Shutdown(suite.GetShutDownContext(time.Second))
+
+	// Then
+	block := blockRegistry.Get("test_wasm_plugin")
+	suite.NotNil(block)
+	suite.True(blockRegistry.IsAvailable(block))
+
+	// Unchanged modules are kept
+	blockRegistry.ReloadWasmPlugins()
+	suite.Same(block, blockRegistry.Get("test_wasm_plugin"))
+
+	// Invalid modules keep the previous version
+	suite.Nil(os.WriteFile(pluginPath, []byte("not a module"), 0644))
+	blockRegistry.ReloadWasmPlugins()
+	suite.Same(block, blockRegistry.Get("test_wasm_plugin"))
+
+	// Changed modules replace the block, a custom section is appended to change the module
+	changedContent := append(append([]byte{}, content...), 0, 6, 4, 't', 'e', 's', 't', 1)
+	suite.Nil(os.WriteFile(pluginPath, changedContent, 0644))
+	blockRegistry.ReloadWasmPlugins()
+	changedBlock := blockRegistry.Get("test_wasm_plugin")
+	suite.NotNil(changedBlock)
+	suite.NotSame(block, changedBlock)
+	suite.False(block.IsAvailable())
+	suite.True(blockRegistry.IsAvailable(changedBlock))
+
+	// Removed modules delete the block, the blocks returned before are not changed
+	registeredBlocks := blockRegistry.GetAll()
+	suite.Nil(os.Remove(pluginPath))
+	blockRegistry.ReloadWasmPlugins()
+	suite.Nil(blockRegistry.Get("test_wasm_plugin"))
+	suite.Same(changedBlock, registeredBlocks["test_wasm_plugin"])
+	suite.NotNil(blockRegistry.Get("http_request"))
+}
+
+func (suite *UnitTestSuite) TestWasmPluginsWatcherReload() {
+	// Given
+	content := suite.GetWasmPluginContent()
+	directory := suite.T().TempDir()
+
+	_config := config.GetConfig()
+	_config.Plugins.Wasm = config.WasmPluginsConfig{
+		Directory:   directory,
+		Watch:       true,
+		ReloadDelay: 50 * time.Millisecond,
+	}
+	defer func() {
+		_config.Plugins.Wasm = config.WasmPluginsConfig{}
+	}()
+
+	blockRegistry := registries.NewBlockRegistry()
+	defer blockRegistry.Shutdown(suite.GetShutDownContext(time.Second))
+	suite.Nil(blockRegistry.Get("test_wasm_plugin"))
+
+	watcher := registries.NewWasmPluginsWatcher(blockRegistry)
+	watcher.Start()
+	defer watcher.Shutdown(suite.GetShutDownContext(time.Second))
+
+	// When
+	suite.Nil(os.WriteFile(filepath.Join(directory, "plugin.wasm"), content, 0644))
+
+	// Then
+	suite.Eventually(func() bool {
+		return blockRegistry.Get("test_wasm_plugin") != nil
+	}, 5*time.Second, 50*time.Millisecond)
+}
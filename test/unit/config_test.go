@@ -1,6 +1,9 @@
 package unit_test
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"data-pipelines-worker/types/config"
@@ -67,3 +70,37 @@ func (suite *UnitTestSuite) TestGetConfig() {
 		_config.Blocks["http_request"].Reliability.PolicyConfig,
 	)
 }
+
+func (suite *UnitTestSuite) TestNewConfigFromFilePipelineValidateBlockOutputs() {
+	// Given
+	configPath := os.Getenv("CONFIG_FILE")
+	if configPath == "" {
+		configPath = config.CONFIG_FILE
+	}
+	configData, err := os.ReadFile(configPath)
+	suite.Nil(err)
+
+	configDir, err := filepath.Abs(filepath.Dir(configPath))
+	suite.Nil(err)
+	configYAML := strings.ReplaceAll(string(configData), `"./`, `"`+configDir+`/`)
+	configYAML = strings.Replace(
+		configYAML,
+		"pipeline_validate_block_outputs: no",
+		"pipeline_validate_block_outputs: yes",
+		1,
+	)
+	suite.Contains(configYAML, "pipeline_validate_block_outputs: yes")
+
+	testConfigPath := filepath.Join(suite.T().TempDir(), "config.yaml")
+	suite.Nil(os.WriteFile(testConfigPath, []byte(configYAML), 0644))
+
+	// When
+	_config := config.NewConfigFromFile(testConfigPath)
+
+	// Then
+	suite.True(_config.Pipeline.ValidateBlockOutputs)
+	suite.Equal(2, _config.Pipeline.OutputValidationRetries)
+	suite.NotNil(_config.Pipeline.SchemaPtr)
+	suite.Equal(filepath.Join(configDir, "pipelines"), _config.Pipeline.Catalogue)
+	suite.False(config.GetConfig().Pipeline.ValidateBlockOutputs)
+}
@@ -0,0 +1,129 @@
+package unit_test
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"data-pipelines-worker/types/blocks"
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/dataclasses"
+)
+
+func (suite *UnitTestSuite) getTestOpenAIClient(baseURL string) *openai.Client {
+	return openai.NewClientWithConfig(
+		openai.ClientConfig{
+			BaseURL:    baseURL,
+			APIType:    openai.APITypeOpenAI,
+			HTTPClient: &http.Client{},
+		},
+	)
+}
+
+func (suite *UnitTestSuite) TestConfigCredentialsProfiles() {
+	// Given
+	channelB := &config.OpenAIConfig{BaseURL: "http://localhost/v1"}
+	openAIConfig := &config.OpenAIConfig{
+		Profiles: map[string]*config.OpenAIConfig{
+			"channel-b": channelB,
+			"channel-a": {},
+		},
+	}
+
+	// When
+	defaultProfile, defaultErr := openAIConfig.GetProfile("")
+	namedProfile, namedErr := openAIConfig.GetProfile("channel-b")
+	_, missingErr := openAIConfig.GetProfile("channel-c")
+
+	// Then
+	suite.Nil(defaultErr)
+	suite.Same(openAIConfig, defaultProfile)
+	suite.Nil(namedErr)
+	suite.Same(channelB, namedProfile)
+	suite.True(errors.Is(missingErr, config.ErrCredentialsProfileNotFound))
+	suite.Equal([]string{"default", "channel-a", "channel-b"}, openAIConfig.GetProfileNames())
+
+	var telegramConfig *config.TelegramConfig
+	suite.Empty(telegramConfig.GetProfileNames())
+}
+
+func (suite *UnitTestSuite) TestDetectorOpenAIProfiles() {
+	// Given
+	modelsURL := suite.GetMockHTTPServerURL(`{"object": "list", "data": []}`, http.StatusOK, 0)
+	channelB := &config.OpenAIConfig{}
+	channelB.SetClient(suite.getTestOpenAIClient(modelsURL))
+	openAIConfig := &config.OpenAIConfig{
+		Profiles: map[string]*config.OpenAIConfig{
+			"channel-b": channelB,
+		},
+	}
+
+	detector := blocks.NewDetectorOpenAI(
+		openAIConfig,
+		config.BlockConfigDetector{CheckInterval: time.Millisecond},
+	)
+
+	// When
+	available := detector.Detect()
+
+	// Then
+	suite.True(available)
+	suite.Equal(
+		map[string]bool{"default": false, "channel-b": true},
+		detector.GetProfilesAvailability(),
+	)
+}
+
+func (suite *UnitTestSuite) TestBlockOpenAIRequestCompletionProcessCredentialsProfile() {
+	// Given
+	block := blocks.NewBlockOpenAIRequestCompletion()
+	data := &dataclasses.BlockData{
+		Id:   "openai_chat_completion",
+		Slug: "request-openai-chat-completion",
+		Input: map[string]interface{}{
+			"user_prompt": "Hello world!",
+			"credentials": "channel-b",
+		},
+	}
+	data.SetBlock(block)
+
+	completionURL := suite.GetMockHTTPServerURL(
+		`{"id": "chatcmpl-123", "choices": [{"index": 0, "message": {"role": "assistant", "content": "Channel B"}}]}`,
+		http.StatusOK,
+		0,
+	)
+	channelB := &config.OpenAIConfig{}
+	channelB.SetClient(suite.getTestOpenAIClient(completionURL))
+
+	suite._config.OpenAI.SetClient(nil)
+	suite._config.OpenAI.Profiles = map[string]*config.OpenAIConfig{"channel-b": channelB}
+	defer func() {
+		suite._config.OpenAI.Profiles = nil
+	}()
+
+	// When
+	result, _, _, _, _, err := block.Process(
+		suite.GetContextWithcancel(),
+		blocks.NewProcessorOpenAIRequestCompletion(),
+		data,
+	)
+
+	// Then
+	suite.Nil(err)
+	suite.Len(result, 1)
+	suite.Equal("Channel B", result[0].String())
+
+	// Unknown profile fails the block
+	data.Input = map[string]interface{}{
+		"user_prompt": "Hello world!",
+		"credentials": "channel-c",
+	}
+	_, _, _, _, _, err = block.Process(
+		suite.GetContextWithcancel(),
+		blocks.NewProcessorOpenAIRequestCompletion(),
+		data,
+	)
+	suite.True(errors.Is(err, config.ErrCredentialsProfileNotFound))
+}
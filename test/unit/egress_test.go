@@ -0,0 +1,255 @@
+package unit_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"data-pipelines-worker/types/blocks"
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/egress"
+)
+
+func (suite *UnitTestSuite) TestEgressPolicyCheckURL() {
+	testCases := []struct {
+		name         string
+		egressConfig config.EgressConfig
+		url          string
+		err          string
+	}{
+		{
+			name:         "public address",
+			egressConfig: config.EgressConfig{BlockPrivateNetworks: true},
+			url:          "https://93.184.215.14/index.html",
+		},
+		{
+			name:         "loopback",
+			egressConfig: config.EgressConfig{BlockPrivateNetworks: true},
+			url:          "http://127.0.0.1:9000/bucket",
+			err:          "egress policy denied: address 127.0.0.1 is in a private network",
+		},
+		{
+			name:         "cloud metadata",
+			egressConfig: config.EgressConfig{BlockPrivateNetworks: true},
+			url:          "http://169.254.169.254/latest/meta-data",
+			err:          "egress policy denied: address 169.254.169.254 is in a private network",
+		},
+		{
+			name:         "ipv6 loopback",
+			egressConfig: config.EgressConfig{BlockPrivateNetworks: true},
+			url:          "http://[::1]:9000/",
+			err:          "egress policy denied: address ::1 is in a private network",
+		},
+		{
+			name:         "private networks are not blocked",
+			egressConfig: config.EgressConfig{},
+			url:          "http://10.0.0.1/",
+		},
+		{
+			name:         "allowed address",
+			egressConfig: config.EgressConfig{BlockPrivateNetworks: true, AllowCIDRs: []string{"10.0.0.0/24"}},
+			url:          "http://10.0.0.1/",
+		},
+		{
+			name:         "denied address",
+			egressConfig: config.EgressConfig{DenyCIDRs: []string{"8.8.8.0/24"}},
+			url:          "http://8.8.8.8/",
+			err:          "egress policy denied: address 8.8.8.8 is denied",
+		},
+		{
+			name:         "denied host",
+			egressConfig: config.EgressConfig{DenyHosts: []string{"metadata.google.internal"}},
+			url:          "http://Metadata.Google.Internal./computeMetadata/v1/",
+			err:          "egress policy denied: host metadata.google.internal is denied",
+		},
+		{
+			name:         "allowed host wildcard",
+			egressConfig: config.EgressConfig{AllowHosts: []string{"*.example.com"}},
+			url:          "https://api.example.com/items",
+		},
+		{
+			name:         "host is not allowed",
+			egressConfig: config.EgressConfig{AllowHosts: []string{"*.example.com"}},
+			url:          "https://example.org/items",
+			err:          "egress policy denied: host example.org is not allowed",
+		},
+		{
+			name:         "scheme is not allowed",
+			egressConfig: config.EgressConfig{},
+			url:          "file:///etc/passwd",
+			err:          `egress policy denied: scheme "file" is not allowed`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		suite.Run(testCase.name, func() {
+			// Given
+			policy, err := egress.NewPolicy(testCase.egressConfig)
+			suite.Nil(err)
+
+			requestUrl, err := url.Parse(testCase.url)
+			suite.Nil(err)
+
+			// When
+			err = policy.CheckURL(requestUrl)
+
+			// Then
+			if testCase.err == "" {
+				suite.Nil(err)
+			} else {
+				suite.ErrorIs(err, egress.ErrEgressDenied)
+				suite.Equal(testCase.err, err.Error())
+			}
+		})
+	}
+}
+
+func (suite *UnitTestSuite) TestEgressPolicyInvalidCIDR() {
+	_, err := egress.NewPolicy(config.EgressConfig{DenyCIDRs: []string{"10.0.0.0/33"}})
+
+	suite.NotNil(err)
+	suite.Contains(err.Error(), `invalid egress CIDR "10.0.0.0/33"`)
+}
+
+func (suite *UnitTestSuite) TestEgressPolicyClientChecksResolvedAddress() {
+	// Given
+	serverUrl := suite.GetMockHTTPServerURL("internal", http.StatusOK, 0)
+	// The host name passes the URL check and resolves to the loopback
+	hostUrl := strings.Replace(serverUrl, "127.0.0.1", "localhost", 1)
+
+	policy, err := egress.NewPolicy(config.EgressConfig{BlockPrivateNetworks: true})
+	suite.Nil(err)
+
+	requestUrl, err := url.Parse(hostUrl)
+	suite.Nil(err)
+	suite.Nil(policy.CheckURL(requestUrl))
+
+	// When
+	_, err = policy.NewClient(0, nil).Get(hostUrl)
+
+	// Then
+	suite.ErrorIs(err, egress.ErrEgressDenied)
+	suite.Contains(err.Error(), "is in a private network")
+}
+
+func (suite *UnitTestSuite) TestBlockHTTPProcessEgressDenied() {
+	// Given
+	suite._config.Egress.BlockPrivateNetworks = true
+	suite._config.Egress.AllowCIDRs = nil
+	_, policyErr := egress.ReloadPolicy()
+	suite.Nil(policyErr)
+
+	block := blocks.NewBlockHTTP()
+	data := &dataclasses.BlockData{
+		Id:   "http_request",
+		Slug: "http-request",
+		Input: map[string]interface{}{
+			"url": suite.GetMockHTTPServerURL("internal", http.StatusOK, 0),
+		},
+	}
+	data.SetBlock(block)
+
+	// When
+	result, _, _, _, _, err := block.Process(
+		suite.GetContextWithcancel(),
+		blocks.NewProcessorHTTP(),
+		data,
+	)
+
+	// Then
+	suite.Empty(result)
+	suite.NotNil(err)
+	suite.Contains(err.Error(), "egress policy denied: address 127.0.0.1 is in a private network")
+}
+
+func (suite *UnitTestSuite) TestEgressPolicySnapshot() {
+	// Given
+	policy, err := egress.GetPolicy()
+	suite.Nil(err)
+	loopbackUrl, _ := url.Parse("http://127.0.0.1")
+
+	// When
+	suite._config.Egress.AllowCIDRs = nil
+	cachedPolicy, cachedErr := egress.GetPolicy()
+	reloadedPolicy, reloadedErr := egress.ReloadPolicy()
+
+	// Then
+	// The config is read again on the reload only
+	suite.Nil(cachedErr)
+	suite.Same(policy, cachedPolicy)
+	suite.Nil(cachedPolicy.CheckURL(loopbackUrl))
+
+	suite.Nil(reloadedErr)
+	suite.NotSame(policy, reloadedPolicy)
+	suite.ErrorIs(reloadedPolicy.CheckURL(loopbackUrl), egress.ErrEgressDenied)
+
+	currentPolicy, _ := egress.GetPolicy()
+	suite.Same(reloadedPolicy, currentPolicy)
+}
+
+func (suite *UnitTestSuite) TestBlockPluginHTTPBypassesEgressPolicy() {
+	// Given
+	pluginUrl := suite.GetPluginHTTPServerURL()
+	suite._config.Egress.AllowCIDRs = nil
+	_, err := egress.ReloadPolicy()
+	suite.Nil(err)
+
+	// When
+	block, err := blocks.NewBlockExternalPlugin(config.ExternalPluginConfig{Name: "test-http-plugin", URL: pluginUrl})
+
+	// Then
+	suite.Nil(err)
+	suite.Equal("test_plugin", block.GetId())
+	suite.True(blocks.NewDetectorPlugin(block.Plugin, block.GetDetectorConfig()).Detect())
+}
+
+func (suite *UnitTestSuite) TestEgressConfigBlocksPrivateNetworksByDefault() {
+	testCases := map[string]struct {
+		section  string
+		expected bool
+	}{
+		"empty section":      {section: "egress:\n  deny_hosts: []\n", expected: true},
+		"explicitly blocked": {section: "egress:\n  block_private_networks: yes\n", expected: true},
+		"explicitly allowed": {section: "egress:\n  block_private_networks: no\n", expected: false},
+	}
+
+	for name, testCase := range testCases {
+		suite.Run(name, func() {
+			// Given
+			parsed := struct {
+				Egress *config.EgressConfig `yaml:"egress"`
+			}{}
+
+			// When
+			err := yaml.Unmarshal([]byte(testCase.section), &parsed)
+
+			// Then
+			suite.Nil(err)
+			suite.NotNil(parsed.Egress)
+			suite.Equal(testCase.expected, parsed.Egress.BlockPrivateNetworks)
+		})
+	}
+
+	// Missing section
+	suite.True(config.NewEgressConfig().BlockPrivateNetworks)
+}
+
+func (suite *UnitTestSuite) TestEgressPolicyClientsShareConnections() {
+	// Given
+	policy, err := egress.NewPolicy(config.EgressConfig{BlockPrivateNetworks: true})
+	suite.Nil(err)
+	otherPolicy, err := egress.NewPolicy(config.EgressConfig{BlockPrivateNetworks: true})
+	suite.Nil(err)
+
+	// When
+	client := policy.NewClient(0, nil)
+	otherClient := policy.NewClient(time.Second, nil)
+
+	// Then
+	suite.Same(client.Transport, otherClient.Transport)
+	suite.NotSame(client.Transport, otherPolicy.NewClient(0, nil).Transport)
+}
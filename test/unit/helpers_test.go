@@ -56,3 +56,61 @@ func (suite *UnitTestSuite) TestGetListAsQuotedString() {
 
 	suite.Equal(`"a", "b", "c"`, quotedList)
 }
+
+func (suite *UnitTestSuite) TestCompareVersions() {
+	testCases := []struct {
+		left     string
+		right    string
+		expected int
+	}{
+		{"1", "1", 0},
+		{"1", "1.0", 0},
+		{"1", "2", -1},
+		{"10", "9", 1},
+		{"1.2", "1.10", -1},
+		{"2.0.1", "2", 1},
+		{"1-beta", "1-alpha", 1},
+	}
+
+	for _, testCase := range testCases {
+		suite.Equal(
+			testCase.expected,
+			helpers.CompareVersions(testCase.left, testCase.right),
+			"%s vs %s", testCase.left, testCase.right,
+		)
+	}
+}
+
+func (suite *UnitTestSuite) TestIsVersionCompatible() {
+	suite.True(helpers.IsVersionCompatible("", "3"))
+	suite.True(helpers.IsVersionCompatible("1", "1"))
+	suite.True(helpers.IsVersionCompatible("1", "1.4"))
+	suite.True(helpers.IsVersionCompatible("1.2", "1.10"))
+	suite.False(helpers.IsVersionCompatible("1.2", "1.1"))
+	suite.False(helpers.IsVersionCompatible("1", "2"))
+	suite.False(helpers.IsVersionCompatible("2", "1.9"))
+}
+
+func (suite *UnitTestSuite) TestMatchVersion() {
+	suite.True(helpers.MatchVersion("", "3"))
+	suite.True(helpers.MatchVersion("1", "1"))
+	suite.True(helpers.MatchVersion("1", "1.0"))
+	suite.False(helpers.MatchVersion("1", "1.4"))
+	suite.True(helpers.MatchVersion("^1", "1.4"))
+	suite.True(helpers.MatchVersion("^1.2", "1.10"))
+	suite.False(helpers.MatchVersion("^1.2", "1.1"))
+	suite.False(helpers.MatchVersion("^1", "2"))
+}
+
+func (suite *UnitTestSuite) TestParseBlockReference() {
+	id, version := helpers.ParseBlockReference("http_request@2")
+	suite.Equal("http_request", id)
+	suite.Equal("2", version)
+
+	id, version = helpers.ParseBlockReference("http_request")
+	suite.Equal("http_request", id)
+	suite.Empty(version)
+
+	suite.Equal("http_request@2", helpers.FormatBlockReference("http_request", "2"))
+	suite.Equal("http_request", helpers.FormatBlockReference("http_request", ""))
+}
@@ -0,0 +1,247 @@
+package unit_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/limiter"
+	"data-pipelines-worker/types/llm"
+	"data-pipelines-worker/types/registries"
+)
+
+func (suite *UnitTestSuite) TestLimiterAcquire() {
+	testCases := []struct {
+		name         string
+		limitsConfig config.ProviderLimitsConfig
+		tokens       []int
+		usedTokens   []int
+		exceeded     bool
+	}{
+		{
+			name:         "requests per minute",
+			limitsConfig: config.ProviderLimitsConfig{RequestsPerMinute: 2},
+			tokens:       []int{0, 0, 0},
+			usedTokens:   []int{-1, -1, -1},
+			exceeded:     true,
+		},
+		{
+			name:         "tokens per minute",
+			limitsConfig: config.ProviderLimitsConfig{TokensPerMinute: 100},
+			tokens:       []int{50, 40},
+			usedTokens:   []int{70, -1},
+			exceeded:     true,
+		},
+		{
+			name:         "tokens of the estimate",
+			limitsConfig: config.ProviderLimitsConfig{TokensPerMinute: 100},
+			tokens:       []int{50, 40},
+			usedTokens:   []int{20, -1},
+			exceeded:     false,
+		},
+		{
+			name:         "request larger than the limit",
+			limitsConfig: config.ProviderLimitsConfig{TokensPerMinute: 100},
+			tokens:       []int{500},
+			usedTokens:   []int{-1},
+			exceeded:     false,
+		},
+		{
+			name:         "concurrent requests",
+			limitsConfig: config.ProviderLimitsConfig{ConcurrentRequests: 1},
+			tokens:       []int{0, 0},
+			usedTokens:   []int{},
+			exceeded:     true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		suite.Run(testCase.name, func() {
+			// Given
+			rateLimiter := limiter.NewLimiter(testCase.limitsConfig)
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			// When
+			var err error
+			for i, tokens := range testCase.tokens {
+				var release func(int)
+				release, err = rateLimiter.Acquire(ctx, tokens)
+				if err != nil {
+					break
+				}
+				if i < len(testCase.usedTokens) {
+					release(testCase.usedTokens[i])
+				}
+			}
+
+			// Then
+			if testCase.exceeded {
+				suite.ErrorIs(err, context.DeadlineExceeded)
+			} else {
+				suite.Nil(err)
+			}
+		})
+	}
+}
+
+func (suite *UnitTestSuite) TestCircuitBreakerStates() {
+	// Given
+	circuitBreaker := limiter.NewCircuitBreaker(config.CircuitBreakerConfig{
+		FailureThreshold: 2,
+		Cooldown:         50 * time.Millisecond,
+	})
+
+	// When
+	suite.Nil(circuitBreaker.Allow())
+	suite.False(circuitBreaker.Failure())
+	suite.Equal(limiter.CircuitStateClosed, circuitBreaker.GetState())
+	suite.True(circuitBreaker.Failure())
+
+	// Then
+	suite.Equal(limiter.CircuitStateOpen, circuitBreaker.GetState())
+	suite.ErrorIs(circuitBreaker.Allow(), limiter.ErrCircuitOpen)
+
+	time.Sleep(60 * time.Millisecond)
+	suite.Equal(limiter.CircuitStateHalfOpen, circuitBreaker.GetState())
+	suite.Nil(circuitBreaker.Allow())
+	suite.ErrorIs(circuitBreaker.Allow(), limiter.ErrCircuitOpen)
+
+	// Failed trial opens the circuit again
+	suite.True(circuitBreaker.Failure())
+	suite.Equal(limiter.CircuitStateOpen, circuitBreaker.GetState())
+
+	time.Sleep(60 * time.Millisecond)
+	suite.Nil(circuitBreaker.Allow())
+	circuitBreaker.Success()
+	suite.Equal(limiter.CircuitStateClosed, circuitBreaker.GetState())
+}
+
+func (suite *UnitTestSuite) TestLimiterRegistryReplacesChangedGuard() {
+	// Given
+	limiterRegistry := limiter.NewLimiterRegistry()
+	circuitBreakerConfig := config.CircuitBreakerConfig{FailureThreshold: 1, Cooldown: time.Minute}
+	guard := limiterRegistry.Get(
+		limiter.PROVIDER_OPENAI,
+		"",
+		config.ProviderLimitsConfig{RequestsPerMinute: 10},
+		circuitBreakerConfig,
+	)
+	limiterInUse := guard.Limiter
+	guard.CircuitBreaker.Failure()
+
+	// When
+	sameGuard := limiterRegistry.Get(
+		limiter.PROVIDER_OPENAI,
+		config.DEFAULT_CREDENTIALS_PROFILE,
+		config.ProviderLimitsConfig{RequestsPerMinute: 10},
+		circuitBreakerConfig,
+	)
+	changedGuard := limiterRegistry.Get(
+		limiter.PROVIDER_OPENAI,
+		"",
+		config.ProviderLimitsConfig{RequestsPerMinute: 20},
+		circuitBreakerConfig,
+	)
+
+	// Then
+	suite.Same(guard, sameGuard)
+	suite.NotSame(guard, changedGuard)
+	// Calls of the replaced Guard keep its limiter
+	suite.Same(limiterInUse, guard.Limiter)
+	suite.NotSame(limiterInUse, changedGuard.Limiter)
+	// Unchanged circuit breaker keeps its state
+	suite.Same(guard.CircuitBreaker, changedGuard.CircuitBreaker)
+	suite.True(limiterRegistry.IsOpen(limiter.PROVIDER_OPENAI, ""))
+}
+
+func (suite *UnitTestSuite) TestLimitedProviderOpensCircuit() {
+	// Given
+	circuitBreakerConfig := suite._config.OpenAI.CircuitBreaker
+	suite._config.OpenAI.CircuitBreaker = config.CircuitBreakerConfig{
+		FailureThreshold: 2,
+		Cooldown:         time.Minute,
+	}
+	defer func() {
+		suite._config.OpenAI.CircuitBreaker = circuitBreakerConfig
+	}()
+
+	stub := &stubLLMProvider{
+		err: &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests, Message: "rate limit reached"},
+	}
+	providerRegistry := llm.GetProviderRegistry(true)
+	providerRegistry.Set("", stub)
+	defer providerRegistry.Delete("")
+
+	provider, err := providerRegistry.Get("")
+	suite.Nil(err)
+
+	// When
+	for i := 0; i < 2; i++ {
+		_, err = provider.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{})
+		suite.NotNil(err)
+		suite.NotErrorIs(err, limiter.ErrCircuitOpen)
+	}
+	_, err = provider.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{})
+
+	// Then
+	suite.ErrorIs(err, limiter.ErrCircuitOpen)
+	suite.Len(stub.requests, 2)
+	suite.True(limiter.GetLimiterRegistry().IsOpen(limiter.PROVIDER_OPENAI, config.DEFAULT_CREDENTIALS_PROFILE))
+}
+
+func (suite *UnitTestSuite) TestLimitedProviderIgnoresClientErrors() {
+	// Given
+	stub := &stubLLMProvider{
+		err: &openai.APIError{HTTPStatusCode: http.StatusBadRequest, Message: "invalid request"},
+	}
+	providerRegistry := llm.GetProviderRegistry(true)
+	providerRegistry.Set("", stub)
+	defer providerRegistry.Delete("")
+
+	provider, err := providerRegistry.Get("")
+	suite.Nil(err)
+
+	// When
+	for i := 0; i < config.DEFAULT_CIRCUIT_BREAKER_FAILURE_THRESHOLD+1; i++ {
+		_, err = provider.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{})
+		suite.NotErrorIs(err, limiter.ErrCircuitOpen)
+	}
+
+	// Then
+	suite.Len(stub.requests, config.DEFAULT_CIRCUIT_BREAKER_FAILURE_THRESHOLD+1)
+	suite.False(limiter.GetLimiterRegistry().IsOpen(limiter.PROVIDER_OPENAI, config.DEFAULT_CREDENTIALS_PROFILE))
+}
+
+func (suite *UnitTestSuite) TestBlockRegistryCircuitOpenBlockUnavailable() {
+	// Given
+	blockRegistry := registries.NewBlockRegistry()
+	defer blockRegistry.Shutdown(suite.GetShutDownContext(time.Second))
+
+	block := blockRegistry.Get("openai_chat_completion")
+	suite.NotNil(block)
+	suite.True(blockRegistry.IsAvailable(block))
+
+	guard := limiter.GetLimiterRegistry().Get(
+		limiter.PROVIDER_OPENAI,
+		config.DEFAULT_CREDENTIALS_PROFILE,
+		config.ProviderLimitsConfig{},
+		config.CircuitBreakerConfig{FailureThreshold: 1, Cooldown: 50 * time.Millisecond},
+	)
+
+	// When
+	guard.CircuitBreaker.Failure()
+
+	// Then
+	suite.False(blockRegistry.IsAvailable(block))
+	suite.NotContains(blockRegistry.GetAvailableBlocks(), block.GetId())
+	suite.True(blockRegistry.IsAvailable(blockRegistry.Get("http_request")))
+
+	// Detected availability is kept for the half-open state
+	suite.True(block.IsAvailable())
+	time.Sleep(60 * time.Millisecond)
+	suite.True(blockRegistry.IsAvailable(block))
+}
@@ -0,0 +1,110 @@
+package unit_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	openai "github.com/sashabaranov/go-openai"
+
+	"data-pipelines-worker/types/blocks"
+	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/llm"
+)
+
+func (suite *UnitTestSuite) TestOutputGuardsCheck() {
+	// Given
+	guards := &llm.OutputGuards{
+		MinWords:       2,
+		MaxCharacters:  40,
+		MaxTokens:      10,
+		JSONSchema:     map[string]interface{}{"type": "object", "required": []interface{}{"title"}},
+		MustMatch:      []string{`^\{`},
+		MustNotMatch:   []string{`(?i)lorem`},
+		ForbiddenWords: []string{"Guarantee"},
+	}
+
+	// When
+	validViolations := guards.Check(`{"title": "Fine answer"}`, 0)
+	invalidViolations := guards.Check(`Lorem, we guarantee nothing at all here today`, 0)
+
+	// Then
+	suite.Nil(guards.Validate())
+	suite.Empty(validViolations)
+	suite.Contains(invalidViolations, "the answer has 45 characters, at most 40 are allowed")
+	suite.Contains(invalidViolations, "the answer has 12 tokens, at most 10 are allowed")
+	suite.Contains(invalidViolations, "the answer must be a valid JSON")
+	suite.Contains(invalidViolations, `the answer must match the pattern "^\\{"`)
+	suite.Contains(invalidViolations, `the answer must not match the pattern "(?i)lorem"`)
+	suite.Contains(invalidViolations, `the answer must not contain the word "Guarantee"`)
+
+	suite.NotNil((&llm.OutputGuards{MustMatch: []string{"("}}).Validate())
+}
+
+func (suite *UnitTestSuite) getGuardedCompletionProcessing(provider llm.Provider) *dataclasses.Processing {
+	block := blocks.NewBlockOpenAIRequestCompletion()
+	data := &dataclasses.BlockData{
+		Id:   "openai_chat_completion",
+		Slug: "request-openai-chat-completion",
+		Input: map[string]interface{}{
+			"user_prompt": "Write a slogan",
+			"credentials": "offline",
+			"max_reasks":  2,
+			"guards": map[string]interface{}{
+				"max_words":       3,
+				"forbidden_words": []interface{}{"best"},
+			},
+		},
+	}
+	data.SetBlock(block)
+
+	llm.GetProviderRegistry().Set("offline", provider)
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	return dataclasses.NewProcessing(ctx, ctxCancel, uuid.New(), nil, block, data)
+}
+
+func (suite *UnitTestSuite) TestProcessingOpenAIRequestCompletionGuardsReAsk() {
+	// Given
+	provider := &stubLLMProvider{
+		contents: []string{"The best slogan in the world", "Simply the best", "Simply great"},
+	}
+	processing := suite.getGuardedCompletionProcessing(provider)
+	defer llm.GetProviderRegistry().Delete("offline")
+
+	// When
+	output := processing.Start()
+
+	// Then
+	suite.Nil(output.GetError())
+	suite.Equal(interfaces.ProcessingStatusCompleted, processing.GetStatus())
+	suite.Equal("Simply great", output.GetValue()[0].String())
+	suite.Len(provider.requests, 3)
+
+	// Re-asks carry the rejected answers and their violations
+	messages := provider.requests[2].Messages
+	suite.Len(messages, 6)
+	suite.Equal(openai.ChatMessageRoleAssistant, messages[2].Role)
+	suite.Equal("The best slogan in the world", messages[2].Content)
+	suite.Contains(messages[3].Content, "the answer has 6 words, at most 3 are allowed")
+	suite.Contains(messages[3].Content, `the answer must not contain the word "best"`)
+	suite.Equal("Simply the best", messages[4].Content)
+	suite.NotContains(messages[5].Content, "words, at most")
+}
+
+func (suite *UnitTestSuite) TestProcessingOpenAIRequestCompletionGuardsExhausted() {
+	// Given
+	provider := &stubLLMProvider{contents: []string{"The best slogan in the world"}}
+	processing := suite.getGuardedCompletionProcessing(provider)
+	defer llm.GetProviderRegistry().Delete("offline")
+
+	// When
+	output := processing.Start()
+
+	// Then
+	suite.True(errors.Is(output.GetError(), llm.ErrOutputGuardViolation))
+	suite.Contains(output.GetError().Error(), "after 2 re-ask(s)")
+	suite.Equal(interfaces.ProcessingStatusFailed, processing.GetStatus())
+	suite.Len(provider.requests, 3)
+}
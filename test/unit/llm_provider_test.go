@@ -0,0 +1,163 @@
+package unit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"data-pipelines-worker/types/blocks"
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/llm"
+)
+
+// stubLLMProvider is an offline stand-in of the OpenAI-compatible server
+type stubLLMProvider struct {
+	llm.Provider
+
+	requests []openai.ChatCompletionRequest
+	response openai.ChatCompletionResponse
+
+	// Contents of the consecutive responses, the last one is repeated
+	contents []string
+
+	// Error of every chat completion
+	err error
+}
+
+func (p *stubLLMProvider) GetName() string {
+	return "stub"
+}
+
+func (p *stubLLMProvider) CreateChatCompletion(
+	_ context.Context,
+	request openai.ChatCompletionRequest,
+) (openai.ChatCompletionResponse, error) {
+	p.requests = append(p.requests, request)
+	if p.err != nil {
+		return openai.ChatCompletionResponse{}, p.err
+	}
+	if len(p.contents) > 0 {
+		content := p.contents[0]
+		if len(p.contents) > 1 {
+			p.contents = p.contents[1:]
+		}
+		return openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content}},
+			},
+		}, nil
+	}
+	if len(p.response.Choices) > 0 {
+		return p.response, nil
+	}
+
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "Offline answer"}},
+		},
+	}, nil
+}
+
+func (p *stubLLMProvider) CreateImage(
+	_ context.Context,
+	request openai.ImageRequest,
+) (openai.ImageResponse, error) {
+	return openai.ImageResponse{
+		Data: make([]openai.ImageResponseDataInner, request.N),
+	}, nil
+}
+
+func (suite *UnitTestSuite) TestOpenAIProviderJSONModeFallback() {
+	// Given
+	requestsLock := sync.Mutex{}
+	requests := make([]map[string]interface{}, 0)
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request := make(map[string]interface{})
+			json.NewDecoder(r.Body).Decode(&request)
+
+			requestsLock.Lock()
+			requests = append(requests, request)
+			requestsLock.Unlock()
+
+			w.Header().Set("Content-Type", "application/json")
+			if _, ok := request["response_format"]; ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": {"message": "response_format is not supported", "type": "invalid_request_error"}}`))
+				return
+			}
+			w.Write([]byte(`{"choices": [{"index": 0, "message": {"role": "assistant", "content": "{\"ok\": true}"}}]}`))
+		}),
+	)
+	defer server.Close()
+
+	profile := &config.OpenAIConfig{}
+	profile.SetClient(suite.getTestOpenAIClient(server.URL))
+	provider := llm.NewOpenAIProvider("local", profile)
+
+	request := openai.ChatCompletionRequest{
+		Model: "llama3",
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleUser, Content: "Hello"},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		},
+	}
+
+	// When
+	response, err := provider.CreateChatCompletion(context.Background(), request)
+
+	// Then
+	suite.Nil(err)
+	suite.Equal(`{"ok": true}`, response.Choices[0].Message.Content)
+	suite.Len(requests, 2)
+
+	fallbackMessages := requests[1]["messages"].([]interface{})
+	suite.Len(fallbackMessages, 2)
+	suite.Equal(llm.JSON_MODE_INSTRUCTION, fallbackMessages[0].(map[string]interface{})["content"])
+
+	// Missing JSON mode is remembered
+	_, err = provider.CreateChatCompletion(context.Background(), request)
+	suite.Nil(err)
+	suite.Len(requests, 3)
+	suite.NotContains(requests[2], "response_format")
+}
+
+func (suite *UnitTestSuite) TestBlockOpenAIRequestCompletionProcessProvider() {
+	// Given
+	block := blocks.NewBlockOpenAIRequestCompletion()
+	data := &dataclasses.BlockData{
+		Id:   "openai_chat_completion",
+		Slug: "request-openai-chat-completion",
+		Input: map[string]interface{}{
+			"model":       "llama3",
+			"user_prompt": "Hello world!",
+			"credentials": "offline",
+		},
+	}
+	data.SetBlock(block)
+
+	provider := &stubLLMProvider{}
+	llm.GetProviderRegistry().Set("offline", provider)
+	defer llm.GetProviderRegistry().Delete("offline")
+
+	// When
+	result, _, _, _, _, err := block.Process(
+		suite.GetContextWithcancel(),
+		blocks.NewProcessorOpenAIRequestCompletion(),
+		data,
+	)
+
+	// Then
+	suite.Nil(err)
+	suite.Len(result, 1)
+	suite.Equal("Offline answer", result[0].String())
+	suite.Len(provider.requests, 1)
+	suite.Equal("llama3", provider.requests[0].Model)
+}
@@ -24,8 +24,10 @@ import (
 	"data-pipelines-worker/types/blocks"
 	"data-pipelines-worker/types/config"
 	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/egress"
 	"data-pipelines-worker/types/generics"
 	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/limiter"
 	"data-pipelines-worker/types/registries"
 )
 
@@ -79,6 +81,11 @@ func (suite *UnitTestSuite) SetupTest() {
 	_config := config.GetConfig()
 	_config.Storage.Local.RootPath = os.TempDir()
 
+	// Mock HTTP servers listen on the loopback denied by the egress policy
+	_config.Egress.AllowCIDRs = []string{"127.0.0.0/8", "::1"}
+	_, err := egress.ReloadPolicy()
+	suite.Nil(err)
+
 	// Make Mock HTTP Server for each URL Block Detector
 
 	// OpenAI
@@ -106,6 +113,9 @@ func (suite *UnitTestSuite) SetupTest() {
 		}
 	}
 
+	// Circuit breakers of the previous tests must not fail fast the providers
+	limiter.GetLimiterRegistry(true)
+
 	suite.registries = make([]generics.Registry[any], 0)
 }
 
@@ -194,6 +204,8 @@ func (suite *UnitTestSuite) TearDownTest() {
 	for _, server := range suite.httpTestServers {
 		server.Close()
 	}
+	// Webhook deliveries save their logs to the storages
+	dataclasses.WaitPipelineWebhookDeliveries(ctx)
 	for _, storage := range suite.storages {
 		storage.Shutdown()
 	}
@@ -286,7 +298,7 @@ func (suite *UnitTestSuite) RegisterTestPipelineAndInputForProcessing(
 	)
 	suite.Nil(err)
 
-	registry.Add(pipeline)
+	suite.Nil(registry.Add(pipeline))
 
 	return pipeline, processingData, registry
 }
@@ -449,6 +461,12 @@ func (s *mockLocalStorage) PutObjectBytes(destination interfaces.StorageLocation
 	s.Lock()
 	defer s.Unlock()
 
+	// Metadata sidecars of the outputs and the saved block inputs are not counted as created files
+	if registries.METADATA_FILE_REGEX.MatchString(destination.GetFileName()) ||
+		registries.INPUT_FILE_REGEX.MatchString(destination.GetFileName()) {
+		return s.storage.PutObjectBytes(destination, content)
+	}
+
 	s.createdFilesChan <- createdFile{
 		filePath: destination.GetFilePath(),
 		data:     bytes.NewBuffer(content.Bytes()),
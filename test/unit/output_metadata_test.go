@@ -0,0 +1,201 @@
+package unit_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+
+	"github.com/google/uuid"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/test/factories"
+	"data-pipelines-worker/types"
+	"data-pipelines-worker/types/blocks"
+	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/registries"
+)
+
+func (suite *UnitTestSuite) TestRecordOutputMetadata() {
+	// Given
+	ctx, recorder := blocks.WithOutputMetadataRecorder(context.Background())
+
+	// When
+	blocks.RecordOutputMetadata(ctx, 1, schemas.OutputMetadataSchema{ContentType: "audio/mpeg"})
+	blocks.RecordOutputMetadata(ctx, 1, schemas.OutputMetadataSchema{
+		FileName: "segment001.mp3",
+		Metadata: map[string]interface{}{"language": "en"},
+	})
+	blocks.RecordOutputMetadata(ctx, 5, schemas.OutputMetadataSchema{ContentType: "audio/mpeg"})
+	blocks.RecordOutputMetadata(context.Background(), 0, schemas.OutputMetadataSchema{ContentType: "audio/mpeg"})
+
+	// Then
+	metadata := recorder.GetMetadata(2)
+	suite.Len(metadata, 2)
+	suite.Empty(metadata[0])
+	suite.Equal("audio/mpeg", metadata[1].ContentType)
+	suite.Equal("segment001.mp3", metadata[1].FileName)
+	suite.Equal("en", metadata[1].Metadata["language"])
+}
+
+func (suite *UnitTestSuite) TestCompleteOutputMetadata() {
+	// Given
+	imageBuffer := factories.GetPNGImageBuffer(12, 8)
+
+	// When
+	imageMetadata := blocks.CompleteOutputMetadata(&imageBuffer, schemas.OutputMetadataSchema{})
+	audioMetadata := blocks.CompleteOutputMetadata(
+		bytes.NewBufferString("ID3 audio"),
+		schemas.OutputMetadataSchema{ContentType: "audio/mpeg"},
+	)
+	emptyMetadata := blocks.CompleteOutputMetadata(&bytes.Buffer{}, schemas.OutputMetadataSchema{})
+
+	// Then
+	suite.Equal("image/png", imageMetadata.ContentType)
+	suite.Equal(12, imageMetadata.Width)
+	suite.Equal(8, imageMetadata.Height)
+	// The content type recorded by the block is kept
+	suite.Equal("audio/mpeg", audioMetadata.ContentType)
+	suite.Empty(emptyMetadata)
+}
+
+func (suite *UnitTestSuite) TestProcessingRecordsOutputMetadata() {
+	// Given
+	block := blocks.NewBlockTextReplace()
+	data := &dataclasses.BlockData{
+		Id:   "text_replace",
+		Slug: "text-replace",
+		Input: map[string]interface{}{
+			"text": "Hello, world!",
+			"old":  "world",
+			"new":  "there",
+		},
+	}
+	data.SetBlock(block)
+	block.SetProcessor(&stubOutputProcessor{
+		output: []*bytes.Buffer{bytes.NewBufferString("Hello"), bytes.NewBufferString("world")},
+		metadata: map[int]schemas.OutputMetadataSchema{
+			1: {ContentType: "text/plain", FileName: "world.txt"},
+		},
+	})
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	processing := dataclasses.NewProcessing(ctx, ctxCancel, uuid.New(), nil, block, data)
+
+	// When
+	output := processing.Start()
+
+	// Then
+	suite.Nil(output.GetError())
+	suite.Len(output.GetMetadata(), 2)
+	suite.Empty(output.GetMetadata()[0])
+	suite.Equal("world.txt", output.GetMetadata()[1].FileName)
+}
+
+func (suite *UnitTestSuite) TestPipelineBlockDataRegistrySaveOutputWithMetadata() {
+	// Given
+	processingId := uuid.New()
+	blockSlug := "test-block-slug"
+	storages := []interfaces.Storage{
+		types.NewLocalStorage(suite.T().TempDir()),
+	}
+	pipelineBlockDataRegistry := registries.NewPipelineBlockDataRegistry(processingId, "test-pipeline-slug", storages)
+	imageBuffer := factories.GetPNGImageBuffer(12, 8)
+
+	// When
+	audioResults := pipelineBlockDataRegistry.SaveOutputWithMetadata(
+		blockSlug,
+		0,
+		bytes.NewBufferString("ID3 audio"),
+		schemas.OutputMetadataSchema{
+			ContentType: "audio/mpeg",
+			FileName:    "speech.mp3",
+			Duration:    1.5,
+			Metadata:    map[string]interface{}{"voice": "alloy"},
+		},
+	)
+	imageResults := pipelineBlockDataRegistry.SaveOutput(blockSlug, 1, &imageBuffer)
+
+	// Then
+	suite.Len(audioResults, 1)
+	suite.Nil(audioResults[0].Error)
+	suite.Equal(".mp3", filepath.Ext(audioResults[0].StorageLocation.GetFileName()))
+	suite.Len(imageResults, 1)
+	suite.Nil(imageResults[0].Error)
+	suite.Equal("image/png", imageResults[0].Metadata.ContentType)
+	suite.Equal(12, imageResults[0].Metadata.Width)
+	suite.Equal(8, imageResults[0].Metadata.Height)
+
+	// The metadata is loaded with the outputs, e.g. by the worker the processing is resumed at
+	loadingRegistry := registries.NewPipelineBlockDataRegistry(processingId, "test-pipeline-slug", storages)
+	outputs := loadingRegistry.LoadOutput(blockSlug)
+	suite.Len(outputs, 2)
+	suite.Equal("ID3 audio", outputs[0].String())
+
+	metadata := loadingRegistry.GetMetadata(blockSlug)
+	suite.Len(metadata, 2)
+	suite.Equal("audio/mpeg", metadata[0].ContentType)
+	suite.Equal("speech.mp3", metadata[0].FileName)
+	suite.Equal(1.5, metadata[0].Duration)
+	suite.Equal("alloy", metadata[0].Metadata["voice"])
+	suite.Equal("image/png", metadata[1].ContentType)
+	suite.Equal(12, metadata[1].Width)
+}
+
+func (suite *UnitTestSuite) TestGetInputConfigDataOutputMetadata() {
+	// Given
+	pipeline := suite.GetTestPipeline(`{
+		"slug": "test-pipeline-slug-two-blocks",
+		"title": "Test Pipeline",
+		"description": "Test Pipeline Description",
+		"blocks": [
+			{
+				"id": "http_request",
+				"slug": "request-speech",
+				"description": "Request Speech",
+				"input": {
+					"url": "https://localhost:8080"
+				}
+			},
+			{
+				"id": "http_request",
+				"slug": "request-with-content-type",
+				"description": "Request with the Content Type of the Speech",
+				"input_config": {
+					"property": {
+						"body": {
+							"origin": "request-speech",
+							"metadata": true,
+							"json_path": "$.content_type"
+						}
+					}
+				},
+				"input": {
+					"url": "https://localhost:8080",
+					"method": "POST"
+				}
+			}
+		]
+	}`)
+	pipelineResults := map[string][]*bytes.Buffer{
+		"request-speech": {bytes.NewBufferString("Hello, world!")},
+	}
+	pipelineResultsMetadata := map[string][]schemas.OutputMetadataSchema{
+		"request-speech": {{ContentType: "audio/mpeg"}},
+	}
+	block := pipeline.GetBlocks()[1]
+
+	// When
+	inputData, _, _, err := block.GetInputConfigData(pipelineResults, pipelineResultsMetadata)
+	detectedInputData, _, _, detectedErr := block.GetInputConfigData(pipelineResults)
+
+	// Then
+	suite.Nil(err)
+	suite.Len(inputData, 1)
+	suite.Equal("audio/mpeg", inputData[0]["body"])
+
+	// The content type is detected for the outputs without metadata
+	suite.Nil(detectedErr)
+	suite.Len(detectedInputData, 1)
+	suite.Equal("text/plain; charset=utf-8", detectedInputData[0]["body"])
+}
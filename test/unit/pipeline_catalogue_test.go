@@ -0,0 +1,269 @@
+package unit_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"data-pipelines-worker/types"
+	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/registries"
+)
+
+func (suite *UnitTestSuite) writeCatalogueFile(cataloguePath string, fileName string, content string) string {
+	filePath := filepath.Join(cataloguePath, fileName)
+	suite.Nil(os.WriteFile(filePath, []byte(content), 0644))
+
+	return filePath
+}
+
+func (suite *UnitTestSuite) TestPipelineCatalogueLoaderInvalidFiles() {
+	// Given
+	cataloguePath := suite.T().TempDir()
+	suite.writeCatalogueFile(cataloguePath, "valid.json", string(suite.GetTestPipelineDefinition()))
+	brokenFile := suite.writeCatalogueFile(cataloguePath, "broken.json", `{"slug": "broken-pipeline",`)
+	invalidFile := suite.writeCatalogueFile(
+		cataloguePath,
+		"invalid.json",
+		`{"slug": "invalid-pipeline", "title": "Invalid Pipeline"}`,
+	)
+	duplicateFile := suite.writeCatalogueFile(cataloguePath, "x-duplicate.json", string(suite.GetTestPipelineDefinition()))
+	suite.writeCatalogueFile(cataloguePath, ".valid.json.swp", "swap file")
+	suite.writeCatalogueFile(cataloguePath, "README.md", "# Pipelines")
+
+	// When
+	pipelines, _, fileErrors, err := dataclasses.NewPipelineCatalogueLoader().LoadCatalogue(cataloguePath)
+
+	// Then
+	suite.Nil(err)
+	suite.Len(pipelines, 1)
+	suite.Contains(pipelines, "test-pipeline-slug")
+
+	suite.Len(fileErrors, 3)
+	suite.Equal(brokenFile, fileErrors[0].File)
+	suite.Equal(invalidFile, fileErrors[1].File)
+	suite.Equal("invalid-pipeline", fileErrors[1].Slug)
+	suite.Contains(fileErrors[1].Error, "Pipeline schema is invalid for pipeline: invalid-pipeline")
+	suite.Equal(duplicateFile, fileErrors[2].File)
+	suite.Contains(fileErrors[2].Error, "duplicate pipeline slug test-pipeline-slug")
+}
+
+func (suite *UnitTestSuite) TestPipelineCatalogueLoaderMissingDirectory() {
+	_, _, _, err := dataclasses.NewPipelineCatalogueLoader().LoadCatalogue(
+		filepath.Join(suite.T().TempDir(), "missing"),
+	)
+
+	suite.NotNil(err)
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryReloadCatalogue() {
+	// Given
+	registry, err := registries.NewPipelineRegistry(
+		registries.GetWorkerRegistry(),
+		registries.GetBlockRegistry(),
+		registries.GetProcessingRegistry(),
+		dataclasses.NewPipelineCatalogueLoader(),
+	)
+	suite.Nil(err)
+	suite.NotNil(registry.Get("openai-yt-short-generation"))
+
+	cataloguePath := suite.T().TempDir()
+	pipelineFile := suite.writeCatalogueFile(cataloguePath, "test.json", string(suite.GetTestPipelineDefinition()))
+	registry.SetCataloguePath(cataloguePath)
+
+	// When
+	report, err := registry.ReloadCatalogue()
+
+	// Then
+	suite.Nil(err)
+	suite.Equal([]string{"test-pipeline-slug"}, report.Pipelines)
+	suite.Contains(report.Removed, "openai-yt-short-generation")
+	suite.Empty(report.Errors)
+	suite.Nil(registry.Get("openai-yt-short-generation"))
+	loadedPipeline := registry.Get("test-pipeline-slug")
+	suite.NotNil(loadedPipeline)
+
+	// Invalid file keeps the previous definition of its Pipeline
+	suite.writeCatalogueFile(cataloguePath, "test.json", `{"slug": "test-pipeline-slug", "title": "Test Pipeline"}`)
+	report, err = registry.ReloadCatalogue()
+	suite.Nil(err)
+	suite.Len(report.Errors, 1)
+	suite.Equal(pipelineFile, report.Errors[0].File)
+	suite.Equal(report, registry.GetCatalogueReload())
+	suite.Same(loadedPipeline, registry.Get("test-pipeline-slug"))
+
+	// Fixed file replaces the definition, the previous one stays usable by running processings
+	suite.writeCatalogueFile(
+		cataloguePath,
+		"test.json",
+		strings.Replace(string(suite.GetTestPipelineDefinition()), `"Test Pipeline"`, `"Changed Pipeline"`, 1),
+	)
+	report, err = registry.ReloadCatalogue()
+	suite.Nil(err)
+	suite.Empty(report.Errors)
+	suite.Equal("Changed Pipeline", registry.Get("test-pipeline-slug").GetTitle())
+	suite.Equal("Test Pipeline", loadedPipeline.GetTitle())
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryReloadCatalogueBrokenSyntax() {
+	// Given
+	cataloguePath := suite.T().TempDir()
+	pipelineFile := suite.writeCatalogueFile(cataloguePath, "test.json", string(suite.GetTestPipelineDefinition()))
+
+	registry, err := registries.NewPipelineRegistry(
+		registries.GetWorkerRegistry(),
+		registries.GetBlockRegistry(),
+		registries.GetProcessingRegistry(),
+		dataclasses.NewPipelineCatalogueLoader(),
+	)
+	suite.Nil(err)
+	registry.SetCataloguePath(cataloguePath)
+	_, err = registry.ReloadCatalogue()
+	suite.Nil(err)
+	loadedPipeline := registry.Get("test-pipeline-slug")
+	suite.NotNil(loadedPipeline)
+
+	// When
+	// The slug can not be read from the file
+	suite.writeCatalogueFile(cataloguePath, "test.json", `{"slug": "test-pipeline-slug",`)
+	report, err := registry.ReloadCatalogue()
+
+	// Then
+	suite.Nil(err)
+	suite.Len(report.Errors, 1)
+	suite.Equal(pipelineFile, report.Errors[0].File)
+	suite.Empty(report.Errors[0].Slug)
+	suite.Equal([]string{"test-pipeline-slug"}, report.Pipelines)
+	suite.Empty(report.Removed)
+	suite.Same(loadedPipeline, registry.Get("test-pipeline-slug"))
+
+	// The file stays broken on the next reloads
+	report, err = registry.ReloadCatalogue()
+	suite.Nil(err)
+	suite.Empty(report.Removed)
+	suite.Same(loadedPipeline, registry.Get("test-pipeline-slug"))
+
+	// Removed file removes the Pipeline
+	suite.Nil(os.Remove(pipelineFile))
+	report, err = registry.ReloadCatalogue()
+	suite.Nil(err)
+	suite.Equal([]string{"test-pipeline-slug"}, report.Removed)
+	suite.Nil(registry.Get("test-pipeline-slug"))
+}
+
+func (suite *UnitTestSuite) TestPipelineCatalogueWatcherReload() {
+	// Given
+	cataloguePath := suite.T().TempDir()
+	registry, err := registries.NewPipelineRegistry(
+		registries.GetWorkerRegistry(),
+		registries.GetBlockRegistry(),
+		registries.GetProcessingRegistry(),
+		dataclasses.NewPipelineCatalogueLoader(),
+	)
+	suite.Nil(err)
+	registry.SetCataloguePath(cataloguePath)
+	_, err = registry.ReloadCatalogue()
+	suite.Nil(err)
+	suite.Empty(registry.GetAll())
+
+	watcher := registries.NewPipelineCatalogueWatcher(registry)
+	watcher.SetWatch(true)
+	watcher.SetReloadDelay(10 * time.Millisecond)
+	watcher.Start()
+	defer watcher.Shutdown(suite.GetShutDownContext(time.Second))
+
+	// When
+	suite.writeCatalogueFile(cataloguePath, "test.json", string(suite.GetTestPipelineDefinition()))
+
+	// Then
+	suite.Eventually(
+		func() bool {
+			return registry.Get("test-pipeline-slug") != nil
+		},
+		5*time.Second,
+		10*time.Millisecond,
+	)
+}
+
+func (suite *UnitTestSuite) TestPipelineCatalogueLoaderStorage() {
+	// Given
+	storage := types.NewLocalStorage(suite.T().TempDir())
+	for key, content := range map[string]string{
+		"catalogue/test.json":        string(suite.GetTestPipelineDefinition()),
+		"catalogue/invalid.json":     `{"slug": "invalid-pipeline", "title": "Invalid Pipeline"}`,
+		"catalogue/nested/test.json": string(suite.GetTestPipelineDefinition()),
+		"other/test.json":            string(suite.GetTestPipelineDefinition()),
+	} {
+		suite.Nil(os.MkdirAll(filepath.Dir(filepath.Join(storage.GetStorageDirectory(), key)), os.ModePerm))
+		suite.Nil(os.WriteFile(filepath.Join(storage.GetStorageDirectory(), key), []byte(content), 0644))
+	}
+
+	loader := dataclasses.NewPipelineCatalogueLoader()
+	loader.SetStorage(storage)
+
+	// When
+	pipelines, _, fileErrors, err := loader.LoadCatalogue("catalogue")
+	version, versionErr := loader.GetCatalogueVersion("catalogue")
+
+	// Then
+	suite.Nil(err)
+	suite.Len(pipelines, 1)
+	suite.Contains(pipelines, "test-pipeline-slug")
+	suite.Len(fileErrors, 1)
+	suite.Equal("local:catalogue/invalid.json", fileErrors[0].File)
+
+	suite.Nil(versionErr)
+	suite.NotEmpty(version)
+
+	// Version changes with any catalogue file only
+	suite.Nil(os.WriteFile(filepath.Join(storage.GetStorageDirectory(), "other/new.json"), []byte("{}"), 0644))
+	sameVersion, _ := loader.GetCatalogueVersion("catalogue")
+	suite.Equal(version, sameVersion)
+
+	suite.Nil(os.Remove(filepath.Join(storage.GetStorageDirectory(), "catalogue/invalid.json")))
+	changedVersion, _ := loader.GetCatalogueVersion("catalogue")
+	suite.NotEqual(version, changedVersion)
+}
+
+func (suite *UnitTestSuite) TestPipelineCatalogueWatcherPollStorage() {
+	// Given
+	storage := types.NewLocalStorage(suite.T().TempDir())
+	suite.Nil(os.MkdirAll(filepath.Join(storage.GetStorageDirectory(), "catalogue"), os.ModePerm))
+
+	loader := dataclasses.NewPipelineCatalogueLoader()
+	loader.SetStorage(storage)
+	registry, err := registries.NewPipelineRegistry(
+		registries.GetWorkerRegistry(),
+		registries.GetBlockRegistry(),
+		registries.GetProcessingRegistry(),
+		loader,
+	)
+	suite.Nil(err)
+	registry.SetCataloguePath("catalogue")
+	_, err = registry.ReloadCatalogue()
+	suite.Nil(err)
+	suite.Empty(registry.GetAll())
+
+	watcher := registries.NewPipelineCatalogueWatcher(registry)
+	watcher.SetWatch(false)
+	watcher.SetPollInterval(10 * time.Millisecond)
+	watcher.Start()
+	defer watcher.Shutdown(suite.GetShutDownContext(time.Second))
+
+	// When
+	suite.writeCatalogueFile(
+		filepath.Join(storage.GetStorageDirectory(), "catalogue"),
+		"test.json",
+		string(suite.GetTestPipelineDefinition()),
+	)
+
+	// Then
+	suite.Eventually(
+		func() bool {
+			return registry.Get("test-pipeline-slug") != nil
+		},
+		5*time.Second,
+		10*time.Millisecond,
+	)
+}
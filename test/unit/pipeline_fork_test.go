@@ -0,0 +1,136 @@
+package unit_test
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types"
+	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/registries"
+)
+
+func (suite *UnitTestSuite) waitForProcessingStatusFile(directory string, pipelineSlug string, processingId uuid.UUID) {
+	suite.Eventually(
+		func() bool {
+			files, _ := filepath.Glob(
+				filepath.Join(directory, pipelineSlug, processingId.String(), "status_*"),
+			)
+			return len(files) > 0
+		},
+		5*time.Second,
+		10*time.Millisecond,
+	)
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryForkPipeline() {
+	// Given
+	secondUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	firstUrl := suite.GetMockHTTPServerURL(secondUrl, http.StatusOK, 0)
+
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineTwoBlocks(firstUrl),
+		"test-pipeline-slug-two-blocks",
+		"test-block-first-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	pipelineRegistry.SetPipelineResultStorages(
+		[]interfaces.Storage{
+			types.NewLocalStorage(storageDirectory),
+		},
+	)
+
+	sourceProcessingId, err := pipelineRegistry.StartPipeline(processingData)
+	suite.Nil(err)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), sourceProcessingId)
+
+	// When
+	processingId, err := pipelineRegistry.ForkPipeline(
+		sourceProcessingId,
+		schemas.PipelineStartInputSchema{
+			Pipeline: schemas.PipelineInputSchema{
+				Slug: pipeline.GetSlug(),
+			},
+			Block: schemas.BlockInputSchema{
+				Slug:        "test-block-second-slug",
+				TargetIndex: -1,
+			},
+		},
+	)
+
+	// Then
+	suite.Nil(err)
+	suite.NotEqual(sourceProcessingId, processingId)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), processingId)
+
+	// Output of the first block is copied from the source processing
+	copiedOutputs, _ := filepath.Glob(
+		filepath.Join(storageDirectory, pipeline.GetSlug(), processingId.String(), "test-block-first-slug", "output_0*"),
+	)
+	suite.Len(copiedOutputs, 1)
+	copiedOutput, err := os.ReadFile(copiedOutputs[0])
+	suite.Nil(err)
+	suite.Equal(secondUrl, string(copiedOutput))
+
+	// Second block is processed with the copied output of the first block
+	forkedOutputs, _ := filepath.Glob(
+		filepath.Join(storageDirectory, pipeline.GetSlug(), processingId.String(), "test-block-second-slug", "output_0*"),
+	)
+	suite.Len(forkedOutputs, 1)
+	forkedOutput, err := os.ReadFile(forkedOutputs[0])
+	suite.Nil(err)
+	suite.Equal("Hello, world!", string(forkedOutput))
+
+	// Lineage is recorded in the status
+	statusFiles, _ := filepath.Glob(
+		filepath.Join(storageDirectory, pipeline.GetSlug(), processingId.String(), "status_*"),
+	)
+	suite.Len(statusFiles, 1)
+	status, err := os.ReadFile(statusFiles[0])
+	suite.Nil(err)
+	suite.Contains(string(status), `"parent_processing_id":"`+sourceProcessingId.String()+`"`)
+	suite.Contains(string(status), `"parent_block_slug":"test-block-second-slug"`)
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryForkPipelineErrors() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, _, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineTwoBlocks(successUrl),
+		"test-pipeline-slug-two-blocks",
+		"test-block-first-slug",
+		nil,
+	)
+	pipelineRegistry.SetPipelineResultStorages(
+		[]interfaces.Storage{
+			types.NewLocalStorage(suite.T().TempDir()),
+		},
+	)
+
+	// When
+	_, unknownProcessingErr := pipelineRegistry.ForkPipeline(
+		uuid.New(),
+		schemas.PipelineStartInputSchema{
+			Pipeline: schemas.PipelineInputSchema{Slug: pipeline.GetSlug()},
+			Block:    schemas.BlockInputSchema{Slug: "test-block-second-slug"},
+		},
+	)
+	_, unknownBlockErr := pipelineRegistry.ForkPipeline(
+		uuid.New(),
+		schemas.PipelineStartInputSchema{
+			Pipeline: schemas.PipelineInputSchema{Slug: pipeline.GetSlug()},
+			Block:    schemas.BlockInputSchema{Slug: "unknown-block-slug"},
+		},
+	)
+
+	// Then
+	suite.True(errors.Is(unknownProcessingErr, registries.ErrProcessingNotFound))
+	suite.NotNil(unknownBlockErr)
+	suite.False(errors.Is(unknownBlockErr, registries.ErrProcessingNotFound))
+}
@@ -0,0 +1,168 @@
+package unit_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types"
+	"data-pipelines-worker/types/interfaces"
+)
+
+func (suite *UnitTestSuite) GetTestPipelineWithInputSchema() interfaces.Pipeline {
+	return suite.GetTestPipeline(
+		`{
+			"slug": "test-pipeline-slug",
+			"title": "Test Pipeline",
+			"description": "Test Pipeline Description",
+			"input_schema": {
+				"type": "object",
+				"properties": {
+					"url": {
+						"type": "string"
+					},
+					"retries": {
+						"type": "integer",
+						"default": 3
+					},
+					"verbose": {
+						"type": "boolean"
+					},
+					"attachment": {
+						"type": "string",
+						"format": "file"
+					}
+				},
+				"required": ["url"]
+			},
+			"blocks": [
+				{
+					"id": "http_request",
+					"slug": "test-block-slug",
+					"description": "Request Local Resourse",
+					"input_config": {
+						"property": {
+							"url": {
+								"origin": "$pipeline.input.url"
+							}
+						}
+					}
+				}
+			]
+		}`,
+	)
+}
+
+func (suite *UnitTestSuite) TestPipelineValidateInput() {
+	// Given
+	pipeline := suite.GetTestPipelineWithInputSchema()
+
+	// When
+	input, err := pipeline.ValidateInput(
+		map[string]interface{}{
+			"url":        "http://localhost",
+			"verbose":    "true",
+			"attachment": []byte("file content"),
+		},
+	)
+
+	// Then
+	suite.Nil(err)
+	suite.Equal(
+		map[string]interface{}{
+			"url":        "http://localhost",
+			"retries":    float64(3),
+			"verbose":    true,
+			"attachment": []byte("file content"),
+		},
+		input,
+	)
+
+	_, err = pipeline.ValidateInput(map[string]interface{}{"retries": "many"})
+	suite.NotNil(err)
+	suite.Contains(err.Error(), "Pipeline input is invalid for pipeline: test-pipeline-slug")
+	suite.Contains(err.Error(), "url is required")
+	suite.Contains(err.Error(), "retries: Invalid type")
+
+	// Pipelines without input schema accept any input
+	input, err = suite.GetTestPipelineOneBlock("http://localhost").ValidateInput(
+		map[string]interface{}{"anything": 1},
+	)
+	suite.Nil(err)
+	suite.Equal(map[string]interface{}{"anything": 1}, input)
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryStartPipelineInput() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, _, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineWithInputSchema(),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	pipelineRegistry.SetPipelineResultStorages(
+		[]interfaces.Storage{
+			types.NewLocalStorage(storageDirectory),
+		},
+	)
+
+	// When
+	processingId, err := pipelineRegistry.StartPipeline(
+		schemas.PipelineStartInputSchema{
+			Pipeline: schemas.PipelineInputSchema{
+				Slug:  pipeline.GetSlug(),
+				Input: map[string]interface{}{"url": successUrl},
+			},
+		},
+	)
+
+	// Then
+	suite.Nil(err)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), processingId)
+
+	outputs := filepath.Join(storageDirectory, pipeline.GetSlug(), processingId.String(), "test-block-slug", "output_0*")
+	files, _ := filepath.Glob(outputs)
+	suite.Len(files, 1)
+	output, err := os.ReadFile(files[0])
+	suite.Nil(err)
+	suite.Equal("Hello, world!", string(output))
+
+	// Resumed processing gets the input parameters from its logs
+	suite.Nil(os.Remove(files[0]))
+	_, err = pipelineRegistry.ResumePipeline(
+		schemas.PipelineStartInputSchema{
+			Pipeline: schemas.PipelineInputSchema{
+				Slug:         pipeline.GetSlug(),
+				ProcessingID: processingId,
+			},
+			Block: schemas.BlockInputSchema{
+				Slug:        "test-block-slug",
+				TargetIndex: -1,
+			},
+		},
+	)
+	suite.Nil(err)
+	suite.Eventually(
+		func() bool {
+			files, _ := filepath.Glob(outputs)
+			return len(files) == 1
+		},
+		5*time.Second,
+		10*time.Millisecond,
+	)
+
+	// Invalid input is rejected before the processing is started
+	_, err = pipelineRegistry.StartPipeline(
+		schemas.PipelineStartInputSchema{
+			Pipeline: schemas.PipelineInputSchema{
+				Slug: pipeline.GetSlug(),
+			},
+		},
+	)
+	suite.NotNil(err)
+	suite.Contains(err.Error(), "url is required")
+}
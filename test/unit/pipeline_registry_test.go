@@ -40,7 +40,7 @@ func (suite *UnitTestSuite) TestPipelineRegistryRegisterCorrect() {
 	suite.Nil(err)
 	suite.NotEmpty(pipeline.GetBlocks())
 
-	registry.Add(pipeline)
+	suite.Nil(registry.Add(pipeline))
 
 	suite.NotEmpty(registry.GetAll())
 }
@@ -62,9 +62,9 @@ func (suite *UnitTestSuite) TestPipelineRegistryRegisterErrorMissingRequiredProp
 	)
 	suite.Nil(err)
 
-	suite.Panics(func() {
-		registry.Add(pipeline)
-	})
+	err = registry.Add(pipeline)
+	suite.NotNil(err)
+	suite.Contains(err.Error(), "Pipeline schema is invalid for pipeline: YT-CHANNEL-video-generation-invalid")
 
 	suite.Empty(registry.Get(pipeline.GetSlug()))
 }
@@ -81,7 +81,7 @@ func (suite *UnitTestSuite) TestPipelineRegistryGet() {
 	pipeline, err := dataclasses.NewPipelineFromBytes(suite.GetTestPipelineDefinition())
 	suite.Nil(err)
 
-	registry.Add(pipeline)
+	suite.Nil(registry.Add(pipeline))
 	suite.NotEmpty(registry.GetAll())
 
 	suite.NotEmpty(registry.Get("test-pipeline-slug"))
@@ -99,7 +99,7 @@ func (suite *UnitTestSuite) TestPipelineRegistryGetAll() {
 	pipeline, err := dataclasses.NewPipelineFromBytes(suite.GetTestPipelineDefinition())
 	suite.Nil(err)
 
-	registry.Add(pipeline)
+	suite.Nil(registry.Add(pipeline))
 	suite.NotEmpty(registry.GetAll())
 }
 
@@ -115,7 +115,7 @@ func (suite *UnitTestSuite) TestPipelineRegistryDelete() {
 	pipeline, err := dataclasses.NewPipelineFromBytes(suite.GetTestPipelineDefinition())
 	suite.Nil(err)
 
-	registry.Add(pipeline)
+	suite.Nil(registry.Add(pipeline))
 	suite.NotEmpty(registry.Get("test-pipeline-slug"))
 
 	registry.Delete("test-pipeline-slug")
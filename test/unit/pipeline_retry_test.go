@@ -0,0 +1,235 @@
+package unit_test
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types"
+	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/registries"
+)
+
+func (suite *UnitTestSuite) TestPipelineRegistryRetryPipelineFailedBlock() {
+	// Given
+	// Fails on the first request and succeeds on the retry
+	flakyServer, _ := suite.GetMockWebhookServer(http.StatusInternalServerError)
+	firstUrl := suite.GetMockHTTPServerURL(flakyServer.URL, http.StatusOK, 0)
+
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineTwoBlocks(firstUrl),
+		"test-pipeline-slug-two-blocks",
+		"test-block-first-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	pipelineRegistry.SetPipelineResultStorages(
+		[]interfaces.Storage{
+			types.NewLocalStorage(storageDirectory),
+		},
+	)
+
+	processingId, err := pipelineRegistry.StartPipeline(processingData)
+	suite.Nil(err)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), processingId)
+
+	// When
+	result, err := pipelineRegistry.RetryPipeline(
+		pipeline.GetSlug(),
+		processingId,
+		schemas.PipelineRetryInputSchema{FailedIndexesOnly: true},
+	)
+
+	// Then
+	suite.Nil(err)
+	suite.Equal(processingId, result.ProcessingID)
+	suite.Equal("test-block-second-slug", result.BlockSlug)
+	suite.Equal([]int{0}, result.TargetIndexes)
+
+	outputs := filepath.Join(storageDirectory, pipeline.GetSlug(), processingId.String(), "test-block-second-slug", "output_0*")
+	suite.Eventually(
+		func() bool {
+			files, _ := filepath.Glob(outputs)
+			return len(files) == 1
+		},
+		5*time.Second,
+		10*time.Millisecond,
+	)
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryRetryPipelineBudgetExceeded() {
+	// Given
+	flakyServer, _ := suite.GetMockWebhookServer(http.StatusInternalServerError)
+	firstUrl := suite.GetMockHTTPServerURL(flakyServer.URL, http.StatusOK, 0)
+
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineTwoBlocks(firstUrl),
+		"test-pipeline-slug-two-blocks",
+		"test-block-first-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	pipelineRegistry.SetPipelineResultStorages(
+		[]interfaces.Storage{
+			types.NewLocalStorage(storageDirectory),
+		},
+	)
+
+	registries.GetUsageRegistry(true)
+	defer registries.GetUsageRegistry(true)
+
+	processingId, err := pipelineRegistry.StartPipeline(processingData)
+	suite.Nil(err)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), processingId)
+
+	suite._config.Costs.Budgets.Pipelines[pipeline.GetSlug()] = 1
+	defer delete(suite._config.Costs.Budgets.Pipelines, pipeline.GetSlug())
+
+	usage := schemas.ProcessingUsageSchema{}
+	usage.AddModelUsage("gpt-4o", schemas.UsageSchema{Cost: 1.5})
+	registries.GetUsageRegistry().Add(pipeline.GetSlug(), uuid.New(), usage)
+
+	// When
+	_, err = pipelineRegistry.RetryPipeline(pipeline.GetSlug(), processingId, schemas.PipelineRetryInputSchema{})
+
+	// Then
+	suite.True(errors.Is(err, registries.ErrBudgetExceeded))
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryRetryPipelineOriginalInput() {
+	// Given
+	failingUrl := suite.GetMockHTTPServerURL("Failed", http.StatusInternalServerError, 0)
+	flakyServer, _ := suite.GetMockWebhookServer(http.StatusInternalServerError)
+
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineOneBlock(failingUrl),
+		"test-pipeline-slug",
+		"test-block-slug",
+		map[string]interface{}{
+			"url": flakyServer.URL,
+		},
+	)
+	storageDirectory := suite.T().TempDir()
+	pipelineRegistry.SetPipelineResultStorages(
+		[]interfaces.Storage{
+			types.NewLocalStorage(storageDirectory),
+		},
+	)
+
+	processingId, err := pipelineRegistry.StartPipeline(processingData)
+	suite.Nil(err)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), processingId)
+
+	// When
+	result, err := pipelineRegistry.RetryPipeline(
+		pipeline.GetSlug(),
+		processingId,
+		schemas.PipelineRetryInputSchema{},
+	)
+
+	// Then
+	suite.Nil(err)
+	suite.Equal("test-block-slug", result.BlockSlug)
+	suite.Empty(result.TargetIndexes)
+
+	// The block is resumed with the input of the request instead of the Pipeline one
+	outputs := filepath.Join(storageDirectory, pipeline.GetSlug(), processingId.String(), "test-block-slug", "output_0*")
+	suite.Eventually(
+		func() bool {
+			files, _ := filepath.Glob(outputs)
+			return len(files) == 1
+		},
+		5*time.Second,
+		10*time.Millisecond,
+	)
+	files, _ := filepath.Glob(outputs)
+	output, err := os.ReadFile(files[0])
+	suite.Nil(err)
+	suite.Equal("null", string(output))
+
+	// Completed processing has nothing to retry
+	suite.Eventually(
+		func() bool {
+			statusFiles, _ := filepath.Glob(
+				filepath.Join(storageDirectory, pipeline.GetSlug(), processingId.String(), "status_*"),
+			)
+			for _, statusFile := range statusFiles {
+				if status, err := os.ReadFile(statusFile); err == nil &&
+					strings.Contains(string(status), `"is_completed":true`) {
+					return true
+				}
+			}
+			return false
+		},
+		5*time.Second,
+		10*time.Millisecond,
+	)
+	_, err = pipelineRegistry.RetryPipeline(
+		pipeline.GetSlug(),
+		processingId,
+		schemas.PipelineRetryInputSchema{},
+	)
+	suite.NotNil(err)
+	suite.False(errors.Is(err, registries.ErrProcessingNotFound))
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryRetryPipelineAuthInput() {
+	// Given
+	failingUrl := suite.GetMockHTTPServerURL("Failed", http.StatusInternalServerError, 0)
+	flakyServer, flakyRequests := suite.GetMockWebhookServer(http.StatusInternalServerError)
+
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineOneBlock(failingUrl),
+		"test-pipeline-slug",
+		"test-block-slug",
+		map[string]interface{}{
+			"url":  flakyServer.URL,
+			"auth": map[string]interface{}{"token": "caller-token"},
+		},
+	)
+	storageDirectory := suite.T().TempDir()
+	pipelineRegistry.SetPipelineResultStorages(
+		[]interfaces.Storage{
+			types.NewLocalStorage(storageDirectory),
+		},
+	)
+
+	processingId, err := pipelineRegistry.StartPipeline(processingData)
+	suite.Nil(err)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), processingId)
+
+	failedRequest := <-flakyRequests
+	suite.Equal("Bearer caller-token", failedRequest.headers.Get("Authorization"))
+
+	// When
+	_, err = pipelineRegistry.RetryPipeline(
+		pipeline.GetSlug(),
+		processingId,
+		schemas.PipelineRetryInputSchema{},
+	)
+
+	// Then
+	suite.Nil(err)
+
+	// The logs mask the token, the retry sends the one the processing was started with
+	select {
+	case retriedRequest := <-flakyRequests:
+		suite.Equal("Bearer caller-token", retriedRequest.headers.Get("Authorization"))
+	case <-time.After(5 * time.Second):
+		suite.FailNow("block was not retried")
+	}
+
+	logs, _ := filepath.Glob(filepath.Join(storageDirectory, pipeline.GetSlug(), processingId.String(), "log_*"))
+	suite.NotEmpty(logs)
+	for _, logFile := range logs {
+		logContent, err := os.ReadFile(logFile)
+		suite.Nil(err)
+		suite.NotContains(string(logContent), "caller-token")
+	}
+}
@@ -0,0 +1,176 @@
+package unit_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"data-pipelines-worker/types"
+	"data-pipelines-worker/types/blocks"
+	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/registries"
+)
+
+func (suite *UnitTestSuite) getTestPipelineVersionDefinition(version string) string {
+	return strings.Replace(
+		string(suite.GetTestPipelineDefinition()),
+		`"slug": "test-pipeline-slug",`,
+		fmt.Sprintf(`"slug": "test-pipeline-slug", "version": "%s",`, version),
+		1,
+	)
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryVersions() {
+	// Given
+	cataloguePath := suite.T().TempDir()
+	suite.writeCatalogueFile(cataloguePath, "test-v1.json", string(suite.GetTestPipelineDefinition()))
+	suite.writeCatalogueFile(cataloguePath, "test-v1.10.json", suite.getTestPipelineVersionDefinition("1.10"))
+	suite.writeCatalogueFile(cataloguePath, "test-v2.json", suite.getTestPipelineVersionDefinition("2"))
+	duplicateFile := suite.writeCatalogueFile(cataloguePath, "x-duplicate.json", suite.getTestPipelineVersionDefinition("2"))
+
+	registry, err := registries.NewPipelineRegistry(
+		registries.GetWorkerRegistry(),
+		registries.GetBlockRegistry(),
+		registries.GetProcessingRegistry(),
+		dataclasses.NewPipelineCatalogueLoader(),
+	)
+	suite.Nil(err)
+	registry.SetCataloguePath(cataloguePath)
+
+	// When
+	report, err := registry.ReloadCatalogue()
+
+	// Then
+	suite.Nil(err)
+	suite.Equal([]string{"test-pipeline-slug"}, report.Pipelines)
+	suite.Len(report.Errors, 1)
+	suite.Equal(duplicateFile, report.Errors[0].File)
+	suite.Contains(report.Errors[0].Error, "duplicate pipeline slug test-pipeline-slug version 2")
+
+	versions := make([]string, 0)
+	for _, pipeline := range registry.GetVersions("test-pipeline-slug") {
+		versions = append(versions, pipeline.GetVersion())
+	}
+	suite.Equal([]string{"1", "1.10", "2"}, versions)
+	suite.Equal("2", registry.Get("test-pipeline-slug").GetVersion())
+	suite.Equal("1.10", registry.GetVersion("test-pipeline-slug", "1.10").GetVersion())
+	suite.Nil(registry.GetVersion("test-pipeline-slug", "3"))
+
+	// Default version is kept across reloads
+	previousPipelines := registry.Pipelines
+	suite.Nil(registry.SetDefaultVersion("test-pipeline-slug", "1"))
+	suite.Equal("1", registry.Get("test-pipeline-slug").GetVersion())
+	suite.Equal("2", previousPipelines["test-pipeline-slug"].GetVersion())
+	_, err = registry.ReloadCatalogue()
+	suite.Nil(err)
+	suite.Equal("1", registry.Get("test-pipeline-slug").GetVersion())
+
+	err = registry.SetDefaultVersion("test-pipeline-slug", "3")
+	suite.True(errors.Is(err, registries.ErrPipelineVersionNotFound))
+	suite.Equal("1", registry.Get("test-pipeline-slug").GetVersion())
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryResumePinnedVersion() {
+	// Given
+	firstVersionUrl := suite.GetMockHTTPServerURL("First version", http.StatusOK, 0)
+	secondVersionUrl := suite.GetMockHTTPServerURL("Second version", http.StatusOK, 0)
+
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineOneBlock(firstVersionUrl),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	pipelineRegistry.SetPipelineResultStorages(
+		[]interfaces.Storage{
+			types.NewLocalStorage(storageDirectory),
+		},
+	)
+
+	processingId, err := pipelineRegistry.StartPipeline(processingData)
+	suite.Nil(err)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), processingId)
+
+	secondVersionDefinition := strings.Replace(pipeline.GetSchemaString(), firstVersionUrl, secondVersionUrl, 1)
+	secondVersion := suite.GetTestPipeline(
+		`{"version": "2",` + strings.TrimPrefix(strings.TrimSpace(secondVersionDefinition), "{"),
+	)
+	suite.Nil(pipelineRegistry.Add(secondVersion))
+	suite.Equal("2", pipelineRegistry.Get(pipeline.GetSlug()).GetVersion())
+
+	details := pipelineRegistry.GetProcessingDetails(pipeline, processingId)
+	suite.Len(details, 1)
+	suite.Equal("1", details[0].(*dataclasses.PipelineProcessingDetails).PipelineVersion)
+
+	outputs := filepath.Join(storageDirectory, pipeline.GetSlug(), processingId.String(), "test-block-slug", "output_0*")
+	files, _ := filepath.Glob(outputs)
+	suite.Len(files, 1)
+	suite.Nil(os.Remove(files[0]))
+
+	// When
+	processingData.Pipeline.ProcessingID = processingId
+	_, err = pipelineRegistry.ResumePipeline(processingData)
+
+	// Then
+	suite.Nil(err)
+	suite.Eventually(
+		func() bool {
+			files, _ := filepath.Glob(outputs)
+			return len(files) == 1
+		},
+		5*time.Second,
+		10*time.Millisecond,
+	)
+	files, _ = filepath.Glob(outputs)
+	output, err := os.ReadFile(files[0])
+	suite.Nil(err)
+	suite.Equal("First version", string(output))
+
+	// New processings are started with the default version
+	processingData.Pipeline.ProcessingID = uuid.Nil
+	newProcessingId, err := pipelineRegistry.StartPipeline(processingData)
+	suite.Nil(err)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), newProcessingId)
+	newDetails := pipelineRegistry.GetProcessingDetails(pipeline, newProcessingId)
+	suite.Len(newDetails, 1)
+	suite.Equal("2", newDetails[0].(*dataclasses.PipelineProcessingDetails).PipelineVersion)
+}
+
+func (suite *UnitTestSuite) TestPipelinePinnedBlockVersions() {
+	// Given
+	// Pipelines look the blocks up in the shared registry
+	blockRegistry := suite.GetBlockRegistry()
+	defer suite.GetBlockRegistry(true)
+
+	blockV2 := blocks.NewBlockJoinStrings()
+	blockV2.Version = "2"
+	blockRegistry.Add(blockV2)
+
+	// When
+	pipeline, err := dataclasses.NewPipelineFromBytes([]byte(`{
+		"slug": "test-pinned-blocks",
+		"title": "Test Pinned Blocks",
+		"blocks": [
+			{"id": "join_strings", "slug": "join-latest", "input": {}},
+			{"id": "join_strings@1", "slug": "join-pinned", "input": {}},
+			{"id": "text_add_prefix_or_suffix@1", "slug": "wrap-pinned", "input": {}}
+		]
+	}`))
+
+	// Then
+	suite.Nil(err)
+	pipelineBlocks := pipeline.GetBlocks()
+	suite.Len(pipelineBlocks, 3)
+	suite.Same(blockV2, pipelineBlocks[0].GetBlock())
+	suite.Equal("1", pipelineBlocks[1].GetBlock().GetVersion())
+	suite.Equal("join_strings", pipelineBlocks[1].GetBlock().GetId())
+	suite.Equal("wrap_text", pipelineBlocks[2].GetBlock().GetId())
+}
@@ -0,0 +1,421 @@
+package unit_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types"
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/egress"
+	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/registries"
+)
+
+type webhookRequest struct {
+	headers http.Header
+	body    []byte
+}
+
+func (suite *UnitTestSuite) GetMockWebhookServer(statusCodes ...int) (*httptest.Server, chan webhookRequest) {
+	suite.Lock()
+	defer suite.Unlock()
+
+	requests := make(chan webhookRequest, 10)
+	attempt := 0
+	attemptMutex := &sync.Mutex{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		attemptMutex.Lock()
+		statusCode := http.StatusOK
+		if attempt < len(statusCodes) {
+			statusCode = statusCodes[attempt]
+		}
+		attempt++
+		attemptMutex.Unlock()
+
+		w.WriteHeader(statusCode)
+		requests <- webhookRequest{headers: r.Header.Clone(), body: body}
+	}))
+	suite.httpTestServers = append(suite.httpTestServers, server)
+
+	return server, requests
+}
+
+func (suite *UnitTestSuite) TestPipelineCallbackValidate() {
+	cases := []struct {
+		callback schemas.PipelineCallbackSchema
+		valid    bool
+	}{
+		{schemas.PipelineCallbackSchema{URL: "https://example.com/hook"}, true},
+		{schemas.PipelineCallbackSchema{URL: "http://example.com/hook", Events: []string{"completed", "moderation_wait"}}, true},
+		{schemas.PipelineCallbackSchema{URL: "ftp://example.com/hook"}, false},
+		{schemas.PipelineCallbackSchema{URL: "not a url"}, false},
+		{schemas.PipelineCallbackSchema{URL: "https://example.com/hook", Events: []string{"finished"}}, false},
+	}
+
+	for _, c := range cases {
+		err := c.callback.Validate()
+		suite.Equal(c.valid, err == nil, c.callback.URL)
+	}
+
+	callback := schemas.PipelineCallbackSchema{URL: "https://example.com/hook", Events: []string{"failed"}}
+	suite.True(callback.IsSubscribed(schemas.PIPELINE_EVENT_FAILED))
+	suite.False(callback.IsSubscribed(schemas.PIPELINE_EVENT_COMPLETED))
+}
+
+func (suite *UnitTestSuite) TestPipelineProcessWebhookCompleted() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	webhookServer, webhookRequests := suite.GetMockWebhookServer()
+
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineOneBlock(successUrl),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	processingData.Callback = &schemas.PipelineCallbackSchema{
+		URL:    webhookServer.URL,
+		Events: []string{schemas.PIPELINE_EVENT_COMPLETED},
+		Secret: "webhook-secret",
+	}
+
+	mockStorage := suite.NewMockLocalStorage(3)
+	pipelineRegistry.SetPipelineResultStorages(
+		[]interfaces.Storage{
+			mockStorage,
+		},
+	)
+
+	// When
+	processingId, err := pipeline.Process(
+		suite.GetWorkerRegistry(true),
+		suite.GetBlockRegistry(),
+		suite.GetProcessingRegistry(true),
+		processingData,
+		pipelineRegistry.GetPipelineResultStorages(),
+	)
+	suite.Nil(err)
+
+	// Then
+	var request webhookRequest
+	select {
+	case request = <-webhookRequests:
+	case <-time.After(5 * time.Second):
+		suite.FailNow("webhook was not delivered")
+	}
+
+	suite.Equal(schemas.PIPELINE_EVENT_COMPLETED, request.headers.Get(dataclasses.WEBHOOK_HEADER_EVENT))
+
+	timestamp, err := strconv.ParseInt(request.headers.Get(dataclasses.WEBHOOK_HEADER_TIMESTAMP), 10, 64)
+	suite.Nil(err)
+	suite.Equal(
+		dataclasses.SignPipelineWebhookPayload("webhook-secret", timestamp, request.body),
+		request.headers.Get(dataclasses.WEBHOOK_HEADER_SIGNATURE),
+	)
+
+	payload := dataclasses.PipelineWebhookPayload{}
+	suite.Nil(json.Unmarshal(request.body, &payload))
+	suite.Equal(processingId, payload.ProcessingId)
+	suite.Equal("test-pipeline-slug", payload.PipelineSlug)
+	suite.True(payload.IsCompleted)
+	suite.False(payload.IsError)
+	suite.Len(payload.Blocks, 1)
+	suite.Equal("test-block-slug", payload.Blocks[0].Slug)
+	suite.Len(payload.Blocks[0].Outputs, 1)
+	suite.Contains(payload.Blocks[0].Outputs[0].Path, "test-block-slug/output_0")
+
+	// The log is saved before the delivery
+	<-mockStorage.GetCreatedFilesChan()
+	pipelineLogFile := <-mockStorage.GetCreatedFilesChan()
+	suite.Contains(pipelineLogFile.filePath, fmt.Sprintf("%s/log_", processingId.String()))
+	suite.Contains(pipelineLogFile.data.String(), `"is_completed":true`)
+
+	// The deliveries are saved next to the log afterwards
+	<-mockStorage.GetCreatedFilesChan()
+	var webhooksLogFile createdFile
+	select {
+	case webhooksLogFile = <-mockStorage.GetCreatedFilesChan():
+	case <-time.After(5 * time.Second):
+		suite.FailNow("webhook deliveries were not saved")
+	}
+	suite.Contains(webhooksLogFile.filePath, fmt.Sprintf("%s/webhooks_", processingId.String()))
+	suite.Contains(webhooksLogFile.data.String(), fmt.Sprintf("Webhook completed delivered to %s", webhookServer.URL))
+}
+
+func (suite *UnitTestSuite) TestPipelineWebhookPayloadEvent() {
+	// Given
+	pipeline := suite.GetTestPipelineOneBlock("http://localhost:8080").(*dataclasses.PipelineData)
+	processingId := uuid.New()
+	pipelineBlockDataRegistry := registries.NewPipelineBlockDataRegistry(
+		processingId,
+		pipeline.GetSlug(),
+		[]interfaces.Storage{},
+	)
+	completedState := dataclasses.NewPipelineProcessingState()
+	completedState.SetCompleted()
+
+	failedState := dataclasses.NewPipelineProcessingState()
+	failedState.SetError(errors.New("block timed out"))
+	failedState.SetError(errors.New("another block input timed out"))
+
+	// Errors the processing recovered from are logged only and do not fail it
+	logLine, _ := json.Marshal(map[string]string{
+		"level":   "ERROR",
+		"message": "Error saving output for block [test-block-slug:http_request]",
+	})
+
+	// When
+	completedPayload := dataclasses.NewPipelineWebhookPayload(
+		pipeline,
+		processingId,
+		completedState,
+		pipelineBlockDataRegistry,
+	)
+	failedPayload := dataclasses.NewPipelineWebhookPayload(
+		pipeline,
+		processingId,
+		failedState,
+		pipelineBlockDataRegistry,
+	)
+	transferredPayload := dataclasses.NewPipelineWebhookPayload(
+		pipeline,
+		processingId,
+		dataclasses.NewPipelineProcessingState(),
+		pipelineBlockDataRegistry,
+	)
+	completedStatus := completedState.NewStatus(
+		processingId,
+		pipeline.GetSlug(),
+		uuid.New(),
+		bytes.NewBuffer(logLine),
+		types.NewLocalStorage(suite.T().TempDir()),
+	).(*dataclasses.PipelineProcessingStatus)
+
+	// Then
+	suite.Equal(schemas.PIPELINE_EVENT_COMPLETED, completedPayload.Event)
+	suite.False(completedPayload.IsError)
+	suite.Empty(completedPayload.Error)
+
+	// The first error the processing failed with is sent
+	suite.Equal(schemas.PIPELINE_EVENT_FAILED, failedPayload.Event)
+	suite.True(failedPayload.IsError)
+	suite.Equal("block timed out", failedPayload.Error)
+
+	suite.Nil(transferredPayload)
+
+	// The status is saved with the same state
+	suite.True(completedStatus.IsCompleted)
+	suite.False(completedStatus.IsError)
+}
+
+func (suite *UnitTestSuite) TestPipelineProcessWebhookSlowCallback() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(2 * time.Second)
+	}))
+	defer webhookServer.Close()
+
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineOneBlock(successUrl),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	processingData.Callback = &schemas.PipelineCallbackSchema{URL: webhookServer.URL}
+
+	mockStorage := suite.NewMockLocalStorage(3)
+	pipelineRegistry.SetPipelineResultStorages(
+		[]interfaces.Storage{
+			mockStorage,
+		},
+	)
+
+	// When
+	processingId, err := pipeline.Process(
+		suite.GetWorkerRegistry(true),
+		suite.GetBlockRegistry(),
+		suite.GetProcessingRegistry(true),
+		processingData,
+		pipelineRegistry.GetPipelineResultStorages(),
+	)
+	suite.Nil(err)
+
+	// Then
+	// The log and the status do not wait for the callback
+	timeout := time.After(time.Second)
+	for _, fileName := range []string{"output_0", "log_", "status_"} {
+		select {
+		case createdFile := <-mockStorage.GetCreatedFilesChan():
+			suite.Contains(createdFile.filePath, fileName)
+		case <-timeout:
+			suite.FailNow("processing log was not saved before the webhook delivery")
+		}
+	}
+	suite.NotEqual(uuid.Nil, processingId)
+
+	// The log of the delivery is saved once the callback responds
+	select {
+	case webhooksLogFile := <-mockStorage.GetCreatedFilesChan():
+		suite.Contains(webhooksLogFile.filePath, fmt.Sprintf("%s/webhooks_", processingId.String()))
+	case <-time.After(5 * time.Second):
+		suite.FailNow("webhooks log was not saved after the webhook delivery")
+	}
+}
+
+func (suite *UnitTestSuite) TestPipelineProcessWebhookPrivateCallback() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineOneBlock(successUrl),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	processingData.Callback = &schemas.PipelineCallbackSchema{URL: "http://169.254.169.254/latest/meta-data"}
+
+	// When
+	_, err := pipeline.Process(
+		suite.GetWorkerRegistry(true),
+		suite.GetBlockRegistry(),
+		suite.GetProcessingRegistry(true),
+		processingData,
+		pipelineRegistry.GetPipelineResultStorages(),
+	)
+
+	// Then
+	suite.ErrorIs(err, egress.ErrEgressDenied)
+}
+
+func (suite *UnitTestSuite) TestPipelineWebhookSenderRetries() {
+	// Given
+	webhookServer, webhookRequests := suite.GetMockWebhookServer(
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusOK,
+	)
+	logger, loggerBuffer := config.GetLoggerForEntity("pipeline", uuid.New())
+
+	sender := dataclasses.NewPipelineWebhookSender()
+	sender.MaxRetries = 3
+	sender.RetryDelay = time.Millisecond
+
+	payload := &dataclasses.PipelineWebhookPayload{
+		Event:        schemas.PIPELINE_EVENT_FAILED,
+		PipelineSlug: "test-pipeline-slug",
+		ProcessingId: uuid.New(),
+	}
+
+	// When
+	err := sender.Send(
+		logger,
+		schemas.PipelineCallbackSchema{URL: webhookServer.URL},
+		payload,
+	)
+
+	// Then
+	suite.Nil(err)
+	suite.Len(webhookRequests, 3)
+
+	deliveries := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		request := <-webhookRequests
+		deliveries[request.headers.Get(dataclasses.WEBHOOK_HEADER_DELIVERY)] = true
+		suite.Empty(request.headers.Get(dataclasses.WEBHOOK_HEADER_SIGNATURE))
+	}
+	suite.Len(deliveries, 1)
+
+	suite.Contains(loggerBuffer.String(), "attempt 1 failed: unexpected status code 500")
+	suite.Contains(loggerBuffer.String(), "attempt 2 failed: unexpected status code 502")
+	suite.Contains(loggerBuffer.String(), "with status 200 ( attempt 3")
+}
+
+func (suite *UnitTestSuite) TestPipelineWebhookSenderNotifyAsyncOrder() {
+	// Given
+	webhookServer, webhookRequests := suite.GetMockWebhookServer(
+		http.StatusInternalServerError,
+		http.StatusOK,
+		http.StatusOK,
+	)
+	logger, loggerBuffer := config.GetLoggerForEntity("webhooks", uuid.New())
+	callbacks := []schemas.PipelineCallbackSchema{{URL: webhookServer.URL}}
+
+	sender := dataclasses.NewPipelineWebhookSender()
+	sender.MaxRetries = 1
+	sender.RetryDelay = 200 * time.Millisecond
+
+	processingId := uuid.New()
+
+	// When
+	sender.NotifyAsync(
+		logger,
+		callbacks,
+		&dataclasses.PipelineWebhookPayload{
+			Event:        schemas.PIPELINE_EVENT_MODERATION_WAIT,
+			PipelineSlug: "test-pipeline-slug",
+			ProcessingId: processingId,
+		},
+		nil,
+	)
+	delivered := sender.NotifyAsync(
+		logger,
+		callbacks,
+		&dataclasses.PipelineWebhookPayload{
+			Event:        schemas.PIPELINE_EVENT_COMPLETED,
+			PipelineSlug: "test-pipeline-slug",
+			ProcessingId: processingId,
+		},
+		nil,
+	)
+	<-delivered
+
+	// Then
+	// The retried event is delivered before the events queued after it
+	suite.Len(webhookRequests, 3)
+	for _, event := range []string{
+		schemas.PIPELINE_EVENT_MODERATION_WAIT,
+		schemas.PIPELINE_EVENT_MODERATION_WAIT,
+		schemas.PIPELINE_EVENT_COMPLETED,
+	} {
+		request := <-webhookRequests
+		suite.Equal(event, request.headers.Get(dataclasses.WEBHOOK_HEADER_EVENT))
+	}
+	suite.Contains(loggerBuffer.String(), "Webhook moderation_wait delivered")
+	suite.Contains(loggerBuffer.String(), "Webhook completed delivered")
+}
+
+func (suite *UnitTestSuite) TestPipelineWebhookSenderClientError() {
+	// Given
+	webhookServer, webhookRequests := suite.GetMockWebhookServer(http.StatusNotFound)
+	logger, _ := config.GetLoggerForEntity("pipeline", uuid.New())
+
+	sender := dataclasses.NewPipelineWebhookSender()
+	sender.MaxRetries = 3
+	sender.RetryDelay = time.Millisecond
+
+	// When
+	err := sender.Send(
+		logger,
+		schemas.PipelineCallbackSchema{URL: webhookServer.URL},
+		&dataclasses.PipelineWebhookPayload{Event: schemas.PIPELINE_EVENT_STOPPED},
+	)
+
+	// Then
+	suite.NotNil(err)
+	suite.Len(webhookRequests, 1)
+}
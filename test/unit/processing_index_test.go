@@ -0,0 +1,331 @@
+package unit_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types"
+	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/registries"
+)
+
+func (suite *UnitTestSuite) newTestProcessingIndexEntry(
+	pipelineSlug string,
+	dateFinished time.Time,
+	isCompleted bool,
+	isError bool,
+) registries.ProcessingIndexEntry {
+	processingId := uuid.New()
+	logId := uuid.New()
+
+	entry, err := registries.NewProcessingIndexEntry(
+		pipelineSlug,
+		"local",
+		fmt.Sprintf("%s/%s/log_%d.json", pipelineSlug, processingId, dateFinished.Unix()),
+		fmt.Sprintf("%s/%s/status_%d.json", pipelineSlug, processingId, dateFinished.Unix()),
+		[]byte(fmt.Sprintf(
+			`{"id":"%s","log_id":"%s","storage":"local","is_stopped":false,"is_completed":%t,"is_error":%t,"date_finished":"%s"}`,
+			processingId,
+			logId,
+			isCompleted,
+			isError,
+			dateFinished.UTC().Format(time.RFC3339),
+		)),
+	)
+	suite.Nil(err)
+
+	return entry
+}
+
+func (suite *UnitTestSuite) TestProcessingIndexQuery() {
+	// Given
+	processingIndex, err := registries.NewProcessingIndex(filepath.Join(suite.T().TempDir(), "processings.db"))
+	suite.Nil(err)
+	defer processingIndex.Close()
+
+	storage := types.NewLocalStorage(suite.T().TempDir())
+	otherStorage := types.NewLocalStorage(suite.T().TempDir())
+	storages := []interfaces.Storage{storage}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	completedEntry := suite.newTestProcessingIndexEntry("test-pipeline-slug", now.Add(-3*time.Hour), true, false)
+	failedEntry := suite.newTestProcessingIndexEntry("test-pipeline-slug", now.Add(-2*time.Hour), false, true)
+	latestEntry := suite.newTestProcessingIndexEntry("test-pipeline-slug", now.Add(-time.Hour), true, false)
+	for _, entry := range []registries.ProcessingIndexEntry{completedEntry, failedEntry, latestEntry} {
+		suite.Nil(processingIndex.Add(storage, entry))
+	}
+	otherStorageEntry := suite.newTestProcessingIndexEntry("test-pipeline-slug", now, true, false)
+	suite.Nil(processingIndex.Add(otherStorage, otherStorageEntry))
+	suite.Nil(processingIndex.Add(storage, suite.newTestProcessingIndexEntry("other-pipeline-slug", now, true, false)))
+
+	// When
+	allEntries, allTotal, allErr := processingIndex.Query(
+		"test-pipeline-slug",
+		schemas.NewPipelineProcessingsQuerySchema(),
+		storages,
+	)
+
+	completedQuery := schemas.NewPipelineProcessingsQuerySchema()
+	suite.Nil(completedQuery.ParseQuery(url.Values{
+		"status": {schemas.PROCESSING_STATUS_COMPLETED},
+		"sort":   {schemas.PROCESSINGS_SORT_DATE_FINISHED},
+	}))
+	completedEntries, completedTotal, completedErr := processingIndex.Query("test-pipeline-slug", completedQuery, storages)
+
+	pageQuery := schemas.NewPipelineProcessingsQuerySchema()
+	suite.Nil(pageQuery.ParseQuery(url.Values{
+		"from":   {now.Add(-150 * time.Minute).Format(time.RFC3339)},
+		"offset": {"1"},
+		"limit":  {"1"},
+	}))
+	pageEntries, pageTotal, pageErr := processingIndex.Query("test-pipeline-slug", pageQuery, storages)
+
+	mergedQuery := schemas.NewPipelineProcessingsQuerySchema()
+	suite.Nil(mergedQuery.ParseQuery(url.Values{
+		"to":    {now.Add(-90 * time.Minute).Format(time.RFC3339)},
+		"limit": {"1"},
+	}))
+	mergedEntries, mergedTotal, mergedErr := processingIndex.Query(
+		"test-pipeline-slug",
+		mergedQuery,
+		[]interfaces.Storage{storage, otherStorage},
+	)
+	_, allStoragesTotal, _ := processingIndex.Query(
+		"test-pipeline-slug",
+		schemas.NewPipelineProcessingsQuerySchema(),
+		[]interfaces.Storage{otherStorage, storage},
+	)
+
+	// Then
+	suite.Nil(allErr)
+	suite.Equal(3, allTotal)
+	suite.Len(allEntries, 3)
+	suite.Equal(latestEntry.ProcessingId, allEntries[0].ProcessingId)
+	suite.Equal(completedEntry.ProcessingId, allEntries[2].ProcessingId)
+
+	suite.Nil(completedErr)
+	suite.Equal(2, completedTotal)
+	suite.Len(completedEntries, 2)
+	suite.Equal(completedEntry.ProcessingId, completedEntries[0].ProcessingId)
+	suite.Equal(latestEntry.ProcessingId, completedEntries[1].ProcessingId)
+
+	suite.Nil(pageErr)
+	suite.Equal(2, pageTotal)
+	suite.Len(pageEntries, 1)
+	suite.Equal(failedEntry.ProcessingId, pageEntries[0].ProcessingId)
+	suite.Equal(schemas.PROCESSING_STATUS_FAILED, pageEntries[0].GetStatus())
+
+	// Entries of the storages are merged by finish date
+	suite.Nil(mergedErr)
+	suite.Equal(2, mergedTotal)
+	suite.Len(mergedEntries, 1)
+	suite.Equal(failedEntry.ProcessingId, mergedEntries[0].ProcessingId)
+	suite.Equal(4, allStoragesTotal)
+
+	// Deleted processings are removed from the index
+	suite.Nil(processingIndex.DeleteProcessing(storage, "test-pipeline-slug", failedEntry.ProcessingId))
+	suite.Empty(processingIndex.GetProcessingEntries("test-pipeline-slug", failedEntry.ProcessingId, storages))
+	suite.Len(processingIndex.GetProcessingEntries("test-pipeline-slug", latestEntry.ProcessingId, storages), 1)
+}
+
+func (suite *UnitTestSuite) TestProcessingIndexQueryInvalid() {
+	for _, values := range []url.Values{
+		{"status": {"unknown"}},
+		{"sort": {"title"}},
+		{"from": {"yesterday"}},
+		{"limit": {"0"}},
+		{"limit": {fmt.Sprint(schemas.PROCESSINGS_MAX_LIMIT + 1)}},
+		{"offset": {"-1"}},
+		{"from": {"2024-09-02T00:00:00Z"}, "to": {"2024-09-01T00:00:00Z"}},
+	} {
+		query := schemas.NewPipelineProcessingsQuerySchema()
+		suite.NotNil(query.ParseQuery(values), values)
+	}
+}
+
+func (suite *UnitTestSuite) TestProcessingIndexRebuild() {
+	// Given
+	processingIndex, err := registries.NewProcessingIndex(filepath.Join(suite.T().TempDir(), "processings.db"))
+	suite.Nil(err)
+	defer processingIndex.Close()
+
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineOneBlock(successUrl),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	storages := []interfaces.Storage{types.NewLocalStorage(storageDirectory)}
+	pipelineRegistry.SetPipelineResultStorages(storages)
+
+	processingId, err := pipelineRegistry.StartPipeline(processingData)
+	suite.Nil(err)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), processingId)
+	suite.False(processingIndex.IsIndexed(pipeline.GetSlug(), storages))
+
+	// When
+	indexed, err := processingIndex.Rebuild(pipeline.GetSlug(), storages)
+
+	// Then
+	suite.Nil(err)
+	suite.Equal(1, indexed)
+	suite.True(processingIndex.IsIndexed(pipeline.GetSlug(), storages))
+
+	entries := processingIndex.GetProcessingEntries(pipeline.GetSlug(), processingId, storages)
+	suite.Len(entries, 1)
+	suite.Equal(processingId, entries[0].ProcessingId)
+	suite.True(entries[0].IsCompleted)
+	suite.Regexp(`^test-pipeline-slug/[-a-f0-9]+/log_\d+`, entries[0].LogPath)
+	suite.Regexp(`^test-pipeline-slug/[-a-f0-9]+/status_\d+`, entries[0].StatusPath)
+}
+
+func (suite *UnitTestSuite) TestProcessingIndexRefresh() {
+	// Given
+	processingIndex, err := registries.NewProcessingIndex(filepath.Join(suite.T().TempDir(), "processings.db"))
+	suite.Nil(err)
+	defer processingIndex.Close()
+
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineOneBlock(successUrl),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	storages := []interfaces.Storage{types.NewLocalStorage(storageDirectory)}
+	pipelineRegistry.SetPipelineResultStorages(storages)
+
+	firstProcessingId, err := pipelineRegistry.StartPipeline(processingData)
+	suite.Nil(err)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), firstProcessingId)
+
+	// When
+	firstIndexed, firstErr := processingIndex.Refresh(pipeline.GetSlug(), storages)
+
+	// The processing is saved by another worker
+	secondProcessingId, err := pipelineRegistry.StartPipeline(processingData)
+	suite.Nil(err)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), secondProcessingId)
+
+	secondIndexed, secondErr := processingIndex.Refresh(pipeline.GetSlug(), storages)
+
+	// The processing is deleted by another worker
+	suite.Nil(os.RemoveAll(filepath.Join(storageDirectory, pipeline.GetSlug(), firstProcessingId.String())))
+	deletedIndexed, deletedErr := processingIndex.Refresh(pipeline.GetSlug(), storages)
+
+	// Then
+	suite.Nil(firstErr)
+	suite.Equal(1, firstIndexed)
+	suite.True(processingIndex.IsIndexed(pipeline.GetSlug(), storages))
+
+	// The completed processing is not read again
+	suite.Nil(secondErr)
+	suite.Equal(1, secondIndexed)
+	entries := processingIndex.GetProcessingEntries(pipeline.GetSlug(), secondProcessingId, storages)
+	suite.Len(entries, 1)
+	suite.Regexp(`^test-pipeline-slug/[-a-f0-9]+/log_\d+`, entries[0].LogPath)
+
+	suite.Nil(deletedErr)
+	suite.Equal(0, deletedIndexed)
+	suite.Empty(processingIndex.GetProcessingEntries(pipeline.GetSlug(), firstProcessingId, storages))
+
+	_, total, err := processingIndex.Query(pipeline.GetSlug(), schemas.NewPipelineProcessingsQuerySchema(), storages)
+	suite.Nil(err)
+	suite.Equal(1, total)
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryQueryProcessingsStatus() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineOneBlock(successUrl),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	pipelineRegistry.SetPipelineResultStorages(
+		[]interfaces.Storage{
+			types.NewLocalStorage(storageDirectory),
+		},
+	)
+
+	processingId, err := pipelineRegistry.StartPipeline(processingData)
+	suite.Nil(err)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), processingId)
+	registries.NewProcessingIndexRefresher(pipelineRegistry).Refresh()
+
+	// When
+	processingsStatus, total := pipelineRegistry.QueryProcessingsStatus(
+		pipeline,
+		schemas.NewPipelineProcessingsQuerySchema(),
+	)
+
+	// Then
+	suite.Equal(1, total)
+	suite.Len(processingsStatus, 1)
+	suite.Equal(processingId, processingsStatus[0].GetId())
+
+	details := pipelineRegistry.GetProcessingDetails(pipeline, processingId)
+	suite.Len(details, 1)
+	suite.Equal(processingsStatus[0].GetLogId(), details[0].GetLogId())
+
+	detailsByLogId := pipelineRegistry.GetProcessingDetailsByLogId(pipeline, processingId, details[0].GetLogId())
+	suite.NotNil(detailsByLogId)
+	suite.Equal(processingId, detailsByLogId.GetId())
+
+	// Deleted processing is not listed anymore
+	_, err = pipelineRegistry.DeleteProcessing(pipeline.GetSlug(), processingId)
+	suite.Nil(err)
+	_, total = pipelineRegistry.QueryProcessingsStatus(
+		pipeline,
+		schemas.NewPipelineProcessingsQuerySchema(),
+	)
+	suite.Equal(0, total)
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryGetProcessingDetailsNotIndexed() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineOneBlock(successUrl),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	storage := types.NewLocalStorage(storageDirectory)
+	pipelineRegistry.SetPipelineResultStorages([]interfaces.Storage{storage})
+
+	processingId, err := pipelineRegistry.StartPipeline(processingData)
+	suite.Nil(err)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), processingId)
+
+	// The processing is saved by another worker after the index was refreshed
+	_, err = registries.GetProcessingIndex().Refresh(pipeline.GetSlug(), []interfaces.Storage{storage})
+	suite.Nil(err)
+	_, total := pipelineRegistry.QueryProcessingsStatus(pipeline, schemas.NewPipelineProcessingsQuerySchema())
+	suite.Equal(1, total)
+	suite.Nil(registries.GetProcessingIndex().DeleteProcessing(storage, pipeline.GetSlug(), processingId))
+
+	// When
+	details := pipelineRegistry.GetProcessingDetails(pipeline, processingId)
+
+	// Then
+	suite.Len(details, 1)
+	suite.Equal(processingId, details[0].GetId())
+
+	detailsByLogId := pipelineRegistry.GetProcessingDetailsByLogId(pipeline, processingId, details[0].GetLogId())
+	suite.NotNil(detailsByLogId)
+	suite.Equal(details[0].GetLogId(), detailsByLogId.GetLogId())
+}
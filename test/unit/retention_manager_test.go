@@ -0,0 +1,312 @@
+package unit_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types"
+	"data-pipelines-worker/types/blocks"
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/registries"
+)
+
+// addTestProcessingArtifacts creates outputs of the blocks and the log of the processing
+// finished at the given time. Zero time means the processing is still running
+func (suite *UnitTestSuite) addTestProcessingArtifacts(
+	storage interfaces.Storage,
+	pipelineSlug string,
+	blockSlugs []string,
+	finishedAt time.Time,
+) uuid.UUID {
+	processingId := uuid.New()
+
+	for _, blockSlug := range blockSlugs {
+		_, err := storage.PutObjectBytes(
+			storage.NewStorageLocation(
+				fmt.Sprintf("%s/%s/%s/output_0", pipelineSlug, processingId, blockSlug),
+			),
+			bytes.NewBufferString(textContent),
+		)
+		suite.Nil(err)
+	}
+
+	if !finishedAt.IsZero() {
+		suite.addTestProcessingStatus(storage, pipelineSlug, processingId, finishedAt, `{"is_completed": true}`)
+	}
+
+	return processingId
+}
+
+// addTestProcessingStatus creates the log and the status of the processing saved at the given time
+func (suite *UnitTestSuite) addTestProcessingStatus(
+	storage interfaces.Storage,
+	pipelineSlug string,
+	processingId uuid.UUID,
+	savedAt time.Time,
+	status string,
+) {
+	for template, content := range map[string]string{
+		registries.LOG_FILE_TEMPLATE:    textContent,
+		registries.STATUS_FILE_TEMPLATE: status,
+	} {
+		_, err := storage.PutObjectBytes(
+			storage.NewStorageLocation(
+				fmt.Sprintf("%s/%s/%s", pipelineSlug, processingId, fmt.Sprintf(template, savedAt.Unix())),
+			),
+			bytes.NewBufferString(content),
+		)
+		suite.Nil(err)
+	}
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryDeleteProcessing() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, _, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineTwoBlocks(successUrl),
+		"test-pipeline-slug-two-blocks",
+		"test-block-first-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	storage := types.NewLocalStorage(storageDirectory)
+	pipelineRegistry.SetPipelineResultStorages([]interfaces.Storage{storage})
+
+	blockSlugs := []string{"test-block-first-slug", "test-block-second-slug"}
+	processingId := suite.addTestProcessingArtifacts(storage, pipeline.GetSlug(), blockSlugs, time.Now())
+	otherProcessingId := suite.addTestProcessingArtifacts(storage, pipeline.GetSlug(), blockSlugs, time.Now())
+
+	// When
+	deletedObjects, err := pipelineRegistry.DeleteProcessing(pipeline.GetSlug(), processingId)
+
+	// Then
+	suite.Nil(err)
+	suite.Len(deletedObjects, 6)
+	suite.NoDirExists(filepath.Join(storageDirectory, pipeline.GetSlug(), processingId.String()))
+	suite.DirExists(filepath.Join(storageDirectory, pipeline.GetSlug(), otherProcessingId.String()))
+
+	_, err = pipelineRegistry.DeleteProcessing(pipeline.GetSlug(), processingId)
+	suite.True(errors.Is(err, registries.ErrProcessingNotFound))
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryDeleteProcessingNotFinished() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, _, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineTwoBlocks(successUrl),
+		"test-pipeline-slug-two-blocks",
+		"test-block-first-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	storage := types.NewLocalStorage(storageDirectory)
+	pipelineRegistry.SetPipelineResultStorages([]interfaces.Storage{storage})
+
+	blockSlugs := []string{"test-block-first-slug", "test-block-second-slug"}
+	runningId := suite.addTestProcessingArtifacts(storage, pipeline.GetSlug(), blockSlugs, time.Time{})
+	moderationId := suite.addTestProcessingArtifacts(storage, pipeline.GetSlug(), blockSlugs, time.Time{})
+	suite.addTestProcessingStatus(storage, pipeline.GetSlug(), moderationId, time.Now(), `{"is_completed": false}`)
+	// Completed before and running again at the worker
+	resumedId := suite.addTestProcessingArtifacts(storage, pipeline.GetSlug(), blockSlugs, time.Now())
+
+	block := blocks.NewBlockHTTP()
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+	pipelineRegistry.GetProcessingRegistry().Add(
+		dataclasses.NewProcessing(
+			ctx,
+			ctxCancel,
+			resumedId,
+			pipeline,
+			block,
+			&dataclasses.BlockData{Id: block.GetId(), Slug: "test-block-first-slug"},
+		),
+	)
+	defer pipelineRegistry.GetProcessingRegistry().Delete(resumedId.String())
+
+	for _, processingId := range []uuid.UUID{runningId, moderationId, resumedId} {
+		// When
+		deletedObjects, err := pipelineRegistry.DeleteProcessing(pipeline.GetSlug(), processingId)
+
+		// Then
+		suite.True(errors.Is(err, registries.ErrProcessingNotFinished))
+		suite.Empty(deletedObjects)
+		suite.DirExists(filepath.Join(storageDirectory, pipeline.GetSlug(), processingId.String(), "test-block-first-slug"))
+	}
+}
+
+func (suite *UnitTestSuite) TestRetentionManagerApply() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, _, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineTwoBlocks(successUrl),
+		"test-pipeline-slug-two-blocks",
+		"test-block-first-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	storage := types.NewLocalStorage(storageDirectory)
+	pipelineRegistry.SetPipelineResultStorages([]interfaces.Storage{storage})
+
+	now := time.Now()
+	day := 24 * time.Hour
+	blockSlugs := []string{"test-block-first-slug", "test-block-second-slug"}
+
+	recentId := suite.addTestProcessingArtifacts(storage, pipeline.GetSlug(), blockSlugs, now.Add(-day))
+	// Kept as one of the latest processings
+	latestId := suite.addTestProcessingArtifacts(storage, pipeline.GetSlug(), blockSlugs, now.Add(-35*day))
+	expiredId := suite.addTestProcessingArtifacts(storage, pipeline.GetSlug(), blockSlugs, now.Add(-300*day))
+	intermediateId := suite.addTestProcessingArtifacts(storage, pipeline.GetSlug(), blockSlugs, now.Add(-40*day))
+	runningId := suite.addTestProcessingArtifacts(storage, pipeline.GetSlug(), blockSlugs, time.Time{})
+	// Paused for the moderation long ago
+	moderationId := suite.addTestProcessingArtifacts(storage, pipeline.GetSlug(), blockSlugs, time.Time{})
+	suite.addTestProcessingStatus(storage, pipeline.GetSlug(), moderationId, now.Add(-300*day), `{"is_completed": false}`)
+
+	manager := registries.NewRetentionManager(pipelineRegistry)
+	defer manager.Shutdown(suite.GetShutDownContext(time.Second))
+	manager.SetPolicy(
+		config.RetentionConfig{
+			KeepDays:             30,
+			KeepFinalOutputsDays: 90,
+			KeepLatest:           2,
+		},
+	)
+
+	processingPath := func(processingId uuid.UUID, elements ...string) string {
+		return filepath.Join(
+			append([]string{storageDirectory, pipeline.GetSlug(), processingId.String()}, elements...)...,
+		)
+	}
+
+	// When
+	dryRunReport := manager.Apply(now, true)
+
+	// Then
+	suite.True(dryRunReport.DryRun)
+	suite.Len(dryRunReport.Processings, 2)
+	suite.DirExists(processingPath(expiredId))
+	suite.DirExists(processingPath(intermediateId, "test-block-first-slug"))
+
+	// When
+	report := manager.Apply(now, false)
+
+	// Then
+	suite.False(report.DryRun)
+	suite.Empty(report.Errors)
+	suite.Len(report.Processings, 2)
+
+	actions := make(map[uuid.UUID]string)
+	for _, processing := range report.Processings {
+		actions[processing.ProcessingID] = processing.Action
+	}
+	suite.Equal(
+		map[uuid.UUID]string{
+			expiredId:      schemas.RETENTION_ACTION_PURGE,
+			intermediateId: schemas.RETENTION_ACTION_PURGE_INTERMEDIATE,
+		},
+		actions,
+	)
+
+	suite.NoDirExists(processingPath(expiredId))
+	suite.NoDirExists(processingPath(intermediateId, "test-block-first-slug"))
+	suite.DirExists(processingPath(intermediateId, "test-block-second-slug"))
+	for _, processingId := range []uuid.UUID{latestId, recentId, runningId, moderationId} {
+		suite.DirExists(processingPath(processingId, "test-block-first-slug"))
+	}
+
+	logs, _ := filepath.Glob(processingPath(intermediateId, "log_*"))
+	suite.Len(logs, 1)
+
+	// Nothing left to delete
+	suite.Empty(manager.Apply(now, false).Processings)
+
+	_, err := os.Stat(storageDirectory)
+	suite.Nil(err)
+}
+
+func (suite *UnitTestSuite) TestRetentionManagerApplyKeepDaysOnly() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, _, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineTwoBlocks(successUrl),
+		"test-pipeline-slug-two-blocks",
+		"test-block-first-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	storage := types.NewLocalStorage(storageDirectory)
+	pipelineRegistry.SetPipelineResultStorages([]interfaces.Storage{storage})
+
+	now := time.Now()
+	day := 24 * time.Hour
+	blockSlugs := []string{"test-block-first-slug", "test-block-second-slug"}
+
+	recentId := suite.addTestProcessingArtifacts(storage, pipeline.GetSlug(), blockSlugs, now.Add(-day))
+	expiredId := suite.addTestProcessingArtifacts(storage, pipeline.GetSlug(), blockSlugs, now.Add(-40*day))
+
+	manager := registries.NewRetentionManager(pipelineRegistry)
+	defer manager.Shutdown(suite.GetShutDownContext(time.Second))
+	manager.SetPolicy(config.RetentionConfig{KeepDays: 30})
+
+	// When
+	report := manager.Apply(now, false)
+
+	// Then
+	// Logs, statuses and final outputs are not kept forever
+	suite.Empty(report.Errors)
+	suite.Len(report.Processings, 1)
+	suite.Equal(expiredId, report.Processings[0].ProcessingID)
+	suite.Equal(schemas.RETENTION_ACTION_PURGE, report.Processings[0].Action)
+	suite.NoDirExists(filepath.Join(storageDirectory, pipeline.GetSlug(), expiredId.String()))
+	suite.DirExists(filepath.Join(storageDirectory, pipeline.GetSlug(), recentId.String()))
+}
+
+func (suite *UnitTestSuite) TestRetentionManagerApplyRemovedPipeline() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	_, _, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineTwoBlocks(successUrl),
+		"test-pipeline-slug-two-blocks",
+		"test-block-first-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	storage := types.NewLocalStorage(storageDirectory)
+	pipelineRegistry.SetPipelineResultStorages([]interfaces.Storage{storage})
+
+	now := time.Now()
+	day := 24 * time.Hour
+	removedSlug := "test-removed-pipeline-slug"
+	blockSlugs := []string{"test-block-first-slug", "test-block-second-slug"}
+
+	expiredId := suite.addTestProcessingArtifacts(storage, removedSlug, blockSlugs, now.Add(-100*day))
+	intermediateId := suite.addTestProcessingArtifacts(storage, removedSlug, blockSlugs, now.Add(-40*day))
+
+	manager := registries.NewRetentionManager(pipelineRegistry)
+	defer manager.Shutdown(suite.GetShutDownContext(time.Second))
+	manager.SetPolicy(config.RetentionConfig{KeepDays: 30, KeepFinalOutputsDays: 90})
+
+	// When
+	report := manager.Apply(now, false)
+
+	// Then
+	// The final block of the removed Pipeline is not known, so intermediate outputs are kept
+	suite.Empty(report.Errors)
+	suite.Len(report.Processings, 1)
+	suite.Equal(expiredId, report.Processings[0].ProcessingID)
+	suite.Equal(removedSlug, report.Processings[0].PipelineSlug)
+	suite.Equal(schemas.RETENTION_ACTION_PURGE, report.Processings[0].Action)
+	suite.NoDirExists(filepath.Join(storageDirectory, removedSlug, expiredId.String()))
+	suite.DirExists(filepath.Join(storageDirectory, removedSlug, intermediateId.String(), "test-block-first-slug"))
+}
@@ -303,6 +303,27 @@ func (suite *UnitTestSuite) TestLocalStorageDeleteObject() {
 	suite.False(storageLocation.Exists())
 }
 
+func (suite *UnitTestSuite) TestLocalStorageDeleteObjectOutsideRoot() {
+	// Given
+	parentDirectory := suite.T().TempDir()
+	storage := types.NewLocalStorage(filepath.Join(parentDirectory, "storage"))
+
+	outsideFile := filepath.Join(parentDirectory, "outside.txt")
+	suite.Nil(os.WriteFile(outsideFile, []byte(textContent), 0644))
+
+	outsideLocation := storage.NewStorageLocation("../outside.txt")
+	rootLocation := storage.NewStorageLocation("")
+
+	// When
+	outsideErr := storage.DeleteObject(outsideLocation)
+	rootErr := storage.DeleteObject(rootLocation)
+
+	// Then
+	suite.NotNil(outsideErr)
+	suite.NotNil(rootErr)
+	suite.FileExists(outsideFile)
+}
+
 func (suite *UnitTestSuite) TestLocalStorageShutDown() {
 	// Given
 	storage := types.NewLocalStorage("")
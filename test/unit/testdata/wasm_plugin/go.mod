@@ -0,0 +1,3 @@
+module wasm_plugin
+
+go 1.24
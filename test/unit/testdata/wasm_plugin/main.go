@@ -0,0 +1,134 @@
+// The test WebAssembly plugin: it uppercases the `text` and reverses the `file` of the input.
+// Built with `GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o plugin.wasm`
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"unsafe"
+)
+
+type File struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+type Request struct {
+	Input map[string]interface{} `json:"input"`
+	Files []File                 `json:"files"`
+}
+
+type ProcessResult struct {
+	Outputs []File `json:"outputs"`
+	Retry   bool   `json:"retry"`
+	Error   string `json:"error,omitempty"`
+}
+
+const description = `{
+	"id": "test_wasm_plugin",
+	"name": "Test WebAssembly Plugin",
+	"description": "Uppercase the text and reverse the file",
+	"version": "1",
+	"schema": {
+		"type": "object",
+		"properties": {
+			"input": {
+				"type": "object",
+				"properties": {
+					"text": {"type": "string"},
+					"file": {"type": "string", "format": "file"},
+					"loop": {"type": "boolean"},
+					"allocate": {"type": "integer"},
+					"fail": {"type": "boolean"}
+				},
+				"required": ["text"]
+			},
+			"output": {"type": "string", "format": "file"}
+		}
+	}
+}`
+
+// Buffers are kept referenced while the host reads and writes them
+var buffers = map[uintptr][]byte{}
+
+func pin(buffer []byte) uint64 {
+	if len(buffer) == 0 {
+		buffer = make([]byte, 1)[:0]
+	}
+	ptr := uintptr(unsafe.Pointer(unsafe.SliceData(buffer[:1])))
+	buffers[ptr] = buffer
+
+	return uint64(ptr)<<32 | uint64(len(buffer))
+}
+
+//go:wasmexport allocate
+func allocate(size int32) int32 {
+	buffer := make([]byte, size+1)
+	ptr := uintptr(unsafe.Pointer(&buffer[0]))
+	buffers[ptr] = buffer
+
+	return int32(ptr)
+}
+
+//go:wasmexport describe
+func describe() int64 {
+	return int64(pin([]byte(description)))
+}
+
+//go:wasmexport process
+func process(ptr int32, size int32) int64 {
+	message := unsafe.Slice((*byte)(unsafe.Pointer(uintptr(ptr))), size)
+	reader := bufio.NewReader(bytes.NewReader(message))
+
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return respond(ProcessResult{Error: err.Error()}, nil)
+	}
+	request := Request{}
+	if err := json.Unmarshal(line, &request); err != nil {
+		return respond(ProcessResult{Error: err.Error()}, nil)
+	}
+
+	if loop, ok := request.Input["loop"].(bool); ok && loop {
+		for {
+		}
+	}
+	if allocateSize, ok := request.Input["allocate"].(float64); ok {
+		buffers[0] = make([]byte, int(allocateSize))
+	}
+	if fail, ok := request.Input["fail"].(bool); ok && fail {
+		return respond(ProcessResult{Error: "fail is requested", Retry: true}, nil)
+	}
+
+	text := []byte(strings.ToUpper(request.Input["text"].(string)))
+	result := ProcessResult{Outputs: []File{{Name: "text", Size: int64(len(text))}}}
+	outputs := [][]byte{text}
+	for _, file := range request.Files {
+		reversed := make([]byte, file.Size)
+		if _, err := io.ReadFull(reader, reversed); err != nil {
+			return respond(ProcessResult{Error: err.Error()}, nil)
+		}
+		for left, right := 0, len(reversed)-1; left < right; left, right = left+1, right-1 {
+			reversed[left], reversed[right] = reversed[right], reversed[left]
+		}
+		outputs = append(outputs, reversed)
+		result.Outputs = append(result.Outputs, File{Name: file.Name, Size: file.Size})
+	}
+
+	return respond(result, outputs)
+}
+
+func respond(result ProcessResult, outputs [][]byte) int64 {
+	header, _ := json.Marshal(result)
+	response := append(header, '\n')
+	for _, output := range outputs {
+		response = append(response, output...)
+	}
+
+	return int64(pin(response))
+}
+
+func main() {}
@@ -0,0 +1,464 @@
+package unit_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"data-pipelines-worker/types"
+	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/registries"
+)
+
+func (suite *UnitTestSuite) GetTestPipelineWithTrigger(successUrl string, trigger string) interfaces.Pipeline {
+	return suite.GetTestPipeline(
+		fmt.Sprintf(
+			`{
+				"slug": "test-pipeline-slug",
+				"title": "Test Pipeline",
+				"description": "Test Pipeline Description",
+				"blocks": [
+					{
+						"id": "http_request",
+						"slug": "test-block-slug",
+						"description": "Request Local Resourse",
+						"input": {
+							"url": "%s"
+						}
+					}
+				],
+				"triggers": [%s]
+			}`,
+			successUrl,
+			trigger,
+		),
+	)
+}
+
+func (suite *UnitTestSuite) TestPipelineTriggerGetNextRun() {
+	// Given
+	after := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		trigger  *dataclasses.PipelineTrigger
+		expected time.Time
+	}{
+		{
+			&dataclasses.PipelineTrigger{Slug: "daily", Cron: "0 9 * * *", Timezone: "UTC", BlockSlug: "block"},
+			time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			&dataclasses.PipelineTrigger{Slug: "descriptor", Cron: "@hourly", Timezone: "UTC", BlockSlug: "block"},
+			time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			// Cron expressions without a timezone run in UTC on every worker
+			&dataclasses.PipelineTrigger{Slug: "default-timezone", Cron: "0 9 * * *", BlockSlug: "block"},
+			time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			&dataclasses.PipelineTrigger{Slug: "interval", Interval: "30m", BlockSlug: "block"},
+			time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC),
+		},
+	}
+
+	for _, c := range cases {
+		// When
+		suite.Nil(c.trigger.Validate())
+		nextRun, err := c.trigger.GetNextRun(after)
+
+		// Then
+		suite.Nil(err)
+		suite.True(c.expected.Equal(nextRun), c.trigger.Slug)
+		suite.True(c.trigger.IsEnabled())
+	}
+}
+
+func (suite *UnitTestSuite) TestPipelineTriggerInvalid() {
+	// Given
+	cases := []string{
+		`{"slug": "bad-cron", "cron": "61 * * * *", "block_slug": "test-block-slug"}`,
+		`{"slug": "bad-interval", "interval": "often", "block_slug": "test-block-slug"}`,
+		`{"slug": "both", "cron": "@daily", "interval": "1h", "block_slug": "test-block-slug"}`,
+		`{"slug": "bad-timezone", "cron": "@daily", "timezone": "Mars/Olympus", "block_slug": "test-block-slug"}`,
+		`{"slug": "missing-block", "cron": "@daily", "block_slug": "unknown-block-slug"}`,
+	}
+
+	for _, trigger := range cases {
+		// When
+		_, err := dataclasses.NewPipelineFromBytes(
+			[]byte(
+				fmt.Sprintf(
+					`{
+						"slug": "test-pipeline-slug",
+						"title": "Test Pipeline",
+						"description": "Test Pipeline Description",
+						"blocks": [
+							{
+								"id": "http_request",
+								"slug": "test-block-slug",
+								"description": "Request Local Resourse",
+								"input": {
+									"url": "http://localhost"
+								}
+							}
+						],
+						"triggers": [%s]
+					}`,
+					trigger,
+				),
+			),
+		)
+
+		// Then
+		suite.NotNil(err, trigger)
+	}
+}
+
+func (suite *UnitTestSuite) GetTestPipelineWithInputSchemaAndTrigger(trigger string) []byte {
+	return []byte(
+		fmt.Sprintf(
+			`{
+				"slug": "test-pipeline-slug",
+				"title": "Test Pipeline",
+				"description": "Test Pipeline Description",
+				"input_schema": {
+					"type": "object",
+					"properties": {
+						"url": {
+							"type": "string"
+						}
+					},
+					"required": ["url"]
+				},
+				"blocks": [
+					{
+						"id": "http_request",
+						"slug": "test-block-slug",
+						"description": "Request Local Resourse",
+						"input_config": {
+							"property": {
+								"url": {
+									"origin": "$pipeline.input.url"
+								}
+							}
+						}
+					}
+				],
+				"triggers": [%s]
+			}`,
+			trigger,
+		),
+	)
+}
+
+func (suite *UnitTestSuite) TestTriggerRegistryPassesPipelineInput() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+
+	// A trigger without the required pipeline input is rejected on load
+	pipelineBytes := suite.GetTestPipelineWithInputSchemaAndTrigger(
+		`{"slug": "every-hour", "interval": "1h", "block_slug": "test-block-slug"}`,
+	)
+	_, err := dataclasses.NewPipelineFromBytes(pipelineBytes)
+	suite.NotNil(err)
+
+	pipelineBytes = suite.GetTestPipelineWithInputSchemaAndTrigger(
+		fmt.Sprintf(
+			`{"slug": "every-hour", "interval": "1h", "block_slug": "test-block-slug", "pipeline_input": {"url": "%s"}}`,
+			successUrl,
+		),
+	)
+	pipeline, _, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipeline(string(pipelineBytes)),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	pipelineRegistry.SetPipelineResultStorages([]interfaces.Storage{types.NewLocalStorage(storageDirectory)})
+
+	triggerRegistry := registries.NewTriggerRegistry(pipelineRegistry)
+	defer triggerRegistry.Shutdown(suite.GetShutDownContext(time.Second))
+
+	stateId := registries.GetPipelineTriggerStateId(pipeline.GetSlug(), "every-hour")
+	scheduledAt := triggerRegistry.Get(stateId).GetNextRun()
+
+	// When
+	triggerRegistry.CheckTriggers(scheduledAt.Add(time.Second))
+
+	// Then
+	state := triggerRegistry.Get(stateId)
+	suite.NotEqual(uuid.Nil, state.GetLastProcessingId())
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), state.GetLastProcessingId())
+}
+
+func (suite *UnitTestSuite) TestTriggerRegistrySyncTriggers() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, _, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineWithTrigger(
+			successUrl,
+			`{"slug": "every-hour", "interval": "1h", "block_slug": "test-block-slug"},
+			 {"slug": "disabled", "cron": "@daily", "block_slug": "test-block-slug", "enabled": false}`,
+		),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	pipelineRegistry.SetPipelineResultStorages([]interfaces.Storage{})
+
+	// When
+	registry := registries.NewTriggerRegistry(pipelineRegistry)
+	defer registry.Shutdown(suite.GetShutDownContext(time.Second))
+
+	// Then
+	states := registry.GetPipelineTriggers(pipeline.GetSlug())
+	suite.Len(states, 2)
+
+	suite.Equal("test-pipeline-slug:disabled", states[0].GetId())
+	suite.True(states[0].GetNextRun().IsZero())
+
+	suite.Equal("test-pipeline-slug:every-hour", states[1].GetId())
+	suite.True(states[1].GetNextRun().After(time.Now()))
+	suite.True(states[1].GetNextRun().Equal(time.Now().Truncate(time.Hour).Add(time.Hour)))
+	suite.True(states[1].GetLastRun().IsZero())
+
+	// Pipeline without Triggers is removed on the next sync
+	suite.Nil(pipelineRegistry.Add(suite.GetTestPipelineOneBlock(successUrl)))
+	registry.SyncTriggers()
+
+	suite.Empty(registry.GetPipelineTriggers(pipeline.GetSlug()))
+}
+
+func (suite *UnitTestSuite) TestTriggerRegistrySyncTriggersWhilePipelinesAdded() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	_, _, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineOneBlock(successUrl),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	pipelineRegistry.SetPipelineResultStorages([]interfaces.Storage{})
+
+	pipelines := make([]interfaces.Pipeline, 0)
+	for i := 0; i < 20; i++ {
+		pipelines = append(pipelines, suite.GetTestPipeline(
+			fmt.Sprintf(
+				`{
+					"slug": "test-pipeline-slug-%d",
+					"title": "Test Pipeline",
+					"description": "Test Pipeline Description",
+					"blocks": [
+						{
+							"id": "http_request",
+							"slug": "test-block-slug",
+							"description": "Request Local Resourse",
+							"input": {
+								"url": "%s"
+							}
+						}
+					],
+					"triggers": [{"slug": "every-hour", "interval": "1h", "block_slug": "test-block-slug"}]
+				}`,
+				i,
+				successUrl,
+			),
+		))
+	}
+
+	registry := registries.NewTriggerRegistry(pipelineRegistry)
+	defer registry.Shutdown(suite.GetShutDownContext(time.Second))
+
+	// When
+	// Run with -race: the Pipelines are added while the Triggers are synced
+	added := make(chan struct{})
+	synced := make(chan struct{})
+	go func() {
+		defer close(synced)
+		for {
+			registry.SyncTriggers()
+
+			select {
+			case <-added:
+				return
+			default:
+			}
+		}
+	}()
+	for _, pipeline := range pipelines {
+		suite.Nil(pipelineRegistry.Add(pipeline))
+		time.Sleep(time.Millisecond)
+	}
+	close(added)
+	<-synced
+	registry.SyncTriggers()
+
+	// Then
+	for _, pipeline := range pipelines {
+		suite.Len(registry.GetPipelineTriggers(pipeline.GetSlug()), 1)
+	}
+}
+
+func (suite *UnitTestSuite) TestTriggerRegistryFiresOnceAcrossWorkers() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, _, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineWithTrigger(
+			successUrl,
+			`{"slug": "every-hour", "interval": "1h", "block_slug": "test-block-slug"}`,
+		),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	sharedStorage := types.NewLocalStorage(storageDirectory)
+	pipelineRegistry.SetPipelineResultStorages([]interfaces.Storage{sharedStorage})
+
+	// Two workers sharing the same storage, started in different seconds
+	stateId := registries.GetPipelineTriggerStateId(pipeline.GetSlug(), "every-hour")
+
+	workerA := registries.NewTriggerRegistry(pipelineRegistry)
+	defer workerA.Shutdown(suite.GetShutDownContext(time.Second))
+	time.Sleep(time.Second)
+	workerB := registries.NewTriggerRegistry(pipelineRegistry)
+	defer workerB.Shutdown(suite.GetShutDownContext(time.Second))
+
+	scheduledAt := workerA.Get(stateId).GetNextRun()
+	suite.True(scheduledAt.Equal(workerB.Get(stateId).GetNextRun()))
+	suite.Zero(scheduledAt.Unix() % int64(time.Hour/time.Second))
+
+	// When
+	workerA.CheckTriggers(scheduledAt.Add(time.Second))
+	workerB.CheckTriggers(scheduledAt.Add(5 * time.Second))
+
+	// Then
+	stateA := workerA.Get(stateId)
+	stateB := workerB.Get(stateId)
+
+	suite.NotEqual(uuid.Nil, stateA.GetLastProcessingId())
+	suite.Equal(uuid.Nil, stateB.GetLastProcessingId())
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), stateA.GetLastProcessingId())
+
+	for _, state := range []interfaces.PipelineTriggerState{stateA, stateB} {
+		suite.True(scheduledAt.Equal(state.GetLastRun()))
+		// The next run follows the scheduled one instead of the check time
+		suite.True(scheduledAt.Add(time.Hour).Equal(state.GetNextRun()))
+	}
+
+	// A restarted worker restores the last run from the claims
+	workerC := registries.NewTriggerRegistry(pipelineRegistry)
+	defer workerC.Shutdown(suite.GetShutDownContext(time.Second))
+
+	suite.True(scheduledAt.Equal(workerC.Get(stateId).GetLastRun()))
+	suite.True(scheduledAt.Add(time.Hour).Equal(workerC.Get(stateId).GetNextRun()))
+}
+
+// unavailableSharedStorage is a shared storage whose server does not respond
+type unavailableSharedStorage struct {
+	*types.LocalStorage
+}
+
+func (s *unavailableSharedStorage) GetStorageName() string {
+	return "minio"
+}
+
+func (s *unavailableSharedStorage) ListObjects(interfaces.StorageLocation) ([]interfaces.StorageLocation, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (s *unavailableSharedStorage) CreateObjectBytes(
+	interfaces.StorageLocation,
+	*bytes.Buffer,
+) (interfaces.StorageLocation, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func (suite *UnitTestSuite) TestTriggerRegistryClaimFailsWithoutSharedStorage() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, _, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineWithTrigger(
+			successUrl,
+			`{"slug": "every-hour", "interval": "1h", "block_slug": "test-block-slug"}`,
+		),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	localStorage := types.NewLocalStorage(suite.T().TempDir())
+	pipelineRegistry.SetPipelineResultStorages(
+		[]interfaces.Storage{
+			&unavailableSharedStorage{types.NewLocalStorage(suite.T().TempDir())},
+			localStorage,
+		},
+	)
+
+	registry := registries.NewTriggerRegistry(pipelineRegistry)
+	defer registry.Shutdown(suite.GetShutDownContext(time.Second))
+
+	stateId := registries.GetPipelineTriggerStateId(pipeline.GetSlug(), "every-hour")
+	registry.Triggers[stateId].SetNextRun(time.Now().Add(-time.Minute))
+
+	// When
+	registry.CheckTriggers(time.Now())
+
+	// Then
+	// The run is not fired unclaimed, e.g. by every worker at once
+	suite.Equal(uuid.Nil, registry.Get(stateId).GetLastProcessingId())
+	suite.Contains(registry.Triggers[stateId].LastError, "connection refused")
+
+	objects, _ := localStorage.ListObjects(localStorage.NewStorageLocation(""))
+	suite.Empty(objects)
+}
+
+func (suite *UnitTestSuite) TestTriggerRegistryDeletesPreviousClaims() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, _, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineWithTrigger(
+			successUrl,
+			`{"slug": "every-hour", "interval": "1h", "block_slug": "test-block-slug"}`,
+		),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	sharedStorage := types.NewLocalStorage(storageDirectory)
+	pipelineRegistry.SetPipelineResultStorages([]interfaces.Storage{sharedStorage})
+
+	registry := registries.NewTriggerRegistry(pipelineRegistry)
+	defer registry.Shutdown(suite.GetShutDownContext(time.Second))
+
+	stateId := registries.GetPipelineTriggerStateId(pipeline.GetSlug(), "every-hour")
+	firstRun := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	secondRun := firstRun.Add(time.Hour)
+
+	// When
+	registry.Triggers[stateId].SetNextRun(firstRun)
+	registry.CheckTriggers(firstRun.Add(time.Second))
+	firstProcessingId := registry.Get(stateId).GetLastProcessingId()
+	registry.Triggers[stateId].SetNextRun(secondRun)
+	registry.CheckTriggers(secondRun.Add(time.Second))
+	secondProcessingId := registry.Get(stateId).GetLastProcessingId()
+
+	for _, processingId := range []uuid.UUID{firstProcessingId, secondProcessingId} {
+		suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), processingId)
+	}
+
+	// Then
+	claimDirectory := sharedStorage.NewStorageLocation(
+		fmt.Sprintf("%s/%s/every-hour", registries.TRIGGERS_STORAGE_PREFIX, pipeline.GetSlug()),
+	)
+	claims, err := sharedStorage.ListObjects(claimDirectory)
+	suite.Nil(err)
+	suite.Len(claims, 1)
+	suite.Equal(fmt.Sprintf("run_%d.json", secondRun.Unix()), claims[0].GetFileName())
+}
@@ -0,0 +1,306 @@
+package unit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	openai "github.com/sashabaranov/go-openai"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types"
+	"data-pipelines-worker/types/blocks"
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/dataclasses"
+	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/llm"
+	"data-pipelines-worker/types/registries"
+)
+
+func (suite *UnitTestSuite) TestProcessingRecordsUsage() {
+	// Given
+	block := blocks.NewBlockOpenAIRequestCompletion()
+	data := &dataclasses.BlockData{
+		Id:   "openai_chat_completion",
+		Slug: "request-openai-chat-completion",
+		Input: map[string]interface{}{
+			"model":       "gpt-4o-mini-2024-07-18",
+			"user_prompt": "Hello world!",
+			"credentials": "offline",
+		},
+	}
+	data.SetBlock(block)
+
+	llm.GetProviderRegistry().Set("offline", &stubLLMProvider{
+		response: openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "Hello"}},
+			},
+			Usage: openai.Usage{PromptTokens: 1000, CompletionTokens: 500},
+		},
+	})
+	defer llm.GetProviderRegistry().Delete("offline")
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	processing := dataclasses.NewProcessing(ctx, ctxCancel, uuid.New(), nil, block, data)
+
+	// When
+	output := processing.Start()
+
+	// Then
+	suite.Nil(output.GetError())
+	usage := output.GetUsage()
+	suite.Equal("USD", usage.Currency)
+	suite.Equal(1000, usage.PromptTokens)
+	suite.Equal(500, usage.CompletionTokens)
+	// gpt-4o-mini prices: 0.15 and 0.6 per million tokens
+	suite.InDelta(0.00045, usage.Cost, 1e-9)
+	suite.InDelta(0.00045, usage.Models["gpt-4o-mini-2024-07-18"].Cost, 1e-9)
+}
+
+func (suite *UnitTestSuite) TestMeteredProviderImagesCost() {
+	// Given
+	provider := llm.NewMeteredProvider(&stubLLMProvider{})
+	ctx, recorder := llm.WithUsageRecorder(context.Background())
+
+	// When
+	_, err := provider.CreateImage(ctx, openai.ImageRequest{
+		Model:   openai.CreateImageModelDallE3,
+		N:       2,
+		Size:    openai.CreateImageSize1792x1024,
+		Quality: openai.CreateImageQualityHD,
+	})
+
+	// Then
+	suite.Nil(err)
+	suite.Equal(2, recorder.GetUsage().Images)
+	suite.InDelta(0.24, recorder.GetUsage().Cost, 1e-9)
+}
+
+func (suite *UnitTestSuite) TestPipelineProcessingStateStatusUsage() {
+	// Given
+	state := dataclasses.NewPipelineProcessingState()
+	logBuffer := bytes.NewBuffer(nil)
+	for index, cost := range []float64{0.25, 0.5} {
+		usage := schemas.ProcessingUsageSchema{}
+		usage.AddModelUsage("gpt-4o", schemas.UsageSchema{PromptTokens: 100, Cost: cost})
+		state.AddUsage(usage)
+
+		usageJSON, _ := json.Marshal(usage)
+		logLine, _ := json.Marshal(map[string]interface{}{
+			"level":   "INFO",
+			"message": fmt.Sprintf(registries.PROCESSING_USAGE_LOG_TEMPLATE, "get-event-text", index, usageJSON),
+		})
+		logBuffer.Write(append(logLine, '\n'))
+	}
+	storage := types.NewLocalStorage(suite.T().TempDir())
+
+	// When
+	status := state.NewStatus(
+		uuid.New(),
+		"test-pipeline-slug",
+		uuid.New(),
+		bytes.NewBuffer(logBuffer.Bytes()),
+		storage,
+	).(*dataclasses.PipelineProcessingStatus)
+	logStatus := dataclasses.NewPipelineProcessingStatusFromLogData(
+		uuid.New(),
+		"test-pipeline-slug",
+		uuid.New(),
+		bytes.NewBuffer(logBuffer.Bytes()),
+		storage,
+	).(*dataclasses.PipelineProcessingStatus)
+
+	// Then
+	suite.NotNil(status.Usage)
+	suite.Equal(200, status.Usage.PromptTokens)
+	suite.InDelta(0.75, status.Usage.Cost, 1e-9)
+	suite.Equal(200, status.Usage.Models["gpt-4o"].PromptTokens)
+	// The usage is not recovered from the log messages
+	suite.Nil(logStatus.Usage)
+}
+
+// saveTestProcessingUsage saves the log of a processing with the cost as another worker does
+func (suite *UnitTestSuite) saveTestProcessingUsage(
+	storages []interfaces.Storage,
+	pipelineSlug string,
+	cost float64,
+) {
+	usage := schemas.ProcessingUsageSchema{}
+	usage.AddModelUsage("gpt-4o", schemas.UsageSchema{Cost: cost})
+	state := dataclasses.NewPipelineProcessingState()
+	state.AddUsage(usage)
+	state.SetCompleted()
+
+	logBuffer := &config.SafeBuffer{}
+	logLine, _ := json.Marshal(map[string]interface{}{
+		"level":   "INFO",
+		"message": fmt.Sprintf("Processing Pipeline %s completed", pipelineSlug),
+	})
+	logBuffer.Write(append(logLine, '\n'))
+
+	registries.NewPipelineBlockDataRegistry(uuid.New(), pipelineSlug, storages).SavePipelineLog(
+		logBuffer,
+		state.NewDetails,
+		state.NewStatus,
+	)
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryStartPipelineBudgetExceeded() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineOneBlock(successUrl),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	pipelineRegistry.SetPipelineResultStorages(
+		[]interfaces.Storage{
+			types.NewLocalStorage(storageDirectory),
+		},
+	)
+
+	suite._config.Costs.Budgets.Pipelines[pipeline.GetSlug()] = 1
+	defer delete(suite._config.Costs.Budgets.Pipelines, pipeline.GetSlug())
+
+	registries.GetUsageRegistry(true)
+	usageRegistry := registries.GetUsageRegistry()
+	defer registries.GetUsageRegistry(true)
+
+	// When
+	processingId, withinBudgetErr := pipelineRegistry.StartPipeline(processingData)
+	suite.Nil(withinBudgetErr)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), processingId)
+
+	runningProcessingId := uuid.New()
+	usage := schemas.ProcessingUsageSchema{}
+	usage.AddModelUsage("gpt-4o", schemas.UsageSchema{Cost: 1.5})
+	usageRegistry.Add(pipeline.GetSlug(), runningProcessingId, usage)
+
+	_, exceededErr := pipelineRegistry.StartPipeline(processingData)
+
+	// Then
+	suite.True(errors.Is(exceededErr, registries.ErrBudgetExceeded))
+	suite.InDelta(1.5, usageRegistry.GetDailyCost(pipeline.GetSlug(), nil), 1e-9)
+
+	// Cost of the processing with the saved log is not counted twice
+	usageRegistry.SetSaved(pipeline.GetSlug(), runningProcessingId)
+	suite.InDelta(0, usageRegistry.GetDailyCost(pipeline.GetSlug(), nil), 1e-9)
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryStartPipelineBudgetSharedByWorkers() {
+	// Given
+	successUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 0)
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineOneBlock(successUrl),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	storages := []interfaces.Storage{
+		types.NewLocalStorage(storageDirectory),
+	}
+	pipelineRegistry.SetPipelineResultStorages(storages)
+
+	suite._config.Costs.Budgets.Pipelines[pipeline.GetSlug()] = 1
+	defer delete(suite._config.Costs.Budgets.Pipelines, pipeline.GetSlug())
+
+	registries.GetUsageRegistry(true)
+	defer registries.GetUsageRegistry(true)
+
+	// When
+	processingId, withinBudgetErr := pipelineRegistry.StartPipeline(processingData)
+	suite.Nil(withinBudgetErr)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), processingId)
+
+	// Spent by another worker sharing the storage
+	suite.saveTestProcessingUsage(storages, pipeline.GetSlug(), 1.5)
+
+	cachedProcessingId, cachedErr := pipelineRegistry.StartPipeline(processingData)
+	suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), cachedProcessingId)
+
+	registries.GetUsageRegistry().SetRefreshInterval(time.Nanosecond)
+	_, exceededErr := pipelineRegistry.StartPipeline(processingData)
+
+	// Then
+	// Cost saved by other workers is seen after the refresh interval
+	suite.Nil(cachedErr)
+	suite.True(errors.Is(exceededErr, registries.ErrBudgetExceeded))
+}
+
+func (suite *UnitTestSuite) TestPipelineRegistryStartPipelineReservesBudget() {
+	// Given
+	slowUrl := suite.GetMockHTTPServerURL("Hello, world!", http.StatusOK, 500*time.Millisecond)
+	pipeline, processingData, pipelineRegistry := suite.RegisterTestPipelineAndInputForProcessing(
+		suite.GetTestPipelineOneBlock(slowUrl),
+		"test-pipeline-slug",
+		"test-block-slug",
+		nil,
+	)
+	storageDirectory := suite.T().TempDir()
+	pipelineRegistry.SetPipelineResultStorages(
+		[]interfaces.Storage{
+			types.NewLocalStorage(storageDirectory),
+		},
+	)
+
+	suite._config.Costs.Budgets.Pipelines[pipeline.GetSlug()] = 1
+	defer delete(suite._config.Costs.Budgets.Pipelines, pipeline.GetSlug())
+
+	registries.GetUsageRegistry(true)
+	usageRegistry := registries.GetUsageRegistry()
+	usageRegistry.SetEstimatedCost(0.6)
+	defer registries.GetUsageRegistry(true)
+
+	// When
+	var (
+		wg            sync.WaitGroup
+		lock          sync.Mutex
+		processingIds []uuid.UUID
+		exceededErrs  int
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			processingId, err := pipelineRegistry.StartPipeline(processingData)
+
+			lock.Lock()
+			defer lock.Unlock()
+			if err == nil {
+				processingIds = append(processingIds, processingId)
+			} else if errors.Is(err, registries.ErrBudgetExceeded) {
+				exceededErrs++
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Then
+	// The second start sees the cost reserved by the first one
+	suite.Len(processingIds, 2)
+	suite.Equal(3, exceededErrs)
+	suite.InDelta(1.2, usageRegistry.GetDailyCost(pipeline.GetSlug(), nil), 1e-9)
+
+	// Reservations are released when the logs are saved
+	for _, processingId := range processingIds {
+		suite.waitForProcessingStatusFile(storageDirectory, pipeline.GetSlug(), processingId)
+	}
+	suite.Eventually(
+		func() bool {
+			return usageRegistry.GetDailyCost(pipeline.GetSlug(), nil) == 0
+		},
+		time.Second,
+		10*time.Millisecond,
+	)
+}
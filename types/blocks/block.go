@@ -24,7 +24,7 @@ type BlockDetectorParent struct {
 
 func NewDetectorParent(config config.BlockConfigDetector) BlockDetectorParent {
 	return BlockDetectorParent{
-		Config:   config,
+		Config:   config.WithDefaults(),
 		stopChan: make(chan struct{}),
 	}
 }
@@ -68,10 +68,12 @@ type BlockParent struct {
 	Name         string               `json:"name"`
 	Description  string               `json:"description"`
 	Version      string               `json:"version"`
+	Deprecated   bool                 `json:"deprecated"`
 	SchemaString string               `json:"-"`
 	SchemaPtr    *gojsonschema.Schema `json:"-"`
 	Schema       interface{}          `json:"schema"`
 	Available    bool                 `json:"available"`
+	Profiles     map[string]bool      `json:"profiles,omitempty"`
 
 	processor interfaces.BlockProcessor
 }
@@ -92,6 +94,11 @@ func (b *BlockParent) GetVersion() string {
 	return b.Version
 }
 
+// IsDeprecated reports whether the version is kept only for the pipelines pinning it
+func (b *BlockParent) IsDeprecated() bool {
+	return b.Deprecated
+}
+
 func (b *BlockParent) GetSchema() *gojsonschema.Schema {
 	b.Lock()
 	defer b.Unlock()
@@ -182,7 +189,20 @@ func (b *BlockParent) Process(
 		return result, false, false, "", -1, fmt.Errorf(errStr, b.GetId(), data.GetStringRepresentation())
 	}
 
-	return processor.Process(ctx, data.GetBlock(), data)
+	output, stop, retry, targetBlock, targetBlockInputIndex, err := processor.Process(ctx, data.GetBlock(), data)
+	if err != nil || retry || !IsOutputValidationEnabled(config.GetConfig(), b.GetId()) {
+		return output, stop, retry, targetBlock, targetBlockInputIndex, err
+	}
+
+	// Invalid outputs of the non-deterministic blocks are retried within the output validation retries,
+	// other blocks fail
+	if err := b.ValidateOutput(output, GetRecordedOutputMetadata(ctx, len(output))); err != nil {
+		logger.Errorf("Block (%s #%d) output validation error: %v", b.GetId(), data.GetInputIndex(), err)
+		nonDeterministicBlock, ok := data.GetBlock().(interfaces.NonDeterministicBlock)
+		return result, false, ok && nonDeterministicBlock.IsNonDeterministic(), "", -1, fmt.Errorf("%w: %w", ErrInvalidOutput, err)
+	}
+
+	return output, stop, retry, targetBlock, targetBlockInputIndex, nil
 }
 
 func (b *BlockParent) SetAvailable(available bool) {
@@ -212,3 +232,18 @@ func (b *BlockParent) GetProcessor() interfaces.BlockProcessor {
 
 	return b.processor
 }
+
+// SetProfilesAvailability records the availability of the credentials profiles of the integration
+func (b *BlockParent) SetProfilesAvailability(profilesAvailability map[string]bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.Profiles = profilesAvailability
+}
+
+func (b *BlockParent) GetProfilesAvailability() map[string]bool {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.Profiles
+}
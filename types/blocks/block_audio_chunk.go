@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"time"
 
+	"data-pipelines-worker/api/schemas"
 	"data-pipelines-worker/types/config"
 	"data-pipelines-worker/types/generics"
 	"data-pipelines-worker/types/helpers"
@@ -70,12 +72,13 @@ func (p *ProcessorAudioChunk) Process(
 		return nil, false, false, "", -1, err
 	}
 
-	audioMimeType, err := helpers.DetectMimeTypeFromBuffer(*bytes.NewBuffer(audio))
+	// The content type of the metadata of the audio is trusted over the detected one
+	audioMimeType, err := helpers.GetMimeType(blockConfig.ContentType, *bytes.NewBuffer(audio))
 	if err != nil {
 		return nil, false, false, "", -1, err
 	}
 
-	if audioMimeType.String() != "audio/mpeg" {
+	if !audioMimeType.Is("audio/mpeg") {
 		return nil, false, false, "", -1, fmt.Errorf("invalid audio format. Only MP3 is supported")
 	}
 
@@ -151,14 +154,17 @@ func (p *ProcessorAudioChunk) Process(
 	}
 
 	// For this example, we return a simple output with chunk paths
-	for _, chunk := range chunks {
+	for chunkIndex, chunk := range chunks {
 		// Read each chunk and write it to the output buffer (or use another approach)
-		fmt.Println("> Chunk:", chunk)
 		chunkData, err := os.ReadFile(chunk)
 		if err != nil {
 			return nil, false, false, "", -1, err
 		}
 		output = append(output, bytes.NewBuffer(chunkData))
+		RecordOutputMetadata(ctx, chunkIndex, schemas.OutputMetadataSchema{
+			ContentType: "audio/mpeg",
+			FileName:    filepath.Base(chunk),
+		})
 	}
 
 	return output, false, false, "", -1, nil
@@ -167,6 +173,7 @@ func (p *ProcessorAudioChunk) Process(
 type BlockAudioChunkConfig struct {
 	FFMPEGBinary string `yaml:"ffmpeg_binary" json:"ffmpeg_binary"`
 	Duration     string `yaml:"duration" json:"duration"`
+	ContentType  string `yaml:"content_type" json:"content_type"`
 }
 
 type BlockAudioChunk struct {
@@ -185,6 +192,18 @@ func (b *BlockAudioChunk) GetBlockConfig(_config config.Config) *BlockAudioChunk
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "audio_chunk",
+		NewBlock: func() interfaces.Block {
+			return NewBlockAudioChunk()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorAudioChunk(detectorConfig)
+		},
+	})
+}
+
 func NewBlockAudioChunk() *BlockAudioChunk {
 	block := &BlockAudioChunk{
 		BlockParent: BlockParent{
@@ -208,6 +227,10 @@ func NewBlockAudioChunk() *BlockAudioChunk {
 								"description": "Duration of the audio",
 								"type": "string",
 								"default": "10m"
+							},
+							"content_type": {
+								"description": "Content type of the audio, e.g. from the metadata of the output. Detected from the audio when empty",
+								"type": "string"
 							}
 						},
 						"required": ["audio"]
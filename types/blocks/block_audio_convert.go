@@ -169,6 +169,18 @@ func (b *BlockAudioConvert) GetBlockConfig(_config config.Config) *BlockAudioCon
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "audio_convert",
+		NewBlock: func() interfaces.Block {
+			return NewBlockAudioConvert()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorAudioConvert(detectorConfig)
+		},
+	})
+}
+
 func NewBlockAudioConvert() *BlockAudioConvert {
 	block := &BlockAudioConvert{
 		BlockParent: BlockParent{
@@ -159,6 +159,18 @@ func (b *BlockAudioFromVideo) GetBlockConfig(_config config.Config) *BlockAudioF
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "audio_from_video",
+		NewBlock: func() interfaces.Block {
+			return NewBlockAudioFromVideo()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorAudioFromVideo(detectorConfig)
+		},
+	})
+}
+
 func NewBlockAudioFromVideo() *BlockAudioFromVideo {
 	block := &BlockAudioFromVideo{
 		BlockParent: BlockParent{
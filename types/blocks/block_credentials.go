@@ -0,0 +1,97 @@
+package blocks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/limiter"
+	"data-pipelines-worker/types/llm"
+)
+
+// getLLMProvider returns the OpenAI-compatible provider of the credentials profile selected by the block input
+func getLLMProvider(profile string) (llm.Provider, error) {
+	return llm.GetProviderRegistry().Get(profile)
+}
+
+// getTelegramClient returns the Telegram client of the credentials profile selected by the block input
+func getTelegramClient(_config config.Config, profile string) (*telegramClient, error) {
+	telegramConfig, err := _config.Telegram.GetProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	client := telegramConfig.GetClient()
+	if client == nil {
+		return nil, getClientNotConfiguredError("telegram", profile)
+	}
+
+	return &telegramClient{
+		BotAPI: client,
+		guard: limiter.GetLimiterRegistry().Get(
+			limiter.PROVIDER_TELEGRAM,
+			profile,
+			telegramConfig.Limits,
+			telegramConfig.CircuitBreaker,
+		),
+	}, nil
+}
+
+// telegramClient runs the requests within the rate limits and the circuit breaker
+// shared by all the blocks of the credentials profile
+type telegramClient struct {
+	*tgbotapi.BotAPI
+
+	guard *limiter.Guard
+}
+
+func (c *telegramClient) Send(ctx context.Context, chattable tgbotapi.Chattable) (message tgbotapi.Message, err error) {
+	err = c.guard.Do(ctx, 0, isTelegramFailure, func() (int, error) {
+		var sendErr error
+		message, sendErr = c.BotAPI.Send(chattable)
+		return -1, sendErr
+	})
+
+	return message, err
+}
+
+func (c *telegramClient) Request(ctx context.Context, chattable tgbotapi.Chattable) (response *tgbotapi.APIResponse, err error) {
+	err = c.guard.Do(ctx, 0, isTelegramFailure, func() (int, error) {
+		var requestErr error
+		response, requestErr = c.BotAPI.Request(chattable)
+		return -1, requestErr
+	})
+
+	return response, err
+}
+
+func (c *telegramClient) GetUpdates(ctx context.Context, updateConfig tgbotapi.UpdateConfig) (updates []tgbotapi.Update, err error) {
+	err = c.guard.Do(ctx, 0, isTelegramFailure, func() (int, error) {
+		var updatesErr error
+		updates, updatesErr = c.BotAPI.GetUpdates(updateConfig)
+		return -1, updatesErr
+	})
+
+	return updates, err
+}
+
+// isTelegramFailure reports the errors opening the circuit of the credentials profile
+func isTelegramFailure(err error) bool {
+	var telegramErr *tgbotapi.Error
+	if errors.As(err, &telegramErr) {
+		return limiter.IsRequestFailure(telegramErr.Code, err)
+	}
+
+	return limiter.IsRequestFailure(0, err)
+}
+
+func getClientNotConfiguredError(integration string, profile string) error {
+	if profile == "" || profile == config.DEFAULT_CREDENTIALS_PROFILE {
+		return fmt.Errorf("%s client is not configured", integration)
+	}
+
+	return fmt.Errorf("%s client of %s credentials profile is not configured", integration, profile)
+}
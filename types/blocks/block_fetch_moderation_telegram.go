@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -121,9 +120,9 @@ func (p *ProcessorFetchModerationFromTelegram) Process(
 	// Stop the pipeline if Moderation is not Approved
 	stopPipeline := blockConfig.StopPipelineIfDecline
 
-	client := _config.Telegram.GetClient()
-	if client == nil {
-		return output, stopPipeline, false, "", -1, errors.New("telegram client is not configured")
+	client, err := getTelegramClient(_config, blockConfig.Credentials)
+	if err != nil {
+		return output, stopPipeline, false, "", -1, err
 	}
 
 	updateConfig := tgbotapi.UpdateConfig{
@@ -151,7 +150,7 @@ func (p *ProcessorFetchModerationFromTelegram) Process(
 			}
 
 			// Fetch updates from Telegram
-			updates, err := client.GetUpdates(updateConfig)
+			updates, err := client.GetUpdates(ctx, updateConfig)
 			if err != nil {
 				return output, stopPipeline, true, "", -1, err
 			}
@@ -227,10 +226,10 @@ func (p *ProcessorFetchModerationFromTelegram) Process(
 				},
 			)
 
-			if _, err := client.Request(edit); err == nil {
+			if _, err := client.Request(ctx, edit); err == nil {
 				// Acknowledge the callback (removes the loading indicator)
 				callback := tgbotapi.NewCallback(mostRecentDecision.ID, "Processing...")
-				client.Request(callback)
+				client.Request(ctx, callback)
 			}
 		}
 
@@ -260,6 +259,7 @@ func (p *ProcessorFetchModerationFromTelegram) Process(
 }
 
 type BlockFetchModerationFromTelegramConfig struct {
+	Credentials           string        `yaml:"credentials" json:"credentials"`
 	BlockSlug             string        `yaml:"block_slug" json:"block_slug"`
 	StopPipelineIfDecline bool          `yaml:"stop_pipeline_if_decline" json:"stop_pipeline_if_decline"`
 	RetryIfUnknown        bool          `yaml:"retry_if_unknown" json:"-"`
@@ -295,6 +295,18 @@ func (b *BlockFetchModerationFromTelegram) GetBlockConfig(_config config.Config)
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "fetch_moderation_tg",
+		NewBlock: func() interfaces.Block {
+			return NewBlockFetchModerationFromTelegram()
+		},
+		NewDetector: func(_config config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorTelegramBot(_config.Telegram, detectorConfig)
+		},
+	})
+}
+
 func NewBlockFetchModerationFromTelegram() *BlockFetchModerationFromTelegram {
 	block := &BlockFetchModerationFromTelegram{
 		BlockParent: BlockParent{
@@ -307,8 +319,13 @@ func NewBlockFetchModerationFromTelegram() *BlockFetchModerationFromTelegram {
 				"properties": {
 					"input": {
 						"type": "object",
-						"description": "This block has no input parameters",
+						"description": "Input parameters",
 						"properties": {
+							"credentials": {
+								"description": "Credentials profile of the Telegram integration",
+								"type": "string",
+								"default": "default"
+							},
 							"block_slug": {
 								"description": "Slug of a Block which has Sent moderation Request",
 								"type": "string"
@@ -147,6 +147,18 @@ func (b *BlockFormatStringFromObject) GetBlockConfig(_config config.Config) *Blo
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "format_string_from_object",
+		NewBlock: func() interfaces.Block {
+			return NewBlockFormatStringFromObject()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorFormatStringFromObject(detectorConfig)
+		},
+	})
+}
+
 func NewBlockFormatStringFromObject() *BlockFormatStringFromObject {
 	block := &BlockFormatStringFromObject{
 		BlockParent: BlockParent{
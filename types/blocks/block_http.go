@@ -3,14 +3,17 @@ package blocks
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
 
-	gjm "github.com/firewut/go-json-map"
-
 	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/egress"
 	"data-pipelines-worker/types/generics"
 	"data-pipelines-worker/types/helpers"
 	"data-pipelines-worker/types/interfaces"
@@ -27,10 +30,12 @@ func NewDetectorHTTP(
 	client *http.Client,
 	detectorConfig config.BlockConfigDetector,
 ) *DetectorHTTP {
+	detectorUrl, _ := detectorConfig.Conditions["url"].(string)
+
 	return &DetectorHTTP{
 		BlockDetectorParent: NewDetectorParent(detectorConfig),
 		Client:              client,
-		Url:                 detectorConfig.Conditions["url"].(string),
+		Url:                 detectorUrl,
 	}
 }
 
@@ -42,6 +47,18 @@ func (d *DetectorHTTP) Detect() bool {
 	return err == nil
 }
 
+const (
+	DEFAULT_HTTP_TIMEOUT           = 30
+	DEFAULT_HTTP_MAX_REDIRECTS     = 10
+	DEFAULT_HTTP_MAX_RESPONSE_SIZE = 10 << 20
+
+	HTTP_BODY_FORMAT_JSON = "json"
+	HTTP_BODY_FORMAT_FORM = "form"
+
+	HTTP_AUTH_BASIC  = "basic"
+	HTTP_AUTH_BEARER = "bearer"
+)
+
 type ProcessorHTTP struct {
 }
 
@@ -63,36 +80,35 @@ func (p *ProcessorHTTP) Process(
 	data interfaces.ProcessableBlockData,
 ) ([]*bytes.Buffer, bool, bool, string, int, error) {
 	output := make([]*bytes.Buffer, 0)
+	blockConfig := &BlockHTTPConfig{}
 
 	logger := config.GetLogger()
 
+	_config := config.GetConfig()
 	_data := data.GetInputData().(map[string]interface{})
 
-	// Fetch the URL from the input data
-	url, err := helpers.GetValue[string](_data, "url")
+	defaultBlockConfig := block.(*BlockHTTP).GetBlockConfig(_config)
+	userBlockConfig := &BlockHTTPConfig{}
+	helpers.MapToJSONStruct(_data, userBlockConfig)
+	helpers.MergeStructs(defaultBlockConfig, userBlockConfig, blockConfig)
+
+	req, err := blockConfig.NewRequest(ctx)
 	if err != nil {
 		return nil, false, false, "", -1, err
 	}
 
-	method := http.MethodGet
-	switch m, err := gjm.GetProperty(_data, "method"); {
-	case err == nil:
-		method = m.(string)
-	default:
-		logger.Debugf("Method not provided, using default: %s", method)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	// URLs of the pipeline input must not reach the internal services
+	policy, err := egress.GetPolicy()
 	if err != nil {
 		return nil, false, false, "", -1, err
 	}
-
-	client := &http.Client{
-		Timeout: 30 * time.Second, // Set a timeout for the request ( use config later )
+	if err := policy.CheckURL(req.URL); err != nil {
+		logger.Errorf("Request of block %s is denied: %s", data.GetSlug(), err)
+		return nil, false, false, "", -1, err
 	}
 
 	// Perform the HTTP request
-	response, err := client.Do(req)
+	response, err := blockConfig.NewClient(policy).Do(req)
 	if err != nil {
 		// Check if the error is due to context cancellation
 		if ctx.Err() == context.Canceled {
@@ -103,15 +119,30 @@ func (p *ProcessorHTTP) Process(
 	}
 	defer response.Body.Close()
 
-	// Create a buffer to store the response
-	output = append(output, &bytes.Buffer{})
-	_, err = io.Copy(output[0], response.Body)
+	// Read one byte over the limit to detect the larger responses
+	body := &bytes.Buffer{}
+	_, err = io.Copy(body, io.LimitReader(response.Body, blockConfig.MaxResponseSize+1))
 	if err != nil {
-		return output, false, false, "", -1, err
+		return nil, false, false, "", -1, err
+	}
+	if int64(body.Len()) > blockConfig.MaxResponseSize {
+		return nil, false, false, "", -1, fmt.Errorf(
+			"HTTP response exceeds the max_response_size of %d bytes",
+			blockConfig.MaxResponseSize,
+		)
 	}
 
+	if blockConfig.OutputEnvelope {
+		envelopeBytes, err := json.Marshal(NewHTTPResponseEnvelope(response, body.Bytes()))
+		if err != nil {
+			return nil, false, false, "", -1, err
+		}
+		body = bytes.NewBuffer(envelopeBytes)
+	}
+	output = append(output, body)
+
 	// Check response status code
-	if response.StatusCode != http.StatusOK {
+	if !blockConfig.IsStatusAccepted(response.StatusCode) {
 		err := fmt.Errorf("HTTP request failed with status code: %d", response.StatusCode)
 		logger.Error(err)
 		return output, false, false, "", -1, err
@@ -120,7 +151,183 @@ func (p *ProcessorHTTP) Process(
 	return output, false, false, "", -1, nil
 }
 
+// HTTPResponseEnvelope is the output with `output_envelope`. JSON bodies are parsed for `json_path`
+type HTTPResponseEnvelope struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    interface{}       `json:"body"`
+}
+
+func NewHTTPResponseEnvelope(response *http.Response, body []byte) HTTPResponseEnvelope {
+	envelope := HTTPResponseEnvelope{
+		Status:  response.StatusCode,
+		Headers: make(map[string]string),
+		Body:    string(body),
+	}
+	for name, values := range response.Header {
+		envelope.Headers[name] = strings.Join(values, ", ")
+	}
+
+	var parsedBody interface{}
+	if json.Valid(body) && json.Unmarshal(body, &parsedBody) == nil {
+		envelope.Body = parsedBody
+	}
+
+	return envelope
+}
+
+type BlockHTTPAuthConfig struct {
+	// basic or bearer, detected from the credentials when empty
+	Type     string `json:"type"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Token    string `json:"token"`
+}
+
 type BlockHTTPConfig struct {
+	Url                 string              `yaml:"-" json:"url"`
+	Method              string              `yaml:"method" json:"method"`
+	Headers             map[string]string   `yaml:"-" json:"headers"`
+	Query               map[string]string   `yaml:"-" json:"query"`
+	Body                interface{}         `yaml:"-" json:"body"`
+	BodyFormat          string              `yaml:"body_format" json:"body_format"`
+	Auth                BlockHTTPAuthConfig `yaml:"-" json:"auth"`
+	Timeout             int                 `yaml:"timeout" json:"timeout"`
+	AcceptedStatusCodes []int               `yaml:"accepted_status_codes" json:"accepted_status_codes"`
+	FollowRedirects     *bool               `yaml:"follow_redirects" json:"follow_redirects"`
+	MaxRedirects        int                 `yaml:"max_redirects" json:"max_redirects"`
+	MaxResponseSize     int64               `yaml:"max_response_size" json:"max_response_size"`
+	OutputEnvelope      bool                `yaml:"output_envelope" json:"output_envelope"`
+}
+
+// NewRequest builds the request of the url, method, query, headers, body and auth of the config
+func (c *BlockHTTPConfig) NewRequest(ctx context.Context) (*http.Request, error) {
+	requestUrl, err := url.Parse(c.Url)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Query) > 0 {
+		query := requestUrl.Query()
+		for name, value := range c.Query {
+			query.Set(name, value)
+		}
+		requestUrl.RawQuery = query.Encode()
+	}
+
+	body, contentType, err := c.getBody()
+	if err != nil {
+		return nil, err
+	}
+
+	method := strings.ToUpper(c.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, requestUrl.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for name, value := range c.Headers {
+		req.Header.Set(name, value)
+	}
+
+	switch {
+	case strings.EqualFold(c.Auth.Type, HTTP_AUTH_BASIC) || (c.Auth.Type == "" && c.Auth.Username != ""):
+		req.SetBasicAuth(c.Auth.Username, c.Auth.Password)
+	case strings.EqualFold(c.Auth.Type, HTTP_AUTH_BEARER) || (c.Auth.Type == "" && c.Auth.Token != ""):
+		req.Header.Set("Authorization", "Bearer "+c.Auth.Token)
+	case c.Auth.Type != "":
+		return nil, fmt.Errorf("unsupported HTTP auth type: %s", c.Auth.Type)
+	}
+
+	return req, nil
+}
+
+// getBody encodes the body and returns its content type. Strings are sent as is,
+// objects and arrays as JSON or as a form with `body_format: form` or the form Content-Type header
+func (c *BlockHTTPConfig) getBody() (io.Reader, string, error) {
+	switch body := c.Body.(type) {
+	case nil:
+		return nil, "", nil
+	case string:
+		return strings.NewReader(body), "", nil
+	}
+
+	bodyFormat := strings.ToLower(c.BodyFormat)
+	for name, value := range c.Headers {
+		if strings.EqualFold(name, "Content-Type") && strings.HasPrefix(value, "application/x-www-form-urlencoded") {
+			bodyFormat = HTTP_BODY_FORMAT_FORM
+		}
+	}
+
+	switch bodyFormat {
+	case HTTP_BODY_FORMAT_FORM:
+		fields, ok := c.Body.(map[string]interface{})
+		if !ok {
+			return nil, "", fmt.Errorf("form body must be an object")
+		}
+
+		form := url.Values{}
+		for name, value := range fields {
+			if values, ok := value.([]interface{}); ok {
+				for _, item := range values {
+					form.Add(name, fmt.Sprint(item))
+				}
+				continue
+			}
+			form.Set(name, fmt.Sprint(value))
+		}
+		return strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", nil
+	case "", HTTP_BODY_FORMAT_JSON:
+		bodyBytes, err := json.Marshal(c.Body)
+		if err != nil {
+			return nil, "", err
+		}
+		return bytes.NewReader(bodyBytes), "application/json", nil
+	}
+
+	return nil, "", fmt.Errorf("unsupported HTTP body_format: %s", c.BodyFormat)
+}
+
+// NewClient returns the client with the timeout and the redirect policy of the config.
+// The egress policy checks the redirects and the addresses of the connections
+func (c *BlockHTTPConfig) NewClient(policy *egress.Policy) *http.Client {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DEFAULT_HTTP_TIMEOUT
+	}
+	maxRedirects := c.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = DEFAULT_HTTP_MAX_REDIRECTS
+	}
+	followRedirects := c.FollowRedirects == nil || *c.FollowRedirects
+
+	return policy.NewClient(
+		time.Duration(timeout)*time.Second,
+		func(req *http.Request, via []*http.Request) error {
+			if !followRedirects {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return nil
+		},
+	)
+}
+
+// IsStatusAccepted checks the `accepted_status_codes`, any 2xx status when empty
+func (c *BlockHTTPConfig) IsStatusAccepted(statusCode int) bool {
+	if len(c.AcceptedStatusCodes) == 0 {
+		return statusCode >= 200 && statusCode < 300
+	}
+
+	return slices.Contains(c.AcceptedStatusCodes, statusCode)
 }
 
 type BlockHTTP struct {
@@ -129,6 +336,7 @@ type BlockHTTP struct {
 }
 
 var _ interfaces.Block = (*BlockHTTP)(nil)
+var _ interfaces.BlockInputMasker = (*BlockHTTP)(nil)
 
 func (b *BlockHTTP) GetBlockConfig(_config config.Config) *BlockHTTPConfig {
 	blockConfig := _config.Blocks[b.GetId()].Config
@@ -136,9 +344,73 @@ func (b *BlockHTTP) GetBlockConfig(_config config.Config) *BlockHTTPConfig {
 	defaultBlockConfig := &BlockHTTPConfig{}
 	helpers.MapToYAMLStruct(blockConfig, defaultBlockConfig)
 
+	if defaultBlockConfig.Timeout <= 0 {
+		defaultBlockConfig.Timeout = DEFAULT_HTTP_TIMEOUT
+	}
+	if defaultBlockConfig.MaxRedirects <= 0 {
+		defaultBlockConfig.MaxRedirects = DEFAULT_HTTP_MAX_REDIRECTS
+	}
+	if defaultBlockConfig.MaxResponseSize <= 0 {
+		defaultBlockConfig.MaxResponseSize = DEFAULT_HTTP_MAX_RESPONSE_SIZE
+	}
+
 	return defaultBlockConfig
 }
 
+// MaskInput masks the auth password and token and the Authorization header of the input
+func (b *BlockHTTP) MaskInput(input map[string]interface{}) map[string]interface{} {
+	maskedInput := make(map[string]interface{}, len(input))
+	for key, value := range input {
+		maskedInput[key] = value
+	}
+
+	if auth, ok := input["auth"].(map[string]interface{}); ok {
+		maskedAuth := make(map[string]interface{}, len(auth))
+		for key, value := range auth {
+			if key == "password" || key == "token" {
+				value = config.SECRET_REDACTED
+			}
+			maskedAuth[key] = value
+		}
+		maskedInput["auth"] = maskedAuth
+	}
+
+	switch headers := input["headers"].(type) {
+	case map[string]interface{}:
+		maskedHeaders := make(map[string]interface{}, len(headers))
+		for name, value := range headers {
+			if strings.EqualFold(name, "Authorization") {
+				value = config.SECRET_REDACTED
+			}
+			maskedHeaders[name] = value
+		}
+		maskedInput["headers"] = maskedHeaders
+	case map[string]string:
+		maskedHeaders := make(map[string]string, len(headers))
+		for name, value := range headers {
+			if strings.EqualFold(name, "Authorization") {
+				value = config.SECRET_REDACTED
+			}
+			maskedHeaders[name] = value
+		}
+		maskedInput["headers"] = maskedHeaders
+	}
+
+	return maskedInput
+}
+
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "http_request",
+		NewBlock: func() interfaces.Block {
+			return NewBlockHTTP()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorHTTP(&http.Client{}, detectorConfig)
+		},
+	})
+}
+
 func NewBlockHTTP() *BlockHTTP {
 	block := &BlockHTTP{
 		BlockParent: BlockParent{
@@ -146,7 +418,16 @@ func NewBlockHTTP() *BlockHTTP {
 			Name:        "Request HTTP Resource",
 			Description: "Block to perform request to a URL and save the Response",
 			Version:     "1",
-			SchemaString: `{
+			SchemaPtr:   nil,
+			Schema:      nil,
+		},
+	}
+
+	_config := config.GetConfig()
+	defaultBlockConfig := block.GetBlockConfig(_config)
+
+	block.SetSchemaString(
+		fmt.Sprintf(`{
 				"type": "object",
 				"properties": {
 					"input": {
@@ -162,7 +443,7 @@ func NewBlockHTTP() *BlockHTTP {
 							"method": {
 								"description": "HTTP method to use",
 								"type": "string",
-								"enum": ["GET", "POST", "PUT", "DELETE"],
+								"enum": ["GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"],
 								"default": "GET"
 							},
 							"headers": {
@@ -187,9 +468,73 @@ func NewBlockHTTP() *BlockHTTP {
 								}
 							},
 							"body": {
-								"description": "Body to send in the request",
+								"description": "Body to send in the request. Strings are sent as is, objects and arrays as JSON or as a form",
 								"type": ["string", "object", "array", "null"]
 							},
+							"body_format": {
+								"description": "Encoding of the object body. The form Content-Type header selects the form",
+								"type": "string",
+								"enum": ["json", "form"],
+								"default": "json"
+							},
+							"auth": {
+								"description": "Credentials of the request. Use the secret references, e.g. ${secret:api-token}",
+								"type": ["object", "null"],
+								"properties": {
+									"type": {
+										"description": "Auth scheme, detected from the credentials when empty",
+										"type": "string",
+										"enum": ["basic", "bearer"]
+									},
+									"username": {
+										"type": "string"
+									},
+									"password": {
+										"type": "string"
+									},
+									"token": {
+										"type": "string"
+									}
+								},
+								"additionalProperties": false
+							},
+							"timeout": {
+								"description": "Timeout of the request in seconds",
+								"type": "integer",
+								"minimum": 1,
+								"default": %d
+							},
+							"accepted_status_codes": {
+								"description": "Response status codes of the successful request, any 2xx when empty",
+								"type": "array",
+								"items": {
+									"type": "integer",
+									"minimum": 100,
+									"maximum": 599
+								}
+							},
+							"follow_redirects": {
+								"description": "Follow the redirects, otherwise the redirect response is the output",
+								"type": "boolean",
+								"default": true
+							},
+							"max_redirects": {
+								"description": "Max number of the redirects to follow",
+								"type": "integer",
+								"minimum": 1,
+								"default": %d
+							},
+							"max_response_size": {
+								"description": "Max size of the response body in bytes",
+								"type": "integer",
+								"minimum": 1,
+								"default": %d
+							},
+							"output_envelope": {
+								"description": "Output {\"status\", \"headers\", \"body\"} with the parsed JSON body instead of the body",
+								"type": "boolean",
+								"default": false
+							},
 							"description": {
 								"description": "Description of the request",
 								"type": ["string", "null"]
@@ -198,16 +543,17 @@ func NewBlockHTTP() *BlockHTTP {
 						"required": ["url"]
 					},
 					"output": {
-						"description": "Content fetched from the URL",
-						"type": ["string", "null"],
+						"description": "Content fetched from the URL or the response envelope with output_envelope",
+						"type": ["string", "object", "null"],
 						"format": "file"
 					}
 				}
 			}`,
-			SchemaPtr: nil,
-			Schema:    nil,
-		},
-	}
+			defaultBlockConfig.Timeout,
+			defaultBlockConfig.MaxRedirects,
+			defaultBlockConfig.MaxResponseSize,
+		),
+	)
 
 	if err := block.ApplySchema(block.GetSchemaString()); err != nil {
 		panic(err)
@@ -282,6 +282,18 @@ func (b *BlockImageAddText) GetBlockConfig(_config config.Config) *BlockImageAdd
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "image_add_text",
+		NewBlock: func() interfaces.Block {
+			return NewBlockImageAddText()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorImageAddText(detectorConfig)
+		},
+	})
+}
+
 func NewBlockImageAddText() *BlockImageAddText {
 	fontsEmbedded, err := config.ListFonts()
 	if err != nil {
@@ -375,7 +387,8 @@ func NewBlockImageAddText() *BlockImageAddText {
 					"output": {
 						"description": "Image with text added",
 						"type": ["string", "null"],
-						"format": "file"
+						"format": "file",
+						"contentMediaType": "image/*"
 					}
 				}
 			}`,
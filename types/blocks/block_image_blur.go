@@ -108,6 +108,18 @@ func (b *BlockImageBlur) GetBlockConfig(_config config.Config) *BlockImageBlurCo
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "image_blur",
+		NewBlock: func() interfaces.Block {
+			return NewBlockImageBlur()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorImageBlur(detectorConfig)
+		},
+	})
+}
+
 func NewBlockImageBlur() *BlockImageBlur {
 	block := &BlockImageBlur{
 		BlockParent: BlockParent{
@@ -139,7 +151,8 @@ func NewBlockImageBlur() *BlockImageBlur {
 					"output": {
 						"description": "Blurred image",
 						"type": ["string", "null"],
-						"format": "file"
+						"format": "file",
+						"contentMediaType": "image/*"
 					}
 				}
 			}`,
@@ -110,6 +110,18 @@ func (b *BlockImageResize) GetBlockConfig(_config config.Config) *BlockImageResi
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "image_resize",
+		NewBlock: func() interfaces.Block {
+			return NewBlockImageResize()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorImageResize(detectorConfig)
+		},
+	})
+}
+
 func NewBlockImageResize() *BlockImageResize {
 	block := &BlockImageResize{
 		BlockParent: BlockParent{
@@ -150,7 +162,8 @@ func NewBlockImageResize() *BlockImageResize {
 					"output": {
 						"description": "Resized image",
 						"type": ["string", "null"],
-						"format": "file"
+						"format": "file",
+						"contentMediaType": "image/*"
 					}
 				}
 			}`,
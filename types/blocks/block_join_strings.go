@@ -100,6 +100,18 @@ func (b *BlockJoinStrings) GetBlockConfig(_config config.Config) *BlockJoinStrin
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "join_strings",
+		NewBlock: func() interfaces.Block {
+			return NewBlockJoinStrings()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorJoinStrings(detectorConfig)
+		},
+	})
+}
+
 func NewBlockJoinStrings() *BlockJoinStrings {
 	block := &BlockJoinStrings{
 		BlockParent: BlockParent{
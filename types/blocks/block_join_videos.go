@@ -195,6 +195,18 @@ func (b *BlockJoinVideos) GetBlockConfig(_config config.Config) *BlockJoinVideos
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "join_videos",
+		NewBlock: func() interfaces.Block {
+			return NewBlockJoinVideos()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorJoinVideos(detectorConfig)
+		},
+	})
+}
+
 func NewBlockJoinVideos() *BlockJoinVideos {
 	block := &BlockJoinVideos{
 		BlockParent: BlockParent{
@@ -235,7 +247,8 @@ func NewBlockJoinVideos() *BlockJoinVideos {
 					"output": {
 						"description": "Video generated from list of videos",
 						"type": ["string", "null"],
-						"format": "file"
+						"format": "file",
+						"contentMediaType": "video/*"
 					}
 				}
 			}`,
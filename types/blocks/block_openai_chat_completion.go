@@ -3,41 +3,72 @@ package blocks
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
 	"time"
 
 	openai "github.com/sashabaranov/go-openai"
+	"github.com/xeipuuv/gojsonschema"
 
 	"data-pipelines-worker/types/config"
 	"data-pipelines-worker/types/generics"
 	"data-pipelines-worker/types/helpers"
 	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/limiter"
+	"data-pipelines-worker/types/llm"
 )
 
 type DetectorOpenAI struct {
 	BlockDetectorParent
 
-	Client *openai.Client
+	Config *config.OpenAIConfig
+
+	profilesAvailability map[string]bool
 }
 
-func NewDetectorOpenAI(client *openai.Client, detectorConfig config.BlockConfigDetector) *DetectorOpenAI {
+var _ interfaces.BlockProfilesDetector = (*DetectorOpenAI)(nil)
+
+func NewDetectorOpenAI(openAIConfig *config.OpenAIConfig, detectorConfig config.BlockConfigDetector) *DetectorOpenAI {
 	return &DetectorOpenAI{
-		BlockDetectorParent: NewDetectorParent(detectorConfig),
-		Client:              client,
+		BlockDetectorParent:  NewDetectorParent(detectorConfig),
+		Config:               openAIConfig,
+		profilesAvailability: make(map[string]bool),
 	}
 }
 
+// Detect checks the client of every credentials profile. The block is available with any of them
 func (d *DetectorOpenAI) Detect() bool {
 	d.Lock()
 	defer d.Unlock()
 
-	if d.Client == nil {
-		return false
+	available := false
+	profilesAvailability := make(map[string]bool)
+	for _, profileName := range d.Config.GetProfileNames() {
+		profile, _ := d.Config.GetProfile(profileName)
+
+		_, err := llm.NewOpenAIProvider(profileName, profile).ListModels(context.Background())
+		profilesAvailability[profileName] = err == nil
+		available = available || err == nil
 	}
+	d.profilesAvailability = profilesAvailability
+
+	return available
+}
+
+func (d *DetectorOpenAI) GetProvider() string {
+	return limiter.PROVIDER_OPENAI
+}
+
+func (d *DetectorOpenAI) GetProfilesAvailability() map[string]bool {
+	d.Lock()
+	defer d.Unlock()
 
-	_, err := d.Client.ListModels(context.Background())
-	return err == nil
+	return d.profilesAvailability
 }
 
 type ProcessorOpenAIRequestCompletion struct {
@@ -55,6 +86,28 @@ func (p *ProcessorOpenAIRequestCompletion) GetRetryInterval(_ interfaces.Block)
 	return 0
 }
 
+// GetDataRetryCount allows the re-asks of the output guards
+func (p *ProcessorOpenAIRequestCompletion) GetDataRetryCount(
+	block interfaces.Block,
+	data interfaces.ProcessableBlockData,
+) int {
+	blockConfig := &BlockOpenAIRequestCompletionConfig{}
+	_data, ok := data.GetInputData().(map[string]interface{})
+	if !ok {
+		return 0
+	}
+
+	defaultBlockConfig := block.(*BlockOpenAIRequestCompletion).GetBlockConfig(config.GetConfig())
+	userBlockConfig := &BlockOpenAIRequestCompletionConfig{}
+	helpers.MapToJSONStruct(_data, userBlockConfig)
+	helpers.MergeStructs(defaultBlockConfig, userBlockConfig, blockConfig)
+
+	if blockConfig.Guards == nil {
+		return 0
+	}
+	return blockConfig.MaxReAsks
+}
+
 func (p *ProcessorOpenAIRequestCompletion) Process(
 	ctx context.Context,
 	block interfaces.Block,
@@ -71,56 +124,301 @@ func (p *ProcessorOpenAIRequestCompletion) Process(
 	helpers.MapToJSONStruct(_data, userBlockConfig)
 	helpers.MergeStructs(defaultBlockConfig, userBlockConfig, blockConfig)
 
-	client := _config.OpenAI.GetClient()
-	if client == nil {
-		return output, false, false, "", -1, errors.New("openAI client is not configured")
+	provider, err := getLLMProvider(blockConfig.Credentials)
+	if err != nil {
+		return output, false, false, "", -1, err
+	}
+
+	if blockConfig.Guards != nil {
+		if err := blockConfig.Guards.Validate(); err != nil {
+			return output, false, false, "", -1, err
+		}
+	}
+
+	images := make([][]byte, 0)
+	if image, err := helpers.GetValue[[]byte](_data, "image"); err == nil {
+		images = append(images, image)
+	}
+	if _images, ok := _data["images"]; ok && _images != nil {
+		val := reflect.ValueOf(_images)
+		for i := 0; i < val.Len(); i++ {
+			if image, ok := val.Index(i).Interface().([]byte); ok {
+				images = append(images, image)
+			}
+		}
 	}
 
-	messages := make([]openai.ChatCompletionMessage, 0)
-	if blockConfig.SystemPrompt != "" {
+	_, hasUserPrompt := _data["user_prompt"]
+	request := openai.ChatCompletionRequest{
+		Model: blockConfig.Model,
+		Messages: getChatCompletionMessages(
+			blockConfig,
+			hasUserPrompt || len(blockConfig.Messages) == 0,
+			images,
+		),
+		Temperature: blockConfig.Temperature,
+		TopP:        blockConfig.TopP,
+		MaxTokens:   blockConfig.MaxTokens,
+		Seed:        blockConfig.Seed,
+		N:           blockConfig.N,
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeText,
+		},
+	}
+
+	var outputSchemaPtr *gojsonschema.Schema
+	switch blockConfig.ResponseFormat {
+	case "json":
+		request.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		}
+	case "json_schema":
+		if blockConfig.JSONSchema == nil {
+			return output, false, false, "", -1, errors.New("json_schema is required for the json_schema response format")
+		}
+		if outputSchemaPtr, err = gojsonschema.NewSchema(gojsonschema.NewGoLoader(blockConfig.JSONSchema)); err != nil {
+			return output, false, false, "", -1, fmt.Errorf("invalid json_schema: %s", err)
+		}
+		schemaJSON, err := json.Marshal(blockConfig.JSONSchema)
+		if err != nil {
+			return output, false, false, "", -1, err
+		}
+		request.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   "output",
+				Schema: json.RawMessage(schemaJSON),
+				Strict: blockConfig.StrictJSONSchema,
+			},
+		}
+	}
+
+	resp, err := provider.CreateChatCompletion(ctx, request)
+	if err != nil {
+		return output, false, false, "", -1, err
+	}
+	if len(resp.Choices) == 0 {
+		return output, false, false, "", -1, errors.New("chat completion has no choices")
+	}
+
+	// Output guards re-ask the model with the violations of the first rejected choice
+	if blockConfig.Guards != nil {
+		for _, choice := range resp.Choices {
+			tokens := 0
+			if len(resp.Choices) == 1 {
+				tokens = resp.Usage.CompletionTokens
+			}
+
+			violations := blockConfig.Guards.Check(choice.Message.Content, tokens)
+			if len(violations) == 0 {
+				continue
+			}
+
+			reAsks := len(blockConfig.ReAskMessages) / 2
+			violationErr := fmt.Errorf(
+				"%w after %d re-ask(s):\n- %s",
+				llm.ErrOutputGuardViolation,
+				reAsks,
+				strings.Join(violations, "\n- "),
+			)
+			if reAsks >= blockConfig.MaxReAsks {
+				return output, false, false, "", -1, violationErr
+			}
+
+			data.SetInputData(
+				withReAskMessages(_data, blockConfig.ReAskMessages, choice.Message.Content, violations),
+			)
+			return output, false, true, "", -1, violationErr
+		}
+	}
+
+	// Every choice is a separate output
+	for _, choice := range resp.Choices {
+		content := choice.Message.Content
+
+		if outputSchemaPtr != nil {
+			if err := validateChatCompletionOutput(outputSchemaPtr, content); err != nil {
+				return make([]*bytes.Buffer, 0), false, false, "", -1, err
+			}
+		}
+
+		if !blockConfig.OutputEnvelope {
+			output = append(output, bytes.NewBufferString(content))
+			continue
+		}
+
+		envelope, err := json.Marshal(
+			ChatCompletionOutputEnvelope{
+				Content:      content,
+				FinishReason: string(choice.FinishReason),
+				Model:        resp.Model,
+				Usage:        resp.Usage,
+			},
+		)
+		if err != nil {
+			return make([]*bytes.Buffer, 0), false, false, "", -1, err
+		}
+		output = append(output, bytes.NewBuffer(envelope))
+	}
+
+	return output, false, false, "", -1, nil
+}
+
+// getChatCompletionMessages builds the conversation: system prompt, `messages` and the user prompt with the images
+func getChatCompletionMessages(
+	blockConfig *BlockOpenAIRequestCompletionConfig,
+	withUserPrompt bool,
+	images [][]byte,
+) []openai.ChatCompletionMessage {
+	messages := make([]openai.ChatCompletionMessage, 0, len(blockConfig.Messages)+2)
+
+	hasSystemMessage := false
+	for _, message := range blockConfig.Messages {
+		if message.Role == openai.ChatMessageRoleSystem {
+			hasSystemMessage = true
+		}
+	}
+	if blockConfig.SystemPrompt != "" && !hasSystemMessage {
 		messages = append(messages, openai.ChatCompletionMessage{
 			Role:    openai.ChatMessageRoleSystem,
 			Content: blockConfig.SystemPrompt,
 		})
 	}
-	messages = append(messages, openai.ChatCompletionMessage{
-		Role:    openai.ChatMessageRoleUser,
-		Content: blockConfig.UserPrompt,
-	})
 
-	responseFormat := &openai.ChatCompletionResponseFormat{
-		Type: openai.ChatCompletionResponseFormatTypeText,
+	for _, message := range blockConfig.Messages {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    message.Role,
+			Content: message.Content,
+		})
 	}
-	if blockConfig.ResponseFormat == "json" {
-		responseFormat = &openai.ChatCompletionResponseFormat{
-			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+
+	if withUserPrompt {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleUser,
+			Content: blockConfig.UserPrompt,
+		})
+	}
+
+	// Images of the vision models are attached to the last user message
+	if len(images) > 0 {
+		lastUserMessage := -1
+		for i, message := range messages {
+			if message.Role == openai.ChatMessageRoleUser {
+				lastUserMessage = i
+			}
+		}
+		if lastUserMessage < 0 {
+			messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser})
+			lastUserMessage = len(messages) - 1
+		}
+
+		parts := make([]openai.ChatMessagePart, 0, len(images)+1)
+		if messages[lastUserMessage].Content != "" {
+			parts = append(parts, openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeText,
+				Text: messages[lastUserMessage].Content,
+			})
 		}
+		for _, image := range images {
+			parts = append(parts, openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeImageURL,
+				ImageURL: &openai.ChatMessageImageURL{
+					URL: fmt.Sprintf(
+						"data:%s;base64,%s",
+						http.DetectContentType(image),
+						base64.StdEncoding.EncodeToString(image),
+					),
+					Detail: openai.ImageURLDetail(blockConfig.ImageDetail),
+				},
+			})
+		}
+		messages[lastUserMessage].Content = ""
+		messages[lastUserMessage].MultiContent = parts
 	}
 
-	resp, err := client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model:          blockConfig.Model,
-			Messages:       messages,
-			ResponseFormat: responseFormat,
-		},
+	for _, message := range blockConfig.ReAskMessages {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    message.Role,
+			Content: message.Content,
+		})
+	}
+
+	return messages
+}
+
+// withReAskMessages returns the input of the next attempt with the rejected answer and its violations
+func withReAskMessages(
+	_data map[string]interface{},
+	reAskMessages []BlockOpenAIRequestCompletionMessage,
+	content string,
+	violations []string,
+) map[string]interface{} {
+	input := make(map[string]interface{}, len(_data)+1)
+	for key, value := range _data {
+		input[key] = value
+	}
+
+	messages := make([]BlockOpenAIRequestCompletionMessage, 0, len(reAskMessages)+2)
+	messages = append(messages, reAskMessages...)
+	messages = append(
+		messages,
+		BlockOpenAIRequestCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content},
+		BlockOpenAIRequestCompletionMessage{Role: openai.ChatMessageRoleUser, Content: llm.GetReAskPrompt(violations)},
 	)
+	input["reask_messages"] = messages
+
+	return input
+}
+
+// validateChatCompletionOutput validates the output of the json_schema response format before it is saved
+func validateChatCompletionOutput(schemaPtr *gojsonschema.Schema, content string) error {
+	validationResult, err := schemaPtr.Validate(gojsonschema.NewStringLoader(content))
 	if err != nil {
-		return output, false, false, "", -1, err
+		return fmt.Errorf("chat completion output is not a valid JSON: %s", err)
+	}
+	if !validationResult.Valid() {
+		errStr := "chat completion output is invalid for json_schema"
+		for _, err := range validationResult.Errors() {
+			errStr += fmt.Sprintf("\n- %s", err)
+		}
+		return errors.New(errStr)
 	}
 
-	output = append(
-		output,
-		bytes.NewBufferString(resp.Choices[0].Message.Content),
-	)
-	return output, false, false, "", -1, err
+	return nil
+}
+
+type BlockOpenAIRequestCompletionMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatCompletionOutputEnvelope is the output of a choice with `output_envelope`
+type ChatCompletionOutputEnvelope struct {
+	Content      string       `json:"content"`
+	FinishReason string       `json:"finish_reason"`
+	Model        string       `json:"model"`
+	Usage        openai.Usage `json:"usage"`
 }
 
 type BlockOpenAIRequestCompletionConfig struct {
-	Model          string `yaml:"model" json:"model"`
-	SystemPrompt   string `yaml:"system_prompt" json:"system_prompt"`
-	UserPrompt     string `yaml:"user_prompt" json:"user_prompt"`
-	ResponseFormat string `yaml:"response_format" json:"response_format"`
+	Credentials      string                                `yaml:"credentials" json:"credentials"`
+	Model            string                                `yaml:"model" json:"model"`
+	SystemPrompt     string                                `yaml:"system_prompt" json:"system_prompt"`
+	UserPrompt       string                                `yaml:"user_prompt" json:"user_prompt"`
+	Messages         []BlockOpenAIRequestCompletionMessage `yaml:"-" json:"messages"`
+	ResponseFormat   string                                `yaml:"response_format" json:"response_format"`
+	JSONSchema       map[string]interface{}                `yaml:"-" json:"json_schema"`
+	StrictJSONSchema bool                                  `yaml:"strict_json_schema" json:"strict_json_schema"`
+	Temperature      float32                               `yaml:"temperature" json:"temperature"`
+	TopP             float32                               `yaml:"top_p" json:"top_p"`
+	MaxTokens        int                                   `yaml:"max_tokens" json:"max_tokens"`
+	Seed             *int                                  `yaml:"seed" json:"seed"`
+	N                int                                   `yaml:"n" json:"n"`
+	ImageDetail      string                                `yaml:"image_detail" json:"image_detail"`
+	OutputEnvelope   bool                                  `yaml:"output_envelope" json:"output_envelope"`
+	Guards           *llm.OutputGuards                     `yaml:"guards" json:"guards"`
+	MaxReAsks        int                                   `yaml:"max_reasks" json:"max_reasks"`
+	ReAskMessages    []BlockOpenAIRequestCompletionMessage `yaml:"-" json:"reask_messages"`
 }
 
 type BlockOpenAIRequestCompletion struct {
@@ -130,6 +428,11 @@ type BlockOpenAIRequestCompletion struct {
 
 var _ interfaces.Block = (*BlockOpenAIRequestCompletion)(nil)
 
+// IsNonDeterministic reports that the invalid outputs of the sampled responses are retried
+func (b *BlockOpenAIRequestCompletion) IsNonDeterministic() bool {
+	return true
+}
+
 func (b *BlockOpenAIRequestCompletion) GetBlockConfig(_config config.Config) *BlockOpenAIRequestCompletionConfig {
 	blockConfig := _config.Blocks[b.GetId()].Config
 
@@ -139,6 +442,18 @@ func (b *BlockOpenAIRequestCompletion) GetBlockConfig(_config config.Config) *Bl
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "openai_chat_completion",
+		NewBlock: func() interfaces.Block {
+			return NewBlockOpenAIRequestCompletion()
+		},
+		NewDetector: func(_config config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorOpenAI(_config.OpenAI, detectorConfig)
+		},
+	})
+}
+
 func NewBlockOpenAIRequestCompletion() *BlockOpenAIRequestCompletion {
 	block := &BlockOpenAIRequestCompletion{
 		BlockParent: BlockParent{
@@ -163,11 +478,15 @@ func NewBlockOpenAIRequestCompletion() *BlockOpenAIRequestCompletion {
 						"type": "object",
 						"description": "Input parameters",
 						"properties": {
+							"credentials": {
+								"description": "Credentials profile of the OpenAI integration",
+								"type": "string",
+								"default": "default"
+							},
 							"model": {
 								"description": "Model to use",
 								"type": "string",
-								"default": "%s",
-								"enum": ["%s"]
+								"default": "%s"
 							},
 							"system_prompt": {
 								"description": "Prompt for OpenAI agent",
@@ -179,14 +498,134 @@ func NewBlockOpenAIRequestCompletion() *BlockOpenAIRequestCompletion {
 								"type": "string",
 								"default": "%s"
 							},
+							"messages": {
+								"description": "Conversation sent before the user prompt",
+								"type": "array",
+								"items": {
+									"type": "object",
+									"properties": {
+										"role": {
+											"type": "string",
+											"enum": ["system", "user", "assistant"]
+										},
+										"content": {
+											"type": "string"
+										}
+									},
+									"required": ["role", "content"]
+								}
+							},
+							"image": {
+								"description": "Image for the vision models",
+								"type": "string",
+								"format": "file"
+							},
+							"images": {
+								"description": "Images for the vision models",
+								"type": "array",
+								"items": {
+									"type": "string",
+									"format": "file"
+								}
+							},
+							"image_detail": {
+								"description": "Detail level of the images",
+								"type": "string",
+								"enum": ["auto", "low", "high"]
+							},
 							"response_format": {
 								"description": "Response format. Check that model supports it at https://platform.openai.com/docs/guides/structured-outputs/introduction",
 								"type": "string",
 								"default": "text",
-								"enum": ["text", "json"]
+								"enum": ["text", "json", "json_schema"]
+							},
+							"json_schema": {
+								"description": "JSON schema of the json_schema response format. The output is validated against it",
+								"type": "object"
+							},
+							"strict_json_schema": {
+								"description": "Strict adherence to the JSON schema",
+								"type": "boolean",
+								"default": false
+							},
+							"temperature": {
+								"description": "Sampling temperature",
+								"type": "number",
+								"minimum": 0,
+								"maximum": 2
+							},
+							"top_p": {
+								"description": "Nucleus sampling probability mass",
+								"type": "number",
+								"minimum": 0,
+								"maximum": 1
+							},
+							"max_tokens": {
+								"description": "Maximum number of the generated tokens",
+								"type": "integer",
+								"minimum": 1
+							},
+							"seed": {
+								"description": "Seed of the deterministic sampling",
+								"type": "integer"
+							},
+							"n": {
+								"description": "Number of the choices, every choice is a separate output",
+								"type": "integer",
+								"minimum": 1,
+								"default": 1
+							},
+							"output_envelope": {
+								"description": "Output a JSON envelope with the content, finish_reason, model and usage",
+								"type": "boolean",
+								"default": false
+							},
+							"guards": {
+								"description": "Checks of the output. Violations are sent back to the model to fix the answer",
+								"type": "object",
+								"properties": {
+									"min_words": {"type": "integer", "minimum": 0},
+									"max_words": {"type": "integer", "minimum": 0},
+									"min_characters": {"type": "integer", "minimum": 0},
+									"max_characters": {"type": "integer", "minimum": 0},
+									"min_tokens": {"type": "integer", "minimum": 0},
+									"max_tokens": {"type": "integer", "minimum": 0},
+									"json_schema": {
+										"description": "JSON schema the output must match",
+										"type": "object"
+									},
+									"must_match": {
+										"description": "Regular expressions the output must match",
+										"type": "array",
+										"items": {"type": "string"}
+									},
+									"must_not_match": {
+										"description": "Regular expressions the output must not match",
+										"type": "array",
+										"items": {"type": "string"}
+									},
+									"forbidden_words": {
+										"description": "Case-insensitive words the output must not contain",
+										"type": "array",
+										"items": {"type": "string"}
+									}
+								}
+							},
+							"max_reasks": {
+								"description": "Re-asks of the guards violations before the block fails",
+								"type": "integer",
+								"minimum": 0,
+								"default": %d
+							},
+							"reask_messages": {
+								"description": "Rejected answers and their violations, filled by the worker on re-ask",
+								"type": "array"
 							}
 						},
-						"required": ["user_prompt"]
+						"anyOf": [
+							{"required": ["user_prompt"]},
+							{"required": ["messages"]}
+						]
 					},
 					"output": {
 						"description": "OpenAI Completion output",
@@ -196,9 +635,9 @@ func NewBlockOpenAIRequestCompletion() *BlockOpenAIRequestCompletion {
 				"required": ["input"]
 			}`,
 			defaultBlockConfig.Model,
-			defaultBlockConfig.Model,
 			defaultBlockConfig.SystemPrompt,
 			defaultBlockConfig.UserPrompt,
+			defaultBlockConfig.MaxReAsks,
 		),
 	)
 
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
-	"errors"
 	"log"
 	"time"
 
@@ -47,12 +46,12 @@ func (p *ProcessorOpenAIRequestImage) Process(
 	helpers.MapToJSONStruct(_data, userBlockConfig)
 	helpers.MergeStructs(defaultBlockConfig, userBlockConfig, blockConfig)
 
-	client := _config.OpenAI.GetClient()
-	if client == nil {
-		return output, false, false, "", -1, errors.New("openAI client is not configured")
+	provider, err := getLLMProvider(blockConfig.Credentials)
+	if err != nil {
+		return output, false, false, "", -1, err
 	}
 
-	resp, err := client.CreateImage(
+	resp, err := provider.CreateImage(
 		ctx,
 		openai.ImageRequest{
 			Prompt:         blockConfig.Prompt,
@@ -78,9 +77,10 @@ func (p *ProcessorOpenAIRequestImage) Process(
 }
 
 type BlockOpenAIRequestImageConfig struct {
-	Prompt  string `yaml:"prompt" json:"prompt"`
-	Quality string `yaml:"quality" json:"quality"`
-	Size    string `yaml:"size" json:"size"`
+	Credentials string `yaml:"credentials" json:"credentials"`
+	Prompt      string `yaml:"prompt" json:"prompt"`
+	Quality     string `yaml:"quality" json:"quality"`
+	Size        string `yaml:"size" json:"size"`
 }
 
 type BlockOpenAIRequestImage struct {
@@ -90,6 +90,11 @@ type BlockOpenAIRequestImage struct {
 
 var _ interfaces.Block = (*BlockOpenAIRequestImage)(nil)
 
+// IsNonDeterministic reports that the invalid outputs of the sampled responses are retried
+func (b *BlockOpenAIRequestImage) IsNonDeterministic() bool {
+	return true
+}
+
 func (b *BlockOpenAIRequestImage) GetBlockConfig(_config config.Config) *BlockOpenAIRequestImageConfig {
 	blockConfig := _config.Blocks[b.GetId()].Config
 
@@ -99,6 +104,18 @@ func (b *BlockOpenAIRequestImage) GetBlockConfig(_config config.Config) *BlockOp
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "openai_image_request",
+		NewBlock: func() interfaces.Block {
+			return NewBlockOpenAIRequestImage()
+		},
+		NewDetector: func(_config config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorOpenAI(_config.OpenAI, detectorConfig)
+		},
+	})
+}
+
 func NewBlockOpenAIRequestImage() *BlockOpenAIRequestImage {
 	block := &BlockOpenAIRequestImage{
 		BlockParent: BlockParent{
@@ -113,6 +130,11 @@ func NewBlockOpenAIRequestImage() *BlockOpenAIRequestImage {
 						"type": "object",
 						"description": "Input parameters",
 						"properties": {
+							"credentials": {
+								"description": "Credentials profile of the OpenAI integration",
+								"type": "string",
+								"default": "default"
+							},
 							"prompt": {
 								"description": "Prompt for the image",
 								"type": "string",
@@ -136,7 +158,8 @@ func NewBlockOpenAIRequestImage() *BlockOpenAIRequestImage {
 					"output": {
 						"description": "OpenAI Image output",
 						"type": "string",
-						"format": "file"
+						"format": "file",
+						"contentMediaType": "image/*"
 					}
 				},
 				"required": ["input"]
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"time"
 
@@ -47,9 +46,9 @@ func (p *ProcessorOpenAIRequestTranscription) Process(
 	helpers.MapToJSONStruct(_data, userBlockConfig)
 	helpers.MergeStructs(defaultBlockConfig, userBlockConfig, blockConfig)
 
-	client := _config.OpenAI.GetClient()
-	if client == nil {
-		return output, false, false, "", -1, errors.New("openAI client is not configured")
+	provider, err := getLLMProvider(blockConfig.Credentials)
+	if err != nil {
+		return output, false, false, "", -1, err
 	}
 
 	audioBytes, err := helpers.GetValue[[]byte](_data, "audio")
@@ -57,7 +56,7 @@ func (p *ProcessorOpenAIRequestTranscription) Process(
 		return nil, false, false, "", -1, err
 	}
 
-	resp, err := client.CreateTranscription(
+	resp, err := provider.CreateTranscription(
 		ctx,
 		openai.AudioRequest{
 			Model:    blockConfig.Model,
@@ -83,9 +82,10 @@ func (p *ProcessorOpenAIRequestTranscription) Process(
 }
 
 type BlockOpenAIRequestTranscriptionConfig struct {
-	Model    string                     `yaml:"model" json:"model"`
-	Language string                     `yaml:"language" json:"language"`
-	Format   openai.AudioResponseFormat `yaml:"format" json:"format"`
+	Credentials string                     `yaml:"credentials" json:"credentials"`
+	Model       string                     `yaml:"model" json:"model"`
+	Language    string                     `yaml:"language" json:"language"`
+	Format      openai.AudioResponseFormat `yaml:"format" json:"format"`
 }
 
 type BlockOpenAIRequestTranscription struct {
@@ -104,6 +104,18 @@ func (b *BlockOpenAIRequestTranscription) GetBlockConfig(_config config.Config)
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "openai_transcription_request",
+		NewBlock: func() interfaces.Block {
+			return NewBlockOpenAIRequestTranscription()
+		},
+		NewDetector: func(_config config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorOpenAI(_config.OpenAI, detectorConfig)
+		},
+	})
+}
+
 func NewBlockOpenAIRequestTranscription() *BlockOpenAIRequestTranscription {
 	block := &BlockOpenAIRequestTranscription{
 		BlockParent: BlockParent{
@@ -118,6 +130,11 @@ func NewBlockOpenAIRequestTranscription() *BlockOpenAIRequestTranscription {
 						"type": "object",
 						"description": "Input parameters",
 						"properties": {
+							"credentials": {
+								"description": "Credentials profile of the OpenAI integration",
+								"type": "string",
+								"default": "default"
+							},
 							"audio": {
 								"description": "Audio file to transcribe",
 								"type": "string",
@@ -126,8 +143,7 @@ func NewBlockOpenAIRequestTranscription() *BlockOpenAIRequestTranscription {
 							"model": {
 								"description": "Model to use",
 								"type": "string",
-								"default": "whisper-1",
-								"enum": ["whisper-1"]
+								"default": "whisper-1"
 							},
 							"format": {
 								"description": "Response format",
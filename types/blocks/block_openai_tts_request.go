@@ -3,7 +3,6 @@ package blocks
 import (
 	"bytes"
 	"context"
-	"errors"
 	"io"
 	"time"
 
@@ -46,11 +45,11 @@ func (p *ProcessorOpenAIRequestTTS) Process(
 	helpers.MapToJSONStruct(_data, userBlockConfig)
 	helpers.MergeStructs(defaultBlockConfig, userBlockConfig, blockConfig)
 
-	client := _config.OpenAI.GetClient()
-	if client == nil {
-		return output, false, false, "", -1, errors.New("openAI client is not configured")
+	provider, err := getLLMProvider(blockConfig.Credentials)
+	if err != nil {
+		return output, false, false, "", -1, err
 	}
-	resp, err := client.CreateSpeech(
+	resp, err := provider.CreateSpeech(
 		ctx,
 		openai.CreateSpeechRequest{
 			Model:          blockConfig.Model,
@@ -76,6 +75,7 @@ func (p *ProcessorOpenAIRequestTTS) Process(
 }
 
 type BlockOpenAIRequestTTSConfig struct {
+	Credentials    string                      `yaml:"credentials" json:"credentials"`
 	Model          openai.SpeechModel          `yaml:"model" json:"model"`
 	Text           string                      `yaml:"text" json:"text"`
 	Voice          openai.SpeechVoice          `yaml:"voice" json:"voice"`
@@ -99,6 +99,18 @@ func (b *BlockOpenAIRequestTTS) GetBlockConfig(_config config.Config) *BlockOpen
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "openai_tts_request",
+		NewBlock: func() interfaces.Block {
+			return NewBlockOpenAIRequestTTS()
+		},
+		NewDetector: func(_config config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorOpenAI(_config.OpenAI, detectorConfig)
+		},
+	})
+}
+
 func NewBlockOpenAIRequestTTS() *BlockOpenAIRequestTTS {
 	block := &BlockOpenAIRequestTTS{
 		BlockParent: BlockParent{
@@ -113,11 +125,15 @@ func NewBlockOpenAIRequestTTS() *BlockOpenAIRequestTTS {
 						"type": "object",
 						"description": "Input parameters",
 						"properties": {
+							"credentials": {
+								"description": "Credentials profile of the OpenAI integration",
+								"type": "string",
+								"default": "default"
+							},
 							"model": {
 								"description": "Model to use",
 								"type": "string",
-								"default": "tts-1",
-								"enum": ["tts-1"]
+								"default": "tts-1"
 							},
 							"text": {
 								"description": "Text to convert to audio",
@@ -0,0 +1,135 @@
+package blocks
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/plugins"
+)
+
+type DetectorPlugin struct {
+	BlockDetectorParent
+
+	Plugin plugins.Plugin
+}
+
+func NewDetectorPlugin(
+	plugin plugins.Plugin,
+	detectorConfig config.BlockConfigDetector,
+) *DetectorPlugin {
+	return &DetectorPlugin{
+		BlockDetectorParent: NewDetectorParent(detectorConfig),
+		Plugin:              plugin,
+	}
+}
+
+func (d *DetectorPlugin) Detect() bool {
+	d.Lock()
+	defer d.Unlock()
+
+	available, err := d.Plugin.Detect(context.Background())
+	if err != nil {
+		config.GetLogger().Warnf("Plugin %s detection failed: %s", d.Plugin.GetName(), err)
+		return false
+	}
+
+	return available
+}
+
+type ProcessorPlugin struct {
+}
+
+func NewProcessorPlugin() *ProcessorPlugin {
+	return &ProcessorPlugin{}
+}
+
+func (p *ProcessorPlugin) GetRetryCount(_ interfaces.Block) int {
+	return 0
+}
+
+func (p *ProcessorPlugin) GetRetryInterval(_ interfaces.Block) time.Duration {
+	return 0
+}
+
+func (p *ProcessorPlugin) Process(
+	ctx context.Context,
+	block interfaces.Block,
+	data interfaces.ProcessableBlockData,
+) ([]*bytes.Buffer, bool, bool, string, int, error) {
+	logger := config.GetLogger()
+
+	_data := data.GetInputData().(map[string]interface{})
+
+	output, result, err := block.(*BlockPlugin).Plugin.Process(ctx, _data)
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			logger.Errorf("Plugin processing was cancelled for block %s", data.GetSlug())
+			return nil, false, false, "", -1, ctx.Err()
+		}
+		if result != nil {
+			return output, false, result.Retry, "", -1, err
+		}
+		return nil, false, false, "", -1, err
+	}
+
+	return output, result.StopPipeline, result.Retry, "", -1, nil
+}
+
+// BlockPlugin is the block of an external plugin or a WebAssembly module described by the plugin itself
+type BlockPlugin struct {
+	BlockParent
+
+	Plugin plugins.Plugin `json:"-"`
+}
+
+var _ interfaces.Block = (*BlockPlugin)(nil)
+
+// NewBlockPlugin describes the plugin and applies the schema of its block
+func NewBlockPlugin(plugin plugins.Plugin) (*BlockPlugin, error) {
+	description, err := plugin.Describe(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	block := &BlockPlugin{
+		BlockParent: BlockParent{
+			Id:          description.Id,
+			Name:        description.Name,
+			Description: description.Description,
+			Version:     description.Version,
+			Deprecated:  description.Deprecated,
+			SchemaPtr:   nil,
+			Schema:      nil,
+		},
+		Plugin: plugin,
+	}
+
+	block.SetSchemaString(string(description.Schema))
+	if err := block.ApplySchema(block.GetSchemaString()); err != nil {
+		return nil, err
+	}
+
+	block.SetProcessor(NewProcessorPlugin())
+
+	return block, nil
+}
+
+// NewBlockExternalPlugin returns the block of the plugin running out of the worker process
+func NewBlockExternalPlugin(pluginConfig config.ExternalPluginConfig) (*BlockPlugin, error) {
+	client, err := plugins.NewClient(pluginConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBlockPlugin(client)
+}
+
+// GetDetectorConfig returns the detector config of the plugin check interval
+func (b *BlockPlugin) GetDetectorConfig() config.BlockConfigDetector {
+	return config.BlockConfigDetector{
+		CheckInterval: b.Plugin.GetCheckInterval(),
+	}
+}
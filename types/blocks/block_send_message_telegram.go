@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"image"
 	"time"
@@ -70,9 +69,9 @@ func (p *ProcessorSendMessageToTelegram) Process(
 	helpers.MapToJSONStruct(_data, userBlockConfig)
 	helpers.MergeStructs(defaultBlockConfig, userBlockConfig, blockConfig)
 
-	client := _config.Telegram.GetClient()
-	if client == nil {
-		return output, false, false, "", -1, errors.New("telegram client is not configured")
+	client, err := getTelegramClient(_config, blockConfig.Credentials)
+	if err != nil {
+		return output, false, false, "", -1, err
 	}
 
 	message := TelegramMessage{
@@ -84,7 +83,6 @@ func (p *ProcessorSendMessageToTelegram) Process(
 
 	// Initialize a variable for the sent message and error handling
 	var sentMessage tgbotapi.Message
-	var err error
 
 	// Attempt to retrieve and decode the image
 	imageBytes, imgErr := helpers.GetValue[[]byte](_data, "image")
@@ -101,7 +99,7 @@ func (p *ProcessorSendMessageToTelegram) Process(
 			}
 			photo := tgbotapi.NewPhoto(blockConfig.GroupId, imgFile)
 			photo.Caption = GenerateTelegramMessage(message)
-			sentMessage, err = client.Send(photo)
+			sentMessage, err = client.Send(ctx, photo)
 		}
 	}
 
@@ -114,7 +112,7 @@ func (p *ProcessorSendMessageToTelegram) Process(
 			}
 			video := tgbotapi.NewVideo(blockConfig.GroupId, videoFile)
 			video.Caption = GenerateTelegramMessage(message)
-			sentMessage, err = client.Send(video)
+			sentMessage, err = client.Send(ctx, video)
 		}
 	}
 
@@ -124,7 +122,7 @@ func (p *ProcessorSendMessageToTelegram) Process(
 			blockConfig.GroupId,
 			GenerateTelegramMessage(message),
 		)
-		sentMessage, err = client.Send(msg)
+		sentMessage, err = client.Send(ctx, msg)
 	}
 
 	if err != nil {
@@ -143,8 +141,9 @@ func (p *ProcessorSendMessageToTelegram) Process(
 }
 
 type BlockSendMessageToTelegramConfig struct {
-	Text    string `yaml:"-" json:"text"`
-	GroupId int64  `yaml:"group_id" json:"group_id"`
+	Credentials string `yaml:"credentials" json:"credentials"`
+	Text        string `yaml:"-" json:"text"`
+	GroupId     int64  `yaml:"group_id" json:"group_id"`
 }
 
 type BlockSendMessageToTelegram struct {
@@ -163,6 +162,18 @@ func (b *BlockSendMessageToTelegram) GetBlockConfig(_config config.Config) *Bloc
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "send_message_tg",
+		NewBlock: func() interfaces.Block {
+			return NewBlockSendMessageToTelegram()
+		},
+		NewDetector: func(_config config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorTelegramBot(_config.Telegram, detectorConfig)
+		},
+	})
+}
+
 func NewBlockSendMessageToTelegram() *BlockSendMessageToTelegram {
 	block := &BlockSendMessageToTelegram{
 		BlockParent: BlockParent{
@@ -177,6 +188,11 @@ func NewBlockSendMessageToTelegram() *BlockSendMessageToTelegram {
 						"type": "object",
 						"description": "Input parameters",
 						"properties": {
+							"credentials": {
+								"description": "Credentials profile of the Telegram integration",
+								"type": "string",
+								"default": "default"
+							},
 							"text": {
 								"description": "Text content",
 								"type": "string"
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"image"
 	"regexp"
@@ -19,6 +18,7 @@ import (
 	"data-pipelines-worker/types/generics"
 	"data-pipelines-worker/types/helpers"
 	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/limiter"
 )
 
 type TelegramReviewMessage struct {
@@ -101,26 +101,55 @@ func ParseTelegramReviewMessage(message string) (TelegramReviewMessage, error) {
 type DetectorTelegramBot struct {
 	BlockDetectorParent
 
-	Client *tgbotapi.BotAPI
+	Config *config.TelegramConfig
+
+	profilesAvailability map[string]bool
 }
 
-func NewDetectorTelegramBot(client *tgbotapi.BotAPI, detectorConfig config.BlockConfigDetector) *DetectorTelegramBot {
+var _ interfaces.BlockProfilesDetector = (*DetectorTelegramBot)(nil)
+
+func NewDetectorTelegramBot(telegramConfig *config.TelegramConfig, detectorConfig config.BlockConfigDetector) *DetectorTelegramBot {
 	return &DetectorTelegramBot{
-		BlockDetectorParent: NewDetectorParent(detectorConfig),
-		Client:              client,
+		BlockDetectorParent:  NewDetectorParent(detectorConfig),
+		Config:               telegramConfig,
+		profilesAvailability: make(map[string]bool),
 	}
 }
 
+// Detect checks the bot of every credentials profile. The block is available with any of them
 func (d *DetectorTelegramBot) Detect() bool {
 	d.Lock()
 	defer d.Unlock()
 
-	if d.Client == nil {
-		return false
+	available := false
+	profilesAvailability := make(map[string]bool)
+	for _, profileName := range d.Config.GetProfileNames() {
+		profile, _ := d.Config.GetProfile(profileName)
+
+		client := profile.GetClient()
+		if client == nil {
+			profilesAvailability[profileName] = false
+			continue
+		}
+
+		_, err := client.GetMe()
+		profilesAvailability[profileName] = err == nil
+		available = available || err == nil
 	}
+	d.profilesAvailability = profilesAvailability
 
-	_, err := d.Client.GetMe()
-	return err == nil
+	return available
+}
+
+func (d *DetectorTelegramBot) GetProvider() string {
+	return limiter.PROVIDER_TELEGRAM
+}
+
+func (d *DetectorTelegramBot) GetProfilesAvailability() map[string]bool {
+	d.Lock()
+	defer d.Unlock()
+
+	return d.profilesAvailability
 }
 
 type ProcessorSendModerationToTelegram struct {
@@ -159,9 +188,9 @@ func (p *ProcessorSendModerationToTelegram) Process(
 	helpers.MapToJSONStruct(_data, userBlockConfig)
 	helpers.MergeStructs(defaultBlockConfig, userBlockConfig, blockConfig)
 
-	client := _config.Telegram.GetClient()
-	if client == nil {
-		return output, false, false, "", -1, errors.New("telegram client is not configured")
+	client, err := getTelegramClient(_config, blockConfig.Credentials)
+	if err != nil {
+		return output, false, false, "", -1, err
 	}
 
 	review := TelegramReviewMessage{
@@ -208,7 +237,6 @@ func (p *ProcessorSendModerationToTelegram) Process(
 
 	// Initialize a variable for the sent message and error handling
 	var sentMessage tgbotapi.Message
-	var err error
 
 	// Attempt to retrieve and decode the image
 	imageBytes, imgErr := helpers.GetValue[[]byte](_data, "image")
@@ -225,7 +253,7 @@ func (p *ProcessorSendModerationToTelegram) Process(
 			photo := tgbotapi.NewPhoto(blockConfig.GroupId, imgFile)
 			photo.Caption = GenerateTelegramReviewMessage(review)
 			photo.ReplyMarkup = keyboard
-			sentMessage, err = client.Send(photo)
+			sentMessage, err = client.Send(ctx, photo)
 		} else {
 			// If decoding failed, fallback to text message
 			err = decodeErr
@@ -239,7 +267,7 @@ func (p *ProcessorSendModerationToTelegram) Process(
 			GenerateTelegramReviewMessage(review),
 		)
 		msg.ReplyMarkup = keyboard
-		sentMessage, err = client.Send(msg)
+		sentMessage, err = client.Send(ctx, msg)
 	}
 
 	if err != nil {
@@ -259,6 +287,7 @@ func (p *ProcessorSendModerationToTelegram) Process(
 }
 
 type BlockSendModerationToTelegramConfig struct {
+	Credentials         string            `yaml:"credentials" json:"credentials"`
 	Text                string            `yaml:"-" json:"text"`
 	GroupId             int64             `yaml:"group_id" json:"group_id"`
 	RegenerateBlockSlug string            `yaml:"-" json:"regenerate_block_slug"`
@@ -284,6 +313,18 @@ func (b *BlockSendModerationToTelegram) GetBlockConfig(_config config.Config) *B
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "send_moderation_tg",
+		NewBlock: func() interfaces.Block {
+			return NewBlockSendModerationToTelegram()
+		},
+		NewDetector: func(_config config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorTelegramBot(_config.Telegram, detectorConfig)
+		},
+	})
+}
+
 func NewBlockSendModerationToTelegram() *BlockSendModerationToTelegram {
 	block := &BlockSendModerationToTelegram{
 		BlockParent: BlockParent{
@@ -298,6 +339,11 @@ func NewBlockSendModerationToTelegram() *BlockSendModerationToTelegram {
 						"type": "object",
 						"description": "Input parameters",
 						"properties": {
+							"credentials": {
+								"description": "Credentials profile of the Telegram integration",
+								"type": "string",
+								"default": "default"
+							},
 							"text": {
 								"description": "Text content to be moderated",
 								"type": "string",
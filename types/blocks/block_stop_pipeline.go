@@ -89,6 +89,18 @@ func (b *BlockStopPipeline) GetBlockConfig(_config config.Config) *BlockStopPipe
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "stop_pipeline",
+		NewBlock: func() interfaces.Block {
+			return NewBlockStopPipeline()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorStopPipeline(detectorConfig)
+		},
+	})
+}
+
 func NewBlockStopPipeline() *BlockStopPipeline {
 	block := &BlockStopPipeline{
 		BlockParent: BlockParent{
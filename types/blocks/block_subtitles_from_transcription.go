@@ -280,6 +280,18 @@ func (b *BlockSubtitlesFromTranscription) GetBlockConfig(_config config.Config)
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "subtitles_from_transcription",
+		NewBlock: func() interfaces.Block {
+			return NewBlockSubtitlesFromTranscription()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorSubtitlesFromTranscription(detectorConfig)
+		},
+	})
+}
+
 func NewBlockSubtitlesFromTranscription() *BlockSubtitlesFromTranscription {
 	block := &BlockSubtitlesFromTranscription{
 		BlockParent: BlockParent{
@@ -102,6 +102,18 @@ func (b *BlockTextReplace) GetBlockConfig(_config config.Config) *BlockTextRepla
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "text_replace",
+		NewBlock: func() interfaces.Block {
+			return NewBlockTextReplace()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorTextReplace(detectorConfig)
+		},
+	})
+}
+
 func NewBlockTextReplace() *BlockTextReplace {
 	block := &BlockTextReplace{
 		BlockParent: BlockParent{
@@ -96,6 +96,18 @@ func (b *BlockUploadFile) GetBlockConfig(_config config.Config) *BlockUploadFile
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "upload_file",
+		NewBlock: func() interfaces.Block {
+			return NewBlockUploadFile()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorUploadFile(detectorConfig)
+		},
+	})
+}
+
 func NewBlockUploadFile() *BlockUploadFile {
 	block := &BlockUploadFile{
 		BlockParent: BlockParent{
@@ -183,6 +183,18 @@ func (b *BlockVideoAddAudio) GetBlockConfig(_config config.Config) *BlockVideoAd
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "video_add_audio",
+		NewBlock: func() interfaces.Block {
+			return NewBlockVideoAddAudio()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorVideoAddAudio(detectorConfig)
+		},
+	})
+}
+
 func NewBlockVideoAddAudio() *BlockVideoAddAudio {
 	block := &BlockVideoAddAudio{
 		BlockParent: BlockParent{
@@ -218,7 +230,8 @@ func NewBlockVideoAddAudio() *BlockVideoAddAudio {
 					"output": {
 						"description": "Video with added Audio",
 						"type": ["string", "null"],
-						"format": "file"
+						"format": "file",
+						"contentMediaType": "video/*"
 					}
 				}
 			}`,
@@ -205,6 +205,18 @@ func (b *BlockVideoAddSubtitles) GetBlockConfig(_config config.Config) *BlockVid
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "video_add_subtitles",
+		NewBlock: func() interfaces.Block {
+			return NewBlockVideoAddSubtitles()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorVideoAddSubtitles(detectorConfig)
+		},
+	})
+}
+
 func NewBlockVideoAddSubtitles() *BlockVideoAddSubtitles {
 	block := &BlockVideoAddSubtitles{
 		BlockParent: BlockParent{
@@ -241,7 +253,8 @@ func NewBlockVideoAddSubtitles() *BlockVideoAddSubtitles {
 					"output": {
 						"description": "Video with added Audio",
 						"type": ["string", "null"],
-						"format": "file"
+						"format": "file",
+						"contentMediaType": "video/*"
 					}
 				}
 			}`,
@@ -164,6 +164,18 @@ func (b *BlockVideoFromImage) GetBlockConfig(_config config.Config) *BlockVideoF
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "video_from_image",
+		NewBlock: func() interfaces.Block {
+			return NewBlockVideoFromImage()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorVideoFromImage(detectorConfig)
+		},
+	})
+}
+
 func NewBlockVideoFromImage() *BlockVideoFromImage {
 	block := &BlockVideoFromImage{
 		BlockParent: BlockParent{
@@ -229,7 +241,8 @@ func NewBlockVideoFromImage() *BlockVideoFromImage {
 					"output": {
 						"description": "Video generated from image",
 						"type": ["string", "null"],
-						"format": "file"
+						"format": "file",
+						"contentMediaType": "video/*"
 					}
 				}
 			}`,
@@ -95,6 +95,20 @@ func (b *BlockTextAddPrefixOrSuffix) GetBlockConfig(_config config.Config) *Bloc
 	return defaultBlockConfig
 }
 
+func init() {
+	RegisterBlock(BlockRegistration{
+		Id: "wrap_text",
+		// Id of the block by its name
+		Aliases: []string{"text_add_prefix_or_suffix"},
+		NewBlock: func() interfaces.Block {
+			return NewBlockTextAddPrefixOrSuffix()
+		},
+		NewDetector: func(_ config.Config, detectorConfig config.BlockConfigDetector) interfaces.BlockDetector {
+			return NewDetectorTextAddPrefixOrSuffix(detectorConfig)
+		},
+	})
+}
+
 func NewBlockTextAddPrefixOrSuffix() *BlockTextAddPrefixOrSuffix {
 	block := &BlockTextAddPrefixOrSuffix{
 		BlockParent: BlockParent{
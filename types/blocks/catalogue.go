@@ -0,0 +1,48 @@
+package blocks
+
+import (
+	"slices"
+	"sort"
+	"sync"
+
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/interfaces"
+)
+
+// BlockRegistration is the entry of a built-in block in the catalogue.
+// The version is the one of the constructed block
+type BlockRegistration struct {
+	Id string
+	// Ids the block is also known by in the pipelines
+	Aliases []string
+
+	NewBlock    func() interfaces.Block
+	NewDetector func(config.Config, config.BlockConfigDetector) interfaces.BlockDetector
+}
+
+var (
+	catalogue     = make([]BlockRegistration, 0)
+	catalogueLock sync.Mutex
+)
+
+// RegisterBlock adds the block to the catalogue, the blocks register themselves on init.
+// Every version of the block id has its own registration
+func RegisterBlock(registration BlockRegistration) {
+	catalogueLock.Lock()
+	defer catalogueLock.Unlock()
+
+	catalogue = append(catalogue, registration)
+}
+
+// GetBlockRegistrations returns the registrations of the catalogue ordered by id
+func GetBlockRegistrations() []BlockRegistration {
+	catalogueLock.Lock()
+	defer catalogueLock.Unlock()
+
+	registrations := slices.Clone(catalogue)
+	sort.SliceStable(registrations, func(i, j int) bool {
+		return registrations[i].Id < registrations[j].Id
+	})
+
+	return registrations
+}
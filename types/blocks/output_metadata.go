@@ -0,0 +1,93 @@
+package blocks
+
+import (
+	"bytes"
+	"context"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+	"sync"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types/helpers"
+)
+
+type outputMetadataRecorderContextKey struct{}
+
+// OutputMetadataRecorder collects the metadata the processors know of their outputs
+type OutputMetadataRecorder struct {
+	sync.Mutex
+
+	metadata map[int]schemas.OutputMetadataSchema
+}
+
+// WithOutputMetadataRecorder returns the context the processors record the metadata of their outputs to
+func WithOutputMetadataRecorder(ctx context.Context) (context.Context, *OutputMetadataRecorder) {
+	recorder := &OutputMetadataRecorder{
+		metadata: make(map[int]schemas.OutputMetadataSchema),
+	}
+
+	return context.WithValue(ctx, outputMetadataRecorderContextKey{}, recorder), recorder
+}
+
+// RecordOutputMetadata records the metadata of the output with the index to the recorder of the context, if any
+func RecordOutputMetadata(ctx context.Context, outputIndex int, metadata schemas.OutputMetadataSchema) {
+	recorder, ok := ctx.Value(outputMetadataRecorderContextKey{}).(*OutputMetadataRecorder)
+	if !ok {
+		return
+	}
+
+	recorder.Lock()
+	defer recorder.Unlock()
+
+	recorder.metadata[outputIndex] = metadata.Merge(recorder.metadata[outputIndex])
+}
+
+// GetRecordedOutputMetadata returns the metadata of the outputs recorded to the recorder of the context, if any
+func GetRecordedOutputMetadata(ctx context.Context, outputsCount int) []schemas.OutputMetadataSchema {
+	recorder, ok := ctx.Value(outputMetadataRecorderContextKey{}).(*OutputMetadataRecorder)
+	if !ok {
+		return make([]schemas.OutputMetadataSchema, outputsCount)
+	}
+
+	return recorder.GetMetadata(outputsCount)
+}
+
+// GetMetadata returns the metadata of the outputs, empty for the outputs without any recorded
+func (r *OutputMetadataRecorder) GetMetadata(outputsCount int) []schemas.OutputMetadataSchema {
+	r.Lock()
+	defer r.Unlock()
+
+	metadata := make([]schemas.OutputMetadataSchema, outputsCount)
+	for outputIndex, outputMetadata := range r.metadata {
+		if outputIndex >= 0 && outputIndex < outputsCount {
+			metadata[outputIndex] = outputMetadata
+		}
+	}
+
+	return metadata
+}
+
+// CompleteOutputMetadata completes the metadata recorded by the block with the content type
+// and the size of the images detected from the output
+func CompleteOutputMetadata(output *bytes.Buffer, metadata schemas.OutputMetadataSchema) schemas.OutputMetadataSchema {
+	if output == nil || output.Len() == 0 {
+		return metadata
+	}
+
+	if metadata.ContentType == "" {
+		if mimeType, err := helpers.DetectMimeTypeFromBuffer(*output); err == nil {
+			metadata.ContentType = mimeType.String()
+		}
+	}
+
+	if metadata.Width == 0 && metadata.Height == 0 && strings.HasPrefix(metadata.ContentType, "image/") {
+		if imageConfig, _, err := image.DecodeConfig(bytes.NewReader(output.Bytes())); err == nil {
+			metadata.Width, metadata.Height = imageConfig.Width, imageConfig.Height
+		}
+	}
+
+	return metadata
+}
@@ -0,0 +1,194 @@
+package blocks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/xeipuuv/gojsonschema"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/helpers"
+)
+
+const MIME_TYPE_JSON = "application/json"
+
+var ErrInvalidOutput = errors.New("invalid block output")
+
+// IsOutputValidationEnabled reports whether the outputs of the block are validated,
+// by the block config or else by the pipeline config
+func IsOutputValidationEnabled(_config config.Config, id string) bool {
+	if validateOutput := _config.Blocks[id].ValidateOutput; validateOutput != nil {
+		return *validateOutput
+	}
+
+	return _config.Pipeline.ValidateBlockOutputs
+}
+
+// GetOutputValidationRetryCount returns the times the invalid outputs of the non-deterministic block
+// are retried, by the block config or else by the pipeline config
+func GetOutputValidationRetryCount(_config config.Config, id string) int {
+	if retries := _config.Blocks[id].OutputValidationRetries; retries != nil {
+		return *retries
+	}
+
+	return _config.Pipeline.OutputValidationRetries
+}
+
+// ValidateOutput checks every output against the `output` of the block schema. Array outputs
+// are checked item by item against the `items`:
+//   - `format: file` outputs are not empty and match the `contentMediaType`, e.g. `image/*`, if any,
+//     by the content type declared in the output metadata or else detected from the content
+//   - `contentMediaType: application/json` outputs are JSON valid for the `contentSchema`, if any
+//   - other outputs are not empty, unless the type allows null
+func (b *BlockParent) ValidateOutput(output []*bytes.Buffer, outputMetadata ...[]schemas.OutputMetadataSchema) error {
+	schema := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(b.GetSchemaString()), &schema); err != nil {
+		return err
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	outputSchema, ok := properties["output"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if items, ok := outputSchema["items"].(map[string]interface{}); ok && hasSchemaType(outputSchema, "array") {
+		outputSchema = items
+	}
+	if isOnlySchemaType(outputSchema, "null") {
+		return nil
+	}
+
+	for index, buffer := range output {
+		metadata := schemas.OutputMetadataSchema{}
+		if len(outputMetadata) > 0 && index < len(outputMetadata[0]) {
+			metadata = outputMetadata[0][index]
+		}
+		if err := validateOutputBuffer(outputSchema, buffer, metadata); err != nil {
+			return fmt.Errorf("block (%s) output #%d is invalid: %w", b.GetId(), index, err)
+		}
+	}
+
+	return nil
+}
+
+func validateOutputBuffer(
+	outputSchema map[string]interface{},
+	buffer *bytes.Buffer,
+	metadata schemas.OutputMetadataSchema,
+) error {
+	if buffer == nil || buffer.Len() == 0 {
+		if outputSchema["format"] != "file" && hasSchemaType(outputSchema, "null") {
+			return nil
+		}
+		return fmt.Errorf("output is empty")
+	}
+
+	mediaType, _ := outputSchema["contentMediaType"].(string)
+	if mediaType == MIME_TYPE_JSON {
+		return validateOutputJSON(outputSchema, buffer)
+	}
+	if mediaType != "" && outputSchema["format"] == "file" {
+		if contentType, _, err := mime.ParseMediaType(metadata.ContentType); err == nil {
+			if !isContentTypeMatching(contentType, mediaType) {
+				return fmt.Errorf("output of %s type is not %s", contentType, mediaType)
+			}
+			return nil
+		}
+
+		mimeType, err := helpers.DetectMimeTypeFromBuffer(*buffer)
+		if err != nil {
+			return err
+		}
+		if !isMimeTypeMatching(mimeType, mediaType) {
+			return fmt.Errorf("output of %s type is not %s", mimeType.String(), mediaType)
+		}
+	}
+
+	return nil
+}
+
+func validateOutputJSON(outputSchema map[string]interface{}, buffer *bytes.Buffer) error {
+	var value interface{}
+	if err := json.Unmarshal(buffer.Bytes(), &value); err != nil {
+		return fmt.Errorf("output is not JSON: %w", err)
+	}
+
+	contentSchema, ok := outputSchema["contentSchema"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	result, err := gojsonschema.Validate(
+		gojsonschema.NewGoLoader(contentSchema),
+		gojsonschema.NewGoLoader(value),
+	)
+	if err != nil {
+		return err
+	}
+	if !result.Valid() {
+		violations := make([]string, 0, len(result.Errors()))
+		for _, violation := range result.Errors() {
+			violations = append(violations, violation.String())
+		}
+		return fmt.Errorf("output does not match the schema: %s", strings.Join(violations, "; "))
+	}
+
+	return nil
+}
+
+// isMimeTypeMatching matches the MIME type or its parents with the media type, `type/*` matches any subtype
+func isMimeTypeMatching(mime *mimetype.MIME, mediaType string) bool {
+	for ; mime != nil; mime = mime.Parent() {
+		if strings.HasSuffix(mediaType, "/*") && strings.HasPrefix(mime.String(), strings.TrimSuffix(mediaType, "*")) {
+			return true
+		}
+		if mime.Is(mediaType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isContentTypeMatching matches the declared content type with the media type, known types match by their parents too
+func isContentTypeMatching(contentType string, mediaType string) bool {
+	if mime := mimetype.Lookup(contentType); mime != nil {
+		return isMimeTypeMatching(mime, mediaType)
+	}
+	if strings.HasSuffix(mediaType, "/*") {
+		return strings.HasPrefix(contentType, strings.TrimSuffix(mediaType, "*"))
+	}
+
+	return strings.EqualFold(contentType, mediaType)
+}
+
+func hasSchemaType(schema map[string]interface{}, schemaType string) bool {
+	switch types := schema["type"].(type) {
+	case string:
+		return types == schemaType
+	case []interface{}:
+		for _, _type := range types {
+			if _type == schemaType {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func isOnlySchemaType(schema map[string]interface{}, schemaType string) bool {
+	switch types := schema["type"].(type) {
+	case string:
+		return types == schemaType
+	case []interface{}:
+		return len(types) == 1 && types[0] == schemaType
+	}
+
+	return false
+}
@@ -19,6 +19,17 @@ import (
 const (
 	CONFIG_FILE             = "config/config.yaml"
 	PIPELINES_CATALOGUE_DIR = "config/pipelines"
+
+	DEFAULT_TRIGGERS_CHECK_INTERVAL  = 10 * time.Second
+	DEFAULT_WEBHOOKS_TIMEOUT         = 10 * time.Second
+	DEFAULT_WEBHOOKS_RETRY_DELAY     = time.Second
+	DEFAULT_RETENTION_CHECK_INTERVAL = time.Hour
+	DEFAULT_CATALOGUE_RELOAD_DELAY   = 500 * time.Millisecond
+	DEFAULT_CATALOGUE_POLL_INTERVAL  = 30 * time.Second
+	DEFAULT_PROCESSING_INDEX_FILE    = "data-pipelines-worker/processings.db"
+	DEFAULT_PROCESSING_INDEX_REFRESH = time.Minute
+	DEFAULT_BLOCK_CHECK_INTERVAL     = time.Minute
+	DEFAULT_BLOCK_RELIABILITY_POLICY = "none"
 )
 
 var (
@@ -42,14 +53,22 @@ func GetConfig(forceNewInstance ...bool) Config {
 }
 
 type Config struct {
-	Log           LogConfig       `yaml:"log" json:"-"`
-	Swagger       bool            `yaml:"swagger" json:"-"`
-	HTTPAPIServer HTTPAPIServer   `yaml:"http_api_server" json:"-"`
-	DNSSD         DNSSD           `yaml:"dns_sd" json:"-"`
-	Storage       StorageConfig   `yaml:"storage" json:"-"`
-	Pipeline      PipelineConfig  `yaml:"pipeline" json:"-"`
-	OpenAI        *OpenAIConfig   `yaml:"openai" json:"-"`
-	Telegram      *TelegramConfig `yaml:"telegram" json:"-"`
+	Log             LogConfig             `yaml:"log" json:"-"`
+	Swagger         bool                  `yaml:"swagger" json:"-"`
+	HTTPAPIServer   HTTPAPIServer         `yaml:"http_api_server" json:"-"`
+	DNSSD           DNSSD                 `yaml:"dns_sd" json:"-"`
+	Storage         StorageConfig         `yaml:"storage" json:"-"`
+	Pipeline        PipelineConfig        `yaml:"pipeline" json:"-"`
+	Triggers        TriggersConfig        `yaml:"triggers" json:"-"`
+	Webhooks        WebhooksConfig        `yaml:"webhooks" json:"-"`
+	Retention       RetentionConfig       `yaml:"retention" json:"-"`
+	ProcessingIndex ProcessingIndexConfig `yaml:"processing_index" json:"-"`
+	Secrets         SecretsConfig         `yaml:"secrets" json:"-"`
+	Costs           CostsConfig           `yaml:"costs" json:"-"`
+	Egress          *EgressConfig         `yaml:"egress" json:"-"`
+	Plugins         *PluginsConfig        `yaml:"plugins" json:"-"`
+	OpenAI          *OpenAIConfig         `yaml:"openai" json:"-"`
+	Telegram        *TelegramConfig       `yaml:"telegram" json:"-"`
 
 	Blocks map[string]BlockConfig `yaml:"blocks" json:"-"`
 }
@@ -96,6 +115,56 @@ type PipelineConfig struct {
 	StoragePath string               `yaml:"pipeline_validation_schema_path" json:"-"`
 	Catalogue   string               `yaml:"pipeline_catalogue" json:"-"`
 	SchemaPtr   *gojsonschema.Schema `yaml:"-" json:"-"`
+
+	// Reload the catalogue when its files change
+	CatalogueWatch bool `yaml:"pipeline_catalogue_watch" json:"-"`
+	// Delay to collect the catalogue changes before the reload
+	CatalogueReloadDelay time.Duration `yaml:"pipeline_catalogue_reload_delay" json:"-"`
+	// Storage of the catalogue: local or minio. Empty one reads the catalogue directory from the disk
+	CatalogueStorage string `yaml:"pipeline_catalogue_storage" json:"-"`
+	// Interval to check the catalogue version and reload it when changed
+	CataloguePollInterval time.Duration `yaml:"pipeline_catalogue_poll_interval" json:"-"`
+	// Validate the outputs of the blocks against their output schema, blocks may override it
+	ValidateBlockOutputs bool `yaml:"pipeline_validate_block_outputs" json:"-"`
+	// Times the invalid outputs of the non-deterministic blocks are retried, blocks may override it
+	OutputValidationRetries int `yaml:"pipeline_output_validation_retries" json:"-"`
+}
+
+type TriggersConfig struct {
+	Enabled       bool          `yaml:"enabled" json:"-"`
+	CheckInterval time.Duration `yaml:"check_interval" json:"-"`
+}
+
+type WebhooksConfig struct {
+	Timeout    time.Duration `yaml:"timeout" json:"-"`
+	MaxRetries int           `yaml:"max_retries" json:"-"`
+	RetryDelay time.Duration `yaml:"retry_delay" json:"-"`
+}
+
+// RetentionConfig describes how long processing artifacts are kept in the storages.
+// Zero values disable the corresponding rule
+type RetentionConfig struct {
+	Enabled       bool          `yaml:"enabled" json:"-"`
+	DryRun        bool          `yaml:"dry_run" json:"-"`
+	CheckInterval time.Duration `yaml:"check_interval" json:"-"`
+
+	// Intermediate block outputs are deleted after KeepDays
+	KeepDays int `yaml:"keep_days" json:"-"`
+	// Whole processings ( final block outputs, logs and statuses ) are deleted after KeepFinalOutputsDays,
+	// which defaults to KeepDays
+	KeepFinalOutputsDays int `yaml:"keep_final_outputs_days" json:"-"`
+	// Latest processings of every Pipeline which are never deleted
+	KeepLatest int `yaml:"keep_latest" json:"-"`
+}
+
+// ProcessingIndexConfig describes the embedded index of processing logs & statuses.
+// Disabled index falls back to listing the storages
+type ProcessingIndexConfig struct {
+	Enabled bool   `yaml:"enabled" json:"-"`
+	Path    string `yaml:"path" json:"-"`
+	// Storages are shared by the workers, so the processings of the other workers
+	// are indexed in the background every RefreshInterval
+	RefreshInterval time.Duration `yaml:"refresh_interval" json:"-"`
 }
 
 type openAIToken struct {
@@ -108,6 +177,24 @@ type OpenAIConfig struct {
 	CredentialsPath string `yaml:"credentials_path" json:"-"`
 	EnvVarName      string `yaml:"env_var_name" json:"-"`
 	Token           string `yaml:"-" json:"-"`
+	BaseURL         string `yaml:"base_url" json:"-"`
+	// openai ( any OpenAI-compatible server ) or azure
+	APIType    string `yaml:"api_type" json:"-"`
+	APIVersion string `yaml:"api_version" json:"-"`
+
+	Capabilities OpenAICapabilitiesConfig `yaml:"capabilities" json:"-"`
+
+	Limits         ProviderLimitsConfig `yaml:"limits" json:"-"`
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker" json:"-"`
+
+	// Named credential profiles selected by the blocks with the `credentials` input
+	Profiles map[string]*OpenAIConfig `yaml:"profiles" json:"-"`
+}
+
+// OpenAICapabilitiesConfig declares the features the OpenAI-compatible server lacks.
+// Unset capabilities are detected from the server errors
+type OpenAICapabilitiesConfig struct {
+	JSONMode *bool `yaml:"json_mode" json:"-"`
 }
 
 type TelegramConfig struct {
@@ -116,21 +203,68 @@ type TelegramConfig struct {
 	CredentialsPath string `yaml:"credentials_path" json:"-"`
 	EnvVarName      string `yaml:"env_var_name" json:"-"`
 	Token           string `yaml:"-" json:"-"`
+	BaseURL         string `yaml:"base_url" json:"-"`
 	BotName         string `yaml:"bot_name" json:"bot_name"`
+
+	Limits         ProviderLimitsConfig `yaml:"limits" json:"-"`
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker" json:"-"`
+
+	// Named credential profiles selected by the blocks with the `credentials` input
+	Profiles map[string]*TelegramConfig `yaml:"profiles" json:"-"`
 }
 
 type BlockConfig struct {
+	// Disabled blocks are not registered, blocks are enabled by default
+	Enabled *bool `yaml:"enabled" json:"-"`
+	// Ids the block is also known by in the pipelines
+	Aliases []string `yaml:"aliases" json:"-"`
+
+	// Validate the outputs against the output schema, unset follows the pipeline config
+	ValidateOutput *bool `yaml:"validate_output" json:"-"`
+	// Times the invalid outputs are retried, unset follows the pipeline config
+	OutputValidationRetries *int `yaml:"output_validation_retries" json:"-"`
+
 	Detector          BlockConfigDetector    `yaml:"detector" json:"-"`
 	Reliability       BlockConfigReliability `yaml:"reliability" json:"-"`
 	ParallelAvailable bool                   `yaml:"parallel_available" json:"-"`
 	Config            map[string]interface{} `yaml:"config" json:"-"`
 }
 
+func (b BlockConfig) IsEnabled() bool {
+	return b.Enabled == nil || *b.Enabled
+}
+
+// WithDefaults returns the config with the defaults in place of the sections missing in the config file
+func (b BlockConfig) WithDefaults() BlockConfig {
+	b.Detector = b.Detector.WithDefaults()
+	if b.Reliability.Policy == "" {
+		b.Reliability.Policy = DEFAULT_BLOCK_RELIABILITY_POLICY
+	}
+	if b.Config == nil {
+		b.Config = make(map[string]interface{})
+	}
+
+	return b
+}
+
 type BlockConfigDetector struct {
 	CheckInterval time.Duration          `yaml:"check_interval" json:"-"`
 	Conditions    map[string]interface{} `yaml:"conditions" json:"-"`
 }
 
+// WithDefaults returns the config with the default check interval in place of the unset one
+// and the empty conditions in place of the missing ones
+func (d BlockConfigDetector) WithDefaults() BlockConfigDetector {
+	if d.CheckInterval <= 0 {
+		d.CheckInterval = DEFAULT_BLOCK_CHECK_INTERVAL
+	}
+	if d.Conditions == nil {
+		d.Conditions = make(map[string]interface{})
+	}
+
+	return d
+}
+
 type BlockConfigReliability struct {
 	Policy       string      `yaml:"policy" json:"-"`
 	PolicyConfig interface{} `yaml:"-" json:"-"`
@@ -165,8 +299,6 @@ type BlockConfigReliabilityExponentialBackoff struct {
 func NewConfig() Config {
 	godotenv.Load()
 
-	config := Config{}
-
 	httpAPIPort := flag.Int("http-api-port", 8080, "HTTP API port")
 
 	flag.Parse()
@@ -177,6 +309,21 @@ func NewConfig() Config {
 		configPath = CONFIG_FILE
 	}
 
+	config := NewConfigFromFile(configPath)
+
+	if httpAPIPort != nil {
+		config.HTTPAPIServer.Port = *httpAPIPort
+		config.DNSSD.ServicePort = *httpAPIPort
+	}
+
+	return config
+}
+
+// NewConfigFromFile loads the config from the YAML file, the relative paths
+// of the credentials and the Pipelines are also looked up in the config file directory
+func NewConfigFromFile(configPath string) Config {
+	config := Config{}
+
 	file, err := os.Open(configPath)
 	if err != nil {
 		panic(err)
@@ -236,7 +383,7 @@ func NewConfig() Config {
 			panic(err)
 		}
 
-		if config.Pipeline.Catalogue != "" {
+		if config.Pipeline.Catalogue != "" && config.Pipeline.CatalogueStorage == "" {
 			// Check directory exists
 			_, err := os.ReadDir(config.Pipeline.Catalogue)
 			if condition := os.IsNotExist(err); condition {
@@ -250,63 +397,85 @@ func NewConfig() Config {
 			}
 		}
 
-		pipelineConfig := PipelineConfig{
-			StoragePath: config.Pipeline.StoragePath,
-			Catalogue:   config.Pipeline.Catalogue,
-			SchemaPtr:   schemaPtr,
-		}
-
-		config.Pipeline = pipelineConfig
+		config.Pipeline.SchemaPtr = schemaPtr
 	}
 
-	// Initialize OpenAI client
-	openAIConfig := config.OpenAI
-
-	err = initializeClient(
-		&openAIConfig.ClientConfig,
-		config.OpenAI.EnvVarName,
-		configPath,
-		config.OpenAI.CredentialsPath,
-		func(token string) (*openai.Client, error) {
-			openAIConfig.Token = token
-			return openai.NewClient(token), nil
-		},
-	)
-	if err != nil {
-		// Handle error appropriately
-		fmt.Printf(
-			"Failed to initialize OpenAI client: %v\n",
-			err,
-		)
+	if config.Pipeline.CatalogueReloadDelay <= 0 {
+		config.Pipeline.CatalogueReloadDelay = DEFAULT_CATALOGUE_RELOAD_DELAY
+	}
+	if config.Pipeline.CatalogueStorage != "" && config.Pipeline.CataloguePollInterval <= 0 {
+		config.Pipeline.CataloguePollInterval = DEFAULT_CATALOGUE_POLL_INTERVAL
+	}
+	if config.Triggers.CheckInterval <= 0 {
+		config.Triggers.CheckInterval = DEFAULT_TRIGGERS_CHECK_INTERVAL
+	}
+	if config.Webhooks.Timeout <= 0 {
+		config.Webhooks.Timeout = DEFAULT_WEBHOOKS_TIMEOUT
+	}
+	if config.Webhooks.RetryDelay <= 0 {
+		config.Webhooks.RetryDelay = DEFAULT_WEBHOOKS_RETRY_DELAY
+	}
+	if config.Retention.CheckInterval <= 0 {
+		config.Retention.CheckInterval = DEFAULT_RETENTION_CHECK_INTERVAL
+	}
+	if config.ProcessingIndex.Path == "" {
+		config.ProcessingIndex.Path = filepath.Join(os.TempDir(), DEFAULT_PROCESSING_INDEX_FILE)
+	}
+	if config.ProcessingIndex.RefreshInterval <= 0 {
+		config.ProcessingIndex.RefreshInterval = DEFAULT_PROCESSING_INDEX_REFRESH
+	}
+	if config.Secrets.EnvPrefix == "" {
+		config.Secrets.EnvPrefix = DEFAULT_SECRETS_ENV_PREFIX
+	}
+	if config.Secrets.EncryptionKeyEnvVar == "" {
+		config.Secrets.EncryptionKeyEnvVar = DEFAULT_SECRETS_ENCRYPTION_KEY_ENV_VAR
+	}
+	if config.Costs.Currency == "" {
+		config.Costs.Currency = DEFAULT_COSTS_CURRENCY
+	}
+	if config.Costs.Prices == nil {
+		config.Costs.Prices = make(map[string]ModelPriceConfig)
+	}
+	if config.Costs.Budgets.Pipelines == nil {
+		config.Costs.Budgets.Pipelines = make(map[string]float64)
+	}
+	if config.Costs.Budgets.RefreshInterval == 0 {
+		config.Costs.Budgets.RefreshInterval = DEFAULT_BUDGETS_REFRESH
+	}
+	if config.Egress == nil {
+		egressConfig := NewEgressConfig()
+		config.Egress = &egressConfig
+	}
+	if config.Plugins == nil {
+		config.Plugins = &PluginsConfig{}
 	}
 
-	// Initialize Telegram client
-	telegramConfig := config.Telegram
-
-	err = initializeClient(
-		&telegramConfig.ClientConfig,
-		config.Telegram.EnvVarName,
-		configPath,
-		config.Telegram.CredentialsPath,
-		func(token string) (*tgbotapi.BotAPI, error) {
-			if token == "" {
-				return nil, fmt.Errorf("empty token")
-			}
-			telegramConfig.Token = token
-			return tgbotapi.NewBotAPI(token)
-		},
-	)
-	if err != nil {
-		// Handle error appropriately
-		fmt.Printf(
-			"Failed to initialize Telegram client: %v\n",
-			err,
-		)
+	// Initialize OpenAI clients of the default and the named profiles
+	for _, profileName := range config.OpenAI.GetProfileNames() {
+		openAIConfig, _ := config.OpenAI.GetProfile(profileName)
+		openAIConfig.CircuitBreaker = openAIConfig.CircuitBreaker.WithDefaults()
+		if err := initializeOpenAIClient(openAIConfig, configPath); err != nil {
+			// Handle error appropriately
+			fmt.Printf(
+				"Failed to initialize OpenAI client of %s profile: %v\n",
+				profileName,
+				err,
+			)
+		}
 	}
 
-	if httpAPIPort != nil {
-		config.HTTPAPIServer.Port = *httpAPIPort
-		config.DNSSD.ServicePort = *httpAPIPort
+	// Initialize Telegram clients of the default and the named profiles
+	for _, profileName := range config.Telegram.GetProfileNames() {
+		telegramConfig, _ := config.Telegram.GetProfile(profileName)
+		telegramConfig.CircuitBreaker = telegramConfig.CircuitBreaker.WithDefaults()
+		if err := initializeTelegramClient(telegramConfig, configPath); err != nil {
+			// Handle error appropriately
+			fmt.Printf(
+				"Failed to initialize Telegram client of %s profile: %v\n",
+				profileName,
+				err,
+			)
+		}
 	}
 
 	return config
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	DEFAULT_COSTS_CURRENCY  = "USD"
+	DEFAULT_BUDGETS_REFRESH = time.Minute
+)
+
+// CostsConfig is the price table of the paid API calls and the budgets of the processings
+type CostsConfig struct {
+	Currency string                      `yaml:"currency" json:"-"`
+	Prices   map[string]ModelPriceConfig `yaml:"prices" json:"-"`
+	Budgets  BudgetsConfig               `yaml:"budgets" json:"-"`
+}
+
+// ModelPriceConfig is the price of a model. Tokens and characters are priced per million
+type ModelPriceConfig struct {
+	PromptTokens     float64 `yaml:"prompt_tokens" json:"-"`
+	CompletionTokens float64 `yaml:"completion_tokens" json:"-"`
+	Characters       float64 `yaml:"characters" json:"-"`
+	Minutes          float64 `yaml:"minutes" json:"-"`
+
+	// Price of an image by `<size>/<quality>`, `<size>` or `default`
+	Images map[string]float64 `yaml:"images" json:"-"`
+}
+
+// BudgetsConfig limits the cost of the processings per UTC day. Zero disables the budget.
+// The cost saved by all workers is read again from the storages every RefreshInterval.
+// A started processing reserves the average cost of the processings saved today, at least EstimatedCost
+type BudgetsConfig struct {
+	Daily           float64            `yaml:"daily" json:"-"`
+	Pipelines       map[string]float64 `yaml:"pipelines" json:"-"`
+	RefreshInterval time.Duration      `yaml:"refresh_interval" json:"-"`
+	EstimatedCost   float64            `yaml:"estimated_cost" json:"-"`
+}
+
+// GetModelPrice returns the price of the model or of the longest model prefix in the table,
+// e.g. `gpt-4o` prices `gpt-4o-2024-08-06`
+func (c CostsConfig) GetModelPrice(model string) (ModelPriceConfig, bool) {
+	if price, ok := c.Prices[model]; ok {
+		return price, true
+	}
+
+	matchedModel := ""
+	for priceModel := range c.Prices {
+		if strings.HasPrefix(model, priceModel) && len(priceModel) > len(matchedModel) {
+			matchedModel = priceModel
+		}
+	}
+	if matchedModel == "" {
+		return ModelPriceConfig{}, false
+	}
+
+	return c.Prices[matchedModel], true
+}
+
+// GetImagePrice returns the price of an image of the size and quality
+func (p ModelPriceConfig) GetImagePrice(size string, quality string) float64 {
+	for _, key := range []string{size + "/" + quality, size, "default"} {
+		if price, ok := p.Images[key]; ok {
+			return price
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,35 @@
+package config
+
+// EgressConfig is the policy of the outgoing requests of the blocks to the URLs of the pipeline input.
+// It covers the `http_request` block and the webhook callbacks, the only requests to the URLs the API
+// callers or the pipelines choose: the other blocks read the outputs of the blocks and the uploaded files,
+// the OpenAI, Telegram and plugin endpoints are set by the operator
+type EgressConfig struct {
+	// Deny the loopback, private, link-local ( cloud metadata ) and other non-public addresses.
+	// On unless the section turns it off
+	BlockPrivateNetworks bool `yaml:"block_private_networks" json:"-"`
+
+	// Hosts of the URLs, `*.example.com` matches the subdomains. Non-empty AllowHosts denies the other hosts
+	AllowHosts []string `yaml:"allow_hosts" json:"-"`
+	DenyHosts  []string `yaml:"deny_hosts" json:"-"`
+
+	// Resolved addresses. AllowCIDRs are exempt from DenyCIDRs and BlockPrivateNetworks
+	AllowCIDRs []string `yaml:"allow_cidrs" json:"-"`
+	DenyCIDRs  []string `yaml:"deny_cidrs" json:"-"`
+}
+
+// NewEgressConfig returns the policy of the missing `egress` section
+func NewEgressConfig() EgressConfig {
+	return EgressConfig{BlockPrivateNetworks: true}
+}
+
+func (c *EgressConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type egressConfig EgressConfig // create a new type to avoid recursion
+	tmp := egressConfig(NewEgressConfig())
+	if err := unmarshal(&tmp); err != nil {
+		return err
+	}
+
+	*c = EgressConfig(tmp)
+	return nil
+}
@@ -0,0 +1,34 @@
+package config
+
+import "time"
+
+const (
+	DEFAULT_CIRCUIT_BREAKER_FAILURE_THRESHOLD = 5
+	DEFAULT_CIRCUIT_BREAKER_COOLDOWN          = time.Minute
+)
+
+// ProviderLimitsConfig is the rate limit shared by all the blocks of the credentials profile. Zero values are not limited
+type ProviderLimitsConfig struct {
+	RequestsPerMinute  int `yaml:"requests_per_minute" json:"-"`
+	TokensPerMinute    int `yaml:"tokens_per_minute" json:"-"`
+	ConcurrentRequests int `yaml:"concurrent_requests" json:"-"`
+}
+
+// CircuitBreakerConfig opens the circuit of the credentials profile after `failure_threshold` consecutive
+// failures ( 429, 5xx or network errors ) and lets a trial request through after the `cooldown`
+type CircuitBreakerConfig struct {
+	FailureThreshold int           `yaml:"failure_threshold" json:"-"`
+	Cooldown         time.Duration `yaml:"cooldown" json:"-"`
+}
+
+// WithDefaults returns the config with the default threshold and cooldown in place of the unset ones
+func (c CircuitBreakerConfig) WithDefaults() CircuitBreakerConfig {
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = DEFAULT_CIRCUIT_BREAKER_FAILURE_THRESHOLD
+	}
+	if c.Cooldown <= 0 {
+		c.Cooldown = DEFAULT_CIRCUIT_BREAKER_COOLDOWN
+	}
+
+	return c
+}
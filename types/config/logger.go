@@ -66,13 +66,14 @@ func (lw *LoggerWrapper) Write(p []byte) (n int, err error) {
 	lw.Lock()
 	defer lw.Unlock()
 
+	// Resolved secrets never reach the processing logs
+	redacted := []byte(RedactSecrets(string(p)))
 	for _, writer := range lw.writers {
-		n, err = writer.Write(p)
-		if err != nil {
-			return n, err
+		if _, err = writer.Write(redacted); err != nil {
+			return 0, err
 		}
 	}
-	return n, nil
+	return len(p), nil
 }
 
 func (lw *LoggerWrapper) AddWriter(writer io.Writer) {
@@ -112,6 +113,7 @@ func GetLogger() echo.Logger {
 	onceLogger.Do(func() {
 		config := GetConfig()
 		e := echo.New()
+		e.Logger.SetOutput(&LoggerWrapper{writers: []io.Writer{os.Stdout}})
 		e.Logger.SetLevel(log.WARN)
 
 		switch strings.ToUpper(config.Log.Level) {
@@ -0,0 +1,96 @@
+package config
+
+import "time"
+
+const (
+	DEFAULT_PLUGIN_TIMEOUT          = 5 * time.Minute
+	DEFAULT_PLUGIN_DESCRIBE_TIMEOUT = 10 * time.Second
+	DEFAULT_PLUGIN_CHECK_INTERVAL   = time.Minute
+	DEFAULT_PLUGIN_MAX_OUTPUT_SIZE  = 512 << 20
+
+	DEFAULT_WASM_PLUGIN_TIMEOUT       = 30 * time.Second
+	DEFAULT_WASM_PLUGIN_MAX_MEMORY_MB = 256
+	DEFAULT_WASM_PLUGINS_RELOAD_DELAY = 500 * time.Millisecond
+)
+
+// PluginsConfig declares the blocks provided by the plugins
+type PluginsConfig struct {
+	// Executables or HTTP endpoints speaking the JSON plugin protocol
+	External []ExternalPluginConfig `yaml:"external" json:"-"`
+	// WebAssembly modules running in the worker process
+	Wasm WasmPluginsConfig `yaml:"wasm" json:"-"`
+}
+
+// ExternalPluginConfig is a plugin running out of the worker process: the Command or the URL
+type ExternalPluginConfig struct {
+	// Name of the plugin in the logs
+	Name string `yaml:"name" json:"-"`
+
+	// Executable called with the method as the last argument
+	Command string            `yaml:"command" json:"-"`
+	Args    []string          `yaml:"args" json:"-"`
+	Env     map[string]string `yaml:"env" json:"-"`
+
+	// Base URL of the HTTP endpoint, the methods are POSTed to `<url>/<method>`
+	URL     string            `yaml:"url" json:"-"`
+	Headers map[string]string `yaml:"headers" json:"-"`
+
+	// Timeout of the processing, the describe and the detect calls
+	Timeout         time.Duration `yaml:"timeout" json:"-"`
+	DescribeTimeout time.Duration `yaml:"describe_timeout" json:"-"`
+	CheckInterval   time.Duration `yaml:"check_interval" json:"-"`
+	// Max size of every output in bytes
+	MaxOutputSize int64 `yaml:"max_output_size" json:"-"`
+}
+
+// WithDefaults returns the config with the default timeouts and limits in place of the unset ones
+func (c ExternalPluginConfig) WithDefaults() ExternalPluginConfig {
+	if c.Name == "" {
+		c.Name = c.Command
+		if c.Name == "" {
+			c.Name = c.URL
+		}
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DEFAULT_PLUGIN_TIMEOUT
+	}
+	if c.DescribeTimeout <= 0 {
+		c.DescribeTimeout = DEFAULT_PLUGIN_DESCRIBE_TIMEOUT
+	}
+	if c.CheckInterval <= 0 {
+		c.CheckInterval = DEFAULT_PLUGIN_CHECK_INTERVAL
+	}
+	if c.MaxOutputSize <= 0 {
+		c.MaxOutputSize = DEFAULT_PLUGIN_MAX_OUTPUT_SIZE
+	}
+
+	return c
+}
+
+// WasmPluginsConfig is the directory of the `.wasm` modules and the limits of their sandbox
+type WasmPluginsConfig struct {
+	Directory string `yaml:"directory" json:"-"`
+
+	// Reload the modules when the files of the directory change
+	Watch       bool          `yaml:"watch" json:"-"`
+	ReloadDelay time.Duration `yaml:"reload_delay" json:"-"`
+
+	// Limits of every call of a module
+	Timeout     time.Duration `yaml:"timeout" json:"-"`
+	MaxMemoryMB int           `yaml:"max_memory_mb" json:"-"`
+}
+
+// WithDefaults returns the config with the default limits in place of the unset ones
+func (c WasmPluginsConfig) WithDefaults() WasmPluginsConfig {
+	if c.ReloadDelay <= 0 {
+		c.ReloadDelay = DEFAULT_WASM_PLUGINS_RELOAD_DELAY
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DEFAULT_WASM_PLUGIN_TIMEOUT
+	}
+	if c.MaxMemoryMB <= 0 {
+		c.MaxMemoryMB = DEFAULT_WASM_PLUGIN_MAX_MEMORY_MB
+	}
+
+	return c
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// DEFAULT_CREDENTIALS_PROFILE is the profile of the integration section itself
+const DEFAULT_CREDENTIALS_PROFILE = "default"
+
+var ErrCredentialsProfileNotFound = errors.New("credentials profile not found")
+
+// getCredentialsProfile returns the named profile. Empty name selects the default profile
+func getCredentialsProfile[P comparable](defaultProfile P, profiles map[string]P, name string) (P, error) {
+	if name == "" || name == DEFAULT_CREDENTIALS_PROFILE {
+		return defaultProfile, nil
+	}
+
+	var zero P
+	profile, ok := profiles[name]
+	if !ok || profile == zero {
+		return zero, fmt.Errorf("%w: %s", ErrCredentialsProfileNotFound, name)
+	}
+
+	return profile, nil
+}
+
+// getCredentialsProfileNames returns the default profile followed by the sorted named profiles
+func getCredentialsProfileNames[P any](profiles map[string]P) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		if name != DEFAULT_CREDENTIALS_PROFILE {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return append([]string{DEFAULT_CREDENTIALS_PROFILE}, names...)
+}
+
+func (c *OpenAIConfig) GetProfile(name string) (*OpenAIConfig, error) {
+	if c == nil {
+		return nil, fmt.Errorf("%w: %s", ErrCredentialsProfileNotFound, name)
+	}
+
+	return getCredentialsProfile(c, c.Profiles, name)
+}
+
+func (c *OpenAIConfig) GetProfileNames() []string {
+	if c == nil {
+		return []string{}
+	}
+
+	return getCredentialsProfileNames(c.Profiles)
+}
+
+func (c *TelegramConfig) GetProfile(name string) (*TelegramConfig, error) {
+	if c == nil {
+		return nil, fmt.Errorf("%w: %s", ErrCredentialsProfileNotFound, name)
+	}
+
+	return getCredentialsProfile(c, c.Profiles, name)
+}
+
+func (c *TelegramConfig) GetProfileNames() []string {
+	if c == nil {
+		return []string{}
+	}
+
+	return getCredentialsProfileNames(c.Profiles)
+}
+
+func initializeOpenAIClient(openAIConfig *OpenAIConfig, configPath string) error {
+	return initializeClient(
+		&openAIConfig.ClientConfig,
+		openAIConfig.EnvVarName,
+		configPath,
+		openAIConfig.CredentialsPath,
+		func(token string) (*openai.Client, error) {
+			openAIConfig.Token = token
+
+			baseURL := strings.TrimSuffix(openAIConfig.BaseURL, "/")
+
+			clientConfig := openai.DefaultConfig(token)
+			if strings.EqualFold(openAIConfig.APIType, string(openai.APITypeAzure)) {
+				clientConfig = openai.DefaultAzureConfig(token, baseURL)
+			} else if baseURL != "" {
+				clientConfig.BaseURL = baseURL
+			}
+			if openAIConfig.APIVersion != "" {
+				clientConfig.APIVersion = openAIConfig.APIVersion
+			}
+			return openai.NewClientWithConfig(clientConfig), nil
+		},
+	)
+}
+
+func initializeTelegramClient(telegramConfig *TelegramConfig, configPath string) error {
+	return initializeClient(
+		&telegramConfig.ClientConfig,
+		telegramConfig.EnvVarName,
+		configPath,
+		telegramConfig.CredentialsPath,
+		func(token string) (*tgbotapi.BotAPI, error) {
+			if token == "" {
+				return nil, fmt.Errorf("empty token")
+			}
+			telegramConfig.Token = token
+
+			if telegramConfig.BaseURL != "" {
+				return tgbotapi.NewBotAPIWithAPIEndpoint(
+					token,
+					strings.TrimSuffix(telegramConfig.BaseURL, "/")+"/bot%s/%s",
+				)
+			}
+			return tgbotapi.NewBotAPI(token)
+		},
+	)
+}
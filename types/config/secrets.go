@@ -0,0 +1,563 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	DEFAULT_SECRETS_ENV_PREFIX             = "SECRET_"
+	DEFAULT_SECRETS_ENCRYPTION_KEY_ENV_VAR = "SECRETS_KEY"
+
+	// Parameters of the scrypt key derivation of the encrypted secrets file
+	SECRETS_KDF_SALT_SIZE = 16
+	SECRETS_KDF_N         = 1 << 15
+	SECRETS_KDF_R         = 8
+	SECRETS_KDF_P         = 1
+
+	// Secrets shorter than that are not redacted to keep the logs readable
+	SECRET_REDACTION_MIN_LENGTH = 4
+	SECRET_REDACTED             = "[REDACTED]"
+)
+
+var (
+	ErrSecretNotFound = errors.New("secret not found")
+	// ErrSecretInputOverridden is returned for the API callers overriding the input of a block using secrets
+	ErrSecretInputOverridden = errors.New("input of the block using secrets can not be overridden")
+
+	// SecretReferenceRegex matches `${secret:name}` references in the block input
+	SecretReferenceRegex = regexp.MustCompile(`\$\{secret:([-\w.]+)\}`)
+
+	secretResolver     *SecretResolver
+	onceSecretResolver sync.Once
+
+	redactedSecrets = &secretRedactionStore{
+		values: make(map[string]bool),
+	}
+)
+
+// SecretsConfig describes the chain of secret providers `${secret:name}` references are resolved with.
+// Providers are asked in order: environment, directory, encrypted file
+type SecretsConfig struct {
+	EnvPrefix           string `yaml:"env_prefix" json:"-"`
+	Directory           string `yaml:"directory" json:"-"`
+	EncryptedFile       string `yaml:"encrypted_file" json:"-"`
+	EncryptionKeyEnvVar string `yaml:"encryption_key_env_var" json:"-"`
+}
+
+// SecretProvider returns the value of the named secret or ErrSecretNotFound
+type SecretProvider interface {
+	GetName() string
+	GetSecret(name string) (string, error)
+}
+
+// EnvSecretProvider reads secrets from the environment variables.
+// Secret `api-key` is read from the variable `<prefix>API_KEY`
+type EnvSecretProvider struct {
+	prefix string
+}
+
+func NewEnvSecretProvider(prefix string) *EnvSecretProvider {
+	return &EnvSecretProvider{prefix: prefix}
+}
+
+func (p *EnvSecretProvider) GetName() string {
+	return "env"
+}
+
+func (p *EnvSecretProvider) GetSecret(name string) (string, error) {
+	envVarName := p.prefix + strings.ToUpper(
+		strings.NewReplacer("-", "_", ".", "_").Replace(name),
+	)
+
+	value, ok := os.LookupEnv(envVarName)
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+
+	return value, nil
+}
+
+// FileSecretProvider reads secrets from the files of a directory, one file per secret,
+// e.g. mounted Docker or Kubernetes secrets
+type FileSecretProvider struct {
+	directory string
+}
+
+func NewFileSecretProvider(directory string) *FileSecretProvider {
+	return &FileSecretProvider{directory: directory}
+}
+
+func (p *FileSecretProvider) GetName() string {
+	return "directory"
+}
+
+func (p *FileSecretProvider) GetSecret(name string) (string, error) {
+	value, err := os.ReadFile(filepath.Join(p.directory, filepath.Base(name)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrSecretNotFound
+		}
+		return "", err
+	}
+
+	return strings.TrimSpace(string(value)), nil
+}
+
+// EncryptedFileSecretProvider reads secrets from a local file holding the JSON object
+// of the secrets encrypted with AES-256-GCM. The file is read again when it is modified
+type EncryptedFileSecretProvider struct {
+	sync.Mutex
+
+	path       string
+	passphrase string
+
+	modTime time.Time
+	secrets map[string]string
+}
+
+func NewEncryptedFileSecretProvider(path string, passphrase string) *EncryptedFileSecretProvider {
+	return &EncryptedFileSecretProvider{
+		path:       path,
+		passphrase: passphrase,
+	}
+}
+
+func (p *EncryptedFileSecretProvider) GetName() string {
+	return "encrypted_file"
+}
+
+func (p *EncryptedFileSecretProvider) GetSecret(name string) (string, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	fileInfo, err := os.Stat(p.path)
+	if err != nil {
+		return "", err
+	}
+
+	if p.secrets == nil || !fileInfo.ModTime().Equal(p.modTime) {
+		content, err := os.ReadFile(p.path)
+		if err != nil {
+			return "", err
+		}
+
+		secrets, err := DecryptSecrets(content, p.passphrase)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt secrets file %s: %s", p.path, err)
+		}
+
+		p.secrets = secrets
+		p.modTime = fileInfo.ModTime()
+	}
+
+	value, ok := p.secrets[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+
+	return value, nil
+}
+
+// getSecretsCipher returns AES-256-GCM with the key derived from the passphrase and the salt by scrypt
+func getSecretsCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, errors.New("encryption key is empty")
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, SECRETS_KDF_N, SECRETS_KDF_R, SECRETS_KDF_P, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// EncryptSecrets encrypts the secrets into the content of the encrypted secrets file:
+// base64 of the random salt, the nonce and the ciphertext
+func EncryptSecrets(secrets map[string]string, passphrase string) ([]byte, error) {
+	salt := make([]byte, SECRETS_KDF_SALT_SIZE)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := getSecretsCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	ciphertext := gcm.Seal(append(salt, nonce...), nonce, plaintext, nil)
+	content := make([]byte, base64.StdEncoding.EncodedLen(len(ciphertext)))
+	base64.StdEncoding.Encode(content, ciphertext)
+
+	return content, nil
+}
+
+// DecryptSecrets decrypts the content of the encrypted secrets file
+func DecryptSecrets(content []byte, passphrase string) (map[string]string, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < SECRETS_KDF_SALT_SIZE {
+		return nil, errors.New("encrypted secrets are too short")
+	}
+
+	salt, ciphertext := ciphertext[:SECRETS_KDF_SALT_SIZE], ciphertext[SECRETS_KDF_SALT_SIZE:]
+	gcm, err := getSecretsCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("encrypted secrets are too short")
+	}
+
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+// SecretResolver resolves `${secret:name}` references with the first provider having the secret
+type SecretResolver struct {
+	providers []SecretProvider
+}
+
+func GetSecretResolver(forceNewInstance ...bool) *SecretResolver {
+	if len(forceNewInstance) > 0 && forceNewInstance[0] {
+		secretResolver = NewSecretResolver(GetConfig().Secrets)
+		onceSecretResolver = sync.Once{}
+		return secretResolver
+	}
+
+	onceSecretResolver.Do(func() {
+		secretResolver = NewSecretResolver(GetConfig().Secrets)
+	})
+
+	return secretResolver
+}
+
+func NewSecretResolver(secretsConfig SecretsConfig) *SecretResolver {
+	providers := []SecretProvider{
+		NewEnvSecretProvider(secretsConfig.EnvPrefix),
+	}
+	if secretsConfig.Directory != "" {
+		providers = append(providers, NewFileSecretProvider(secretsConfig.Directory))
+	}
+	if secretsConfig.EncryptedFile != "" {
+		providers = append(
+			providers,
+			NewEncryptedFileSecretProvider(
+				secretsConfig.EncryptedFile,
+				os.Getenv(secretsConfig.EncryptionKeyEnvVar),
+			),
+		)
+	}
+
+	return NewSecretResolverWithProviders(providers...)
+}
+
+func NewSecretResolverWithProviders(providers ...SecretProvider) *SecretResolver {
+	return &SecretResolver{providers: providers}
+}
+
+func (r *SecretResolver) GetProviders() []SecretProvider {
+	return r.providers
+}
+
+// GetSecret returns the secret of the first provider having it. Returned secrets are redacted from the logs
+func (r *SecretResolver) GetSecret(name string) (string, error) {
+	for _, provider := range r.providers {
+		value, err := provider.GetSecret(name)
+		if errors.Is(err, ErrSecretNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("secret %s from %s provider: %w", name, provider.GetName(), err)
+		}
+
+		AddRedactedSecret(value)
+		return value, nil
+	}
+
+	return "", fmt.Errorf("secret %s: %w", name, ErrSecretNotFound)
+}
+
+// ResolveReferences replaces `${secret:name}` references in the strings of the value.
+// Maps and slices are copied, the value itself is not modified
+func (r *SecretResolver) ResolveReferences(value interface{}) (interface{}, error) {
+	switch typedValue := value.(type) {
+	case string:
+		if !strings.Contains(typedValue, "${secret:") {
+			return typedValue, nil
+		}
+
+		var resolveErr error
+		resolved := SecretReferenceRegex.ReplaceAllStringFunc(
+			typedValue,
+			func(reference string) string {
+				if resolveErr != nil {
+					return reference
+				}
+
+				secret, err := r.GetSecret(SecretReferenceRegex.FindStringSubmatch(reference)[1])
+				if err != nil {
+					resolveErr = err
+					return reference
+				}
+				return secret
+			},
+		)
+		if resolveErr != nil {
+			return nil, resolveErr
+		}
+
+		return resolved, nil
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(typedValue))
+		for key, item := range typedValue {
+			resolvedItem, err := r.ResolveReferences(item)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = resolvedItem
+		}
+
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(typedValue))
+		for i, item := range typedValue {
+			resolvedItem, err := r.ResolveReferences(item)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = resolvedItem
+		}
+
+		return resolved, nil
+	case []string:
+		resolved := make([]string, len(typedValue))
+		for i, item := range typedValue {
+			resolvedItem, err := r.ResolveReferences(item)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = resolvedItem.(string)
+		}
+
+		return resolved, nil
+	}
+
+	return value, nil
+}
+
+// ContainsSecretReferences tells if the strings of the value have `${secret:name}` references
+func ContainsSecretReferences(value interface{}) bool {
+	switch typedValue := value.(type) {
+	case string:
+		return SecretReferenceRegex.MatchString(typedValue)
+	case map[string]interface{}:
+		for _, item := range typedValue {
+			if ContainsSecretReferences(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range typedValue {
+			if ContainsSecretReferences(item) {
+				return true
+			}
+		}
+	case []string:
+		for _, item := range typedValue {
+			if ContainsSecretReferences(item) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ResolveInput resolves secret references in the input of a block
+func (r *SecretResolver) ResolveInput(input map[string]interface{}) (map[string]interface{}, error) {
+	if input == nil {
+		return nil, nil
+	}
+
+	resolved, err := r.ResolveReferences(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolved.(map[string]interface{}), nil
+}
+
+// ResolveDefinedInput resolves secret references in the input values defined by the Pipeline.
+// Values of the input which differ from the defined ones at the same path come from the API callers
+// or the outputs of the other blocks, so they are kept literal and can not read the secrets
+func (r *SecretResolver) ResolveDefinedInput(
+	input map[string]interface{},
+	definedInput map[string]interface{},
+) (map[string]interface{}, error) {
+	if input == nil {
+		return nil, nil
+	}
+
+	resolved, err := r.resolveDefinedReferences(input, definedInput)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolved.(map[string]interface{}), nil
+}
+
+func (r *SecretResolver) resolveDefinedReferences(value interface{}, definedValue interface{}) (interface{}, error) {
+	switch typedValue := value.(type) {
+	case string:
+		if definedString, ok := definedValue.(string); ok && definedString == typedValue {
+			return r.ResolveReferences(typedValue)
+		}
+
+		return typedValue, nil
+	case map[string]interface{}:
+		definedMap, _ := definedValue.(map[string]interface{})
+		resolved := make(map[string]interface{}, len(typedValue))
+		for key, item := range typedValue {
+			resolvedItem, err := r.resolveDefinedReferences(item, definedMap[key])
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = resolvedItem
+		}
+
+		return resolved, nil
+	case []interface{}:
+		definedSlice, _ := definedValue.([]interface{})
+		resolved := make([]interface{}, len(typedValue))
+		for i, item := range typedValue {
+			var definedItem interface{}
+			if i < len(definedSlice) {
+				definedItem = definedSlice[i]
+			}
+			resolvedItem, err := r.resolveDefinedReferences(item, definedItem)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = resolvedItem
+		}
+
+		return resolved, nil
+	case []string:
+		definedSlice, _ := definedValue.([]string)
+		resolved := make([]string, len(typedValue))
+		for i, item := range typedValue {
+			var definedItem interface{}
+			if i < len(definedSlice) {
+				definedItem = definedSlice[i]
+			}
+			resolvedItem, err := r.resolveDefinedReferences(item, definedItem)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = resolvedItem.(string)
+		}
+
+		return resolved, nil
+	}
+
+	return value, nil
+}
+
+// secretRedactionStore keeps the resolved secrets to redact them from the logs and API responses
+type secretRedactionStore struct {
+	sync.RWMutex
+
+	values   map[string]bool
+	replacer *strings.Replacer
+}
+
+// AddRedactedSecret registers the value to be redacted
+func AddRedactedSecret(value string) {
+	if len(value) < SECRET_REDACTION_MIN_LENGTH {
+		return
+	}
+
+	redactedSecrets.Lock()
+	defer redactedSecrets.Unlock()
+
+	if redactedSecrets.values[value] {
+		return
+	}
+	redactedSecrets.values[value] = true
+
+	forms := make([]string, 0, len(redactedSecrets.values)*2)
+	for secret := range redactedSecrets.values {
+		forms = append(forms, secret)
+		// Secrets are also redacted in their JSON string form
+		if escaped, err := json.Marshal(secret); err == nil {
+			if escapedSecret := string(escaped[1 : len(escaped)-1]); escapedSecret != secret {
+				forms = append(forms, escapedSecret)
+			}
+		}
+	}
+	// Longer secrets are replaced first not to leave parts of them
+	sort.Slice(forms, func(i, j int) bool {
+		return len(forms[i]) > len(forms[j])
+	})
+
+	oldNew := make([]string, 0, len(forms)*2)
+	for _, form := range forms {
+		oldNew = append(oldNew, form, SECRET_REDACTED)
+	}
+	redactedSecrets.replacer = strings.NewReplacer(oldNew...)
+}
+
+// RedactSecrets replaces the resolved secrets in the text
+func RedactSecrets(text string) string {
+	redactedSecrets.RLock()
+	replacer := redactedSecrets.replacer
+	redactedSecrets.RUnlock()
+
+	if replacer == nil {
+		return text
+	}
+
+	return replacer.Replace(text)
+}
@@ -10,6 +10,8 @@ import (
 
 	"github.com/oliveagle/jsonpath"
 
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types/blocks"
 	"data-pipelines-worker/types/helpers"
 	"data-pipelines-worker/types/interfaces"
 )
@@ -192,6 +194,7 @@ func (b *BlockData) CastValueToValidType(
 
 func (b *BlockData) GetInputConfigData(
 	pipelineResults map[string][]*bytes.Buffer,
+	pipelineResultsMetadata ...map[string][]schemas.OutputMetadataSchema,
 ) ([]map[string]interface{}, bool, bool, error) {
 	//  input_config.type = "array"
 	//      the function returns an array of maps
@@ -217,6 +220,8 @@ func (b *BlockData) GetInputConfigData(
 	//  property
 	//  	array_input = true
 	//      	the function assigns an array to the property
+	//  	metadata = true
+	//      	the function uses the metadata of the origin outputs instead of their content
 
 	inputData := make([]map[string]interface{}, 0)
 	inputTypeArrayParallel := false
@@ -276,6 +281,10 @@ func (b *BlockData) GetInputConfigData(
 						}
 
 						if results, ok := pipelineResults[origin]; ok {
+							if useMetadata, _ := property_config.(map[string]interface{})["metadata"].(bool); useMetadata {
+								results = getResultsMetadata(results, origin, pipelineResultsMetadata)
+							}
+
 							for _, resultValue := range results {
 								var rawValue interface{}
 
@@ -400,6 +409,36 @@ func (b *BlockData) GetInputConfigData(
 	return inputData, inputTypeArray, inputTypeArrayParallel, nil
 }
 
+// getResultsMetadata returns the metadata of the origin results as JSON, completed for the results without any
+func getResultsMetadata(
+	results []*bytes.Buffer,
+	origin string,
+	pipelineResultsMetadata []map[string][]schemas.OutputMetadataSchema,
+) []*bytes.Buffer {
+	originMetadata := make([]schemas.OutputMetadataSchema, 0)
+	for _, resultsMetadata := range pipelineResultsMetadata {
+		if metadata, ok := resultsMetadata[origin]; ok {
+			originMetadata = metadata
+		}
+	}
+
+	resultsMetadata := make([]*bytes.Buffer, 0, len(results))
+	for index, result := range results {
+		metadata := schemas.OutputMetadataSchema{}
+		if index < len(originMetadata) {
+			metadata = originMetadata[index]
+		}
+
+		metadataJSON, err := json.Marshal(blocks.CompleteOutputMetadata(result, metadata))
+		if err != nil {
+			metadataJSON = []byte("{}")
+		}
+		resultsMetadata = append(resultsMetadata, bytes.NewBuffer(metadataJSON))
+	}
+
+	return resultsMetadata
+}
+
 // MergeMaps function definition remains unchanged
 func MergeMaps(maps []map[string]interface{}) []map[string]interface{} {
 	if len(maps) == 0 {
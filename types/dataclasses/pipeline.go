@@ -5,7 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -15,11 +17,15 @@ import (
 
 	"data-pipelines-worker/api/schemas"
 	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/helpers"
 	"data-pipelines-worker/types/interfaces"
 	"data-pipelines-worker/types/registries"
 	"data-pipelines-worker/types/validators"
 )
 
+// DEFAULT_PIPELINE_VERSION is the version of the Pipelines defined without one
+const DEFAULT_PIPELINE_VERSION = "1"
+
 // PipelineData represents the structure of a pipeline in the system.
 // It includes the pipeline's metadata, description, and associated blocks.
 //
@@ -34,6 +40,10 @@ type PipelineData struct {
 	// example: "pipeline-abc123"
 	Slug string `json:"slug"`
 
+	// The version of the pipeline. Processings are pinned to the version they are started with
+	// example: "2"
+	Version string `json:"version"`
+
 	// The title of the pipeline
 	// required: true
 	// example: "Example Pipeline"
@@ -43,31 +53,51 @@ type PipelineData struct {
 	// example: "This pipeline processes data in a series of blocks."
 	Description string `json:"description"`
 
+	// The JSON schema of the named input parameters the pipeline is started with.
+	// Blocks reference the parameters with the `$pipeline.input` origin
+	InputSchema map[string]interface{} `json:"input_schema,omitempty"`
+
 	// A list of blocks that make up the pipeline
 	// required: true
 	Blocks []interfaces.ProcessableBlockData `json:"blocks"`
 
+	// A list of schedules which start the pipeline automatically
+	Triggers []interfaces.PipelineTrigger `json:"triggers,omitempty"`
+
+	// Default webhooks notified about every processing of the pipeline.
+	// Hidden from the API output as they may contain secrets
+	Webhooks []schemas.PipelineCallbackSchema `json:"-"`
+
 	// internal field for storing the schema string
 	schemaString string
 
 	// internal field for storing the parsed schema pointer
 	schemaPtr *gojsonschema.Schema
+
+	// internal field for storing the parsed input schema pointer
+	inputSchemaPtr *gojsonschema.Schema
 }
 
 func (p *PipelineData) UnmarshalJSON(data []byte) error {
 	// Declare Blocks a BlockData array
 	type Alias PipelineData
 	aux := &struct {
-		Blocks []*BlockData `json:"blocks"`
+		Blocks   []*BlockData                     `json:"blocks"`
+		Triggers []*PipelineTrigger               `json:"triggers"`
+		Webhooks []schemas.PipelineCallbackSchema `json:"webhooks"`
 		*Alias
 	}{
-		Blocks: make([]*BlockData, 0),
-		Alias:  (*Alias)(p),
+		Blocks:   make([]*BlockData, 0),
+		Triggers: make([]*PipelineTrigger, 0),
+		Alias:    (*Alias)(p),
 	}
 
 	if err := json.Unmarshal(data, aux); err != nil {
 		return err
 	}
+	if aux.Version == "" {
+		aux.Version = DEFAULT_PIPELINE_VERSION
+	}
 
 	aux.schemaString = string(data)
 
@@ -79,15 +109,23 @@ func (p *PipelineData) UnmarshalJSON(data []byte) error {
 	p.schemaString = aux.schemaString
 	p.schemaPtr = schemaPtr
 
-	// List of Blocks
-	registryBlocks := registries.GetBlockRegistry().GetAll()
+	if p.InputSchema != nil {
+		inputSchemaPtr, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(p.InputSchema))
+		if err != nil {
+			return fmt.Errorf("invalid input_schema: %s", err)
+		}
+		p.inputSchemaPtr = inputSchemaPtr
+	}
+
+	// Blocks are looked up by the id or the alias
+	blockRegistry := registries.GetBlockRegistry()
 
 	// Convert []BlockData to []interfaces.ProcessableBlockData
 	p.Blocks = make([]interfaces.ProcessableBlockData, len(aux.Blocks))
 
 	// Loop through each BlockData
 	for i, block := range aux.Blocks {
-		registryBlock := registryBlocks[block.GetId()]
+		registryBlock := blockRegistry.Get(block.GetId())
 
 		block.SetPipeline(p)
 		block.SetBlock(registryBlock)
@@ -95,10 +133,61 @@ func (p *PipelineData) UnmarshalJSON(data []byte) error {
 		p.Blocks[i] = block
 	}
 
+	// Convert []PipelineTrigger to []interfaces.PipelineTrigger
+	p.Triggers = make([]interfaces.PipelineTrigger, len(aux.Triggers))
+	triggerSlugs := make(map[string]bool, len(aux.Triggers))
+	for i, trigger := range aux.Triggers {
+		if err := trigger.Validate(); err != nil {
+			return err
+		}
+		if triggerSlugs[trigger.GetSlug()] {
+			return fmt.Errorf("duplicate trigger slug %s", trigger.GetSlug())
+		}
+		triggerSlugs[trigger.GetSlug()] = true
+
+		blockFound := false
+		for _, block := range p.Blocks {
+			if block.GetSlug() == trigger.GetBlockSlug() {
+				blockFound = true
+				break
+			}
+		}
+		if !blockFound {
+			return fmt.Errorf(
+				"trigger %s: block %s not found in pipeline",
+				trigger.GetSlug(),
+				trigger.GetBlockSlug(),
+			)
+		}
+		if _, err := p.ValidateInput(trigger.GetPipelineInput()); err != nil {
+			return fmt.Errorf("trigger %s: %w", trigger.GetSlug(), err)
+		}
+
+		p.Triggers[i] = trigger
+	}
+
+	for _, webhook := range aux.Webhooks {
+		if err := webhook.Validate(); err != nil {
+			return err
+		}
+	}
+	p.Webhooks = aux.Webhooks
+
 	p.Id = uuid.New()
 	return nil
 }
 
+// MarshalJSON redacts the resolved secrets from the Pipeline definition
+func (p *PipelineData) MarshalJSON() ([]byte, error) {
+	type Alias PipelineData
+	data, err := json.Marshal((*Alias)(p))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(config.RedactSecrets(string(data))), nil
+}
+
 func NewPipelineFromBytes(data []byte) (*PipelineData, error) {
 	pipeline := &PipelineData{}
 	err := pipeline.UnmarshalJSON(data)
@@ -108,8 +197,10 @@ func NewPipelineFromBytes(data []byte) (*PipelineData, error) {
 
 func NewPipelineData() *PipelineData {
 	pipeline := &PipelineData{
-		Id:     uuid.New(),
-		Blocks: make([]interfaces.ProcessableBlockData, 0),
+		Id:       uuid.New(),
+		Version:  DEFAULT_PIPELINE_VERSION,
+		Blocks:   make([]interfaces.ProcessableBlockData, 0),
+		Triggers: make([]interfaces.PipelineTrigger, 0),
 	}
 
 	return pipeline
@@ -123,6 +214,10 @@ func (p *PipelineData) GetSlug() string {
 	return p.Slug
 }
 
+func (p *PipelineData) GetVersion() string {
+	return p.Version
+}
+
 func (p *PipelineData) GetTitle() string {
 	return p.Title
 }
@@ -135,6 +230,14 @@ func (p *PipelineData) GetBlocks() []interfaces.ProcessableBlockData {
 	return p.Blocks
 }
 
+func (p *PipelineData) GetTriggers() []interfaces.PipelineTrigger {
+	return p.Triggers
+}
+
+func (p *PipelineData) GetWebhooks() []schemas.PipelineCallbackSchema {
+	return p.Webhooks
+}
+
 func (p *PipelineData) GetSchemaString() string {
 	return p.schemaString
 }
@@ -155,6 +258,16 @@ func (p *PipelineData) Process(
 
 	// Check if the block exists in the pipeline
 	pipelineBlocks := p.GetBlocks()
+
+	// Pipelines started with the input parameters only are processed from the first block
+	if inputData.Block.Slug == "" && len(pipelineBlocks) > 0 {
+		inputData.Block = schemas.BlockInputSchema{
+			Slug:        pipelineBlocks[0].GetSlug(),
+			Input:       inputData.Block.Input,
+			TargetIndex: -1,
+		}
+	}
+
 	processedBlocks := make([]interfaces.ProcessableBlockData, 0)
 	processBlocks := make([]interfaces.ProcessableBlockData, 0)
 	for i, block := range pipelineBlocks {
@@ -184,11 +297,82 @@ func (p *PipelineData) Process(
 		)
 	}
 
+	if inputData.Callback != nil {
+		if err := inputData.Callback.Validate(); err != nil {
+			return uuid.UUID{}, err
+		}
+		if err := CheckPipelineCallbackURL(*inputData.Callback); err != nil {
+			return uuid.UUID{}, err
+		}
+	}
+	// Secrets of a block go where its defined input sends them. The callers overriding any of its fields
+	// ( e.g. the url next to the token ) could send them to their own destinations
+	if len(inputData.Block.Input) > 0 && config.ContainsSecretReferences(processBlocks[0].GetInputData()) {
+		return uuid.UUID{}, fmt.Errorf(
+			"%w: block %s",
+			config.ErrSecretInputOverridden,
+			processBlocks[0].GetSlug(),
+		)
+	}
+
+	webhookSender := NewPipelineWebhookSender()
+	callbacks := GetPipelineCallbacks(p, inputData)
+	// Deliveries are saved apart from the processing log, they may finish after it is saved
+	webhooksLogger, webhooksLoggerBuffer := config.GetLoggerForEntity("webhooks", processingId)
+
+	logger.Infof(registries.PIPELINE_VERSION_LOG_TEMPLATE, p.GetVersion())
+
+	if inputData.Pipeline.ParentProcessingID != uuid.Nil {
+		logger.Infof(
+			"Processing forked from processing %s at block %s",
+			inputData.Pipeline.ParentProcessingID,
+			inputData.Block.Slug,
+		)
+	}
+
+	// Record the input parameters to resume the processing with them.
+	// Files are not recorded
+	if len(inputData.Pipeline.Input) > 0 {
+		if pipelineInputJSON, err := json.Marshal(getLoggedPipelineInput(inputData.Pipeline.Input)); err == nil {
+			logger.Infof(registries.PIPELINE_INPUT_LOG_TEMPLATE, pipelineInputJSON)
+		}
+	}
+	pipelineInputResults := GetPipelineInputResults(inputData.Pipeline.Input)
+
 	pipelineBlockDataRegistry := registries.NewPipelineBlockDataRegistry(
 		processingId,
 		p.Slug,
 		resultStorages,
 	)
+	processingState := NewPipelineProcessingState()
+
+	// Save the block input to retry the processing with it and log it with the credentials masked.
+	// Binary values ( e.g. uploaded files ) are not recorded
+	if len(inputData.Block.Input) > 0 {
+		blockInput := make(map[string]interface{}, len(inputData.Block.Input))
+		for key, value := range inputData.Block.Input {
+			if _, isBinary := value.([]byte); !isBinary {
+				blockInput[key] = value
+			}
+		}
+		pipelineBlockDataRegistry.SaveBlockInput(inputData.Block.Slug, blockInput)
+
+		for _, blockData := range p.GetBlocks() {
+			if blockData.GetSlug() != inputData.Block.Slug {
+				continue
+			}
+			if inputMasker, ok := blockData.GetBlock().(interfaces.BlockInputMasker); ok {
+				blockInput = inputMasker.MaskInput(blockInput)
+			}
+		}
+		if blockInputJSON, err := json.Marshal(blockInput); err == nil {
+			logger.Infof(
+				registries.PROCESSING_INPUT_LOG_TEMPLATE,
+				inputData.Block.Slug,
+				blockInputJSON,
+			)
+		}
+	}
 
 	// Prepare results of previous Pipeline execution
 	// e.g. if previous Worker passed request to this worker
@@ -197,7 +381,7 @@ func (p *PipelineData) Process(
 	}
 
 	// If inputData.Block.TargetIndex is set - Load it's result also
-	if inputData.Block.TargetIndex >= 0 {
+	if inputData.Block.HasTargetIndex() {
 		pipelineBlockDataRegistry.LoadOutput(inputData.Block.Slug)
 	}
 
@@ -206,11 +390,32 @@ func (p *PipelineData) Process(
 
 		// Save result of Pipeline execution in any case
 		defer func() {
+			if processingErr := processingState.GetError(); processingErr != nil {
+				logger.Errorf(registries.PIPELINE_FAILED_LOG_TEMPLATE, p.GetSlug(), processingErr)
+			}
+
+			webhookPayload := NewPipelineWebhookPayload(
+				p,
+				processingId,
+				processingState,
+				pipelineBlockDataRegistry,
+			)
+
 			pipelineBlockDataRegistry.SavePipelineLog(
 				loggerBuffer,
-				NewPipelineProcessingDetailsFromLogData,
-				NewPipelineProcessingStatusFromLogData,
+				processingState.NewDetails,
+				processingState.NewStatus,
 			)
+			registries.GetUsageRegistry().SetSaved(p.GetSlug(), processingId)
+
+			// Deliveries are retried with backoff, so they must not delay the final log and status.
+			// The final event is delivered after the events queued before and the log of all
+			// the deliveries is saved next to the processing log afterwards
+			if len(callbacks) > 0 {
+				webhookSender.NotifyAsync(webhooksLogger, callbacks, webhookPayload, func() {
+					pipelineBlockDataRegistry.SaveWebhooksLog(webhooksLoggerBuffer)
+				})
+			}
 		}()
 
 		// Loop through each block
@@ -223,6 +428,7 @@ func (p *PipelineData) Process(
 					blockData.GetSlug(),
 				)
 				logger.Error(err)
+				processingState.SetError(err)
 				return
 			}
 
@@ -249,31 +455,39 @@ func (p *PipelineData) Process(
 			if blockData.GetInputConfig() != nil {
 				var err error
 
-				// Get historical data ( previous steps )
-				processingData := pipelineBlockDataRegistry.GetAll()
+				// Get historical data ( previous steps ) and the input parameters
+				processingData := make(map[string][]*bytes.Buffer)
+				for origin, results := range pipelineInputResults {
+					processingData[origin] = results
+				}
+				for blockSlug, results := range pipelineBlockDataRegistry.GetAll() {
+					processingData[blockSlug] = results
+				}
 
 				// If input data passed for the block - remove it from processingData
 				if (blockRelativeIndex == 0 &&
 					inputData.Block.Slug == blockData.GetSlug()) &&
 					inputData.Block.Input != nil &&
 					len(inputData.Block.Input) > 0 &&
-					inputData.Block.TargetIndex < 0 {
+					!inputData.Block.HasTargetIndex() {
 
 					processingData[inputData.Block.Slug] = nil
 				}
 
-				inputConfigValue, isArray, parallel, err = blockData.GetInputConfigData(processingData)
+				inputConfigValue, isArray, parallel, err = blockData.GetInputConfigData(
+					processingData,
+					pipelineBlockDataRegistry.GetAllMetadata(),
+				)
 				if err != nil {
 					logger.Error(err)
-					tmpProcessing.Stop(
-						interfaces.ProcessingStatusFailed,
-						fmt.Errorf(
-							"error getting input config data for block [%s:%s]. Error: %s",
-							blockData.GetSlug(),
-							block.GetId(),
-							err,
-						),
+					err = fmt.Errorf(
+						"error getting input config data for block [%s:%s]. Error: %s",
+						blockData.GetSlug(),
+						block.GetId(),
+						err,
 					)
+					tmpProcessing.Stop(interfaces.ProcessingStatusFailed, err)
+					processingState.SetError(err)
 					return
 				}
 			}
@@ -313,20 +527,26 @@ func (p *PipelineData) Process(
 					Pipeline: schemas.PipelineInputSchema{
 						Slug:         blockData.GetPipeline().GetSlug(),
 						ProcessingID: processingId,
+						Version:      p.GetVersion(),
+						Input:        inputData.Pipeline.Input,
 					},
 					Block: schemas.BlockInputSchema{
 						Slug:  blockData.GetSlug(),
 						Input: make(map[string]interface{}),
 					},
+					Callback: inputData.Callback,
 				}
 				if blockRelativeIndex == 0 {
 					_inputData = inputData
+					_inputData.Pipeline.Version = p.GetVersion()
 				}
 
+				// Workers must have a version compatible with the pinned one
+				_, pinnedVersion := helpers.ParseBlockReference(blockData.GetId())
 				if err := workerRegistry.ResumeProcessing(
 					blockData.GetPipeline().GetSlug(),
 					processingId,
-					block.GetId(),
+					helpers.FormatBlockReference(block.GetId(), pinnedVersion),
 					_inputData,
 				); err != nil {
 					tmpProcessing.Stop(interfaces.ProcessingStatusFailed, err)
@@ -337,6 +557,31 @@ func (p *PipelineData) Process(
 				return
 			}
 
+			// Secret references are resolved right before the processing,
+			// blockInputsData keeps them unresolved for the regeneration.
+			// Only the references of the Pipeline definition are resolved, the input of the API callers
+			// and the outputs of the other blocks are literal, so they can not read the other secrets.
+			// The callers can not override the input of the blocks using secrets, see above
+			secretResolver := config.GetSecretResolver()
+			definedBlockInput, _ := blockData.GetInputData().(map[string]interface{})
+			resolvedBlockInputData := make([]map[string]interface{}, len(blockInputData))
+			for blockInputIndex, blockInput := range blockInputData {
+				resolvedBlockInput, err := secretResolver.ResolveDefinedInput(blockInput, definedBlockInput)
+				if err != nil {
+					logger.Error(err)
+					err = fmt.Errorf(
+						"error resolving secrets for block [%s:%s]. Error: %s",
+						blockData.GetSlug(),
+						block.GetId(),
+						err,
+					)
+					tmpProcessing.Stop(interfaces.ProcessingStatusFailed, err)
+					processingState.SetError(err)
+					return
+				}
+				resolvedBlockInputData[blockInputIndex] = resolvedBlockInput
+			}
+
 			logger.Infof(
 				"Starting processing data for block [%s:%s]",
 				blockData.GetSlug(),
@@ -353,12 +598,12 @@ func (p *PipelineData) Process(
 			blockInputProcessingResults := make(chan blockInputProcessingResult, len(blockInputData))
 			defer close(blockInputProcessingResults)
 
-			pipelineBlockDataRegistry.PrepareBlockData(blockData.GetSlug(), len(blockInputData))
+			pipelineBlockDataRegistry.PrepareBlockData(blockData.GetSlug(), len(resolvedBlockInputData))
 
-			for blockInputIndex, blockInput := range blockInputData {
-				if inputData.Block.TargetIndex >= 0 {
+			for blockInputIndex, blockInput := range resolvedBlockInputData {
+				if inputData.Block.HasTargetIndex() {
 					if blockRelativeIndex == 0 || (destinationBlockIndex >= 0 && blockIndex < destinationBlockIndex) {
-						if blockInputIndex != inputData.Block.TargetIndex {
+						if !inputData.Block.IsTargetIndex(blockInputIndex) {
 							blockInputProcessingResults <- blockInputProcessingResult{
 								index:   blockInputIndex,
 								err:     nil,
@@ -408,6 +653,21 @@ func (p *PipelineData) Process(
 					defer blockInputWg.Done()
 
 					processingOutput := processingRegistry.StartProcessing(_processing)
+
+					// Paid API calls are accounted for the failed processings too
+					if usage := processingOutput.GetUsage(); !usage.IsZero() {
+						if usageJSON, err := json.Marshal(usage); err == nil {
+							logger.Infof(
+								registries.PROCESSING_USAGE_LOG_TEMPLATE,
+								_blockData.GetSlug(),
+								blockInputIndex,
+								usageJSON,
+							)
+						}
+						processingState.AddUsage(usage)
+						registries.GetUsageRegistry().Add(p.GetSlug(), processingId, usage)
+					}
+
 					_blockInputProcessingResults <- blockInputProcessingResult{
 						index:   blockInputIndex,
 						err:     processingOutput.GetError(),
@@ -425,6 +685,7 @@ func (p *PipelineData) Process(
 						)
 						if processingOutput.GetError() != context.Canceled {
 							logger.Error(_err)
+							processingState.SetError(_err)
 						}
 						return processingOutput, _err
 					}
@@ -446,6 +707,7 @@ func (p *PipelineData) Process(
 
 						if targetBlockSlug != "" &&
 							targetBlockInputIndex >= 0 {
+							processingState.SetStopped()
 							logger.Warnf(
 								"Pipeline stopped by block [%s:%s] with index %d. Regenerating block %s with index %d",
 								_blockData.GetSlug(),
@@ -476,6 +738,8 @@ func (p *PipelineData) Process(
 									Pipeline: schemas.PipelineInputSchema{
 										Slug:         p.GetSlug(),
 										ProcessingID: processingId,
+										Version:      p.GetVersion(),
+										Input:        inputData.Pipeline.Input,
 									},
 									Block: schemas.BlockInputSchema{
 										Slug:        _targetBlockSlug,
@@ -484,6 +748,7 @@ func (p *PipelineData) Process(
 										// Will process only TargetIndex until it reaches DestinationSlug
 										// DestinationSlug: _destinationBlockSlug,
 									},
+									Callback: inputData.Callback,
 								}
 
 								p.Process(
@@ -504,6 +769,7 @@ func (p *PipelineData) Process(
 								destinationBlockSlug,
 							)
 						} else {
+							processingState.SetStopped()
 							logger.Infof(
 								"Pipeline stopped by block [%s:%s]",
 								_blockData.GetSlug(),
@@ -524,19 +790,24 @@ func (p *PipelineData) Process(
 					// else we need to save all output Buffers as independent indexes
 					if isArray {
 						outputResult := bytes.NewBuffer(nil)
+						outputMetadata := schemas.OutputMetadataSchema{}
 						if processingOutput.GetValue() != nil && len(processingOutput.GetValue()) > 0 {
 							outputResult = processingOutput.GetValue()[0]
 						}
+						if len(processingOutput.GetMetadata()) > 0 {
+							outputMetadata = processingOutput.GetMetadata()[0]
+						}
 						pipelineBlockDataRegistry.UpdateBlockData(
 							_blockData.GetSlug(),
 							blockInputIndex,
 							outputResult,
 						)
 						// Save result to Storage
-						saveOutputResults := pipelineBlockDataRegistry.SaveOutput(
+						saveOutputResults := pipelineBlockDataRegistry.SaveOutputWithMetadata(
 							_blockData.GetSlug(),
 							blockInputIndex,
 							outputResult,
+							outputMetadata,
 						)
 						for _, saveOutputResult := range saveOutputResults {
 							if saveOutputResult.Error != nil {
@@ -563,6 +834,7 @@ func (p *PipelineData) Process(
 						if processingOutput.GetValue() != nil && len(processingOutput.GetValue()) > 0 {
 							outputResult = processingOutput.GetValue()
 						}
+						outputMetadata := processingOutput.GetMetadata()
 
 						pipelineBlockDataRegistry.PrepareBlockData(blockData.GetSlug(), len(outputResult))
 						for outputIndex, output := range outputResult {
@@ -571,10 +843,15 @@ func (p *PipelineData) Process(
 								outputIndex,
 								output,
 							)
-							saveOutputResults := pipelineBlockDataRegistry.SaveOutput(
+							metadata := schemas.OutputMetadataSchema{}
+							if outputIndex < len(outputMetadata) {
+								metadata = outputMetadata[outputIndex]
+							}
+							saveOutputResults := pipelineBlockDataRegistry.SaveOutputWithMetadata(
 								_blockData.GetSlug(),
 								outputIndex,
 								output,
+								metadata,
 							)
 							for _, saveOutputResult := range saveOutputResults {
 								if saveOutputResult.Error != nil {
@@ -601,6 +878,22 @@ func (p *PipelineData) Process(
 						}
 					}
 
+					if slices.Contains(MODERATION_WAIT_BLOCK_IDS, block.GetId()) {
+						webhookSender.NotifyAsync(
+							webhooksLogger,
+							callbacks,
+							&PipelineWebhookPayload{
+								Event:        schemas.PIPELINE_EVENT_MODERATION_WAIT,
+								PipelineSlug: p.GetSlug(),
+								ProcessingId: processingId,
+								BlockSlug:    _blockData.GetSlug(),
+								Blocks:       GetPipelineWebhookBlockSummaries(p, pipelineBlockDataRegistry),
+								Timestamp:    time.Now().UTC(),
+							},
+							nil,
+						)
+					}
+
 					return processingOutput, nil
 				}
 
@@ -634,12 +927,148 @@ func (p *PipelineData) Process(
 			}
 		}
 
+		processingState.SetCompleted()
 		logger.Infof("Processing Pipeline %s completed", p.GetSlug())
 	}()
 
 	return processingId, nil
 }
 
+// getProcessingIndex returns the Processing Index of the Pipeline processings in the storages
+// or nil if the storages have to be listed instead, e.g. until the Pipeline is indexed in the background.
+// The processings saved by the other workers are indexed in the background, never on request
+func (p *PipelineData) getProcessingIndex(resultStorages []interfaces.Storage) *registries.ProcessingIndex {
+	processingIndex := registries.GetProcessingIndex()
+	if !processingIndex.IsEnabled() || !processingIndex.IsIndexed(p.GetSlug(), resultStorages) {
+		return nil
+	}
+
+	return processingIndex
+}
+
+// getProcessingIndexLogs returns the logs of the indexed processing entries
+func (p *PipelineData) getProcessingIndexLogs(
+	entries []registries.ProcessingIndexEntry,
+	resultStorages []interfaces.Storage,
+) []interfaces.PipelineProcessingDetails {
+	processings := make([]interfaces.PipelineProcessingDetails, 0)
+
+	for _, entry := range entries {
+		if entry.LogPath == "" {
+			continue
+		}
+
+		for _, storage := range resultStorages {
+			if storage.GetStorageName() != entry.Storage {
+				continue
+			}
+
+			logDataBuffer, err := storage.GetObjectBytes(storage.NewStorageLocation(entry.LogPath))
+			if err != nil {
+				continue
+			}
+			processings = append(
+				processings,
+				NewProcessingDetailsFromLogFile(
+					entry.ProcessingId,
+					p.GetSlug(),
+					logDataBuffer,
+					storage,
+				),
+			)
+			break
+		}
+	}
+
+	return processings
+}
+
+// QueryProcessingsStatus returns a page of the processing statuses matching the query
+// and the total number of matching statuses
+func (p *PipelineData) QueryProcessingsStatus(
+	query schemas.PipelineProcessingsQuerySchema,
+	resultStorages []interfaces.Storage,
+) ([]interfaces.PipelineProcessingStatus, int) {
+	var entries []registries.ProcessingIndexEntry
+	total := 0
+
+	processingIndex := p.getProcessingIndex(resultStorages)
+	if processingIndex != nil {
+		var err error
+		entries, total, err = processingIndex.Query(p.GetSlug(), query, resultStorages)
+		if err != nil {
+			processingIndex = nil
+		}
+	}
+	if processingIndex == nil {
+		// List the storages when the index is unavailable
+		allEntries := make([]registries.ProcessingIndexEntry, 0)
+		for _, storage := range resultStorages {
+			// Unavailable storages are skipped
+			storageEntries, _ := registries.ListProcessingIndexEntries(storage, p.GetSlug())
+			allEntries = append(allEntries, storageEntries...)
+		}
+		entries, total = registries.QueryProcessingIndexEntries(allEntries, query)
+	}
+
+	processingsStatus := make([]interfaces.PipelineProcessingStatus, 0, len(entries))
+	for _, entry := range entries {
+		processingStatus := &PipelineProcessingStatus{
+			Id:           entry.ProcessingId,
+			PipelineSlug: p.GetSlug(),
+			LogId:        entry.LogId,
+			Storage:      entry.Storage,
+			IsStopped:    entry.IsStopped,
+			IsCompleted:  entry.IsCompleted,
+			IsError:      entry.IsError,
+			DateFinished: entry.DateFinished,
+		}
+		if len(entry.Status) > 0 {
+			if err := json.Unmarshal(entry.Status, processingStatus); err != nil {
+				config.GetLogger().Error(err)
+			}
+		}
+
+		processingsStatus = append(processingsStatus, processingStatus)
+	}
+
+	return processingsStatus, total
+}
+
+// QueryProcessingsUsage sums the usage of the processing statuses matching the query
+func (p *PipelineData) QueryProcessingsUsage(
+	query schemas.PipelineProcessingsQuerySchema,
+	resultStorages []interfaces.Storage,
+) schemas.PipelineUsageSchema {
+	pipelineUsage := schemas.PipelineUsageSchema{
+		PipelineSlug: p.GetSlug(),
+		From:         query.From,
+		To:           query.To,
+		Usage: schemas.ProcessingUsageSchema{
+			Currency: config.GetConfig().Costs.Currency,
+		},
+	}
+
+	query.Offset = 0
+	query.Limit = schemas.PROCESSINGS_MAX_LIMIT
+	for {
+		processingsStatus, total := p.QueryProcessingsStatus(query, resultStorages)
+		for _, processingStatus := range processingsStatus {
+			if usage := processingStatus.(*PipelineProcessingStatus).Usage; usage != nil {
+				pipelineUsage.Processings++
+				pipelineUsage.Usage.Add(*usage)
+			}
+		}
+
+		query.Offset += len(processingsStatus)
+		if len(processingsStatus) == 0 || query.Offset >= total {
+			break
+		}
+	}
+
+	return pipelineUsage
+}
+
 func (p *PipelineData) GetProcessingsStatus(resultStorages []interfaces.Storage) map[uuid.UUID][]interfaces.PipelineProcessingStatus {
 	pipelineProcessingsPath := fmt.Sprintf(
 		"%s", p.GetSlug(),
@@ -698,6 +1127,22 @@ func (p *PipelineData) GetProcessingsStatus(resultStorages []interfaces.Storage)
 }
 
 func (p *PipelineData) GetProcessingDetailsByLogId(processingId uuid.UUID, logId uuid.UUID, resultStorages []interfaces.Storage) interfaces.PipelineProcessingDetails {
+	if processingIndex := p.getProcessingIndex(resultStorages); processingIndex != nil {
+		entries := processingIndex.GetProcessingEntries(p.GetSlug(), processingId, resultStorages)
+		for _, entry := range entries {
+			if entry.LogId != logId {
+				continue
+			}
+			if processings := p.getProcessingIndexLogs(
+				[]registries.ProcessingIndexEntry{entry},
+				resultStorages,
+			); len(processings) > 0 {
+				return processings[0]
+			}
+		}
+		// The log may be saved by another worker after the index was refreshed, so the storages are listed
+	}
+
 	pipelineProcessingsPath := fmt.Sprintf(
 		"%s", p.GetSlug(),
 	)
@@ -713,9 +1158,10 @@ func (p *PipelineData) GetProcessingDetailsByLogId(processingId uuid.UUID, logId
 	var processing interfaces.PipelineProcessingDetails
 
 	for _, storage := range resultStorages {
+		// Local storages list a single directory, so the processing directory is listed
 		objects, err := storage.ListObjects(
 			storage.NewStorageLocation(
-				pipelineProcessingsPath,
+				path.Join(pipelineProcessingsPath, processingId.String()),
 			),
 		)
 		if err != nil || len(objects) == 0 {
@@ -751,6 +1197,16 @@ func (p *PipelineData) GetProcessingDetailsByLogId(processingId uuid.UUID, logId
 }
 
 func (p *PipelineData) GetProcessingDetails(processingId uuid.UUID, resultStorages []interfaces.Storage) []interfaces.PipelineProcessingDetails {
+	if processingIndex := p.getProcessingIndex(resultStorages); processingIndex != nil {
+		if processings := p.getProcessingIndexLogs(
+			processingIndex.GetProcessingEntries(p.GetSlug(), processingId, resultStorages),
+			resultStorages,
+		); len(processings) > 0 {
+			return processings
+		}
+		// The processing may be saved by another worker after the index was refreshed, so the storages are listed
+	}
+
 	pipelineProcessingsPath := fmt.Sprintf(
 		"%s", p.GetSlug(),
 	)
@@ -765,9 +1221,10 @@ func (p *PipelineData) GetProcessingDetails(processingId uuid.UUID, resultStorag
 
 	processings := make([]interfaces.PipelineProcessingDetails, 0)
 	for _, storage := range resultStorages {
+		// Local storages list a single directory, so the processing directory is listed
 		objects, err := storage.ListObjects(
 			storage.NewStorageLocation(
-				pipelineProcessingsPath,
+				path.Join(pipelineProcessingsPath, processingId.String()),
 			),
 		)
 		if err != nil || len(objects) == 0 {
@@ -806,14 +1263,38 @@ func (p *PipelineData) GetProcessingDetails(processingId uuid.UUID, resultStorag
 //
 // swagger:model
 type PipelineProcessingStatus struct {
-	Id           uuid.UUID `json:"id"`
-	PipelineSlug string    `json:"pipeline_slug"`
-	LogId        uuid.UUID `json:"log_id"`
-	Storage      string    `json:"storage"`
-	IsStopped    bool      `json:"is_stopped"`
-	IsCompleted  bool      `json:"is_completed"`
-	IsError      bool      `json:"is_error"`
-	DateFinished time.Time `json:"date_finished"`
+	Id                 uuid.UUID `json:"id"`
+	PipelineSlug       string    `json:"pipeline_slug"`
+	PipelineVersion    string    `json:"pipeline_version"`
+	LogId              uuid.UUID `json:"log_id"`
+	Storage            string    `json:"storage"`
+	IsStopped          bool      `json:"is_stopped"`
+	IsCompleted        bool      `json:"is_completed"`
+	IsError            bool      `json:"is_error"`
+	DateFinished       time.Time `json:"date_finished"`
+	ParentProcessingId uuid.UUID `json:"parent_processing_id"`
+	ParentBlockSlug    string    `json:"parent_block_slug"`
+
+	Usage *schemas.ProcessingUsageSchema `json:"usage"`
+}
+
+// PipelineProcessingForkedRegex matches the log message of a forked processing
+var PipelineProcessingForkedRegex = regexp.MustCompile(
+	`"message":"Processing forked from processing ([a-f0-9-]{36}) at block ([-\w]+)"`,
+)
+
+// PipelineProcessingVersionRegex matches the log message with the Pipeline version of the processing
+var PipelineProcessingVersionRegex = regexp.MustCompile(
+	`"message":"Processing Pipeline version ([-\w.]+)"`,
+)
+
+// getParentProcessingId returns a pointer to the parent processing ID if it is set
+func (p *PipelineProcessingStatus) getParentProcessingId() *uuid.UUID {
+	if p.ParentProcessingId == uuid.Nil {
+		return nil
+	}
+
+	return &p.ParentProcessingId
 }
 
 func (p *PipelineProcessingStatus) GetId() uuid.UUID {
@@ -826,21 +1307,29 @@ func (p *PipelineProcessingStatus) GetLogId() uuid.UUID {
 
 func (p *PipelineProcessingStatus) MarshalJSON() ([]byte, error) {
 	customRepresentation := struct {
-		Id           uuid.UUID `json:"id"`
-		LogId        uuid.UUID `json:"log_id"`
-		Storage      string    `json:"storage"`
-		IsStopped    bool      `json:"is_stopped"`
-		IsCompleted  bool      `json:"is_completed"`
-		IsError      bool      `json:"is_error"`
-		DateFinished time.Time `json:"date_finished"`
+		Id                 uuid.UUID                      `json:"id"`
+		PipelineVersion    string                         `json:"pipeline_version,omitempty"`
+		LogId              uuid.UUID                      `json:"log_id"`
+		Storage            string                         `json:"storage"`
+		IsStopped          bool                           `json:"is_stopped"`
+		IsCompleted        bool                           `json:"is_completed"`
+		IsError            bool                           `json:"is_error"`
+		DateFinished       time.Time                      `json:"date_finished"`
+		ParentProcessingId *uuid.UUID                     `json:"parent_processing_id,omitempty"`
+		ParentBlockSlug    string                         `json:"parent_block_slug,omitempty"`
+		Usage              *schemas.ProcessingUsageSchema `json:"usage,omitempty"`
 	}{
-		Id:           p.Id,
-		LogId:        p.LogId,
-		Storage:      p.Storage,
-		IsStopped:    p.IsStopped,
-		IsCompleted:  p.IsCompleted,
-		IsError:      p.IsError,
-		DateFinished: p.DateFinished,
+		Id:                 p.Id,
+		PipelineVersion:    p.PipelineVersion,
+		LogId:              p.LogId,
+		Storage:            p.Storage,
+		IsStopped:          p.IsStopped,
+		IsCompleted:        p.IsCompleted,
+		IsError:            p.IsError,
+		DateFinished:       p.DateFinished,
+		ParentProcessingId: p.getParentProcessingId(),
+		ParentBlockSlug:    p.ParentBlockSlug,
+		Usage:              p.Usage,
 	}
 
 	return json.Marshal(customRepresentation)
@@ -872,44 +1361,31 @@ func NewPipelineProcessingStatusFromLogData(
 	logBuffer *bytes.Buffer,
 	storage interfaces.Storage,
 ) interfaces.PipelineProcessingStatus {
-	is_stopped := false
-	is_completed := false
-	is_error := false
-
 	logData := logBuffer.String()
 
-	// Check that pipeline is correct
-	if strings.Contains(
-		logData, fmt.Sprintf(`"prefix":"pipeline:%s"`, id.String()),
-	) {
-		if strings.Contains(
-			logData, fmt.Sprintf(`"message":"Processing Pipeline %s completed"`, pipelineSlug),
-		) {
-			is_completed = true
-		}
-		if strings.Contains(
-			logData, `"message":"Pipeline stopped by block [`,
-		) {
-			is_stopped = true
-		}
+	pipelineVersion := ""
+	if matches := PipelineProcessingVersionRegex.FindStringSubmatch(logData); len(matches) == 2 {
+		pipelineVersion = matches[1]
 	}
 
-	if strings.Contains(
-		logData,
-		`"ERROR"`,
-	) {
-		is_error = true
+	parentProcessingId := uuid.Nil
+	parentBlockSlug := ""
+	if matches := PipelineProcessingForkedRegex.FindStringSubmatch(logData); len(matches) == 3 {
+		if parsedId, err := uuid.Parse(matches[1]); err == nil {
+			parentProcessingId = parsedId
+			parentBlockSlug = matches[2]
+		}
 	}
 
 	return &PipelineProcessingStatus{
-		Id:           id,
-		Storage:      storage.GetStorageName(),
-		PipelineSlug: pipelineSlug,
-		LogId:        logId,
-		IsStopped:    is_stopped,
-		IsCompleted:  is_completed,
-		IsError:      is_error,
-		DateFinished: time.Now().UTC(),
+		Id:                 id,
+		Storage:            storage.GetStorageName(),
+		PipelineSlug:       pipelineSlug,
+		PipelineVersion:    pipelineVersion,
+		LogId:              logId,
+		DateFinished:       time.Now().UTC(),
+		ParentProcessingId: parentProcessingId,
+		ParentBlockSlug:    parentBlockSlug,
 	}
 }
 
@@ -932,25 +1408,33 @@ func (p *PipelineProcessingDetails) GetLogId() uuid.UUID {
 
 func (p *PipelineProcessingDetails) MarshalJSON() ([]byte, error) {
 	customRepresentation := struct {
-		Id           uuid.UUID                `json:"id"`
-		PipelineSlug string                   `json:"pipeline_slug"`
-		LogId        uuid.UUID                `json:"log_id"`
-		Storage      string                   `json:"storage"`
-		IsStopped    bool                     `json:"is_stopped"`
-		IsCompleted  bool                     `json:"is_completed"`
-		IsError      bool                     `json:"is_error"`
-		DateFinished time.Time                `json:"date_finished"`
-		LogData      []map[string]interface{} `json:"log_data"`
+		Id                 uuid.UUID                      `json:"id"`
+		PipelineSlug       string                         `json:"pipeline_slug"`
+		PipelineVersion    string                         `json:"pipeline_version,omitempty"`
+		LogId              uuid.UUID                      `json:"log_id"`
+		Storage            string                         `json:"storage"`
+		IsStopped          bool                           `json:"is_stopped"`
+		IsCompleted        bool                           `json:"is_completed"`
+		IsError            bool                           `json:"is_error"`
+		DateFinished       time.Time                      `json:"date_finished"`
+		ParentProcessingId *uuid.UUID                     `json:"parent_processing_id,omitempty"`
+		ParentBlockSlug    string                         `json:"parent_block_slug,omitempty"`
+		Usage              *schemas.ProcessingUsageSchema `json:"usage,omitempty"`
+		LogData            []map[string]interface{}       `json:"log_data"`
 	}{
-		Id:           p.Id,
-		PipelineSlug: p.PipelineSlug,
-		LogId:        p.LogId,
-		Storage:      p.Storage,
-		IsStopped:    p.IsStopped,
-		IsCompleted:  p.IsCompleted,
-		IsError:      p.IsError,
-		DateFinished: p.DateFinished,
-		LogData:      p.LogData,
+		Id:                 p.Id,
+		PipelineSlug:       p.PipelineSlug,
+		PipelineVersion:    p.PipelineVersion,
+		LogId:              p.LogId,
+		Storage:            p.Storage,
+		IsStopped:          p.IsStopped,
+		IsCompleted:        p.IsCompleted,
+		IsError:            p.IsError,
+		DateFinished:       p.DateFinished,
+		ParentProcessingId: p.getParentProcessingId(),
+		ParentBlockSlug:    p.ParentBlockSlug,
+		Usage:              p.Usage,
+		LogData:            p.LogData,
 	}
 
 	return json.Marshal(customRepresentation)
@@ -999,3 +1483,127 @@ func NewPipelineProcessingDetailsFromLogData(
 		LogData:                  logData,
 	}
 }
+
+// PipelineProcessingState is the state of a running processing which is saved with its status
+// and sent to the callbacks instead of being recovered from the log
+type PipelineProcessingState struct {
+	sync.Mutex
+
+	isCompleted bool
+	isStopped   bool
+	// First error the processing failed with, parallel block inputs may fail at once
+	err   error
+	usage *schemas.ProcessingUsageSchema
+}
+
+func NewPipelineProcessingState() *PipelineProcessingState {
+	return &PipelineProcessingState{}
+}
+
+func (s *PipelineProcessingState) SetCompleted() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.isCompleted = true
+}
+
+func (s *PipelineProcessingState) IsCompleted() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.isCompleted
+}
+
+// SetStopped marks the processing stopped by a block, e.g. declined by the moderator
+func (s *PipelineProcessingState) SetStopped() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.isStopped = true
+}
+
+func (s *PipelineProcessingState) IsStopped() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.isStopped
+}
+
+// SetError records the error the processing failed with. Errors the processing recovered from,
+// e.g. failed output saving, are logged only
+func (s *PipelineProcessingState) SetError(err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+func (s *PipelineProcessingState) GetError() error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.err
+}
+
+// AddUsage adds the usage of the paid API calls of a processed block input
+func (s *PipelineProcessingState) AddUsage(usage schemas.ProcessingUsageSchema) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.usage == nil {
+		s.usage = &schemas.ProcessingUsageSchema{}
+	}
+	s.usage.Add(usage)
+}
+
+// GetUsage returns a copy of the usage or nil if the processing did not use paid API calls
+func (s *PipelineProcessingState) GetUsage() *schemas.ProcessingUsageSchema {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.usage == nil {
+		return nil
+	}
+
+	usage := &schemas.ProcessingUsageSchema{}
+	usage.Add(*s.usage)
+
+	return usage
+}
+
+// NewStatus builds the processing status saved with the log
+func (s *PipelineProcessingState) NewStatus(
+	id uuid.UUID,
+	slug string,
+	logId uuid.UUID,
+	logBuffer *bytes.Buffer,
+	storage interfaces.Storage,
+) interfaces.PipelineProcessingStatus {
+	status := NewPipelineProcessingStatusFromLogData(id, slug, logId, logBuffer, storage).(*PipelineProcessingStatus)
+	s.setStatus(status)
+
+	return status
+}
+
+// NewDetails builds the processing details saved as the log
+func (s *PipelineProcessingState) NewDetails(
+	id uuid.UUID,
+	slug string,
+	logId uuid.UUID,
+	logBuffer *bytes.Buffer,
+	storage interfaces.Storage,
+) interfaces.PipelineProcessingDetails {
+	details := NewPipelineProcessingDetailsFromLogData(id, slug, logId, logBuffer, storage).(*PipelineProcessingDetails)
+	s.setStatus(&details.PipelineProcessingStatus)
+
+	return details
+}
+
+func (s *PipelineProcessingState) setStatus(status *PipelineProcessingStatus) {
+	status.IsCompleted = s.IsCompleted()
+	status.IsStopped = s.IsStopped()
+	status.IsError = s.GetError() != nil
+	status.Usage = s.GetUsage()
+}
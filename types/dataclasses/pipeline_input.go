@@ -0,0 +1,147 @@
+package dataclasses
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// PIPELINE_INPUT_ORIGIN is the `input_config` origin of the Pipeline input parameters.
+// A single parameter is referenced as `$pipeline.input.<name>`
+const PIPELINE_INPUT_ORIGIN = "$pipeline.input"
+
+func (p *PipelineData) GetInputSchema() map[string]interface{} {
+	return p.InputSchema
+}
+
+// getInputProperties returns the declared parameters of the input schema
+func (p *PipelineData) getInputProperties() map[string]interface{} {
+	properties, _ := p.InputSchema["properties"].(map[string]interface{})
+
+	return properties
+}
+
+// ValidateInput validates the input parameters against the input schema of the Pipeline.
+// Form values are cast to the declared types and missing parameters get their default values
+func (p *PipelineData) ValidateInput(input map[string]interface{}) (map[string]interface{}, error) {
+	if p.inputSchemaPtr == nil {
+		return input, nil
+	}
+
+	properties := p.getInputProperties()
+
+	validInput := make(map[string]interface{}, len(input))
+	for name, value := range input {
+		property, _ := properties[name].(map[string]interface{})
+		validInput[name] = castPipelineInputValue(value, property)
+	}
+	for name, property := range properties {
+		if _, ok := validInput[name]; ok {
+			continue
+		}
+		if propertyMap, ok := property.(map[string]interface{}); ok {
+			if defaultValue, ok := propertyMap["default"]; ok {
+				validInput[name] = defaultValue
+			}
+		}
+	}
+
+	validationResult, err := p.inputSchemaPtr.Validate(gojsonschema.NewGoLoader(validInput))
+	if err != nil {
+		return nil, err
+	}
+	if !validationResult.Valid() {
+		errStr := fmt.Sprintf("Pipeline input is invalid for pipeline: %s", p.GetSlug())
+		for _, err := range validationResult.Errors() {
+			errStr += fmt.Sprintf("\n- %s", err)
+		}
+		return nil, errors.New(errStr)
+	}
+
+	return validInput, nil
+}
+
+// castPipelineInputValue casts the form value to the declared type of the parameter.
+// Values which can not be cast are left as is to be reported by the validation
+func castPipelineInputValue(value interface{}, property map[string]interface{}) interface{} {
+	propertyType, _ := property["type"].(string)
+
+	switch typedValue := value.(type) {
+	case string:
+		switch propertyType {
+		case "integer":
+			if castedValue, err := strconv.ParseInt(typedValue, 10, 64); err == nil {
+				return castedValue
+			}
+		case "number":
+			if castedValue, err := strconv.ParseFloat(typedValue, 64); err == nil {
+				return castedValue
+			}
+		case "boolean":
+			if castedValue, err := strconv.ParseBool(typedValue); err == nil {
+				return castedValue
+			}
+		case "object", "array":
+			var castedValue interface{}
+			if err := json.Unmarshal([]byte(typedValue), &castedValue); err == nil {
+				return castedValue
+			}
+		}
+	case []string:
+		items, _ := property["items"].(map[string]interface{})
+		castedValues := make([]interface{}, len(typedValue))
+		for i, item := range typedValue {
+			castedValues[i] = castPipelineInputValue(item, items)
+		}
+		return castedValues
+	}
+
+	return value
+}
+
+// getLoggedPipelineInput returns the input parameters without files to record them in the log
+func getLoggedPipelineInput(input map[string]interface{}) map[string]interface{} {
+	loggedInput := make(map[string]interface{}, len(input))
+	for name, value := range input {
+		if _, isBinary := value.([]byte); !isBinary {
+			loggedInput[name] = value
+		}
+	}
+
+	return loggedInput
+}
+
+// GetPipelineInputResults returns the input parameters as results of the `$pipeline.input` origins.
+// `$pipeline.input` holds the JSON object of the parameters, `$pipeline.input.<name>` the value
+// of the parameter, e.g. the content of the uploaded file
+func GetPipelineInputResults(input map[string]interface{}) map[string][]*bytes.Buffer {
+	results := make(map[string][]*bytes.Buffer, len(input)+1)
+
+	inputJSON, err := json.Marshal(getLoggedPipelineInput(input))
+	if err != nil {
+		inputJSON = []byte("{}")
+	}
+	results[PIPELINE_INPUT_ORIGIN] = []*bytes.Buffer{bytes.NewBuffer(inputJSON)}
+
+	for name, value := range input {
+		var valueBytes []byte
+		switch typedValue := value.(type) {
+		case []byte:
+			valueBytes = typedValue
+		case string:
+			valueBytes = []byte(typedValue)
+		default:
+			if valueBytes, err = json.Marshal(typedValue); err != nil {
+				continue
+			}
+		}
+
+		results[fmt.Sprintf("%s.%s", PIPELINE_INPUT_ORIGIN, name)] = []*bytes.Buffer{bytes.NewBuffer(valueBytes)}
+	}
+
+	return results
+}
@@ -1,13 +1,23 @@
 package dataclasses
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 
-	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/api/schemas"
 	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/registries"
 )
 
+// PipelineCatalogueLoader loads Pipelines from the catalogue directory
+// or from the catalogue prefix of the Storage if it is set
 type PipelineCatalogueLoader struct {
 	storage interfaces.Storage
 }
@@ -24,39 +34,216 @@ func (pcl *PipelineCatalogueLoader) GetStorage() interfaces.Storage {
 	return pcl.storage
 }
 
+// catalogueFile is a Pipeline definition file of the catalogue
+type catalogueFile struct {
+	name string
+	read func() ([]byte, error)
+	// version returns the file version without reading it if possible
+	version func() (string, error)
+}
+
+// isCatalogueFileName checks if the file is a Pipeline definition.
+// Hidden and temporary files of editors are skipped
+func isCatalogueFileName(fileName string) bool {
+	return !strings.HasPrefix(fileName, ".") && filepath.Ext(fileName) == ".json"
+}
+
+// listCatalogueFiles returns the catalogue files ordered by name
+func (pcl *PipelineCatalogueLoader) listCatalogueFiles(cataloguePath string) ([]catalogueFile, error) {
+	catalogueFiles := make([]catalogueFile, 0)
+
+	storage := pcl.GetStorage()
+	if storage == nil {
+		files, err := os.ReadDir(cataloguePath)
+		if err != nil {
+			return catalogueFiles, err
+		}
+
+		for _, file := range files {
+			if file.IsDir() || !isCatalogueFileName(file.Name()) {
+				continue
+			}
+
+			filePath := filepath.Join(cataloguePath, file.Name())
+			catalogueFiles = append(
+				catalogueFiles,
+				catalogueFile{
+					name: filePath,
+					read: func() ([]byte, error) {
+						return os.ReadFile(filePath)
+					},
+					version: func() (string, error) {
+						info, err := os.Stat(filePath)
+						if err != nil {
+							return "", err
+						}
+						return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()), nil
+					},
+				},
+			)
+		}
+
+		return catalogueFiles, nil
+	}
+
+	cataloguePrefix := path.Clean(filepath.ToSlash(cataloguePath))
+	objects, err := storage.ListObjects(storage.NewStorageLocation(cataloguePrefix))
+	if err != nil {
+		return catalogueFiles, err
+	}
+
+	for _, object := range objects {
+		key := registries.GetStorageObjectKey(storage, object)
+		if path.Dir(key) != cataloguePrefix || !isCatalogueFileName(path.Base(key)) {
+			continue
+		}
+
+		location := storage.NewStorageLocation(key)
+		catalogueFiles = append(
+			catalogueFiles,
+			catalogueFile{
+				name: fmt.Sprintf("%s:%s", storage.GetStorageName(), key),
+				read: func() ([]byte, error) {
+					content, err := storage.GetObjectBytes(location)
+					if err != nil {
+						return nil, err
+					}
+					return content.Bytes(), nil
+				},
+				version: func() (string, error) {
+					if versionedStorage, ok := storage.(interfaces.VersionedStorage); ok {
+						return versionedStorage.GetObjectVersion(location)
+					}
+
+					content, err := storage.GetObjectBytes(location)
+					if err != nil {
+						return "", err
+					}
+					contentHash := sha256.Sum256(content.Bytes())
+					return hex.EncodeToString(contentHash[:]), nil
+				},
+			},
+		)
+	}
+
+	sort.Slice(catalogueFiles, func(i, j int) bool {
+		return catalogueFiles[i].name < catalogueFiles[j].name
+	})
+
+	return catalogueFiles, nil
+}
+
+// GetCatalogueVersion returns the hash of the catalogue files versions.
+// Storages report ETags, so the files are not downloaded to detect changes
+func (pcl *PipelineCatalogueLoader) GetCatalogueVersion(cataloguePath string) (string, error) {
+	catalogueFiles, err := pcl.listCatalogueFiles(cataloguePath)
+	if err != nil {
+		return "", err
+	}
+
+	catalogueHash := sha256.New()
+	for _, file := range catalogueFiles {
+		version, err := file.version()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(catalogueHash, "%s=%s\n", file.name, version)
+	}
+
+	return hex.EncodeToString(catalogueHash.Sum(nil)), nil
+}
+
+// LoadCatalogue loads every JSON file of the catalogue.
+// Each file defines a version of the Pipeline, so the slug may be defined by several files.
+// Invalid files are returned as errors instead of failing the whole catalogue
 func (pcl *PipelineCatalogueLoader) LoadCatalogue(
 	cataloguePath string,
 ) (
+	map[string]map[string]interfaces.Pipeline,
 	map[string]interfaces.Pipeline,
+	[]schemas.PipelineCatalogueErrorSchema,
 	error,
 ) {
-	// TODO: Respect storage
-	_config := config.GetConfig()
-	pipelines := make(map[string]interfaces.Pipeline)
+	pipelines := make(map[string]map[string]interfaces.Pipeline)
+	pipelineFiles := make(map[string]interfaces.Pipeline)
+	catalogueKeyFiles := make(map[string]string)
+	fileErrors := make([]schemas.PipelineCatalogueErrorSchema, 0)
 
-	// List all files in the directory
-	files, err := os.ReadDir(_config.Pipeline.Catalogue)
+	catalogueFiles, err := pcl.listCatalogueFiles(cataloguePath)
 	if err != nil {
-		panic(err)
+		return pipelines, pipelineFiles, fileErrors, err
 	}
 
-	for _, file := range files {
-		if file.IsDir() {
+	for _, file := range catalogueFiles {
+		fileContent, err := file.read()
+
+		var pipeline interfaces.Pipeline
+		if err == nil {
+			pipeline, err = loadCatalogueFile(fileContent)
+		}
+		if err == nil {
+			if duplicateFileName, ok := catalogueKeyFiles[getCatalogueKey(pipeline.GetSlug(), pipeline.GetVersion())]; ok {
+				err = fmt.Errorf(
+					"duplicate pipeline slug %s version %s already defined in %s",
+					pipeline.GetSlug(),
+					pipeline.GetVersion(),
+					duplicateFileName,
+				)
+			}
+		}
+		if err != nil {
+			slug, version := getCatalogueFileSlug(fileContent)
+			fileErrors = append(
+				fileErrors,
+				schemas.PipelineCatalogueErrorSchema{
+					File:    file.name,
+					Slug:    slug,
+					Version: version,
+					Error:   err.Error(),
+				},
+			)
 			continue
 		}
 
-		filePath := filepath.Join(_config.Pipeline.Catalogue, file.Name())
-
-		if fileContent, err := os.ReadFile(filePath); err == nil {
-			if pipeline, err := NewPipelineFromBytes(fileContent); err == nil {
-				pipelines[pipeline.GetSlug()] = pipeline
-			} else {
-				panic(err)
-			}
-		} else {
-			panic(err)
+		if _, ok := pipelines[pipeline.GetSlug()]; !ok {
+			pipelines[pipeline.GetSlug()] = make(map[string]interfaces.Pipeline)
 		}
+		pipelines[pipeline.GetSlug()][pipeline.GetVersion()] = pipeline
+		pipelineFiles[file.name] = pipeline
+		catalogueKeyFiles[getCatalogueKey(pipeline.GetSlug(), pipeline.GetVersion())] = file.name
+	}
+
+	return pipelines, pipelineFiles, fileErrors, nil
+}
+
+func loadCatalogueFile(fileContent []byte) (interfaces.Pipeline, error) {
+	pipeline, err := NewPipelineFromBytes(fileContent)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := registries.ValidatePipelineSchema(pipeline); err != nil {
+		return nil, err
+	}
+
+	return pipeline, nil
+}
+
+func getCatalogueKey(slug string, version string) string {
+	return fmt.Sprintf("%s@%s", slug, version)
+}
+
+// getCatalogueFileSlug reads the Pipeline slug and version of the invalid file if possible
+func getCatalogueFileSlug(fileContent []byte) (string, string) {
+	pipeline := struct {
+		Slug    string `json:"slug"`
+		Version string `json:"version"`
+	}{}
+	json.Unmarshal(fileContent, &pipeline)
+
+	if pipeline.Slug != "" && pipeline.Version == "" {
+		pipeline.Version = DEFAULT_PIPELINE_VERSION
 	}
 
-	return pipelines, nil
+	return pipeline.Slug, pipeline.Version
 }
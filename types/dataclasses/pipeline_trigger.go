@@ -0,0 +1,135 @@
+package dataclasses
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"data-pipelines-worker/types/interfaces"
+)
+
+// PipelineTrigger represents a schedule which starts the pipeline
+// from a given block with a static input and input parameters.
+//
+// swagger:model
+type PipelineTrigger struct {
+	// The unique slug of the trigger within the pipeline
+	// required: true
+	// example: "daily"
+	Slug string `json:"slug"`
+
+	// The description of the trigger
+	// example: "Generate a video every morning"
+	Description string `json:"description,omitempty"`
+
+	// Cron expression ( standard 5 fields or descriptors like @daily )
+	// example: "0 9 * * *"
+	Cron string `json:"cron,omitempty"`
+
+	// Interval between runs ( Go duration format )
+	// example: "24h"
+	Interval string `json:"interval,omitempty"`
+
+	// Timezone of the cron expression. Defaults to UTC, so every worker claims the same runs
+	// example: "Europe/Berlin"
+	Timezone string `json:"timezone,omitempty"`
+
+	// The slug of the block to start the pipeline from
+	// required: true
+	// example: "get-event-text"
+	BlockSlug string `json:"block_slug"`
+
+	// The static input passed to the starting block
+	Input map[string]interface{} `json:"input,omitempty"`
+
+	// The input parameters of the pipeline, validated against its input schema
+	PipelineInput map[string]interface{} `json:"pipeline_input,omitempty"`
+
+	// Whether the trigger is active. Defaults to true
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// Ensure PipelineTrigger implements the PipelineTrigger
+var _ interfaces.PipelineTrigger = (*PipelineTrigger)(nil)
+
+func (t *PipelineTrigger) GetSlug() string {
+	return t.Slug
+}
+
+func (t *PipelineTrigger) GetBlockSlug() string {
+	return t.BlockSlug
+}
+
+func (t *PipelineTrigger) GetInput() map[string]interface{} {
+	input := make(map[string]interface{}, len(t.Input))
+	for key, value := range t.Input {
+		input[key] = value
+	}
+
+	return input
+}
+
+func (t *PipelineTrigger) GetPipelineInput() map[string]interface{} {
+	input := make(map[string]interface{}, len(t.PipelineInput))
+	for key, value := range t.PipelineInput {
+		input[key] = value
+	}
+
+	return input
+}
+
+func (t *PipelineTrigger) IsEnabled() bool {
+	return t.Enabled == nil || *t.Enabled
+}
+
+func (t *PipelineTrigger) Validate() error {
+	if t.Slug == "" {
+		return fmt.Errorf("trigger slug is required")
+	}
+	if t.BlockSlug == "" {
+		return fmt.Errorf("trigger %s: block_slug is required", t.Slug)
+	}
+	if (t.Cron == "") == (t.Interval == "") {
+		return fmt.Errorf("trigger %s: exactly one of cron or interval must be set", t.Slug)
+	}
+
+	_, err := t.GetNextRun(time.Now())
+	return err
+}
+
+// GetNextRun returns the first run strictly after the given time.
+// Interval runs are aligned to the Unix epoch, so every worker computes
+// the same schedule regardless of when it was started.
+func (t *PipelineTrigger) GetNextRun(after time.Time) (time.Time, error) {
+	if t.Interval != "" {
+		interval, err := time.ParseDuration(t.Interval)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("trigger %s: invalid interval: %w", t.Slug, err)
+		}
+		if interval < time.Second {
+			return time.Time{}, fmt.Errorf("trigger %s: interval must be at least 1s", t.Slug)
+		}
+
+		epoch := time.Unix(0, 0)
+		elapsed := after.Sub(epoch)
+
+		return epoch.Add(elapsed - elapsed%interval + interval).In(after.Location()), nil
+	}
+
+	location := time.UTC
+	if t.Timezone != "" {
+		var err error
+		location, err = time.LoadLocation(t.Timezone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("trigger %s: invalid timezone: %w", t.Slug, err)
+		}
+	}
+
+	schedule, err := cron.ParseStandard(t.Cron)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("trigger %s: invalid cron expression: %w", t.Slug, err)
+	}
+
+	return schedule.Next(after.In(location)), nil
+}
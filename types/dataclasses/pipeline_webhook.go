@@ -0,0 +1,387 @@
+package dataclasses
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/egress"
+	"data-pipelines-worker/types/helpers"
+	"data-pipelines-worker/types/registries"
+)
+
+const (
+	WEBHOOK_HEADER_EVENT     = "X-Pipeline-Event"
+	WEBHOOK_HEADER_DELIVERY  = "X-Pipeline-Delivery"
+	WEBHOOK_HEADER_TIMESTAMP = "X-Pipeline-Timestamp"
+	WEBHOOK_HEADER_SIGNATURE = "X-Pipeline-Signature"
+)
+
+// Blocks which wait for the moderator decision after they are processed
+var MODERATION_WAIT_BLOCK_IDS = []string{
+	"send_moderation_tg",
+}
+
+// PipelineWebhookOutput represents the location of a saved block output
+//
+// swagger:model
+type PipelineWebhookOutput struct {
+	Index    int                          `json:"index"`
+	Storage  string                       `json:"storage"`
+	Path     string                       `json:"path"`
+	Metadata schemas.OutputMetadataSchema `json:"metadata"`
+}
+
+// PipelineWebhookBlockSummary represents the block outputs produced during the processing
+//
+// swagger:model
+type PipelineWebhookBlockSummary struct {
+	Slug    string                  `json:"slug"`
+	BlockId string                  `json:"block_id"`
+	Outputs []PipelineWebhookOutput `json:"outputs"`
+}
+
+// PipelineWebhookPayload represents the JSON body POSTed to the processing callbacks
+//
+// swagger:model
+type PipelineWebhookPayload struct {
+	Event        string                        `json:"event"`
+	PipelineSlug string                        `json:"pipeline_slug"`
+	ProcessingId uuid.UUID                     `json:"processing_id"`
+	IsCompleted  bool                          `json:"is_completed"`
+	IsStopped    bool                          `json:"is_stopped"`
+	IsError      bool                          `json:"is_error"`
+	Error        string                        `json:"error,omitempty"`
+	BlockSlug    string                        `json:"block_slug,omitempty"`
+	Blocks       []PipelineWebhookBlockSummary `json:"blocks"`
+	Timestamp    time.Time                     `json:"timestamp"`
+}
+
+// NewPipelineWebhookPayload builds the payload from the processing state and the saved outputs.
+// It returns nil when the processing has no final state ( e.g. processing was transferred to another worker )
+func NewPipelineWebhookPayload(
+	p *PipelineData,
+	processingId uuid.UUID,
+	processingState *PipelineProcessingState,
+	pipelineBlockDataRegistry *registries.PipelineBlockDataRegistry,
+) *PipelineWebhookPayload {
+	payload := &PipelineWebhookPayload{
+		PipelineSlug: p.GetSlug(),
+		ProcessingId: processingId,
+		IsCompleted:  processingState.IsCompleted(),
+		IsStopped:    processingState.IsStopped(),
+		Blocks:       GetPipelineWebhookBlockSummaries(p, pipelineBlockDataRegistry),
+		Timestamp:    time.Now().UTC(),
+	}
+	if err := processingState.GetError(); err != nil {
+		payload.IsError = true
+		payload.Error = err.Error()
+	}
+
+	switch {
+	case payload.IsError:
+		payload.Event = schemas.PIPELINE_EVENT_FAILED
+	case payload.IsStopped:
+		payload.Event = schemas.PIPELINE_EVENT_STOPPED
+	case payload.IsCompleted:
+		payload.Event = schemas.PIPELINE_EVENT_COMPLETED
+	default:
+		return nil
+	}
+
+	return payload
+}
+
+// GetPipelineWebhookBlockSummaries returns saved outputs of the processing ordered as Pipeline blocks
+func GetPipelineWebhookBlockSummaries(
+	p *PipelineData,
+	pipelineBlockDataRegistry *registries.PipelineBlockDataRegistry,
+) []PipelineWebhookBlockSummary {
+	savedOutputs := pipelineBlockDataRegistry.GetSavedOutputs()
+
+	summaries := make([]PipelineWebhookBlockSummary, 0)
+	for _, blockData := range p.GetBlocks() {
+		blockOutputs, ok := savedOutputs[blockData.GetSlug()]
+		if !ok {
+			continue
+		}
+
+		summary := PipelineWebhookBlockSummary{
+			Slug:    blockData.GetSlug(),
+			BlockId: blockData.GetId(),
+			Outputs: make([]PipelineWebhookOutput, 0),
+		}
+
+		indexes := make([]int, 0, len(blockOutputs))
+		for outputIndex := range blockOutputs {
+			indexes = append(indexes, outputIndex)
+		}
+		sort.Ints(indexes)
+
+		for _, outputIndex := range indexes {
+			for _, savedOutput := range blockOutputs[outputIndex] {
+				if savedOutput.Error != nil || savedOutput.StorageLocation == nil {
+					continue
+				}
+				summary.Outputs = append(
+					summary.Outputs,
+					PipelineWebhookOutput{
+						Index:    outputIndex,
+						Storage:  savedOutput.StorageLocation.GetStorageName(),
+						Path:     savedOutput.StorageLocation.GetFilePath(),
+						Metadata: savedOutput.Metadata,
+					},
+				)
+			}
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries
+}
+
+// GetPipelineCallbacks returns Pipeline default webhooks and the processing callback
+func GetPipelineCallbacks(
+	p *PipelineData,
+	inputData schemas.PipelineStartInputSchema,
+) []schemas.PipelineCallbackSchema {
+	callbacks := make([]schemas.PipelineCallbackSchema, 0)
+	callbacks = append(callbacks, p.GetWebhooks()...)
+
+	if inputData.Callback != nil {
+		callbacks = append(callbacks, *inputData.Callback)
+	}
+
+	return callbacks
+}
+
+// CheckPipelineCallbackURL checks the callback URL against the egress policy,
+// so the callbacks passed to the API can not reach the internal services
+func CheckPipelineCallbackURL(callback schemas.PipelineCallbackSchema) error {
+	callbackUrl, err := url.Parse(callback.URL)
+	if err != nil {
+		return fmt.Errorf("invalid callback url %s", callback.URL)
+	}
+
+	policy, err := egress.GetPolicy()
+	if err != nil {
+		return err
+	}
+
+	return policy.CheckURL(callbackUrl)
+}
+
+// SignPipelineWebhookPayload returns the signature of the payload sent with the timestamp
+func SignPipelineWebhookPayload(secret string, timestamp int64, body []byte) string {
+	signedContent := append([]byte(fmt.Sprintf("%d.", timestamp)), body...)
+
+	return fmt.Sprintf("sha256=%s", helpers.HmacSHA256(secret, signedContent))
+}
+
+// PipelineWebhookSender delivers webhook payloads with retries and exponential backoff
+type PipelineWebhookSender struct {
+	sync.Mutex
+
+	Client     *http.Client
+	MaxRetries int
+	RetryDelay time.Duration
+
+	// Closed when the last queued delivery is done
+	queued chan struct{}
+}
+
+// NewPipelineWebhookSender returns the sender connecting to the addresses allowed by the egress policy only
+func NewPipelineWebhookSender() *PipelineWebhookSender {
+	webhooksConfig := config.GetConfig().Webhooks
+
+	policy, err := egress.GetPolicy()
+	if err != nil {
+		config.GetLogger().Errorf("Invalid egress policy, webhooks are sent to the public networks only: %s", err)
+		policy, _ = egress.NewPolicy(config.EgressConfig{BlockPrivateNetworks: true})
+	}
+
+	return &PipelineWebhookSender{
+		Client:     policy.NewClient(webhooksConfig.Timeout, nil),
+		MaxRetries: webhooksConfig.MaxRetries,
+		RetryDelay: webhooksConfig.RetryDelay,
+	}
+}
+
+// Notify sends the payload to every callback subscribed to the payload event.
+// Deliveries are recorded with the logger
+func (s *PipelineWebhookSender) Notify(
+	logger echo.Logger,
+	callbacks []schemas.PipelineCallbackSchema,
+	payload *PipelineWebhookPayload,
+) {
+	if payload == nil {
+		return
+	}
+
+	wg := sync.WaitGroup{}
+	for _, callback := range callbacks {
+		if !callback.IsSubscribed(payload.Event) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(callback schemas.PipelineCallbackSchema) {
+			defer wg.Done()
+
+			// Failed delivery must not mark the processing as failed
+			if err := s.Send(logger, callback, payload); err != nil {
+				logger.Warnf(
+					"Webhook %s delivery to %s failed: %s",
+					payload.Event,
+					callback.URL,
+					err,
+				)
+			}
+		}(callback)
+	}
+
+	wg.Wait()
+}
+
+// Deliveries queued by the senders of all the processings, the shutdown waits for them
+var pipelineWebhookDeliveries sync.WaitGroup
+
+// WaitPipelineWebhookDeliveries waits for the queued deliveries and the saves of their logs
+func WaitPipelineWebhookDeliveries(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		pipelineWebhookDeliveries.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// NotifyAsync queues the payload to be sent after the payloads queued before, so the events
+// are delivered in the order they happened. The delivered callback, if any, runs after it is sent
+// and the returned channel is closed after the callback
+func (s *PipelineWebhookSender) NotifyAsync(
+	logger echo.Logger,
+	callbacks []schemas.PipelineCallbackSchema,
+	payload *PipelineWebhookPayload,
+	delivered func(),
+) <-chan struct{} {
+	done := make(chan struct{})
+
+	s.Lock()
+	previous := s.queued
+	s.queued = done
+	s.Unlock()
+
+	pipelineWebhookDeliveries.Add(1)
+	go func() {
+		defer pipelineWebhookDeliveries.Done()
+		defer close(done)
+
+		if previous != nil {
+			<-previous
+		}
+		s.Notify(logger, callbacks, payload)
+		if delivered != nil {
+			delivered()
+		}
+	}()
+
+	return done
+}
+
+// Send POSTs the signed payload to the callback URL retrying on network errors and 5xx/429 responses
+func (s *PipelineWebhookSender) Send(
+	logger echo.Logger,
+	callback schemas.PipelineCallbackSchema,
+	payload *PipelineWebhookPayload,
+) error {
+	s.Lock()
+	client := s.Client
+	maxRetries := s.MaxRetries
+	retryDelay := s.RetryDelay
+	s.Unlock()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	deliveryId := uuid.New()
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(retryDelay * time.Duration(1<<(attempt-1)))
+		}
+
+		request, err := http.NewRequest(http.MethodPost, callback.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		timestamp := time.Now().Unix()
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set(WEBHOOK_HEADER_EVENT, payload.Event)
+		request.Header.Set(WEBHOOK_HEADER_DELIVERY, deliveryId.String())
+		request.Header.Set(WEBHOOK_HEADER_TIMESTAMP, strconv.FormatInt(timestamp, 10))
+		if callback.Secret != "" {
+			request.Header.Set(
+				WEBHOOK_HEADER_SIGNATURE,
+				SignPipelineWebhookPayload(callback.Secret, timestamp, body),
+			)
+		}
+
+		response, err := client.Do(request)
+		if err != nil {
+			lastErr = err
+		} else {
+			response.Body.Close()
+
+			if response.StatusCode >= 200 && response.StatusCode < 300 {
+				logger.Infof(
+					"Webhook %s delivered to %s with status %d ( attempt %d, delivery %s )",
+					payload.Event,
+					callback.URL,
+					response.StatusCode,
+					attempt+1,
+					deliveryId,
+				)
+				return nil
+			}
+
+			lastErr = fmt.Errorf("unexpected status code %d", response.StatusCode)
+			if response.StatusCode < 500 && response.StatusCode != http.StatusTooManyRequests {
+				break
+			}
+		}
+
+		logger.Warnf(
+			"Webhook %s delivery to %s attempt %d failed: %s",
+			payload.Event,
+			callback.URL,
+			attempt+1,
+			lastErr,
+		)
+	}
+
+	return lastErr
+}
@@ -3,14 +3,18 @@ package dataclasses
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types/blocks"
 	"data-pipelines-worker/types/config"
 	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/llm"
 )
 
 type Processing struct {
@@ -49,6 +53,11 @@ func NewProcessing(
 
 	ctx = context.WithValue(ctx, interfaces.ContextKeyProcessingID{}, id)
 
+	// Processors read the defaults of the block config from the block of the data
+	if blockData.GetBlock() == nil {
+		blockData.SetBlock(block)
+	}
+
 	return &Processing{
 		Id:                          id,
 		instanceId:                  instanceId,
@@ -186,8 +195,19 @@ func (p *Processing) Start() interfaces.ProcessingOutput {
 	p.SetStatus(interfaces.ProcessingStatusRunning)
 
 	retryCount := p.processor.GetRetryCount(p.block)
+	if dataRetryProcessor, ok := p.processor.(interfaces.BlockDataRetryProcessor); ok {
+		retryCount = dataRetryProcessor.GetDataRetryCount(p.block, p.blockData)
+	}
 	retryInterval := p.processor.GetRetryInterval(p.block)
 
+	// Invalid outputs of the non-deterministic blocks are retried within their own budget,
+	// the processors retry only the failed requests
+	outputValidationRetryCount := blocks.GetOutputValidationRetryCount(config.GetConfig(), p.block.GetId())
+	retries, outputValidationRetries := 0, 0
+
+	// Usage of the paid API calls of all the attempts
+	processCtx, usageRecorder := llm.WithUsageRecorder(p.ctx)
+
 	var (
 		output                []*bytes.Buffer
 		stop, retry           bool
@@ -197,7 +217,7 @@ func (p *Processing) Start() interfaces.ProcessingOutput {
 	)
 
 	// Retry loop
-	for attempt := 0; attempt <= retryCount; attempt++ {
+	for attempt := 0; attempt <= retryCount+outputValidationRetryCount; attempt++ {
 		// Check if the context was canceled before processing
 		if p.ctx.Err() == context.Canceled {
 			processingOutput.SetError(
@@ -214,8 +234,13 @@ func (p *Processing) Start() interfaces.ProcessingOutput {
 			return processingOutput
 		}
 
-		output, stop, retry, targetBlock, targetBlockInputIndex, err = p.block.Process(p.ctx, p.processor, p.blockData)
+		// Metadata of the outputs of the last attempt only
+		attemptCtx, outputMetadataRecorder := blocks.WithOutputMetadataRecorder(processCtx)
+
+		output, stop, retry, targetBlock, targetBlockInputIndex, err = p.block.Process(attemptCtx, p.processor, p.blockData)
 		processingOutput.SetValue(output)
+		processingOutput.SetMetadata(outputMetadataRecorder.GetMetadata(len(output)))
+		processingOutput.SetUsage(usageRecorder.GetUsage())
 		processingOutput.SetError(err)
 		processingOutput.SetRetry(retry)
 		processingOutput.SetRetryAttempt(attempt)
@@ -245,8 +270,14 @@ func (p *Processing) Start() interfaces.ProcessingOutput {
 			return processingOutput
 		}
 
+		attemptRetries, attemptRetryCount := &retries, retryCount
+		if errors.Is(err, blocks.ErrInvalidOutput) {
+			attemptRetries, attemptRetryCount = &outputValidationRetries, outputValidationRetryCount
+		}
+
 		// If retry is required and we haven't exhausted retry attempts
-		if retry && attempt < retryCount {
+		if retry && *attemptRetries < attemptRetryCount {
+			*attemptRetries++
 			p.SetStatus(interfaces.ProcessingStatusRetry)
 
 			logger.Warnf(
@@ -254,8 +285,8 @@ func (p *Processing) Start() interfaces.ProcessingOutput {
 				p.GetId().String(),
 				p.GetData().GetSlug(),
 				p.GetData().GetId(),
-				attempt+1,
-				retryCount,
+				*attemptRetries,
+				attemptRetryCount,
 			)
 
 			time.Sleep(retryInterval)
@@ -263,16 +294,18 @@ func (p *Processing) Start() interfaces.ProcessingOutput {
 		}
 
 		// If we reach here and retry is still required, mark the process as failed
-		if attempt == retryCount && retry {
-			processingOutput.SetError(
-				fmt.Errorf(
-					"processing with id %s at block [%s:%s] failed after exhausting all %d retry attempts",
-					p.GetId().String(),
-					p.GetData().GetSlug(),
-					p.GetData().GetId(),
-					retryCount,
-				),
+		if retry {
+			retryFailedErr := fmt.Errorf(
+				"processing with id %s at block [%s:%s] failed after exhausting all %d retry attempts",
+				p.GetId().String(),
+				p.GetData().GetSlug(),
+				p.GetData().GetId(),
+				attemptRetryCount,
 			)
+			if err != nil {
+				retryFailedErr = fmt.Errorf("%w: %w", retryFailedErr, err)
+			}
+			processingOutput.SetError(retryFailedErr)
 			p.SetStatus(interfaces.ProcessingStatusRetryFailed)
 			p.sendResult(false)
 
@@ -395,6 +428,8 @@ type ProcessingOutput struct {
 	err                   error
 	targetBlockInputIndex int
 	targetBlockSlug       string
+	usage                 schemas.ProcessingUsageSchema
+	metadata              []schemas.OutputMetadataSchema
 }
 
 func NewProcessingOutput(
@@ -523,3 +558,32 @@ func (po *ProcessingOutput) SetTargetBlockInputIndex(targetBlockInputIndex int)
 
 	po.targetBlockInputIndex = targetBlockInputIndex
 }
+
+func (po *ProcessingOutput) GetUsage() schemas.ProcessingUsageSchema {
+	po.Lock()
+	defer po.Unlock()
+
+	return po.usage
+}
+
+func (po *ProcessingOutput) SetUsage(usage schemas.ProcessingUsageSchema) {
+	po.Lock()
+	defer po.Unlock()
+
+	po.usage = usage
+}
+
+// GetMetadata returns the metadata of the outputs by the output index
+func (po *ProcessingOutput) GetMetadata() []schemas.OutputMetadataSchema {
+	po.Lock()
+	defer po.Unlock()
+
+	return po.metadata
+}
+
+func (po *ProcessingOutput) SetMetadata(metadata []schemas.OutputMetadataSchema) {
+	po.Lock()
+	defer po.Unlock()
+
+	po.metadata = metadata
+}
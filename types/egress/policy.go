@@ -0,0 +1,272 @@
+package egress
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"data-pipelines-worker/types/config"
+)
+
+var ErrEgressDenied = errors.New("egress policy denied")
+
+// Non-public ranges missing in the net.IP predicates
+var privateNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"100.64.0.0/10",
+	"192.0.0.0/24",
+	"198.18.0.0/15",
+	"240.0.0.0/4",
+	"64:ff9b::/96",
+)
+
+// Policy of the config published for the clients, built on the first GetPolicy
+var currentPolicy atomic.Pointer[policySnapshot]
+
+type policySnapshot struct {
+	policy *Policy
+	err    error
+}
+
+// Policy checks the hosts of the URLs and the addresses the requests connect to
+type Policy struct {
+	config config.EgressConfig
+
+	allowCIDRs []*net.IPNet
+	denyCIDRs  []*net.IPNet
+
+	// Connections of all the clients of the Policy, the clients differ only in the timeouts and redirects
+	transport *http.Transport
+}
+
+func NewPolicy(egressConfig config.EgressConfig) (*Policy, error) {
+	allowCIDRs, err := parseCIDRs(egressConfig.AllowCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	denyCIDRs, err := parseCIDRs(egressConfig.DenyCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &Policy{
+		config:     egressConfig,
+		allowCIDRs: allowCIDRs,
+		denyCIDRs:  denyCIDRs,
+	}
+	policy.transport = policy.newTransport()
+
+	return policy, nil
+}
+
+// GetPolicy returns the Policy of the `egress` section of the config.
+// The config is read once, ReloadPolicy publishes the Policy of the changed config
+func GetPolicy() (*Policy, error) {
+	snapshot := currentPolicy.Load()
+	if snapshot == nil {
+		snapshot = newPolicySnapshot()
+		if !currentPolicy.CompareAndSwap(nil, snapshot) {
+			snapshot = currentPolicy.Load()
+		}
+	}
+
+	return snapshot.policy, snapshot.err
+}
+
+// ReloadPolicy builds the Policy of the `egress` section of the current config and publishes it
+// to the next GetPolicy calls. Clients made before keep the previous Policy, its idle connections are closed
+func ReloadPolicy() (*Policy, error) {
+	snapshot := newPolicySnapshot()
+	if previous := currentPolicy.Swap(snapshot); previous != nil && previous.policy != nil {
+		previous.policy.transport.CloseIdleConnections()
+	}
+
+	return snapshot.policy, snapshot.err
+}
+
+func newPolicySnapshot() *policySnapshot {
+	egressConfig := config.NewEgressConfig()
+	if configEgress := config.GetConfig().Egress; configEgress != nil {
+		egressConfig = *configEgress
+		// The config slices must not be shared with the published Policy
+		egressConfig.AllowHosts = append([]string(nil), configEgress.AllowHosts...)
+		egressConfig.DenyHosts = append([]string(nil), configEgress.DenyHosts...)
+	}
+
+	policy, err := NewPolicy(egressConfig)
+	return &policySnapshot{policy: policy, err: err}
+}
+
+// CheckURL checks the scheme and the host of the URL before the request. IP hosts are checked as addresses
+func (p *Policy) CheckURL(requestUrl *url.URL) error {
+	if requestUrl.Scheme != "http" && requestUrl.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q is not allowed", ErrEgressDenied, requestUrl.Scheme)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(requestUrl.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("%w: URL has no host", ErrEgressDenied)
+	}
+
+	if matchHosts(p.config.DenyHosts, host) {
+		return fmt.Errorf("%w: host %s is denied", ErrEgressDenied, host)
+	}
+	if len(p.config.AllowHosts) > 0 && !matchHosts(p.config.AllowHosts, host) {
+		return fmt.Errorf("%w: host %s is not allowed", ErrEgressDenied, host)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return p.CheckIP(ip)
+	}
+
+	return nil
+}
+
+// CheckIP checks the address the request connects to
+func (p *Policy) CheckIP(ip net.IP) error {
+	if containsIP(p.allowCIDRs, ip) {
+		return nil
+	}
+	if containsIP(p.denyCIDRs, ip) {
+		return fmt.Errorf("%w: address %s is denied", ErrEgressDenied, ip)
+	}
+	if p.config.BlockPrivateNetworks && IsPrivateIP(ip) {
+		return fmt.Errorf("%w: address %s is in a private network", ErrEgressDenied, ip)
+	}
+
+	return nil
+}
+
+// NewClient returns the client checking the URLs of the redirects and the addresses of every connection.
+// Clients share the connections of the Policy, so they are cheap to make for every request
+func (p *Policy) NewClient(timeout time.Duration, checkRedirect func(*http.Request, []*http.Request) error) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: p.transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if err := p.CheckURL(req.URL); err != nil {
+				return err
+			}
+			if checkRedirect != nil {
+				return checkRedirect(req, via)
+			}
+			return nil
+		},
+	}
+}
+
+// newTransport returns the transport checking the addresses after the DNS resolution, right before
+// the connection, so the DNS rebinding can not swap a checked host to a private address.
+// The proxies of the environment are not used
+func (p *Policy) newTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_ string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("%w: address %s is not an IP", ErrEgressDenied, host)
+			}
+
+			return p.CheckIP(ip)
+		},
+	}
+
+	return &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// IsPrivateIP reports the loopback, private, link-local, multicast, unspecified and reserved addresses
+func IsPrivateIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		containsIP(privateNetworks, ip)
+}
+
+// matchHosts matches the host against the hosts and the `*.example.com` wildcards
+func matchHosts(hosts []string, host string) bool {
+	for _, pattern := range hosts {
+		pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		// Single addresses are accepted as the networks of one address
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil {
+				if ip.To4() != nil {
+					cidr += "/32"
+				} else {
+					cidr += "/128"
+				}
+			}
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid egress CIDR %q: %s", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks, err := parseCIDRs(cidrs)
+	if err != nil {
+		panic(err)
+	}
+
+	return networks
+}
@@ -1,6 +1,7 @@
 package helpers
 
 import (
+	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 )
@@ -18,3 +19,10 @@ func VerifyHash(input, receivedHash string) bool {
 	computedHash := HashInput(input)
 	return computedHash == receivedHash // Return true if they match
 }
+
+// HmacSHA256 signs the input with the secret using HMAC-SHA256
+func HmacSHA256(secret string, input []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(input)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -3,6 +3,7 @@ package helpers
 import (
 	"bytes"
 	"io"
+	"mime"
 
 	"github.com/gabriel-vasile/mimetype"
 )
@@ -24,3 +25,14 @@ func DetectMimeTypeFromBuffer(largeBuffer bytes.Buffer) (*mimetype.MIME, error)
 
 	return mimetype.Detect(smallBuffer[:bytesRead]), nil
 }
+
+// GetMimeType returns the MIME type of the content type, or detects it from the content for unknown types
+func GetMimeType(contentType string, content bytes.Buffer) (*mimetype.MIME, error) {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if mimeType := mimetype.Lookup(mediaType); mimeType != nil {
+			return mimeType, nil
+		}
+	}
+
+	return DetectMimeTypeFromBuffer(content)
+}
@@ -0,0 +1,89 @@
+package helpers
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// BLOCK_VERSION_SEPARATOR separates the block id and the pinned version in `id@version`
+	BLOCK_VERSION_SEPARATOR = "@"
+	// BLOCK_VERSION_RANGE_PREFIX marks the range of the compatible versions in `id@^version`
+	BLOCK_VERSION_RANGE_PREFIX = "^"
+)
+
+// ParseBlockReference returns the id and the pinned version, if any, of the `id@version` reference
+func ParseBlockReference(reference string) (string, string) {
+	id, version, _ := strings.Cut(reference, BLOCK_VERSION_SEPARATOR)
+
+	return id, version
+}
+
+// FormatBlockReference returns the `id@version` reference, or the id without the version
+func FormatBlockReference(id string, version string) string {
+	if version == "" {
+		return id
+	}
+
+	return id + BLOCK_VERSION_SEPARATOR + version
+}
+
+// CompareVersions compares the dotted versions component by component, numerically when both are numbers.
+// Missing components are zeros, so "1" equals "1.0"
+func CompareVersions(left string, right string) int {
+	leftParts := strings.Split(left, ".")
+	rightParts := strings.Split(right, ".")
+
+	for i := 0; i < len(leftParts) || i < len(rightParts); i++ {
+		leftPart, rightPart := "0", "0"
+		if i < len(leftParts) {
+			leftPart = leftParts[i]
+		}
+		if i < len(rightParts) {
+			rightPart = rightParts[i]
+		}
+
+		leftNumber, leftErr := strconv.Atoi(leftPart)
+		rightNumber, rightErr := strconv.Atoi(rightPart)
+		switch {
+		case leftErr == nil && rightErr == nil && leftNumber != rightNumber:
+			if leftNumber < rightNumber {
+				return -1
+			}
+			return 1
+		case (leftErr != nil || rightErr != nil) && leftPart != rightPart:
+			return strings.Compare(leftPart, rightPart)
+		}
+	}
+
+	return 0
+}
+
+// IsVersionCompatible reports whether the version provides the required one:
+// the same major version and not older than the required
+func IsVersionCompatible(required string, version string) bool {
+	if required == "" {
+		return true
+	}
+
+	requiredMajor, _, _ := strings.Cut(required, ".")
+	major, _, _ := strings.Cut(version, ".")
+	if CompareVersions(requiredMajor, major) != 0 {
+		return false
+	}
+
+	return CompareVersions(version, required) >= 0
+}
+
+// MatchVersion reports whether the version matches the pinned one: `^1.2` is the range of the compatible
+// versions ( see IsVersionCompatible ), other pins match the same version only
+func MatchVersion(pinned string, version string) bool {
+	if required, isRange := strings.CutPrefix(pinned, BLOCK_VERSION_RANGE_PREFIX); isRange {
+		return IsVersionCompatible(required, version)
+	}
+	if pinned == "" {
+		return true
+	}
+
+	return CompareVersions(pinned, version) == 0
+}
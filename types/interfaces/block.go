@@ -7,10 +7,17 @@ import (
 	"time"
 
 	"github.com/xeipuuv/gojsonschema"
+
+	"data-pipelines-worker/api/schemas"
 )
 
 // BlockDetector represents a detector for a block in a pipeline.
 // It provides methods to detect the availability of a block and manage the detection loop.
+type WasmPluginsWatcher interface {
+	Start()
+	Shutdown(context.Context) error
+}
+
 type BlockDetector interface {
 
 	// Detect performs a detection operation.
@@ -29,6 +36,18 @@ type BlockDetector interface {
 	Stop(*sync.WaitGroup)
 }
 
+// BlockProfilesDetector is a BlockDetector of the integrations with several credentials profiles
+type BlockProfilesDetector interface {
+
+	// GetProvider returns the provider of the credentials profiles, e.g. openai or telegram.
+	// @return string The provider name.
+	GetProvider() string
+
+	// GetProfilesAvailability returns the availability of every credentials profile of the last detection.
+	// @return map[string]bool The availability by the profile name.
+	GetProfilesAvailability() map[string]bool
+}
+
 // BlockProcessor represents a processor for a block in a pipeline.
 // It provides methods to process block data.
 type BlockProcessor interface {
@@ -63,6 +82,33 @@ type BlockProcessor interface {
 	)
 }
 
+// BlockDataRetryProcessor is a BlockProcessor with the retry count depending on the block data
+type BlockDataRetryProcessor interface {
+
+	// GetDataRetryCount returns the retry count for the given block and data.
+	// @param block The block for which to get the retry count.
+	// @param data The data to be processed.
+	// @return int The retry count, it replaces the retry count of the block.
+	GetDataRetryCount(Block, ProcessableBlockData) int
+}
+
+// BlockInputMasker is a Block with credentials in the input which must not be recorded in the processing log
+type BlockInputMasker interface {
+
+	// MaskInput returns the copy of the input with the credentials masked.
+	// @param input The block input to be logged.
+	// @return map[string]interface{} The masked input.
+	MaskInput(map[string]interface{}) map[string]interface{}
+}
+
+// NonDeterministicBlock is a Block which outputs may differ for the same input, e.g. the LLM blocks
+type NonDeterministicBlock interface {
+
+	// IsNonDeterministic reports whether another attempt may produce a valid output.
+	// @return bool True if the invalid outputs of the block are retried.
+	IsNonDeterministic() bool
+}
+
 // Block represents a block in a pipeline.
 // It provides methods to get and set block properties, schema, processor, and availability status.
 type Block interface {
@@ -82,6 +128,7 @@ type Block interface {
 	// GetVersion returns the version of the block.
 	// @return string The version of the block.
 	GetVersion() string
+	IsDeprecated() bool
 
 	// SetSchemaString sets the schema string for the block.
 	// @param schema The schema string to be set.
@@ -114,6 +161,15 @@ type Block interface {
 	// @return bool True if the block is available, false otherwise.
 	IsAvailable() bool
 
+	// SetProfilesAvailability sets the availability of the credentials profiles of the block.
+	// @param profilesAvailability The availability by the profile name.
+	// @return void
+	SetProfilesAvailability(map[string]bool)
+
+	// GetProfilesAvailability returns the availability of the credentials profiles of the block.
+	// @return map[string]bool The availability by the profile name, nil for the blocks without profiles.
+	GetProfilesAvailability() map[string]bool
+
 	// SetProcessor sets the processor for the block.
 	// @param processor The processor to be set.
 	// @return void
@@ -219,8 +275,9 @@ type ProcessableBlockData interface {
 
 	// GetInputConfigData retrieves input configuration data based on provided buffers.
 	// @param buffers A map of string keys to slice of bytes buffers.
+	// @param metadata Optional map of string keys to the metadata of the buffers by index.
 	// @return The input configuration data as a slice of maps, bool indicating array, bool indicating success, error if any.
-	GetInputConfigData(map[string][]*bytes.Buffer) ([]map[string]interface{}, bool, bool, error)
+	GetInputConfigData(map[string][]*bytes.Buffer, ...map[string][]schemas.OutputMetadataSchema) ([]map[string]interface{}, bool, bool, error)
 
 	// GetStringRepresentation returns a string representation of the block data.
 	// @return The string representation of the data.
@@ -1,6 +1,8 @@
 package interfaces
 
 import (
+	"context"
+
 	"github.com/google/uuid"
 	"github.com/xeipuuv/gojsonschema"
 
@@ -24,9 +26,14 @@ type PipelineProcessingDetails interface {
 type Pipeline interface {
 	GetId() string
 	GetSlug() string
+	GetVersion() string
 	GetTitle() string
 	GetDescription() string
 	GetBlocks() []ProcessableBlockData
+	GetTriggers() []PipelineTrigger
+	GetWebhooks() []schemas.PipelineCallbackSchema
+	GetInputSchema() map[string]interface{}
+	ValidateInput(map[string]interface{}) (map[string]interface{}, error)
 	GetSchemaString() string
 	GetSchemaPtr() *gojsonschema.Schema
 
@@ -39,6 +46,8 @@ type Pipeline interface {
 	) (uuid.UUID, error)
 
 	GetProcessingsStatus([]Storage) map[uuid.UUID][]PipelineProcessingStatus
+	QueryProcessingsStatus(schemas.PipelineProcessingsQuerySchema, []Storage) ([]PipelineProcessingStatus, int)
+	QueryProcessingsUsage(schemas.PipelineProcessingsQuerySchema, []Storage) schemas.PipelineUsageSchema
 	GetProcessingDetails(uuid.UUID, []Storage) []PipelineProcessingDetails
 	GetProcessingDetailsByLogId(uuid.UUID, uuid.UUID, []Storage) PipelineProcessingDetails
 }
@@ -47,5 +56,22 @@ type PipelineCatalogueLoader interface {
 	SetStorage(Storage)
 	GetStorage() Storage
 
-	LoadCatalogue(string) (map[string]Pipeline, error)
+	// LoadCatalogue returns valid Pipelines of the catalogue by slug and version, the same by file
+	// and errors of the invalid files
+	LoadCatalogue(string) (map[string]map[string]Pipeline, map[string]Pipeline, []schemas.PipelineCatalogueErrorSchema, error)
+	// GetCatalogueVersion returns the version of the catalogue which changes with any of its files
+	GetCatalogueVersion(string) (string, error)
+}
+
+type PipelineCatalogueWatcher interface {
+	Start()
+	Shutdown(context.Context) error
+}
+
+type ProcessingIndexRefresher interface {
+	Start()
+	Shutdown(context.Context) error
+
+	// Refresh indexes the processings of every Pipeline saved to the storages since the last refresh
+	Refresh()
 }
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"data-pipelines-worker/api/schemas"
 )
 
 type ProcessingStatus int
@@ -55,6 +57,8 @@ type ProcessingOutput interface {
 	GetRetryAttempt() int
 	GetTargetBlockSlug() string
 	GetTargetBlockInputIndex() int
+	GetUsage() schemas.ProcessingUsageSchema
+	GetMetadata() []schemas.OutputMetadataSchema
 
 	SetId(string)
 	SetValue([]*bytes.Buffer)
@@ -64,4 +68,6 @@ type ProcessingOutput interface {
 	SetRetryAttempt(int)
 	SetTargetBlockSlug(string)
 	SetTargetBlockInputIndex(int)
+	SetUsage(schemas.ProcessingUsageSchema)
+	SetMetadata([]schemas.OutputMetadataSchema)
 }
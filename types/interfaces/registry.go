@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"bytes"
+	"context"
 
 	"github.com/google/uuid"
 
@@ -31,20 +32,44 @@ type WorkerRegistry interface {
 }
 
 type PipelineRegistry interface {
-	generics.Registry[Pipeline]
+	Add(Pipeline) error
+
+	Get(string) Pipeline
+	GetAll() map[string]Pipeline
+
+	Delete(string)
+	DeleteAll()
+
+	Shutdown(context.Context) error
 
 	AddPipelineResultStorage(Storage)
 	SetPipelineResultStorages([]Storage)
 	GetPipelineResultStorages() []Storage
 
+	GetVersion(string, string) Pipeline
+	GetVersions(string) []Pipeline
+	SetDefaultVersion(string, string) error
+
 	StartPipeline(schemas.PipelineStartInputSchema) (uuid.UUID, error)
 	ResumePipeline(schemas.PipelineStartInputSchema) (uuid.UUID, error)
+	ForkPipeline(uuid.UUID, schemas.PipelineStartInputSchema) (uuid.UUID, error)
+	RetryPipeline(string, uuid.UUID, schemas.PipelineRetryInputSchema) (schemas.PipelineRetryOutputSchema, error)
+	DeleteProcessing(string, uuid.UUID) ([]string, error)
+
+	SetCataloguePath(string)
+	GetCataloguePath() string
+	GetPipelineCatalogueLoader() PipelineCatalogueLoader
+	ReloadCatalogue() (schemas.PipelineCatalogueReloadSchema, error)
+	GetCatalogueReload() schemas.PipelineCatalogueReloadSchema
 
 	GetWorkerRegistry() WorkerRegistry
 	GetBlockRegistry() BlockRegistry
 	GetProcessingRegistry() ProcessingRegistry
 
 	GetProcessingsStatus(Pipeline) map[uuid.UUID][]PipelineProcessingStatus
+	QueryProcessingsStatus(Pipeline, schemas.PipelineProcessingsQuerySchema) ([]PipelineProcessingStatus, int)
+	QueryProcessingsUsage(Pipeline, schemas.PipelineProcessingsQuerySchema) schemas.PipelineUsageSchema
+	ReindexProcessings(Pipeline) (int, error)
 	GetProcessingDetails(Pipeline, uuid.UUID) []PipelineProcessingDetails
 	GetProcessingDetailsByLogId(Pipeline, uuid.UUID, uuid.UUID) PipelineProcessingDetails
 }
@@ -53,6 +78,8 @@ type BlockRegistry interface {
 	generics.Registry[Block]
 
 	DetectBlocks()
+	ReloadWasmPlugins()
+	GetVersions(string) map[string]Block
 	GetAvailableBlocks() map[string]Block
 	IsAvailable(Block) bool
 }
@@ -66,6 +93,14 @@ type ProcessingRegistry interface {
 	SetNotificationChannel(chan Processing)
 }
 
+type TriggerRegistry interface {
+	generics.Registry[PipelineTriggerState]
+
+	Start()
+	SyncTriggers()
+	GetPipelineTriggers(string) []PipelineTriggerState
+}
+
 type PipelineBlockDataRegistry interface {
 	generics.Registry[[]*bytes.Buffer]
 
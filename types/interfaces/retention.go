@@ -0,0 +1,16 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"data-pipelines-worker/api/schemas"
+)
+
+type RetentionManager interface {
+	Start()
+	Shutdown(context.Context) error
+
+	// Apply deletes processing artifacts which are expired at the given time
+	Apply(now time.Time, dryRun bool) schemas.RetentionReportSchema
+}
@@ -51,3 +51,41 @@ type Storage interface {
 	// Shutdown closes the storage connection
 	Shutdown()
 }
+
+// VersionedStorage is a Storage which reports versions of its objects
+// to detect changes without downloading them
+type VersionedStorage interface {
+	Storage
+
+	// GetObjectVersion returns the version of the object which changes with its content
+	GetObjectVersion(location StorageLocation) (string, error)
+}
+
+// ContentTypeStorage is a Storage which saves the objects with the known content type
+// instead of detecting it from the content
+type ContentTypeStorage interface {
+	Storage
+
+	// PutObjectBytesWithContentType copies a file from a buffer to destination with the content type,
+	// the content type is detected when empty
+	PutObjectBytesWithContentType(destination StorageLocation, content *bytes.Buffer, contentType string) (StorageLocation, error)
+}
+
+// ExclusiveStorage is a Storage which creates the objects atomically,
+// so concurrent writers of the same object can not overwrite each other
+type ExclusiveStorage interface {
+	Storage
+
+	// CreateObjectBytes writes the content to destination only if it does not exist yet,
+	// it returns false without an error if the destination already exists
+	CreateObjectBytes(destination StorageLocation, content *bytes.Buffer) (StorageLocation, bool, error)
+}
+
+// DirectoryStorage is a Storage which lists the direct children of a directory
+// without listing the objects of the nested directories
+type DirectoryStorage interface {
+	Storage
+
+	// ListDirectory returns the files and the nested directories of the location
+	ListDirectory(location StorageLocation) ([]StorageLocation, error)
+}
@@ -0,0 +1,32 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PipelineTrigger interface {
+	GetSlug() string
+	GetBlockSlug() string
+	GetInput() map[string]interface{}
+	GetPipelineInput() map[string]interface{}
+	IsEnabled() bool
+
+	// Validate checks the schedule definition of the trigger
+	Validate() error
+
+	// GetNextRun returns the first scheduled time after the given one
+	GetNextRun(time.Time) (time.Time, error)
+}
+
+type PipelineTriggerState interface {
+	GetId() string
+	GetPipelineSlug() string
+	GetTrigger() PipelineTrigger
+	GetLastRun() time.Time
+	GetNextRun() time.Time
+	GetLastProcessingId() uuid.UUID
+
+	MarshalJSON() ([]byte, error)
+}
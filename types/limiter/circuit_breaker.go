@@ -0,0 +1,115 @@
+package limiter
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"data-pipelines-worker/types/config"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type CircuitState string
+
+const (
+	CircuitStateClosed   CircuitState = "closed"
+	CircuitStateOpen     CircuitState = "open"
+	CircuitStateHalfOpen CircuitState = "half-open"
+)
+
+// CircuitBreaker fails fast the requests of a credentials profile after consecutive failures.
+// After the cooldown it half-opens and lets a single trial request decide whether to close again
+type CircuitBreaker struct {
+	sync.Mutex
+
+	config config.CircuitBreakerConfig
+
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+func NewCircuitBreaker(circuitBreakerConfig config.CircuitBreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{
+		config: circuitBreakerConfig.WithDefaults(),
+		state:  CircuitStateClosed,
+	}
+}
+
+func (b *CircuitBreaker) GetConfig() config.CircuitBreakerConfig {
+	return b.config
+}
+
+func (b *CircuitBreaker) GetState() CircuitState {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.getState()
+}
+
+func (b *CircuitBreaker) getState() CircuitState {
+	if b.state == CircuitStateOpen && time.Since(b.openedAt) >= b.config.Cooldown {
+		b.state = CircuitStateHalfOpen
+		b.trial = false
+	}
+
+	return b.state
+}
+
+// Allow returns ErrCircuitOpen while the circuit is open or the half-open trial is in flight
+func (b *CircuitBreaker) Allow() error {
+	b.Lock()
+	defer b.Unlock()
+
+	switch b.getState() {
+	case CircuitStateOpen:
+		return ErrCircuitOpen
+	case CircuitStateHalfOpen:
+		if b.trial {
+			return ErrCircuitOpen
+		}
+		b.trial = true
+	}
+
+	return nil
+}
+
+func (b *CircuitBreaker) Success() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.state = CircuitStateClosed
+	b.failures = 0
+	b.trial = false
+}
+
+// Cancel frees the half-open trial of the request without the outcome
+func (b *CircuitBreaker) Cancel() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.trial = false
+}
+
+// Failure returns true when the failure opens the circuit
+func (b *CircuitBreaker) Failure() bool {
+	b.Lock()
+	defer b.Unlock()
+
+	b.failures++
+	state := b.getState()
+	if state == CircuitStateOpen {
+		return false
+	}
+	if state == CircuitStateClosed && b.failures < b.config.FailureThreshold {
+		return false
+	}
+
+	b.state = CircuitStateOpen
+	b.openedAt = time.Now()
+	b.trial = false
+
+	return true
+}
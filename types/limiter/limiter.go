@@ -0,0 +1,119 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"data-pipelines-worker/types/config"
+)
+
+const LIMITER_WINDOW = time.Minute
+
+type limiterEntry struct {
+	at     time.Time
+	tokens int
+}
+
+// Limiter throttles the requests of a credentials profile within a sliding minute:
+// requests per minute, tokens per minute and concurrent requests
+type Limiter struct {
+	sync.Mutex
+
+	config config.ProviderLimitsConfig
+
+	entries     []*limiterEntry
+	concurrency chan struct{}
+}
+
+func NewLimiter(limitsConfig config.ProviderLimitsConfig) *Limiter {
+	limiter := &Limiter{
+		config:  limitsConfig,
+		entries: make([]*limiterEntry, 0),
+	}
+	if limitsConfig.ConcurrentRequests > 0 {
+		limiter.concurrency = make(chan struct{}, limitsConfig.ConcurrentRequests)
+	}
+
+	return limiter
+}
+
+func (l *Limiter) GetConfig() config.ProviderLimitsConfig {
+	return l.config
+}
+
+// Acquire waits until the request with the estimated tokens fits the limits.
+// The returned release func must be called with the actual tokens of the request, negative keeps the estimate
+func (l *Limiter) Acquire(ctx context.Context, tokens int) (func(int), error) {
+	if l.concurrency != nil {
+		select {
+		case l.concurrency <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	for {
+		entry, wait := l.reserve(time.Now(), tokens)
+		if entry != nil {
+			return l.getRelease(entry), nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			if l.concurrency != nil {
+				<-l.concurrency
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// reserve records the request when it fits the window, otherwise returns the time until the oldest entry expires
+func (l *Limiter) reserve(now time.Time, tokens int) (*limiterEntry, time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+
+	windowStart := now.Add(-LIMITER_WINDOW)
+	entries := make([]*limiterEntry, 0, len(l.entries))
+	usedTokens := 0
+	for _, entry := range l.entries {
+		if entry.at.After(windowStart) {
+			entries = append(entries, entry)
+			usedTokens += entry.tokens
+		}
+	}
+	l.entries = entries
+
+	requestsExceeded := l.config.RequestsPerMinute > 0 && len(l.entries) >= l.config.RequestsPerMinute
+	// A request larger than the whole budget passes alone in the window
+	tokensExceeded := l.config.TokensPerMinute > 0 && usedTokens > 0 && usedTokens+tokens > l.config.TokensPerMinute
+	if requestsExceeded || tokensExceeded {
+		return nil, l.entries[0].at.Add(LIMITER_WINDOW).Sub(now)
+	}
+
+	entry := &limiterEntry{at: now, tokens: tokens}
+	l.entries = append(l.entries, entry)
+
+	return entry, 0
+}
+
+func (l *Limiter) getRelease(entry *limiterEntry) func(int) {
+	once := sync.Once{}
+
+	return func(tokens int) {
+		once.Do(func() {
+			if tokens >= 0 {
+				l.Lock()
+				entry.tokens = tokens
+				l.Unlock()
+			}
+			if l.concurrency != nil {
+				<-l.concurrency
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"data-pipelines-worker/types/config"
+)
+
+const (
+	PROVIDER_OPENAI   = "openai"
+	PROVIDER_TELEGRAM = "telegram"
+)
+
+var (
+	limiterRegistry     *LimiterRegistry
+	onceLimiterRegistry sync.Once
+)
+
+// Guard is the limiter and the circuit breaker of a credentials profile of a provider.
+// It is not changed once created, the registry replaces it when the config changes
+type Guard struct {
+	Provider string
+	Profile  string
+
+	Limiter        *Limiter
+	CircuitBreaker *CircuitBreaker
+}
+
+// Do runs the call within the limits and records its outcome in the circuit breaker.
+// The call returns the actual tokens it used, negative keeps the estimate
+func (g *Guard) Do(ctx context.Context, tokens int, isFailure func(error) bool, call func() (int, error)) error {
+	if err := g.CircuitBreaker.Allow(); err != nil {
+		return fmt.Errorf("%w: %s credentials profile %s", err, g.Provider, g.Profile)
+	}
+
+	release, err := g.Limiter.Acquire(ctx, tokens)
+	if err != nil {
+		g.CircuitBreaker.Cancel()
+		return err
+	}
+
+	usedTokens, err := call()
+	release(usedTokens)
+
+	switch {
+	case err == nil:
+		g.CircuitBreaker.Success()
+	case ctx.Err() != nil:
+		// The canceled request tells nothing about the provider
+		g.CircuitBreaker.Cancel()
+	case isFailure(err):
+		if g.CircuitBreaker.Failure() {
+			config.GetLogger().Warnf(
+				"Circuit breaker of %s credentials profile %s is open for %s: %s",
+				g.Provider,
+				g.Profile,
+				g.CircuitBreaker.GetConfig().Cooldown,
+				err,
+			)
+		}
+	default:
+		g.CircuitBreaker.Success()
+	}
+
+	return err
+}
+
+// LimiterRegistry shares the Guards of the credentials profiles between all the blocks of the worker
+type LimiterRegistry struct {
+	sync.Mutex
+
+	guards map[string]*Guard
+}
+
+func GetLimiterRegistry(forceNewInstance ...bool) *LimiterRegistry {
+	if len(forceNewInstance) > 0 && forceNewInstance[0] {
+		limiterRegistry = NewLimiterRegistry()
+		onceLimiterRegistry = sync.Once{}
+		return limiterRegistry
+	}
+
+	onceLimiterRegistry.Do(func() {
+		limiterRegistry = NewLimiterRegistry()
+	})
+
+	return limiterRegistry
+}
+
+func NewLimiterRegistry() *LimiterRegistry {
+	return &LimiterRegistry{
+		guards: make(map[string]*Guard),
+	}
+}
+
+func getGuardKey(provider string, profile string) string {
+	if profile == "" {
+		profile = config.DEFAULT_CREDENTIALS_PROFILE
+	}
+
+	return provider + "/" + profile
+}
+
+// Get returns the Guard of the credentials profile. The Guard is replaced when the reloaded config changes its settings,
+// the calls of the previous Guard finish with its limiter and circuit breaker
+func (r *LimiterRegistry) Get(
+	provider string,
+	profile string,
+	limitsConfig config.ProviderLimitsConfig,
+	circuitBreakerConfig config.CircuitBreakerConfig,
+) *Guard {
+	r.Lock()
+	defer r.Unlock()
+
+	circuitBreakerConfig = circuitBreakerConfig.WithDefaults()
+
+	key := getGuardKey(provider, profile)
+	guard, ok := r.guards[key]
+	if ok &&
+		guard.Limiter.GetConfig() == limitsConfig &&
+		guard.CircuitBreaker.GetConfig() == circuitBreakerConfig {
+		return guard
+	}
+
+	// Unchanged limiter or circuit breaker keeps its state
+	newGuard := &Guard{
+		Provider: provider,
+		Profile:  profile,
+	}
+	if ok && guard.Limiter.GetConfig() == limitsConfig {
+		newGuard.Limiter = guard.Limiter
+	} else {
+		newGuard.Limiter = NewLimiter(limitsConfig)
+	}
+	if ok && guard.CircuitBreaker.GetConfig() == circuitBreakerConfig {
+		newGuard.CircuitBreaker = guard.CircuitBreaker
+	} else {
+		newGuard.CircuitBreaker = NewCircuitBreaker(circuitBreakerConfig)
+	}
+	r.guards[key] = newGuard
+
+	return newGuard
+}
+
+// IsOpen reports whether the circuit of the credentials profile is open
+func (r *LimiterRegistry) IsOpen(provider string, profile string) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	guard, ok := r.guards[getGuardKey(provider, profile)]
+	if !ok {
+		return false
+	}
+
+	return guard.CircuitBreaker.GetState() == CircuitStateOpen
+}
+
+// IsRequestFailure reports the errors counted by the circuit breaker: the server
+// throttling ( 429 ), the server errors ( 5xx ) and the errors without a status code
+func IsRequestFailure(statusCode int, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	if statusCode == 0 {
+		return true
+	}
+
+	return statusCode == 429 || statusCode >= 500
+}
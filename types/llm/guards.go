@@ -0,0 +1,141 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// ErrOutputGuardViolation is returned when the output still violates the guards after the re-asks
+var ErrOutputGuardViolation = errors.New("output guards violated")
+
+// REASK_PROMPT asks the model to fix the violations of its previous answer
+const REASK_PROMPT = "Your previous answer is rejected:\n%s\nRespond again to the same request and fix these problems."
+
+// OutputGuards are the declarative checks of the LLM output. Zero values are not checked
+type OutputGuards struct {
+	MinWords       int                    `yaml:"min_words" json:"min_words"`
+	MaxWords       int                    `yaml:"max_words" json:"max_words"`
+	MinCharacters  int                    `yaml:"min_characters" json:"min_characters"`
+	MaxCharacters  int                    `yaml:"max_characters" json:"max_characters"`
+	MinTokens      int                    `yaml:"min_tokens" json:"min_tokens"`
+	MaxTokens      int                    `yaml:"max_tokens" json:"max_tokens"`
+	JSONSchema     map[string]interface{} `yaml:"json_schema" json:"json_schema"`
+	MustMatch      []string               `yaml:"must_match" json:"must_match"`
+	MustNotMatch   []string               `yaml:"must_not_match" json:"must_not_match"`
+	ForbiddenWords []string               `yaml:"forbidden_words" json:"forbidden_words"`
+}
+
+// EstimateTokens approximates the token count of the text when the server does not report the usage
+func EstimateTokens(text string) int {
+	return int(math.Ceil(float64(utf8.RuneCountInString(text)) / 4))
+}
+
+// Validate checks the regular expressions and the JSON schema of the guards
+func (g *OutputGuards) Validate() error {
+	for _, pattern := range append(append([]string{}, g.MustMatch...), g.MustNotMatch...) {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid guard pattern %q: %s", pattern, err)
+		}
+	}
+
+	if g.JSONSchema != nil {
+		if _, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(g.JSONSchema)); err != nil {
+			return fmt.Errorf("invalid guard json_schema: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// Check returns the violations of the output. Tokens are estimated when `tokens` is not positive
+func (g *OutputGuards) Check(content string, tokens int) []string {
+	violations := make([]string, 0)
+
+	words := len(strings.Fields(content))
+	if g.MinWords > 0 && words < g.MinWords {
+		violations = append(violations, fmt.Sprintf("the answer has %d words, at least %d are required", words, g.MinWords))
+	}
+	if g.MaxWords > 0 && words > g.MaxWords {
+		violations = append(violations, fmt.Sprintf("the answer has %d words, at most %d are allowed", words, g.MaxWords))
+	}
+
+	characters := utf8.RuneCountInString(content)
+	if g.MinCharacters > 0 && characters < g.MinCharacters {
+		violations = append(violations, fmt.Sprintf("the answer has %d characters, at least %d are required", characters, g.MinCharacters))
+	}
+	if g.MaxCharacters > 0 && characters > g.MaxCharacters {
+		violations = append(violations, fmt.Sprintf("the answer has %d characters, at most %d are allowed", characters, g.MaxCharacters))
+	}
+
+	if tokens <= 0 {
+		tokens = EstimateTokens(content)
+	}
+	if g.MinTokens > 0 && tokens < g.MinTokens {
+		violations = append(violations, fmt.Sprintf("the answer has %d tokens, at least %d are required", tokens, g.MinTokens))
+	}
+	if g.MaxTokens > 0 && tokens > g.MaxTokens {
+		violations = append(violations, fmt.Sprintf("the answer has %d tokens, at most %d are allowed", tokens, g.MaxTokens))
+	}
+
+	if g.JSONSchema != nil {
+		violations = append(violations, g.checkJSONSchema(content)...)
+	}
+
+	for _, pattern := range g.MustMatch {
+		if regex, err := regexp.Compile(pattern); err == nil && !regex.MatchString(content) {
+			violations = append(violations, fmt.Sprintf("the answer must match the pattern %q", pattern))
+		}
+	}
+	for _, pattern := range g.MustNotMatch {
+		if regex, err := regexp.Compile(pattern); err == nil && regex.MatchString(content) {
+			violations = append(violations, fmt.Sprintf("the answer must not match the pattern %q", pattern))
+		}
+	}
+
+	if len(g.ForbiddenWords) > 0 {
+		contentWords := make(map[string]bool)
+		for _, word := range strings.FieldsFunc(strings.ToLower(content), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '-' && r != '\''
+		}) {
+			contentWords[word] = true
+		}
+		for _, word := range g.ForbiddenWords {
+			if contentWords[strings.ToLower(word)] {
+				violations = append(violations, fmt.Sprintf("the answer must not contain the word %q", word))
+			}
+		}
+	}
+
+	return violations
+}
+
+func (g *OutputGuards) checkJSONSchema(content string) []string {
+	schemaPtr, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(g.JSONSchema))
+	if err != nil {
+		return []string{fmt.Sprintf("guard json_schema is invalid: %s", err)}
+	}
+
+	validationResult, err := schemaPtr.Validate(gojsonschema.NewStringLoader(content))
+	if err != nil {
+		return []string{"the answer must be a valid JSON"}
+	}
+
+	violations := make([]string, 0)
+	for _, err := range validationResult.Errors() {
+		violations = append(violations, fmt.Sprintf("the answer does not match the JSON schema: %s", err))
+	}
+
+	return violations
+}
+
+// GetReAskPrompt builds the follow-up message with the violations of the previous answer
+func GetReAskPrompt(violations []string) string {
+	return fmt.Sprintf(REASK_PROMPT, "- "+strings.Join(violations, "\n- "))
+}
@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"errors"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/limiter"
+)
+
+// LimitedProvider runs the calls within the rate limits and the circuit breaker
+// shared by all the blocks of the credentials profile
+type LimitedProvider struct {
+	Provider
+
+	profile string
+}
+
+var _ Provider = (*LimitedProvider)(nil)
+
+func NewLimitedProvider(profile string, provider Provider) *LimitedProvider {
+	return &LimitedProvider{
+		Provider: provider,
+		profile:  profile,
+	}
+}
+
+// getGuard follows the limits of the reloaded config. Providers set explicitly for an unknown profile are not limited
+func (p *LimitedProvider) getGuard() *limiter.Guard {
+	limitsConfig := config.ProviderLimitsConfig{}
+	circuitBreakerConfig := config.CircuitBreakerConfig{}
+	if openAIConfig, err := config.GetConfig().OpenAI.GetProfile(p.profile); err == nil {
+		limitsConfig = openAIConfig.Limits
+		circuitBreakerConfig = openAIConfig.CircuitBreaker
+	}
+
+	return limiter.GetLimiterRegistry().Get(
+		limiter.PROVIDER_OPENAI,
+		p.profile,
+		limitsConfig,
+		circuitBreakerConfig,
+	)
+}
+
+func (p *LimitedProvider) CreateChatCompletion(
+	ctx context.Context,
+	request openai.ChatCompletionRequest,
+) (response openai.ChatCompletionResponse, err error) {
+	err = p.getGuard().Do(ctx, EstimateRequestTokens(request), IsProviderFailure, func() (int, error) {
+		var callErr error
+		response, callErr = p.Provider.CreateChatCompletion(ctx, request)
+		if callErr != nil || response.Usage.TotalTokens <= 0 {
+			return -1, callErr
+		}
+
+		return response.Usage.TotalTokens, nil
+	})
+
+	return response, err
+}
+
+func (p *LimitedProvider) CreateSpeech(
+	ctx context.Context,
+	request openai.CreateSpeechRequest,
+) (response openai.RawResponse, err error) {
+	err = p.getGuard().Do(ctx, 0, IsProviderFailure, func() (int, error) {
+		var callErr error
+		response, callErr = p.Provider.CreateSpeech(ctx, request)
+		return -1, callErr
+	})
+
+	return response, err
+}
+
+func (p *LimitedProvider) CreateTranscription(
+	ctx context.Context,
+	request openai.AudioRequest,
+) (response openai.AudioResponse, err error) {
+	err = p.getGuard().Do(ctx, 0, IsProviderFailure, func() (int, error) {
+		var callErr error
+		response, callErr = p.Provider.CreateTranscription(ctx, request)
+		return -1, callErr
+	})
+
+	return response, err
+}
+
+func (p *LimitedProvider) CreateImage(
+	ctx context.Context,
+	request openai.ImageRequest,
+) (response openai.ImageResponse, err error) {
+	err = p.getGuard().Do(ctx, 0, IsProviderFailure, func() (int, error) {
+		var callErr error
+		response, callErr = p.Provider.CreateImage(ctx, request)
+		return -1, callErr
+	})
+
+	return response, err
+}
+
+// EstimateRequestTokens approximates the prompt tokens of the messages and adds the completion tokens limit
+func EstimateRequestTokens(request openai.ChatCompletionRequest) int {
+	tokens := request.MaxTokens
+	if request.MaxCompletionTokens > 0 {
+		tokens = request.MaxCompletionTokens
+	}
+
+	for _, message := range request.Messages {
+		tokens += EstimateTokens(message.Content)
+		for _, part := range message.MultiContent {
+			tokens += EstimateTokens(part.Text)
+		}
+	}
+
+	return tokens
+}
+
+// IsProviderFailure reports the errors opening the circuit of the credentials profile
+func IsProviderFailure(err error) bool {
+	var apiErr *openai.APIError
+	var requestErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		return limiter.IsRequestFailure(apiErr.HTTPStatusCode, err)
+	case errors.As(err, &requestErr):
+		return limiter.IsRequestFailure(requestErr.HTTPStatusCode, err)
+	}
+
+	return limiter.IsRequestFailure(0, err)
+}
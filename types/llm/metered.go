@@ -0,0 +1,116 @@
+package llm
+
+import (
+	"context"
+	"unicode/utf8"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"data-pipelines-worker/api/schemas"
+)
+
+const (
+	DEFAULT_IMAGE_SIZE    = openai.CreateImageSize1024x1024
+	DEFAULT_IMAGE_QUALITY = openai.CreateImageQualityStandard
+)
+
+// MeteredProvider records the usage and the cost of the successful calls to the context
+type MeteredProvider struct {
+	Provider
+}
+
+var _ Provider = (*MeteredProvider)(nil)
+
+func NewMeteredProvider(provider Provider) *MeteredProvider {
+	return &MeteredProvider{Provider: provider}
+}
+
+func (p *MeteredProvider) CreateChatCompletion(
+	ctx context.Context,
+	request openai.ChatCompletionRequest,
+) (openai.ChatCompletionResponse, error) {
+	response, err := p.Provider.CreateChatCompletion(ctx, request)
+	if err != nil {
+		return response, err
+	}
+
+	model := request.Model
+	if model == "" {
+		model = response.Model
+	}
+	recordPricedUsage(ctx, model, schemas.UsageSchema{
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+	})
+
+	return response, nil
+}
+
+func (p *MeteredProvider) CreateSpeech(
+	ctx context.Context,
+	request openai.CreateSpeechRequest,
+) (openai.RawResponse, error) {
+	response, err := p.Provider.CreateSpeech(ctx, request)
+	if err != nil {
+		return response, err
+	}
+
+	recordPricedUsage(ctx, string(request.Model), schemas.UsageSchema{
+		Characters: utf8.RuneCountInString(request.Input),
+	})
+
+	return response, nil
+}
+
+// CreateTranscription records the audio duration reported with the verbose_json format
+func (p *MeteredProvider) CreateTranscription(
+	ctx context.Context,
+	request openai.AudioRequest,
+) (openai.AudioResponse, error) {
+	response, err := p.Provider.CreateTranscription(ctx, request)
+	if err != nil {
+		return response, err
+	}
+
+	recordPricedUsage(ctx, request.Model, schemas.UsageSchema{
+		Seconds: response.Duration,
+	})
+
+	return response, nil
+}
+
+func (p *MeteredProvider) CreateImage(
+	ctx context.Context,
+	request openai.ImageRequest,
+) (openai.ImageResponse, error) {
+	response, err := p.Provider.CreateImage(ctx, request)
+	if err != nil {
+		return response, err
+	}
+
+	model := request.Model
+	if model == "" {
+		model = openai.CreateImageModelDallE2
+	}
+	size := request.Size
+	if size == "" {
+		size = DEFAULT_IMAGE_SIZE
+	}
+	quality := request.Quality
+	if quality == "" {
+		quality = DEFAULT_IMAGE_QUALITY
+	}
+
+	usage := schemas.UsageSchema{
+		Images: len(response.Data),
+	}
+	usage.Cost = GetImagesCost(model, size, quality, usage.Images)
+	RecordUsage(ctx, model, usage)
+
+	return response, nil
+}
+
+func recordPricedUsage(ctx context.Context, model string, usage schemas.UsageSchema) {
+	usage.Cost = GetUsageCost(model, usage)
+	RecordUsage(ctx, model, usage)
+}
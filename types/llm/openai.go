@@ -0,0 +1,212 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"data-pipelines-worker/types/config"
+)
+
+// JSON_MODE_INSTRUCTION replaces the JSON response format on the servers without the JSON mode
+const JSON_MODE_INSTRUCTION = "Respond with a valid JSON object only, without any other text."
+
+// OpenAIProvider sends the requests with the client of an `openai` credentials profile
+type OpenAIProvider struct {
+	sync.Mutex
+
+	name    string
+	profile *config.OpenAIConfig
+
+	// JSON mode support, nil until it is declared or detected
+	jsonMode *bool
+}
+
+var _ Provider = (*OpenAIProvider)(nil)
+
+func NewOpenAIProvider(name string, profile *config.OpenAIConfig) *OpenAIProvider {
+	return &OpenAIProvider{
+		name:     name,
+		profile:  profile,
+		jsonMode: profile.Capabilities.JSONMode,
+	}
+}
+
+func (p *OpenAIProvider) GetName() string {
+	return p.name
+}
+
+// getClient returns the current client of the profile, it is replaced by the tests
+func (p *OpenAIProvider) getClient() (*openai.Client, error) {
+	client := p.profile.GetClient()
+	if client != nil {
+		return client, nil
+	}
+
+	if p.name == config.DEFAULT_CREDENTIALS_PROFILE {
+		return nil, errors.New("openAI client is not configured")
+	}
+	return nil, fmt.Errorf("openAI client of %s credentials profile is not configured", p.name)
+}
+
+func (p *OpenAIProvider) supportsJSONMode() bool {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.jsonMode == nil || *p.jsonMode
+}
+
+func (p *OpenAIProvider) disableJSONMode() {
+	p.Lock()
+	defer p.Unlock()
+
+	jsonMode := false
+	p.jsonMode = &jsonMode
+}
+
+func (p *OpenAIProvider) ListModels(ctx context.Context) ([]string, error) {
+	client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	modelsList, err := client.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	models := make([]string, len(modelsList.Models))
+	for i, model := range modelsList.Models {
+		models[i] = model.ID
+	}
+
+	return models, nil
+}
+
+// CreateChatCompletion asks for the JSON object in the prompt on the servers without the JSON mode
+func (p *OpenAIProvider) CreateChatCompletion(
+	ctx context.Context,
+	request openai.ChatCompletionRequest,
+) (openai.ChatCompletionResponse, error) {
+	client, err := p.getClient()
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+
+	if isJSONModeRequest(request) && !p.supportsJSONMode() {
+		request = withoutJSONMode(request)
+	}
+
+	response, err := client.CreateChatCompletion(ctx, request)
+	if err != nil && isJSONModeRequest(request) && isResponseFormatUnsupported(err) {
+		config.GetLogger().Warnf(
+			"OpenAI server of %s credentials profile does not support the JSON mode, falling back to the prompt: %s",
+			p.name,
+			err,
+		)
+		p.disableJSONMode()
+
+		return client.CreateChatCompletion(ctx, withoutJSONMode(request))
+	}
+
+	return response, err
+}
+
+func (p *OpenAIProvider) CreateSpeech(
+	ctx context.Context,
+	request openai.CreateSpeechRequest,
+) (openai.RawResponse, error) {
+	client, err := p.getClient()
+	if err != nil {
+		return openai.RawResponse{}, err
+	}
+
+	return client.CreateSpeech(ctx, request)
+}
+
+func (p *OpenAIProvider) CreateTranscription(
+	ctx context.Context,
+	request openai.AudioRequest,
+) (openai.AudioResponse, error) {
+	client, err := p.getClient()
+	if err != nil {
+		return openai.AudioResponse{}, err
+	}
+
+	return client.CreateTranscription(ctx, request)
+}
+
+func (p *OpenAIProvider) CreateImage(
+	ctx context.Context,
+	request openai.ImageRequest,
+) (openai.ImageResponse, error) {
+	client, err := p.getClient()
+	if err != nil {
+		return openai.ImageResponse{}, err
+	}
+
+	return client.CreateImage(ctx, request)
+}
+
+func isJSONModeRequest(request openai.ChatCompletionRequest) bool {
+	return request.ResponseFormat != nil &&
+		(request.ResponseFormat.Type == openai.ChatCompletionResponseFormatTypeJSONObject ||
+			request.ResponseFormat.Type == openai.ChatCompletionResponseFormatTypeJSONSchema)
+}
+
+// withoutJSONMode moves the JSON response format of the request to the system prompt
+func withoutJSONMode(request openai.ChatCompletionRequest) openai.ChatCompletionRequest {
+	instruction := JSON_MODE_INSTRUCTION
+	if jsonSchema := request.ResponseFormat.JSONSchema; jsonSchema != nil && jsonSchema.Schema != nil {
+		if schemaJSON, err := jsonSchema.Schema.MarshalJSON(); err == nil {
+			instruction = fmt.Sprintf("%s The object must match the JSON schema: %s", JSON_MODE_INSTRUCTION, schemaJSON)
+		}
+	}
+	request.ResponseFormat = nil
+
+	messages := make([]openai.ChatCompletionMessage, 0, len(request.Messages)+1)
+	if len(request.Messages) > 0 && request.Messages[0].Role == openai.ChatMessageRoleSystem {
+		systemMessage := request.Messages[0]
+		systemMessage.Content = strings.TrimSpace(systemMessage.Content + "\n" + instruction)
+		messages = append(messages, systemMessage)
+		messages = append(messages, request.Messages[1:]...)
+	} else {
+		messages = append(
+			messages,
+			openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: instruction,
+			},
+		)
+		messages = append(messages, request.Messages...)
+	}
+	request.Messages = messages
+
+	return request
+}
+
+// isResponseFormatUnsupported detects the servers rejecting the `response_format` of the request
+func isResponseFormatUnsupported(err error) bool {
+	statusCode := 0
+	var apiErr *openai.APIError
+	var requestErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		statusCode = apiErr.HTTPStatusCode
+	case errors.As(err, &requestErr):
+		statusCode = requestErr.HTTPStatusCode
+	default:
+		return false
+	}
+
+	if statusCode != http.StatusBadRequest && statusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+
+	return strings.Contains(strings.ToLower(err.Error()), "response_format")
+}
@@ -0,0 +1,123 @@
+package llm
+
+import (
+	"context"
+	"sync"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"data-pipelines-worker/types/config"
+)
+
+var (
+	providerRegistry     *ProviderRegistry
+	onceProviderRegistry sync.Once
+)
+
+// Provider is an OpenAI-compatible LLM backend of a credentials profile:
+// api.openai.com, Azure or a local server ( llama.cpp, Ollama, vLLM )
+type Provider interface {
+	GetName() string
+
+	ListModels(context.Context) ([]string, error)
+	CreateChatCompletion(context.Context, openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+	CreateSpeech(context.Context, openai.CreateSpeechRequest) (openai.RawResponse, error)
+	CreateTranscription(context.Context, openai.AudioRequest) (openai.AudioResponse, error)
+	CreateImage(context.Context, openai.ImageRequest) (openai.ImageResponse, error)
+}
+
+type providerEntry struct {
+	// Profile the provider is created for, nil for the providers set explicitly
+	profile  *config.OpenAIConfig
+	provider Provider
+}
+
+// ProviderRegistry holds the Providers of the credentials profiles of the `openai` section
+type ProviderRegistry struct {
+	sync.Mutex
+
+	providers map[string]providerEntry
+}
+
+func GetProviderRegistry(forceNewInstance ...bool) *ProviderRegistry {
+	if len(forceNewInstance) > 0 && forceNewInstance[0] {
+		providerRegistry = NewProviderRegistry()
+		onceProviderRegistry = sync.Once{}
+		return providerRegistry
+	}
+
+	onceProviderRegistry.Do(func() {
+		providerRegistry = NewProviderRegistry()
+	})
+
+	return providerRegistry
+}
+
+func NewProviderRegistry() *ProviderRegistry {
+	return &ProviderRegistry{
+		providers: make(map[string]providerEntry),
+	}
+}
+
+func getProfileName(profile string) string {
+	if profile == "" {
+		return config.DEFAULT_CREDENTIALS_PROFILE
+	}
+
+	return profile
+}
+
+// Get returns the metered and limited Provider of the credentials profile.
+// Providers follow the profiles of the reloaded config
+func (r *ProviderRegistry) Get(profile string) (Provider, error) {
+	profile = getProfileName(profile)
+	provider, err := r.get(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMeteredProvider(NewLimitedProvider(profile, provider)), nil
+}
+
+func (r *ProviderRegistry) get(profile string) (Provider, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	entry, ok := r.providers[profile]
+	if ok && entry.profile == nil {
+		return entry.provider, nil
+	}
+
+	openAIConfig, err := config.GetConfig().OpenAI.GetProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+	if ok && entry.profile == openAIConfig {
+		return entry.provider, nil
+	}
+
+	provider := NewOpenAIProvider(profile, openAIConfig)
+	r.providers[profile] = providerEntry{
+		profile:  openAIConfig,
+		provider: provider,
+	}
+
+	return provider, nil
+}
+
+// Set replaces the Provider of the credentials profile, e.g. with a stand-in of the offline deployments
+func (r *ProviderRegistry) Set(profile string, provider Provider) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.providers[getProfileName(profile)] = providerEntry{
+		provider: provider,
+	}
+}
+
+func (r *ProviderRegistry) Delete(profile string) {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.providers, getProfileName(profile))
+}
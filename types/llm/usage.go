@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"context"
+	"sync"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types/config"
+)
+
+type usageRecorderContextKey struct{}
+
+// UsageRecorder collects the usage of the provider calls of a processing
+type UsageRecorder struct {
+	sync.Mutex
+
+	usage schemas.ProcessingUsageSchema
+}
+
+// WithUsageRecorder returns the context the provider calls record their usage to
+func WithUsageRecorder(ctx context.Context) (context.Context, *UsageRecorder) {
+	recorder := &UsageRecorder{
+		usage: schemas.ProcessingUsageSchema{
+			Currency: config.GetConfig().Costs.Currency,
+		},
+	}
+
+	return context.WithValue(ctx, usageRecorderContextKey{}, recorder), recorder
+}
+
+// RecordUsage adds the usage of a call to the recorder of the context, if any
+func RecordUsage(ctx context.Context, model string, usage schemas.UsageSchema) {
+	recorder, ok := ctx.Value(usageRecorderContextKey{}).(*UsageRecorder)
+	if !ok {
+		return
+	}
+
+	recorder.Lock()
+	defer recorder.Unlock()
+
+	recorder.usage.AddModelUsage(model, usage)
+}
+
+func (r *UsageRecorder) GetUsage() schemas.ProcessingUsageSchema {
+	r.Lock()
+	defer r.Unlock()
+
+	usage := r.usage
+	usage.Models = make(map[string]schemas.UsageSchema, len(r.usage.Models))
+	for model, modelUsage := range r.usage.Models {
+		usage.Models[model] = modelUsage
+	}
+
+	return usage
+}
+
+// GetUsageCost prices the tokens, characters and audio of the usage with the price table.
+// Images are priced by size and quality with GetImagesCost
+func GetUsageCost(model string, usage schemas.UsageSchema) float64 {
+	price, ok := config.GetConfig().Costs.GetModelPrice(model)
+	if !ok {
+		return 0
+	}
+
+	return float64(usage.PromptTokens)*price.PromptTokens/1e6 +
+		float64(usage.CompletionTokens)*price.CompletionTokens/1e6 +
+		float64(usage.Characters)*price.Characters/1e6 +
+		usage.Seconds/60*price.Minutes
+}
+
+func GetImagesCost(model string, size string, quality string, images int) float64 {
+	price, ok := config.GetConfig().Costs.GetModelPrice(model)
+	if !ok {
+		return 0
+	}
+
+	return float64(images) * price.GetImagePrice(size, quality)
+}
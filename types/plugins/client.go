@@ -0,0 +1,226 @@
+package plugins
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"time"
+
+	"data-pipelines-worker/types/config"
+)
+
+// Client calls the describe, detect and process methods of a plugin
+type Client struct {
+	Name      string
+	Transport Transport
+	Config    config.ExternalPluginConfig
+}
+
+func NewClient(pluginConfig config.ExternalPluginConfig) (*Client, error) {
+	pluginConfig = pluginConfig.WithDefaults()
+
+	var transport Transport
+	switch {
+	case pluginConfig.Command != "" && pluginConfig.URL != "":
+		return nil, fmt.Errorf("plugin %s must have either command or url", pluginConfig.Name)
+	case pluginConfig.Command != "":
+		transport = NewExecTransport(pluginConfig.Command, pluginConfig.Args, pluginConfig.Env)
+	case pluginConfig.URL != "":
+		transport = NewHTTPTransport(pluginConfig.URL, pluginConfig.Headers)
+	default:
+		return nil, fmt.Errorf("plugin %s has neither command nor url", pluginConfig.Name)
+	}
+
+	return &Client{
+		Name:      pluginConfig.Name,
+		Transport: transport,
+		Config:    pluginConfig,
+	}, nil
+}
+
+// Ensure Client implements the Plugin
+var _ Plugin = (*Client)(nil)
+
+func (c *Client) GetName() string {
+	return c.Name
+}
+
+func (c *Client) GetCheckInterval() time.Duration {
+	return c.Config.CheckInterval
+}
+
+// Describe returns the metadata and the schema of the plugin block
+func (c *Client) Describe(ctx context.Context) (*Description, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Config.DescribeTimeout)
+	defer cancel()
+
+	description := &Description{}
+	if err := c.call(ctx, Request{Method: METHOD_DESCRIBE}, nil, description, nil); err != nil {
+		return nil, c.wrapError(ctx, err, c.Config.DescribeTimeout)
+	}
+	if description.Error != "" {
+		return nil, fmt.Errorf("plugin %s failed to describe: %s", c.Name, description.Error)
+	}
+	if description.Id == "" {
+		return nil, fmt.Errorf("%w: plugin %s description has no id", ErrPluginProtocol, c.Name)
+	}
+	if len(description.Schema) == 0 {
+		return nil, fmt.Errorf("%w: plugin %s description has no schema", ErrPluginProtocol, c.Name)
+	}
+	if description.Name == "" {
+		description.Name = description.Id
+	}
+	if description.Version == "" {
+		description.Version = "1"
+	}
+
+	return description, nil
+}
+
+// Detect reports whether the plugin is able to process the requests
+func (c *Client) Detect(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Config.DescribeTimeout)
+	defer cancel()
+
+	result := &DetectResult{}
+	if err := c.call(ctx, Request{Method: METHOD_DETECT}, nil, result, nil); err != nil {
+		return false, c.wrapError(ctx, err, c.Config.DescribeTimeout)
+	}
+	if result.Error != "" {
+		return false, fmt.Errorf("plugin %s failed to detect: %s", c.Name, result.Error)
+	}
+
+	return result.Available, nil
+}
+
+// Process sends the input with the files of the input to the plugin and returns the outputs.
+// Error of the result is returned as the error along with the outputs
+func (c *Client) Process(ctx context.Context, input map[string]interface{}) ([]*bytes.Buffer, *ProcessResult, error) {
+	processCtx, cancel := context.WithTimeout(ctx, c.Config.Timeout)
+	defer cancel()
+
+	input, files, readers := SplitFiles(input)
+
+	result := &ProcessResult{}
+	outputs := make([]*bytes.Buffer, 0)
+	err := c.call(
+		processCtx,
+		Request{Method: METHOD_PROCESS, Input: input, Files: files},
+		readers,
+		result,
+		func(r *bufio.Reader) error {
+			var err error
+			outputs, err = ReadFiles(r, result.Outputs, c.Config.MaxOutputSize)
+			return err
+		},
+	)
+	if err != nil {
+		// Cancellation of the processing is reported as is
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, c.wrapError(processCtx, err, c.Config.Timeout)
+	}
+	if result.Error != "" {
+		return outputs, result, fmt.Errorf("plugin %s failed to process: %s", c.Name, result.Error)
+	}
+
+	return outputs, result, nil
+}
+
+// call sends the request and reads the header of the response. readFiles reads the files following the header
+func (c *Client) call(
+	ctx context.Context,
+	request Request,
+	files []io.Reader,
+	header interface{},
+	readFiles func(*bufio.Reader) error,
+) error {
+	request.Protocol = PROTOCOL_VERSION
+
+	response, err := c.Transport.Call(ctx, request.Method, func(w io.Writer) error {
+		return WriteMessage(w, request, files)
+	})
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(response)
+	err = ReadHeader(reader, header)
+	if err == nil && readFiles != nil {
+		err = readFiles(reader)
+	}
+
+	// Exit errors of the plugin explain the broken responses better
+	if closeErr := response.Close(); closeErr != nil {
+		return closeErr
+	}
+
+	return err
+}
+
+func (c *Client) wrapError(ctx context.Context, err error, timeout time.Duration) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("plugin %s timed out after %s", c.Name, timeout)
+	}
+
+	return err
+}
+
+// SplitFiles moves the []byte values and the arrays of them out of the input into the files of the request
+func SplitFiles(input map[string]interface{}) (map[string]interface{}, []File, []io.Reader) {
+	names := make([]string, 0, len(input))
+	for name := range input {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	jsonInput := make(map[string]interface{}, len(input))
+	files := make([]File, 0)
+	readers := make([]io.Reader, 0)
+	for _, name := range names {
+		value := input[name]
+
+		if fileBytes, ok := value.([]byte); ok {
+			files = append(files, File{Name: name, Size: int64(len(fileBytes))})
+			readers = append(readers, bytes.NewReader(fileBytes))
+			continue
+		}
+
+		if items, ok := getFileItems(value); ok {
+			for index, fileBytes := range items {
+				files = append(files, File{Name: name, Index: &index, Size: int64(len(fileBytes))})
+				readers = append(readers, bytes.NewReader(fileBytes))
+			}
+			continue
+		}
+
+		jsonInput[name] = value
+	}
+
+	return jsonInput, files, readers
+}
+
+// getFileItems returns the items of the non-empty arrays of []byte
+func getFileItems(value interface{}) ([][]byte, bool) {
+	val := reflect.ValueOf(value)
+	if val.Kind() != reflect.Slice || val.Len() == 0 {
+		return nil, false
+	}
+
+	items := make([][]byte, 0, val.Len())
+	for i := 0; i < val.Len(); i++ {
+		item, ok := val.Index(i).Interface().([]byte)
+		if !ok {
+			return nil, false
+		}
+		items = append(items, item)
+	}
+
+	return items, true
+}
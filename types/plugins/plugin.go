@@ -0,0 +1,17 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"time"
+)
+
+// Plugin implements a plugin block: an external plugin or a WebAssembly module
+type Plugin interface {
+	GetName() string
+	GetCheckInterval() time.Duration
+
+	Describe(context.Context) (*Description, error)
+	Detect(context.Context) (bool, error)
+	Process(context.Context, map[string]interface{}) ([]*bytes.Buffer, *ProcessResult, error)
+}
@@ -0,0 +1,140 @@
+package plugins
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Messages of the plugin protocol are a JSON header line followed by the raw bytes
+// of the files the header lists with their sizes, so the files are streamed as is
+const (
+	PROTOCOL_VERSION = 1
+
+	METHOD_DESCRIBE = "describe"
+	METHOD_DETECT   = "detect"
+	METHOD_PROCESS  = "process"
+
+	// Max size of the JSON header line
+	MAX_HEADER_SIZE = 16 << 20
+)
+
+var ErrPluginProtocol = errors.New("plugin protocol error")
+
+// File is the header of a file of the message. Index is set for the files of an array input
+type File struct {
+	Name  string `json:"name"`
+	Index *int   `json:"index,omitempty"`
+	Size  int64  `json:"size"`
+}
+
+// Request is the header of the requests to the plugin
+type Request struct {
+	Protocol int                    `json:"protocol"`
+	Method   string                 `json:"method"`
+	Input    map[string]interface{} `json:"input,omitempty"`
+	Files    []File                 `json:"files,omitempty"`
+}
+
+// Description is the response to the describe request. Schema is the JSON schema of the block
+type Description struct {
+	Id          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Version     string          `json:"version"`
+	Deprecated  bool            `json:"deprecated"`
+	Schema      json.RawMessage `json:"schema"`
+	Error       string          `json:"error"`
+}
+
+// DetectResult is the response to the detect request
+type DetectResult struct {
+	Available bool   `json:"available"`
+	Error     string `json:"error"`
+}
+
+// ProcessResult is the response to the process request. Outputs are the files of the response.
+// Error of the responses fails the request
+type ProcessResult struct {
+	Outputs      []File `json:"outputs"`
+	StopPipeline bool   `json:"stop_pipeline"`
+	Retry        bool   `json:"retry"`
+	Error        string `json:"error"`
+}
+
+// WriteMessage writes the header line and the files in the order of the header
+func WriteMessage(w io.Writer, header interface{}, files []io.Reader) error {
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(append(headerBytes, '\n')); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if _, err := io.Copy(w, file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadHeader reads the header line of the message into the header
+func ReadHeader(r *bufio.Reader, header interface{}) error {
+	headerBytes := make([]byte, 0)
+	for {
+		line, isPrefix, err := r.ReadLine()
+		if err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("%w: message has no header", ErrPluginProtocol)
+			}
+			return err
+		}
+
+		headerBytes = append(headerBytes, line...)
+		if len(headerBytes) > MAX_HEADER_SIZE {
+			return fmt.Errorf("%w: header exceeds %d bytes", ErrPluginProtocol, MAX_HEADER_SIZE)
+		}
+		if !isPrefix {
+			break
+		}
+	}
+
+	if err := json.Unmarshal(headerBytes, header); err != nil {
+		return fmt.Errorf("%w: invalid header: %s", ErrPluginProtocol, err)
+	}
+
+	return nil
+}
+
+// ReadFiles reads the files of the header following it. Files larger than maxSize fail the read
+func ReadFiles(r *bufio.Reader, files []File, maxSize int64) ([]*bytes.Buffer, error) {
+	buffers := make([]*bytes.Buffer, 0, len(files))
+	for _, file := range files {
+		if file.Size < 0 {
+			return nil, fmt.Errorf("%w: file %s has negative size", ErrPluginProtocol, file.Name)
+		}
+		if maxSize > 0 && file.Size > maxSize {
+			return nil, fmt.Errorf(
+				"%w: file %s of %d bytes exceeds %d bytes",
+				ErrPluginProtocol,
+				file.Name,
+				file.Size,
+				maxSize,
+			)
+		}
+
+		buffer := &bytes.Buffer{}
+		if _, err := io.CopyN(buffer, r, file.Size); err != nil {
+			return nil, fmt.Errorf("%w: file %s is truncated: %s", ErrPluginProtocol, file.Name, err)
+		}
+		buffers = append(buffers, buffer)
+	}
+
+	return buffers, nil
+}
@@ -0,0 +1,207 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	PROTOCOL_CONTENT_TYPE = "application/x-data-pipelines-plugin"
+
+	// Time to wait for the output of the killed plugin process
+	EXEC_WAIT_DELAY = 5 * time.Second
+	// Bytes of the plugin stderr or the HTTP error body kept for the errors
+	ERROR_OUTPUT_SIZE = 4 << 10
+)
+
+// Variables of the worker environment passed to the plugin executables along with their `env`.
+// The rest of it ( e.g. the secrets and the API tokens ) is not inherited
+var EXEC_INHERITED_ENV = []string{"PATH"}
+
+// Transport delivers the request to the plugin and returns the response message.
+// Request is streamed by writeRequest, the response must be closed to release the plugin
+type Transport interface {
+	Call(ctx context.Context, method string, writeRequest func(io.Writer) error) (io.ReadCloser, error)
+}
+
+// ExecTransport runs the executable for every call with the method as the last argument.
+// Request is written to stdin and the response is read from stdout. Cancellation kills the process.
+// The process gets only the EXEC_INHERITED_ENV of the worker environment and the Env
+type ExecTransport struct {
+	Command string
+	Args    []string
+	Env     map[string]string
+}
+
+func NewExecTransport(command string, args []string, env map[string]string) *ExecTransport {
+	return &ExecTransport{
+		Command: command,
+		Args:    args,
+		Env:     env,
+	}
+}
+
+func (t *ExecTransport) Call(ctx context.Context, method string, writeRequest func(io.Writer) error) (io.ReadCloser, error) {
+	cmd := exec.CommandContext(ctx, t.Command, append(slices.Clone(t.Args), method)...)
+	cmd.WaitDelay = EXEC_WAIT_DELAY
+	cmd.Env = make([]string, 0, len(EXEC_INHERITED_ENV)+len(t.Env))
+	for _, name := range EXEC_INHERITED_ENV {
+		if value, ok := os.LookupEnv(name); ok {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", name, value))
+		}
+	}
+	for name, value := range t.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", name, value))
+	}
+
+	stderr := &tailBuffer{size: ERROR_OUTPUT_SIZE}
+	cmd.Stderr = stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	// Plugins may respond without reading the whole request, the write errors are reported only with the exit errors
+	writeErr := make(chan error, 1)
+	go func() {
+		err := writeRequest(stdin)
+		stdin.Close()
+		writeErr <- err
+	}()
+
+	return &execResponse{
+		ReadCloser: stdout,
+		cmd:        cmd,
+		stderr:     stderr,
+		writeErr:   writeErr,
+	}, nil
+}
+
+type execResponse struct {
+	io.ReadCloser
+
+	cmd      *exec.Cmd
+	stderr   *tailBuffer
+	writeErr chan error
+}
+
+// Close waits for the process and reports its exit error with the tail of the stderr
+func (r *execResponse) Close() error {
+	// Unread output must not block the process
+	io.Copy(io.Discard, r.ReadCloser)
+
+	err := r.cmd.Wait()
+	writeErr := <-r.writeErr
+	if err == nil {
+		return nil
+	}
+
+	if stderr := strings.TrimSpace(r.stderr.String()); stderr != "" {
+		err = fmt.Errorf("%w: %s", err, stderr)
+	} else if writeErr != nil {
+		err = fmt.Errorf("%w: %s", err, writeErr)
+	}
+
+	return fmt.Errorf("plugin %s failed: %w", r.cmd.Path, err)
+}
+
+// HTTPTransport POSTs the request to `<url>/<method>` and reads the response from the body.
+// Endpoints are configured by the operator, so they are called by a client of their own and not through
+// the egress policy guarding the URLs the pipelines request. Redirects are not followed
+type HTTPTransport struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+func NewHTTPTransport(url string, headers map[string]string) *HTTPTransport {
+	return &HTTPTransport{
+		URL:     strings.TrimSuffix(url, "/"),
+		Headers: headers,
+		// Calls are limited by the timeouts of the contexts
+		Client: &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (t *HTTPTransport) Call(ctx context.Context, method string, writeRequest func(io.Writer) error) (io.ReadCloser, error) {
+	bodyReader, bodyWriter := io.Pipe()
+	go func() {
+		bodyWriter.CloseWithError(writeRequest(bodyWriter))
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL+"/"+method, bodyReader)
+	if err != nil {
+		bodyReader.CloseWithError(err)
+		return nil, err
+	}
+	req.Header.Set("Content-Type", PROTOCOL_CONTENT_TYPE)
+	for name, value := range t.Headers {
+		req.Header.Set(name, value)
+	}
+
+	response, err := t.Client.Do(req)
+	if err != nil {
+		bodyReader.CloseWithError(err)
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+
+		body, _ := io.ReadAll(io.LimitReader(response.Body, ERROR_OUTPUT_SIZE))
+		return nil, fmt.Errorf(
+			"plugin %s responded with status code %d: %s",
+			t.URL,
+			response.StatusCode,
+			strings.TrimSpace(string(body)),
+		)
+	}
+
+	return response.Body, nil
+}
+
+// tailBuffer keeps the last bytes written to it
+type tailBuffer struct {
+	sync.Mutex
+
+	size int
+	data []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.data = append(b.data, p...)
+	if len(b.data) > b.size {
+		b.data = b.data[len(b.data)-b.size:]
+	}
+
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+
+	return string(b.data)
+}
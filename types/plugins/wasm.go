@@ -0,0 +1,261 @@
+package plugins
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"data-pipelines-worker/types/config"
+)
+
+// WebAssembly modules export the memory and the functions:
+//   - `allocate(size i32) i32` returns the pointer to write the request of the size to
+//   - `describe() i64` returns the Description JSON
+//   - `process(ptr i32, size i32) i64` takes the process request message and returns the response message
+//
+// Returned i64 are the pointer of the result in the upper 32 bits and its size in the lower ones
+const (
+	WASM_FUNCTION_ALLOCATE = "allocate"
+	WASM_FUNCTION_DESCRIBE = "describe"
+	WASM_FUNCTION_PROCESS  = "process"
+
+	WASM_PAGE_SIZE = 64 << 10
+	WASM_MAX_PAGES = 65536
+)
+
+var ErrPluginClosed = errors.New("plugin is closed")
+
+// Compiled code is shared by the runtimes of the modules with the same content
+var wasmCompilationCache = wazero.NewCompilationCache()
+
+// WasmModule runs every call in a new instance of the compiled module, sandboxed without the file system,
+// the network and the environment, within the memory and the time limits
+type WasmModule struct {
+	Path string
+	Name string
+
+	config   config.WasmPluginsConfig
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+
+	// Calls are not started after the module is closed
+	mutex  sync.Mutex
+	closed bool
+	calls  sync.WaitGroup
+}
+
+// Ensure WasmModule implements the Plugin
+var _ Plugin = (*WasmModule)(nil)
+
+// LoadWasmModule compiles the module of the path content and checks its exports
+func LoadWasmModule(ctx context.Context, path string, content []byte, wasmConfig config.WasmPluginsConfig) (*WasmModule, error) {
+	wasmConfig = wasmConfig.WithDefaults()
+
+	memoryLimitPages := uint32(WASM_MAX_PAGES)
+	if pages := int64(wasmConfig.MaxMemoryMB) * (1 << 20) / WASM_PAGE_SIZE; pages < WASM_MAX_PAGES {
+		memoryLimitPages = uint32(pages)
+	}
+
+	runtime := wazero.NewRuntimeWithConfig(
+		ctx,
+		wazero.NewRuntimeConfig().
+			WithMemoryLimitPages(memoryLimitPages).
+			WithCloseOnContextDone(true).
+			WithCompilationCache(wasmCompilationCache),
+	)
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+
+	compiled, err := runtime.CompileModule(ctx, content)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+
+	exportedFunctions := compiled.ExportedFunctions()
+	for _, function := range []string{WASM_FUNCTION_ALLOCATE, WASM_FUNCTION_DESCRIBE, WASM_FUNCTION_PROCESS} {
+		if _, ok := exportedFunctions[function]; !ok {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("%w: module %s does not export %s", ErrPluginProtocol, path, function)
+		}
+	}
+
+	return &WasmModule{
+		Path:     path,
+		Name:     strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		config:   wasmConfig,
+		runtime:  runtime,
+		compiled: compiled,
+	}, nil
+}
+
+func (m *WasmModule) GetName() string {
+	return m.Name
+}
+
+func (m *WasmModule) GetCheckInterval() time.Duration {
+	return config.DEFAULT_PLUGIN_CHECK_INTERVAL
+}
+
+// Describe returns the metadata and the schema of the module block
+func (m *WasmModule) Describe(ctx context.Context) (*Description, error) {
+	response, err := m.call(ctx, WASM_FUNCTION_DESCRIBE, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	description := &Description{}
+	if err := json.Unmarshal(response, description); err != nil {
+		return nil, fmt.Errorf("%w: module %s description is invalid: %s", ErrPluginProtocol, m.Name, err)
+	}
+	if description.Error != "" {
+		return nil, fmt.Errorf("plugin %s failed to describe: %s", m.Name, description.Error)
+	}
+	if description.Id == "" {
+		return nil, fmt.Errorf("%w: plugin %s description has no id", ErrPluginProtocol, m.Name)
+	}
+	if len(description.Schema) == 0 {
+		return nil, fmt.Errorf("%w: plugin %s description has no schema", ErrPluginProtocol, m.Name)
+	}
+	if description.Name == "" {
+		description.Name = description.Id
+	}
+	if description.Version == "" {
+		description.Version = "1"
+	}
+
+	return description, nil
+}
+
+// Detect reports the compiled module as available
+func (m *WasmModule) Detect(_ context.Context) (bool, error) {
+	return true, nil
+}
+
+// Process passes the process request message with the files of the input to the module
+func (m *WasmModule) Process(ctx context.Context, input map[string]interface{}) ([]*bytes.Buffer, *ProcessResult, error) {
+	input, files, readers := SplitFiles(input)
+
+	request := &bytes.Buffer{}
+	if err := WriteMessage(
+		request,
+		Request{Protocol: PROTOCOL_VERSION, Method: METHOD_PROCESS, Input: input, Files: files},
+		readers,
+	); err != nil {
+		return nil, nil, err
+	}
+
+	response, err := m.call(ctx, WASM_FUNCTION_PROCESS, request.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader := bufio.NewReader(bytes.NewReader(response))
+	result := &ProcessResult{}
+	if err := ReadHeader(reader, result); err != nil {
+		return nil, nil, err
+	}
+	outputs, err := ReadFiles(reader, result.Outputs, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if result.Error != "" {
+		return outputs, result, fmt.Errorf("plugin %s failed to process: %s", m.Name, result.Error)
+	}
+
+	return outputs, result, nil
+}
+
+// call runs the function in a new instance of the module and returns a copy of the result
+func (m *WasmModule) call(ctx context.Context, function string, request []byte) ([]byte, error) {
+	m.mutex.Lock()
+	if m.closed {
+		m.mutex.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrPluginClosed, m.Name)
+	}
+	m.calls.Add(1)
+	m.mutex.Unlock()
+	defer m.calls.Done()
+
+	callCtx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	stderr := &tailBuffer{size: ERROR_OUTPUT_SIZE}
+	instance, err := m.runtime.InstantiateModule(
+		callCtx,
+		m.compiled,
+		wazero.NewModuleConfig().
+			WithName("").
+			WithStartFunctions("_initialize").
+			WithStderr(stderr),
+	)
+	if err != nil {
+		return nil, m.wrapError(ctx, callCtx, err, stderr)
+	}
+	defer instance.Close(context.Background())
+
+	params := make([]uint64, 0)
+	if request != nil {
+		results, err := instance.ExportedFunction(WASM_FUNCTION_ALLOCATE).Call(callCtx, uint64(len(request)))
+		if err != nil {
+			return nil, m.wrapError(ctx, callCtx, err, stderr)
+		}
+
+		ptr := uint32(results[0])
+		if !instance.Memory().Write(ptr, request) {
+			return nil, fmt.Errorf("%w: module %s allocated memory out of range", ErrPluginProtocol, m.Name)
+		}
+		params = append(params, uint64(ptr), uint64(len(request)))
+	}
+
+	results, err := instance.ExportedFunction(function).Call(callCtx, params...)
+	if err != nil {
+		return nil, m.wrapError(ctx, callCtx, err, stderr)
+	}
+
+	ptr, size := uint32(results[0]>>32), uint32(results[0])
+	response, ok := instance.Memory().Read(ptr, size)
+	if !ok {
+		return nil, fmt.Errorf("%w: module %s result is out of memory range", ErrPluginProtocol, m.Name)
+	}
+
+	return bytes.Clone(response), nil
+}
+
+// wrapError reports the cancellation as is, the timeout and the stderr of the failed call
+func (m *WasmModule) wrapError(ctx context.Context, callCtx context.Context, err error, stderr *tailBuffer) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("plugin %s timed out after %s", m.Name, m.config.Timeout)
+	}
+	if output := strings.TrimSpace(stderr.String()); output != "" {
+		return fmt.Errorf("plugin %s failed: %w: %s", m.Name, err, output)
+	}
+
+	return fmt.Errorf("plugin %s failed: %w", m.Name, err)
+}
+
+// Close rejects the new calls and releases the runtime of the module after the calls in flight
+func (m *WasmModule) Close(ctx context.Context) error {
+	m.mutex.Lock()
+	m.closed = true
+	m.mutex.Unlock()
+
+	m.calls.Wait()
+
+	return m.runtime.Close(ctx)
+}
@@ -2,14 +2,18 @@ package registries
 
 import (
 	"context"
-	"net/http"
+	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/google/uuid"
 
 	"data-pipelines-worker/types/blocks"
 	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/helpers"
 	"data-pipelines-worker/types/interfaces"
+	"data-pipelines-worker/types/limiter"
+	"data-pipelines-worker/types/plugins"
 )
 
 var (
@@ -36,13 +40,28 @@ func GetBlockRegistry(forceNewInstance ...bool) *BlockRegistry {
 type BlockRegistry struct {
 	sync.Mutex
 
-	Id             uuid.UUID
-	Blocks         map[string]interfaces.Block
+	Id uuid.UUID
+	// Default versions of the blocks by id
+	Blocks map[string]interfaces.Block
+	// Registered versions of the blocks by id and version
+	versions       map[string]map[string]interfaces.Block
 	blocksDetector map[interfaces.Block]interfaces.BlockDetector
+	// Ids of the blocks by their aliases
+	aliases map[string]string
+
+	// WebAssembly plugins by the module path
+	wasmPlugins    map[string]*wasmPlugin
+	wasmReloadLock sync.Mutex
 
 	shutdownWg *sync.WaitGroup
 }
 
+type wasmPlugin struct {
+	hash   string
+	block  *blocks.BlockPlugin
+	module *plugins.WasmModule
+}
+
 // Ensure BlockRegistry implements the BlockRegistry
 var _ interfaces.BlockRegistry = (*BlockRegistry)(nil)
 
@@ -50,7 +69,10 @@ func NewBlockRegistry() *BlockRegistry {
 	registry := &BlockRegistry{
 		Id:             uuid.New(),
 		Blocks:         make(map[string]interfaces.Block),
+		versions:       make(map[string]map[string]interfaces.Block),
 		blocksDetector: make(map[interfaces.Block]interfaces.BlockDetector),
+		aliases:        make(map[string]string),
+		wasmPlugins:    make(map[string]*wasmPlugin),
 		shutdownWg:     &sync.WaitGroup{},
 	}
 
@@ -60,169 +82,354 @@ func NewBlockRegistry() *BlockRegistry {
 }
 
 func (br *BlockRegistry) DetectBlocks() {
-	br.Lock()
-
 	_config := config.GetConfig()
+	logger := config.GetLogger()
+
+	// Plugins are described before the registry is locked, so the slow plugins do not block the lookups
+	blocksDetector := make(map[interfaces.Block]interfaces.BlockDetector)
+	aliases := make(map[string]string)
+
+	// Built-in blocks of the catalogue, unless disabled in the config
+	for _, registration := range blocks.GetBlockRegistrations() {
+		blockConfig := _config.Blocks[registration.Id].WithDefaults()
+		if !blockConfig.IsEnabled() {
+			logger.Infof("Block %s is disabled", registration.Id)
+			continue
+		}
+
+		block := registration.NewBlock()
+		if isDetected(blocksDetector, block.GetId(), block.GetVersion()) {
+			logger.Errorf(
+				"Failed to register block %s: it is already registered",
+				helpers.FormatBlockReference(block.GetId(), block.GetVersion()),
+			)
+			continue
+		}
+		blocksDetector[block] = registration.NewDetector(_config, blockConfig.Detector)
 
-	httpBlock := blocks.NewBlockHTTP()
-	openAIRequestCompletionBlock := blocks.NewBlockOpenAIRequestCompletion()
-	openAIRequestTTSBlock := blocks.NewBlockOpenAIRequestTTS()
-	openAIRequestTranscriptionBlock := blocks.NewBlockOpenAIRequestTranscription()
-	openAIRequestImageBlock := blocks.NewBlockOpenAIRequestImage()
-	imageAddTextBlock := blocks.NewBlockImageAddText()
-	imageResizeBlock := blocks.NewBlockImageResize()
-	imageBlurBlock := blocks.NewBlockImageBlur()
-	stopPipelineBlock := blocks.NewBlockStopPipeline()
-	sendModerationToTelegramBlock := blocks.NewBlockSendModerationToTelegram()
-	fetchModerationFromTelegramBlock := blocks.NewBlockFetchModerationFromTelegram()
-	textReplaceBlock := blocks.NewBlockTextReplace()
-	textAddPrefixOrSuffixBlock := blocks.NewBlockTextAddPrefixOrSuffix()
-	videoFromImageBlock := blocks.NewBlockVideoFromImage()
-	joinVideosBlock := blocks.NewBlockJoinVideos()
-	joinStringsBlock := blocks.NewBlockJoinStrings()
-	videoAddAudioBlock := blocks.NewBlockVideoAddAudio()
-	sendMessageToTelegramBlock := blocks.NewBlockSendMessageToTelegram()
-	formatStringFromObjectBlock := blocks.NewBlockFormatStringFromObject()
-	audioFromVideoBlock := blocks.NewBlockAudioFromVideo()
-	audioChunkBlock := blocks.NewBlockAudioChunk()
-	audioConvertBlock := blocks.NewBlockAudioConvert()
-	subtitlesFromTranscriptionBlock := blocks.NewBlockSubtitlesFromTranscription()
-	uploadFileBlock := blocks.NewBlockUploadFile()
-	videoAddSubtitlesBlock := blocks.NewBlockVideoAddSubtitles()
-
-	br.blocksDetector = map[interfaces.Block]interfaces.BlockDetector{
-		httpBlock: blocks.NewDetectorHTTP(
-			&http.Client{},
-			_config.Blocks[httpBlock.GetId()].Detector,
-		),
-		openAIRequestCompletionBlock: blocks.NewDetectorOpenAI(
-			_config.OpenAI.GetClient(),
-			_config.Blocks[openAIRequestCompletionBlock.GetId()].Detector,
-		),
-		openAIRequestTTSBlock: blocks.NewDetectorOpenAI(
-			_config.OpenAI.GetClient(),
-			_config.Blocks[openAIRequestTTSBlock.GetId()].Detector,
-		),
-		openAIRequestTranscriptionBlock: blocks.NewDetectorOpenAI(
-			_config.OpenAI.GetClient(),
-			_config.Blocks[openAIRequestTranscriptionBlock.GetId()].Detector,
-		),
-		openAIRequestImageBlock: blocks.NewDetectorOpenAI(
-			_config.OpenAI.GetClient(),
-			_config.Blocks[openAIRequestImageBlock.GetId()].Detector,
-		),
-		imageAddTextBlock: blocks.NewDetectorImageAddText(
-			_config.Blocks[imageAddTextBlock.GetId()].Detector,
-		),
-		imageResizeBlock: blocks.NewDetectorImageResize(
-			_config.Blocks[imageResizeBlock.GetId()].Detector,
-		),
-		imageBlurBlock: blocks.NewDetectorImageBlur(
-			_config.Blocks[imageBlurBlock.GetId()].Detector,
-		),
-		stopPipelineBlock: blocks.NewDetectorStopPipeline(
-			_config.Blocks[stopPipelineBlock.GetId()].Detector,
-		),
-		sendModerationToTelegramBlock: blocks.NewDetectorTelegramBot(
-			_config.Telegram.GetClient(),
-			_config.Blocks[sendModerationToTelegramBlock.GetId()].Detector,
-		),
-		fetchModerationFromTelegramBlock: blocks.NewDetectorTelegramBot(
-			_config.Telegram.GetClient(),
-			_config.Blocks[fetchModerationFromTelegramBlock.GetId()].Detector,
-		),
-		textReplaceBlock: blocks.NewDetectorTextReplace(
-			_config.Blocks[textReplaceBlock.GetId()].Detector,
-		),
-		textAddPrefixOrSuffixBlock: blocks.NewDetectorTextAddPrefixOrSuffix(
-			_config.Blocks[textAddPrefixOrSuffixBlock.GetId()].Detector,
-		),
-		videoFromImageBlock: blocks.NewDetectorVideoFromImage(
-			_config.Blocks[videoFromImageBlock.GetId()].Detector,
-		),
-		joinVideosBlock: blocks.NewDetectorJoinVideos(
-			_config.Blocks[joinVideosBlock.GetId()].Detector,
-		),
-		joinStringsBlock: blocks.NewDetectorJoinStrings(
-			_config.Blocks[joinStringsBlock.GetId()].Detector,
-		),
-		videoAddAudioBlock: blocks.NewDetectorVideoAddAudio(
-			_config.Blocks[videoAddAudioBlock.GetId()].Detector,
-		),
-		sendMessageToTelegramBlock: blocks.NewDetectorTelegramBot(
-			_config.Telegram.GetClient(),
-			_config.Blocks[sendMessageToTelegramBlock.GetId()].Detector,
-		),
-		formatStringFromObjectBlock: blocks.NewDetectorFormatStringFromObject(
-			_config.Blocks[formatStringFromObjectBlock.GetId()].Detector,
-		),
-		audioFromVideoBlock: blocks.NewDetectorAudioFromVideo(
-			_config.Blocks[audioFromVideoBlock.GetId()].Detector,
-		),
-		audioChunkBlock: blocks.NewDetectorAudioChunk(
-			_config.Blocks[audioChunkBlock.GetId()].Detector,
-		),
-		audioConvertBlock: blocks.NewDetectorAudioConvert(
-			_config.Blocks[audioConvertBlock.GetId()].Detector,
-		),
-		subtitlesFromTranscriptionBlock: blocks.NewDetectorSubtitlesFromTranscription(
-			_config.Blocks[subtitlesFromTranscriptionBlock.GetId()].Detector,
-		),
-		uploadFileBlock: blocks.NewDetectorUploadFile(
-			_config.Blocks[uploadFileBlock.GetId()].Detector,
-		),
-		videoAddSubtitlesBlock: blocks.NewDetectorVideoAddSubtitles(
-			_config.Blocks[videoAddSubtitlesBlock.GetId()].Detector,
-		),
+		for _, alias := range registration.Aliases {
+			aliases[alias] = registration.Id
+		}
 	}
 
+	// Plugin blocks are described by the plugins. Unreachable plugins are skipped
+	for _, pluginConfig := range _config.Plugins.External {
+		pluginBlock, err := blocks.NewBlockExternalPlugin(pluginConfig)
+		if err != nil {
+			logger.Errorf("Failed to load plugin %s: %s", pluginConfig.WithDefaults().Name, err)
+			continue
+		}
+		if !_config.Blocks[pluginBlock.GetId()].IsEnabled() {
+			logger.Infof("Block %s is disabled", pluginBlock.GetId())
+			continue
+		}
+		if isDetected(blocksDetector, pluginBlock.GetId(), pluginBlock.GetVersion()) {
+			logger.Errorf(
+				"Failed to load plugin %s: block %s is already registered",
+				pluginBlock.Plugin.GetName(),
+				helpers.FormatBlockReference(pluginBlock.GetId(), pluginBlock.GetVersion()),
+			)
+			continue
+		}
+
+		blocksDetector[pluginBlock] = blocks.NewDetectorPlugin(
+			pluginBlock.Plugin,
+			pluginBlock.GetDetectorConfig(),
+		)
+	}
+
+	for id, blockConfig := range _config.Blocks {
+		for _, alias := range blockConfig.Aliases {
+			aliases[alias] = id
+		}
+	}
+
+	br.Lock()
+	// Detectors of the previous detection are replaced
+	for _, detector := range br.blocksDetector {
+		detector.Stop(br.shutdownWg)
+	}
+	br.blocksDetector = make(map[interfaces.Block]interfaces.BlockDetector, len(blocksDetector))
+	for block, detector := range blocksDetector {
+		br.blocksDetector[block] = detector
+	}
+	br.aliases = aliases
 	br.Blocks = make(map[string]interfaces.Block)
+	br.versions = make(map[string]map[string]interfaces.Block)
+
+	// WebAssembly plugins are registered again after the detection
+	for _, plugin := range br.wasmPlugins {
+		go plugin.module.Close(context.Background())
+	}
+	br.wasmPlugins = make(map[string]*wasmPlugin)
 	br.Unlock()
 
 	startUpWg := &sync.WaitGroup{}
 
-	for block, detector := range br.blocksDetector {
+	for block, detector := range blocksDetector {
 		startUpWg.Add(1)
 
 		go func() {
-			br.Lock()
-			defer br.Unlock()
 			defer startUpWg.Done()
 
+			// Integrations with credentials profiles report the availability of every profile
+			detectionFunc := detector.Detect
+			if profilesDetector, ok := detector.(interfaces.BlockProfilesDetector); ok {
+				detectionFunc = func() bool {
+					available := detector.Detect()
+					block.SetProfilesAvailability(profilesDetector.GetProfilesAvailability())
+
+					return available
+				}
+			}
+
 			block.SetAvailable(false)
-			if detector.Detect() {
+			if detectionFunc() {
 				block.SetAvailable(true)
 			}
 
+			br.Lock()
+			defer br.Unlock()
+
 			br.shutdownWg.Add(1)
-			detector.Start(block, detector.Detect)
+			detector.Start(block, detectionFunc)
 
-			br.Blocks[block.GetId()] = block
+			br.addVersion(block)
 		}()
 	}
 
 	startUpWg.Wait()
+
+	br.ReloadWasmPlugins()
+}
+
+// ReloadWasmPlugins registers the blocks of the `.wasm` modules of the plugins directory.
+// Blocks of the changed modules are replaced and blocks of the removed modules are deleted,
+// modules failing to load keep their previous version
+func (br *BlockRegistry) ReloadWasmPlugins() {
+	br.wasmReloadLock.Lock()
+	defer br.wasmReloadLock.Unlock()
+
+	wasmConfig := config.GetConfig().Plugins.Wasm.WithDefaults()
+	if wasmConfig.Directory == "" {
+		return
+	}
+
+	logger := config.GetLogger()
+
+	paths, err := filepath.Glob(filepath.Join(wasmConfig.Directory, "*.wasm"))
+	if err != nil {
+		logger.Errorf("Failed to list WebAssembly plugins %s: %s", wasmConfig.Directory, err)
+		return
+	}
+
+	loadedPaths := make(map[string]bool)
+	for _, path := range paths {
+		loadedPaths[path] = true
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			logger.Errorf("Failed to read WebAssembly plugin %s: %s", path, err)
+			continue
+		}
+		hash := helpers.HashInput(string(content))
+
+		br.Lock()
+		current := br.wasmPlugins[path]
+		br.Unlock()
+		if current != nil && current.hash == hash {
+			continue
+		}
+
+		module, err := plugins.LoadWasmModule(context.Background(), path, content, wasmConfig)
+		if err != nil {
+			logger.Errorf("Failed to load WebAssembly plugin %s: %s", path, err)
+			continue
+		}
+		block, err := blocks.NewBlockPlugin(module)
+		if err != nil {
+			module.Close(context.Background())
+			logger.Errorf("Failed to load WebAssembly plugin %s: %s", path, err)
+			continue
+		}
+		if !config.GetConfig().Blocks[block.GetId()].IsEnabled() {
+			module.Close(context.Background())
+			logger.Infof("Block %s of WebAssembly plugin %s is disabled", block.GetId(), path)
+			continue
+		}
+
+		br.Lock()
+		registered := br.versions[block.GetId()][block.GetVersion()]
+		if registered != nil && (current == nil || registered != current.block) {
+			br.Unlock()
+			module.Close(context.Background())
+			logger.Errorf(
+				"Failed to load WebAssembly plugin %s: block %s is already registered",
+				path,
+				helpers.FormatBlockReference(block.GetId(), block.GetVersion()),
+			)
+			continue
+		}
+		if current != nil {
+			br.unregister(current.block)
+			go current.module.Close(context.Background())
+		}
+		br.register(block, blocks.NewDetectorPlugin(module, block.GetDetectorConfig()))
+		br.wasmPlugins[path] = &wasmPlugin{
+			hash:   hash,
+			block:  block,
+			module: module,
+		}
+		br.Unlock()
+
+		logger.Infof("Loaded WebAssembly plugin %s of block %s", path, block.GetId())
+	}
+
+	br.Lock()
+	defer br.Unlock()
+
+	for path, plugin := range br.wasmPlugins {
+		if loadedPaths[path] {
+			continue
+		}
+
+		br.unregister(plugin.block)
+		go plugin.module.Close(context.Background())
+		delete(br.wasmPlugins, path)
+
+		logger.Infof("Unloaded WebAssembly plugin %s of block %s", path, plugin.block.GetId())
+	}
+}
+
+// register adds the block and starts its detector. Lock must be held
+func (br *BlockRegistry) register(block interfaces.Block, detector interfaces.BlockDetector) {
+	block.SetAvailable(detector.Detect())
+
+	br.shutdownWg.Add(1)
+	detector.Start(block, detector.Detect)
+
+	br.blocksDetector[block] = detector
+	br.addVersion(block)
+}
+
+// unregister stops the detector of the block and deletes the block. Lock must be held
+func (br *BlockRegistry) unregister(block interfaces.Block) {
+	if detector, ok := br.blocksDetector[block]; ok {
+		detector.Stop(br.shutdownWg)
+		delete(br.blocksDetector, block)
+	}
+	block.SetAvailable(false)
+
+	if br.versions[block.GetId()][block.GetVersion()] == block {
+		delete(br.versions[block.GetId()], block.GetVersion())
+		br.updateDefaultVersion(block.GetId())
+	}
+}
+
+// addVersion adds the version of the block and updates the default version of the id. Lock must be held
+func (br *BlockRegistry) addVersion(block interfaces.Block) {
+	if br.versions[block.GetId()] == nil {
+		br.versions[block.GetId()] = make(map[string]interfaces.Block)
+	}
+	br.versions[block.GetId()][block.GetVersion()] = block
+
+	br.updateDefaultVersion(block.GetId())
+}
+
+// updateDefaultVersion makes the latest version the default one, the deprecated versions
+// are the default only when there are no others. Lock must be held
+func (br *BlockRegistry) updateDefaultVersion(id string) {
+	var defaultBlock interfaces.Block
+	for version, block := range br.versions[id] {
+		if defaultBlock == nil ||
+			defaultBlock.IsDeprecated() && !block.IsDeprecated() ||
+			defaultBlock.IsDeprecated() == block.IsDeprecated() &&
+				helpers.CompareVersions(version, defaultBlock.GetVersion()) > 0 {
+			defaultBlock = block
+		}
+	}
+
+	if defaultBlock == nil {
+		delete(br.versions, id)
+		delete(br.Blocks, id)
+		return
+	}
+	br.Blocks[id] = defaultBlock
+}
+
+// isDetected reports whether the version of the block with the id has a detector
+func isDetected(blocksDetector map[interfaces.Block]interfaces.BlockDetector, id string, version string) bool {
+	for block := range blocksDetector {
+		if block.GetId() == id && block.GetVersion() == version {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (br *BlockRegistry) Add(block interfaces.Block) {
 	br.Lock()
 	defer br.Unlock()
 
-	br.Blocks[block.GetId()] = block
+	br.addVersion(block)
+}
+
+// Get returns the block of the id or of the alias. The `id@version` references return the pinned version,
+// the `id@^version` ones the latest compatible version, see helpers.MatchVersion
+func (br *BlockRegistry) Get(reference string) interfaces.Block {
+	br.Lock()
+	defer br.Unlock()
+
+	id, version := helpers.ParseBlockReference(reference)
+	if _, ok := br.versions[id]; !ok {
+		id = br.aliases[id]
+	}
+	if version == "" {
+		return br.Blocks[id]
+	}
+
+	var matchedBlock interfaces.Block
+	for blockVersion, block := range br.versions[id] {
+		if !helpers.MatchVersion(version, blockVersion) {
+			continue
+		}
+		if matchedBlock == nil || helpers.CompareVersions(blockVersion, matchedBlock.GetVersion()) > 0 {
+			matchedBlock = block
+		}
+	}
+
+	return matchedBlock
 }
 
-func (br *BlockRegistry) Get(id string) interfaces.Block {
+// GetVersions returns the registered versions of the block of the id or of the alias
+func (br *BlockRegistry) GetVersions(id string) map[string]interfaces.Block {
 	br.Lock()
 	defer br.Unlock()
 
-	return br.Blocks[id]
+	if _, ok := br.versions[id]; !ok {
+		id = br.aliases[id]
+	}
+
+	versions := make(map[string]interfaces.Block, len(br.versions[id]))
+	for version, block := range br.versions[id] {
+		versions[version] = block
+	}
+
+	return versions
 }
 
+// GetAll returns the copy of the registered Blocks, the plugins reload replaces them
 func (br *BlockRegistry) GetAll() map[string]interfaces.Block {
 	br.Lock()
 	defer br.Unlock()
 
-	return br.Blocks
+	registeredBlocks := make(map[string]interfaces.Block, len(br.Blocks))
+	for id, block := range br.Blocks {
+		registeredBlocks[id] = block
+	}
+
+	return registeredBlocks
 }
 
 func (br *BlockRegistry) GetAvailableBlocks() map[string]interfaces.Block {
@@ -231,7 +438,7 @@ func (br *BlockRegistry) GetAvailableBlocks() map[string]interfaces.Block {
 
 	availableBlocks := make(map[string]interfaces.Block)
 	for id, block := range br.Blocks {
-		if block.IsAvailable() {
+		if block.IsAvailable() && br.isCircuitClosed(block) {
 			availableBlocks[id] = block
 		}
 	}
@@ -243,11 +450,15 @@ func (br *BlockRegistry) Delete(id string) {
 	br.Lock()
 	defer br.Unlock()
 
-	// Stop the Detector
-	if detector, ok := br.blocksDetector[br.Blocks[id]]; ok {
-		detector.Stop(br.shutdownWg)
+	// Stop the Detectors of every version
+	for _, block := range br.versions[id] {
+		if detector, ok := br.blocksDetector[block]; ok {
+			detector.Stop(br.shutdownWg)
+			delete(br.blocksDetector, block)
+		}
 	}
 
+	delete(br.versions, id)
 	delete(br.Blocks, id)
 }
 
@@ -258,6 +469,9 @@ func (br *BlockRegistry) DeleteAll() {
 	for id := range br.Blocks {
 		delete(br.Blocks, id)
 	}
+	for id := range br.versions {
+		delete(br.versions, id)
+	}
 }
 
 func (br *BlockRegistry) Shutdown(ctx context.Context) error {
@@ -270,19 +484,58 @@ func (br *BlockRegistry) Shutdown(ctx context.Context) error {
 
 	// Any Processing Pipelines will transfer requests
 	// to the other Workers
-	for _, block := range br.Blocks {
-		block.SetAvailable(false)
+	for _, versions := range br.versions {
+		for _, block := range versions {
+			block.SetAvailable(false)
+		}
 	}
 
 	br.shutdownWg.Wait()
 
+	for _, plugin := range br.wasmPlugins {
+		plugin.module.Close(ctx)
+	}
+
 	return nil
 }
 
+// IsAvailable reports whether the registered version of the block is available
 func (br *BlockRegistry) IsAvailable(block interfaces.Block) bool {
-	availableBlocks := br.GetAvailableBlocks()
-	_, ok := availableBlocks[block.GetId()]
-	block.SetAvailable(ok)
+	br.Lock()
+	registered := br.versions[block.GetId()][block.GetVersion()]
+	ok := registered != nil && registered.IsAvailable() && br.isCircuitClosed(registered)
+	circuitClosed := br.isCircuitClosed(block)
+	br.Unlock()
+
+	// The open circuit breakers keep the detected availability for the half-open state
+	if ok || circuitClosed {
+		block.SetAvailable(ok)
+	}
 
 	return ok
 }
+
+// isCircuitClosed reports whether any available credentials profile of the block accepts the requests.
+// Blocks of the integrations without credentials profiles are always closed
+func (br *BlockRegistry) isCircuitClosed(block interfaces.Block) bool {
+	registered := br.versions[block.GetId()][block.GetVersion()]
+	profilesDetector, ok := br.blocksDetector[registered].(interfaces.BlockProfilesDetector)
+	if !ok {
+		return true
+	}
+
+	provider := profilesDetector.GetProvider()
+	limiterRegistry := limiter.GetLimiterRegistry()
+
+	profilesAvailability := block.GetProfilesAvailability()
+	if len(profilesAvailability) == 0 {
+		return !limiterRegistry.IsOpen(provider, config.DEFAULT_CREDENTIALS_PROFILE)
+	}
+	for profile, available := range profilesAvailability {
+		if available && !limiterRegistry.IsOpen(provider, profile) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -6,12 +6,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"path"
+	"path/filepath"
 	"regexp"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types/blocks"
 	"data-pipelines-worker/types/config"
 	"data-pipelines-worker/types/interfaces"
 )
@@ -23,14 +26,49 @@ const (
 	LOG_FILE_TEMPLATE_REGEX    = "log_\\d+"
 	STATUS_FILE_TEMPLATE       = "status_%d"
 	STATUS_FILE_TEMPLATE_REGEX = "status_\\d+"
+	// Sidecar of the output with the same index
+	METADATA_FILE_TEMPLATE       = "metadata_%d"
+	METADATA_FILE_TEMPLATE_REGEX = "metadata_\\d+"
+	// Log of the webhook deliveries made after the processing log was saved
+	WEBHOOKS_FILE_TEMPLATE       = "webhooks_%d"
+	WEBHOOKS_FILE_TEMPLATE_REGEX = "webhooks_\\d+"
+	// Input the block was started with by the API callers, the credentials are not masked in it
+	INPUT_FILE_TEMPLATE       = "input_%d"
+	INPUT_FILE_TEMPLATE_REGEX = "input_\\d+"
 )
 
 var (
 	OUTPUT_FILE_REGEX = regexp.MustCompile(OUTPUT_FILE_TEMPLATE_REGEX)
 	LOG_FILE_REGEX    = regexp.MustCompile(LOG_FILE_TEMPLATE_REGEX)
 	STATUS_FILE_REGEX = regexp.MustCompile(STATUS_FILE_TEMPLATE_REGEX)
+
+	METADATA_FILE_REGEX = regexp.MustCompile(METADATA_FILE_TEMPLATE_REGEX)
+	WEBHOOKS_FILE_REGEX = regexp.MustCompile(WEBHOOKS_FILE_TEMPLATE_REGEX)
+	INPUT_FILE_REGEX    = regexp.MustCompile(INPUT_FILE_TEMPLATE_REGEX)
+)
+
+// Processing log message with the block input, masked. The retries take the saved input
+const PROCESSING_INPUT_LOG_TEMPLATE = "Processing input for block %s: %s"
+
+// Processing log messages used to find the block to retry the processing from
+var (
+	BLOCK_INPUT_FAILED_LOG_REGEX    = regexp.MustCompile(`^error processing data for block \[([-\w]+):[^\]]*\] with index (\d+)\.`)
+	BLOCK_INPUT_COMPLETED_LOG_REGEX = regexp.MustCompile(`^Processing data for block \[([-\w]+):[^\]]*\] with index (\d+) completed$`)
 )
 
+// Processing log message with the Pipeline version the processing is pinned to
+const PIPELINE_VERSION_LOG_TEMPLATE = "Processing Pipeline version %s"
+
+var PIPELINE_VERSION_LOG_REGEX = regexp.MustCompile(`^Processing Pipeline version ([-\w.]+)$`)
+
+// Processing log message with the Pipeline input parameters
+const PIPELINE_INPUT_LOG_TEMPLATE = "Processing Pipeline input: %s"
+
+var PIPELINE_INPUT_LOG_REGEX = regexp.MustCompile(`^Processing Pipeline input: (.*)$`)
+
+// Processing log message with the reason the processing failed
+const PIPELINE_FAILED_LOG_TEMPLATE = "Processing Pipeline %s failed: %s"
+
 type PipelineBlockDataRegistry struct {
 	sync.Mutex
 
@@ -41,6 +79,12 @@ type PipelineBlockDataRegistry struct {
 	// TODO: Follow Registry interface and replace []*bytes.Buffer
 	//	to interfaces.StorageLocation
 	pipelineBlockData map[string][]*bytes.Buffer
+
+	// Metadata of the block data by block slug and output index
+	pipelineBlockMetadata map[string][]schemas.OutputMetadataSchema
+
+	// Outputs saved during this processing by block slug and output index
+	savedOutputs map[string]map[int][]PipelineBlockDataRegistrySavedOutput
 }
 
 // Ensure PipelineBlockDataRegistry implements the PipelineBlockDataRegistry
@@ -48,6 +92,7 @@ var _ interfaces.PipelineBlockDataRegistry = (*PipelineBlockDataRegistry)(nil)
 
 type PipelineBlockDataRegistrySavedOutput struct {
 	StorageLocation interfaces.StorageLocation
+	Metadata        schemas.OutputMetadataSchema
 	Error           error
 }
 
@@ -57,10 +102,12 @@ func NewPipelineBlockDataRegistry(
 	storages []interfaces.Storage,
 ) *PipelineBlockDataRegistry {
 	registry := &PipelineBlockDataRegistry{
-		pipelineBlockData: make(map[string][]*bytes.Buffer),
-		processingId:      processingId,
-		pipelineSlug:      pipelineSlug,
-		storages:          storages,
+		pipelineBlockData:     make(map[string][]*bytes.Buffer),
+		pipelineBlockMetadata: make(map[string][]schemas.OutputMetadataSchema),
+		savedOutputs:          make(map[string]map[int][]PipelineBlockDataRegistrySavedOutput),
+		processingId:          processingId,
+		pipelineSlug:          pipelineSlug,
+		storages:              storages,
 	}
 
 	return registry
@@ -125,11 +172,50 @@ func (r *PipelineBlockDataRegistry) GetAll() map[string][]*bytes.Buffer {
 	return r.pipelineBlockData
 }
 
+// UpdateBlockMetadata sets the metadata of the block data with the index
+func (r *PipelineBlockDataRegistry) UpdateBlockMetadata(blockSlug string, index int, metadata schemas.OutputMetadataSchema) {
+	r.Lock()
+	defer r.Unlock()
+
+	if index < 0 {
+		return
+	}
+
+	if index >= len(r.pipelineBlockMetadata[blockSlug]) {
+		newMetadata := make([]schemas.OutputMetadataSchema, index+1)
+		copy(newMetadata, r.pipelineBlockMetadata[blockSlug])
+		r.pipelineBlockMetadata[blockSlug] = newMetadata
+	}
+	r.pipelineBlockMetadata[blockSlug][index] = metadata
+}
+
+// GetMetadata returns the metadata of the block data by index
+func (r *PipelineBlockDataRegistry) GetMetadata(blockSlug string) []schemas.OutputMetadataSchema {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.pipelineBlockMetadata[blockSlug]
+}
+
+// GetAllMetadata returns the metadata of the block data of every block
+func (r *PipelineBlockDataRegistry) GetAllMetadata() map[string][]schemas.OutputMetadataSchema {
+	r.Lock()
+	defer r.Unlock()
+
+	metadata := make(map[string][]schemas.OutputMetadataSchema, len(r.pipelineBlockMetadata))
+	for blockSlug, blockMetadata := range r.pipelineBlockMetadata {
+		metadata[blockSlug] = blockMetadata
+	}
+
+	return metadata
+}
+
 func (r *PipelineBlockDataRegistry) Delete(blockSlug string) {
 	r.Lock()
 	defer r.Unlock()
 
 	delete(r.pipelineBlockData, blockSlug)
+	delete(r.pipelineBlockMetadata, blockSlug)
 }
 
 func (r *PipelineBlockDataRegistry) DeleteAll() {
@@ -139,6 +225,9 @@ func (r *PipelineBlockDataRegistry) DeleteAll() {
 	for blockSlug := range r.pipelineBlockData {
 		delete(r.pipelineBlockData, blockSlug)
 	}
+	for blockSlug := range r.pipelineBlockMetadata {
+		delete(r.pipelineBlockMetadata, blockSlug)
+	}
 }
 
 func (r *PipelineBlockDataRegistry) Shutdown(context context.Context) error {
@@ -200,24 +289,115 @@ func (r *PipelineBlockDataRegistry) LoadOutput(blockSlug string) []*bytes.Buffer
 			continue
 		}
 
+		// If there is only one storage, we can assume that the data is the same
+		// otherwise, we only add the data if the storage is minio ( Remote )
+		if storage.GetStorageName() != "minio" && len(storages) != 1 {
+			continue
+		}
+
+		metadataObjects := make(map[int]interfaces.StorageLocation)
+		for _, object := range objects {
+			objectName := path.Base(filepath.ToSlash(object.GetFilePath()))
+			var outputIndex int
+			if _, err := fmt.Sscanf(objectName, METADATA_FILE_TEMPLATE, &outputIndex); err == nil {
+				metadataObjects[outputIndex] = object
+			}
+		}
+
 		for _, object := range objects {
 			// TODO: Add respect to file suffix ( output_{i}.<mimetype> )
+			objectName := path.Base(filepath.ToSlash(object.GetFilePath()))
+			if !OUTPUT_FILE_REGEX.MatchString(objectName) {
+				continue
+			}
+
 			data, err := storage.GetObjectBytes(object)
 			if err != nil {
 				continue
 			}
 
-			// If there is only one storage, we can assume that the data is the same
-			// otherwise, we only add the data if the storage is minio ( Remote )
-			if storage.GetStorageName() == "minio" || len(storages) == 1 {
-				r.AddBlockData(blockSlug, data)
+			r.AddBlockData(blockSlug, data)
+
+			// Outputs saved before the metadata was recorded have none
+			metadata := schemas.OutputMetadataSchema{}
+			var outputIndex int
+			if _, err := fmt.Sscanf(objectName, OUTPUT_FILE_TEMPLATE, &outputIndex); err == nil {
+				if metadataObject, ok := metadataObjects[outputIndex]; ok {
+					metadata = loadOutputMetadata(storage, metadataObject)
+				}
 			}
+			r.UpdateBlockMetadata(blockSlug, len(r.Get(blockSlug))-1, blocks.CompleteOutputMetadata(data, metadata))
 		}
 	}
 
 	return r.Get(blockSlug)
 }
 
+func loadOutputMetadata(storage interfaces.Storage, location interfaces.StorageLocation) schemas.OutputMetadataSchema {
+	metadata := schemas.OutputMetadataSchema{}
+
+	data, err := storage.GetObjectBytes(location)
+	if err != nil {
+		config.GetLogger().Errorf("Failed to load output metadata %s: %s", location.GetFilePath(), err)
+		return metadata
+	}
+	if err := json.Unmarshal(data.Bytes(), &metadata); err != nil {
+		config.GetLogger().Errorf("Failed to load output metadata %s: %s", location.GetFilePath(), err)
+	}
+
+	return metadata
+}
+
+// SaveBlockInput saves the input the block is started with next to its outputs
+func (r *PipelineBlockDataRegistry) SaveBlockInput(blockSlug string, input map[string]interface{}) {
+	inputContent, err := json.Marshal(input)
+	if err != nil {
+		config.GetLogger().Error(err)
+		return
+	}
+
+	inputPath := path.Join(
+		r.pipelineSlug,
+		r.processingId.String(),
+		blockSlug,
+		fmt.Sprintf(INPUT_FILE_TEMPLATE, time.Now().UnixNano()),
+	)
+	for _, storage := range r.GetStorages() {
+		if _, err := storage.PutObjectBytes(
+			storage.NewStorageLocation(inputPath),
+			bytes.NewBuffer(inputContent),
+		); err != nil {
+			config.GetLogger().Error(err)
+		}
+	}
+}
+
+// SaveWebhooksLog saves the log of the webhook deliveries made after the Pipeline Execution Log was saved
+func (r *PipelineBlockDataRegistry) SaveWebhooksLog(logBuffer *config.SafeBuffer) {
+	r.Lock()
+	defer r.Unlock()
+
+	logBytes := logBuffer.Bytes()
+	logBuffer.Reset()
+	if len(logBytes) == 0 {
+		return
+	}
+
+	webhooksPath := path.Join(
+		r.pipelineSlug,
+		r.processingId.String(),
+		fmt.Sprintf(WEBHOOKS_FILE_TEMPLATE, time.Now().Unix()),
+	)
+	for _, storage := range r.storages {
+		if _, err := storage.PutObjectBytes(
+			storage.NewStorageLocation(webhooksPath),
+			bytes.NewBuffer(logBytes),
+		); err != nil {
+			config.GetLogger().Error(err)
+		}
+	}
+}
+
 // SavePipelineLog saves the Pipeline Execution Log & Status
 func (r *PipelineBlockDataRegistry) SavePipelineLog(
 	logBuffer *config.SafeBuffer,
@@ -262,8 +442,11 @@ func (r *PipelineBlockDataRegistry) SavePipelineLog(
 			logFileContent = bytes.NewBuffer(logContent)
 		}
 
-		if _, err := storage.PutObjectBytes(logStorageLocation, logFileContent); err != nil {
+		logPath := ""
+		if savedLogLocation, err := storage.PutObjectBytes(logStorageLocation, logFileContent); err != nil {
 			logger.Error(err)
+		} else {
+			logPath = GetStorageObjectKey(storage, savedLogLocation)
 		}
 
 		// STATUS file
@@ -278,11 +461,32 @@ func (r *PipelineBlockDataRegistry) SavePipelineLog(
 			logger.Error(err)
 			continue
 		}
-		if _, err := storage.PutObjectBytes(
+		savedStatusLocation, err := storage.PutObjectBytes(
 			statusStorageLocation,
 			bytes.NewBuffer(statusContent),
-		); err != nil {
+		)
+		if err != nil {
 			logger.Error(err)
+			continue
+		}
+
+		// Index the saved log & status to find them without listing the storage
+		processingIndex := GetProcessingIndex()
+		if !processingIndex.IsEnabled() {
+			continue
+		}
+		indexEntry, err := NewProcessingIndexEntry(
+			r.pipelineSlug,
+			storage.GetStorageName(),
+			logPath,
+			GetStorageObjectKey(storage, savedStatusLocation),
+			statusContent,
+		)
+		if err == nil {
+			err = processingIndex.Add(storage, indexEntry)
+		}
+		if err != nil {
+			logger.Errorf("Failed to index processing %s log: %s", r.processingId, err)
 		}
 	}
 }
@@ -292,11 +496,28 @@ func (r *PipelineBlockDataRegistry) SaveOutput(
 	blockSlug string,
 	outputIndex int,
 	output *bytes.Buffer,
+) []PipelineBlockDataRegistrySavedOutput {
+	return r.SaveOutputWithMetadata(blockSlug, outputIndex, output, schemas.OutputMetadataSchema{})
+}
+
+// SaveOutputWithMetadata saves the output and its metadata sidecar to all storages.
+// The content type and the size of the images are detected when the block did not record them
+func (r *PipelineBlockDataRegistry) SaveOutputWithMetadata(
+	blockSlug string,
+	outputIndex int,
+	output *bytes.Buffer,
+	metadata schemas.OutputMetadataSchema,
 ) []PipelineBlockDataRegistrySavedOutput {
 	// Generates is a file named:
 	// <pipeline-slug>/<processing-id>/<block-slug>/output_{i}.<mimetype>
+	// with the metadata in <pipeline-slug>/<processing-id>/<block-slug>/metadata_{i}.json
 
 	result := make([]PipelineBlockDataRegistrySavedOutput, 0)
+	metadata = blocks.CompleteOutputMetadata(output, metadata)
+	metadataContent, err := json.Marshal(metadata)
+	if err != nil {
+		metadataContent = []byte("{}")
+	}
 
 	filePath := fmt.Sprintf(
 		"%s/%s/%s",
@@ -311,24 +532,70 @@ func (r *PipelineBlockDataRegistry) SaveOutput(
 			dataCopy = bytes.NewBuffer([]byte("null"))
 		}
 
-		destinationStorageLocation, err := storage.PutObjectBytes(
-			storage.NewStorageLocation(
-				path.Join(
-					filePath,
-					fmt.Sprintf(OUTPUT_FILE_TEMPLATE, outputIndex),
-				),
+		destination := storage.NewStorageLocation(
+			path.Join(
+				filePath,
+				fmt.Sprintf(OUTPUT_FILE_TEMPLATE, outputIndex),
 			),
-			dataCopy,
 		)
+		var destinationStorageLocation interfaces.StorageLocation
+		if contentTypeStorage, ok := storage.(interfaces.ContentTypeStorage); ok && output.Len() > 0 {
+			destinationStorageLocation, err = contentTypeStorage.PutObjectBytesWithContentType(
+				destination,
+				dataCopy,
+				metadata.ContentType,
+			)
+		} else {
+			destinationStorageLocation, err = storage.PutObjectBytes(destination, dataCopy)
+		}
+
+		if err == nil {
+			_, err = storage.PutObjectBytes(
+				storage.NewStorageLocation(
+					path.Join(
+						filePath,
+						fmt.Sprintf(METADATA_FILE_TEMPLATE, outputIndex),
+					),
+				),
+				bytes.NewBuffer(metadataContent),
+			)
+		}
 
 		result = append(
 			result,
 			PipelineBlockDataRegistrySavedOutput{
 				StorageLocation: destinationStorageLocation,
+				Metadata:        metadata,
 				Error:           err,
 			},
 		)
 	}
 
+	r.UpdateBlockMetadata(blockSlug, outputIndex, metadata)
+
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.savedOutputs[blockSlug]; !ok {
+		r.savedOutputs[blockSlug] = make(map[int][]PipelineBlockDataRegistrySavedOutput)
+	}
+	r.savedOutputs[blockSlug][outputIndex] = result
+
 	return result
 }
+
+// GetSavedOutputs returns the outputs saved during this processing by block slug and output index
+func (r *PipelineBlockDataRegistry) GetSavedOutputs() map[string]map[int][]PipelineBlockDataRegistrySavedOutput {
+	r.Lock()
+	defer r.Unlock()
+
+	savedOutputs := make(map[string]map[int][]PipelineBlockDataRegistrySavedOutput, len(r.savedOutputs))
+	for blockSlug, outputs := range r.savedOutputs {
+		savedOutputs[blockSlug] = make(map[int][]PipelineBlockDataRegistrySavedOutput, len(outputs))
+		for outputIndex, output := range outputs {
+			savedOutputs[blockSlug][outputIndex] = output
+		}
+	}
+
+	return savedOutputs
+}
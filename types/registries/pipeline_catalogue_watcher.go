@@ -0,0 +1,190 @@
+package registries
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/interfaces"
+)
+
+// PipelineCatalogueWatcher reloads the Pipelines catalogue on SIGHUP
+// and, if enabled, when the catalogue files change. Catalogues of the storages
+// are polled for a changed version
+type PipelineCatalogueWatcher struct {
+	sync.Mutex
+
+	pipelineRegistry interfaces.PipelineRegistry
+	watch            bool
+	reloadDelay      time.Duration
+	pollInterval     time.Duration
+
+	started  bool
+	stopOnce sync.Once
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Ensure PipelineCatalogueWatcher implements the PipelineCatalogueWatcher
+var _ interfaces.PipelineCatalogueWatcher = (*PipelineCatalogueWatcher)(nil)
+
+func NewPipelineCatalogueWatcher(pipelineRegistry interfaces.PipelineRegistry) *PipelineCatalogueWatcher {
+	pipelineConfig := config.GetConfig().Pipeline
+
+	reloadDelay := pipelineConfig.CatalogueReloadDelay
+	if reloadDelay <= 0 {
+		reloadDelay = config.DEFAULT_CATALOGUE_RELOAD_DELAY
+	}
+
+	return &PipelineCatalogueWatcher{
+		pipelineRegistry: pipelineRegistry,
+		watch:            pipelineConfig.CatalogueWatch,
+		reloadDelay:      reloadDelay,
+		pollInterval:     pipelineConfig.CataloguePollInterval,
+		stopChan:         make(chan struct{}),
+	}
+}
+
+func (w *PipelineCatalogueWatcher) SetWatch(watch bool) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.watch = watch
+}
+
+func (w *PipelineCatalogueWatcher) SetReloadDelay(reloadDelay time.Duration) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.reloadDelay = reloadDelay
+}
+
+func (w *PipelineCatalogueWatcher) SetPollInterval(pollInterval time.Duration) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.pollInterval = pollInterval
+}
+
+// Start reloads the catalogue on SIGHUP and catalogue changes until Shutdown is called
+func (w *PipelineCatalogueWatcher) Start() {
+	w.Lock()
+	if w.started {
+		w.Unlock()
+		return
+	}
+	w.started = true
+	watch := w.watch
+	reloadDelay := w.reloadDelay
+	pollInterval := w.pollInterval
+	w.Unlock()
+
+	logger := config.GetLogger()
+	cataloguePath := w.pipelineRegistry.GetCataloguePath()
+
+	hangupChan := make(chan os.Signal, 1)
+	signal.Notify(hangupChan, syscall.SIGHUP)
+
+	var events chan fsnotify.Event
+	var watchErrors chan error
+	var watcher *fsnotify.Watcher
+	// Only catalogue directories on the disk are watched
+	if watch && w.pipelineRegistry.GetPipelineCatalogueLoader().GetStorage() == nil {
+		var err error
+		watcher, err = fsnotify.NewWatcher()
+		if err == nil {
+			err = watcher.Add(cataloguePath)
+		}
+		if err != nil {
+			logger.Errorf("Failed to watch Pipelines catalogue %s: %s", cataloguePath, err)
+		} else {
+			events = watcher.Events
+			watchErrors = watcher.Errors
+			logger.Infof("Watching Pipelines catalogue %s", cataloguePath)
+		}
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer signal.Stop(hangupChan)
+		if watcher != nil {
+			defer watcher.Close()
+		}
+
+		// Editors write files in several steps, so changes are collected before the reload
+		reloadTimer := time.NewTimer(reloadDelay)
+		reloadTimer.Stop()
+		defer reloadTimer.Stop()
+
+		var pollChan <-chan time.Time
+		if pollInterval > 0 {
+			pollTicker := time.NewTicker(pollInterval)
+			defer pollTicker.Stop()
+			pollChan = pollTicker.C
+		}
+
+		for {
+			select {
+			case <-w.stopChan:
+				return
+			case <-hangupChan:
+				logger.Info("Reloading Pipelines catalogue on SIGHUP")
+				w.pipelineRegistry.ReloadCatalogue()
+			case event := <-events:
+				if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+					continue
+				}
+				reloadTimer.Reset(reloadDelay)
+			case err := <-watchErrors:
+				logger.Errorf("Pipelines catalogue watcher error: %s", err)
+			case <-reloadTimer.C:
+				logger.Info("Reloading changed Pipelines catalogue")
+				w.pipelineRegistry.ReloadCatalogue()
+			case <-pollChan:
+				if w.isCatalogueChanged() {
+					logger.Info("Reloading Pipelines catalogue of a new version")
+					w.pipelineRegistry.ReloadCatalogue()
+				}
+			}
+		}
+	}()
+}
+
+// isCatalogueChanged compares the catalogue version with the loaded one
+func (w *PipelineCatalogueWatcher) isCatalogueChanged() bool {
+	version, err := w.pipelineRegistry.GetPipelineCatalogueLoader().GetCatalogueVersion(
+		w.pipelineRegistry.GetCataloguePath(),
+	)
+	if err != nil {
+		config.GetLogger().Errorf("Failed to get Pipelines catalogue version: %s", err)
+		return false
+	}
+
+	return version != w.pipelineRegistry.GetCatalogueReload().Version
+}
+
+func (w *PipelineCatalogueWatcher) Shutdown(ctx context.Context) error {
+	w.stopOnce.Do(func() {
+		close(w.stopChan)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
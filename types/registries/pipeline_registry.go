@@ -2,14 +2,23 @@ package registries
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/xeipuuv/gojsonschema"
 
 	"data-pipelines-worker/api/schemas"
 	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/helpers"
 	"data-pipelines-worker/types/interfaces"
 )
 
@@ -17,9 +26,17 @@ import (
 type PipelineRegistry struct {
 	sync.Mutex
 
+	// Pipelines are the default versions of the registered Pipelines
 	Pipelines               map[string]interfaces.Pipeline
+	pipelineVersions        map[string]map[string]interfaces.Pipeline
+	defaultVersions         map[string]string
 	pipelineResultStorages  []interfaces.Storage
 	pipelineCatalogueLoader interfaces.PipelineCatalogueLoader
+	cataloguePath           string
+	// Pipelines by the catalogue file defining them, restored when the file fails to load
+	catalogueFiles      map[string]interfaces.Pipeline
+	catalogueReload     schemas.PipelineCatalogueReloadSchema
+	catalogueReloadLock sync.Mutex
 
 	workerRegistry     interfaces.WorkerRegistry
 	blockRegistry      interfaces.BlockRegistry
@@ -42,45 +59,244 @@ func NewPipelineRegistry(
 		blockRegistry:           blockRegistry,
 		processingRegistry:      processingRegistry,
 		Pipelines:               make(map[string]interfaces.Pipeline),
+		pipelineVersions:        make(map[string]map[string]interfaces.Pipeline),
+		catalogueFiles:          make(map[string]interfaces.Pipeline),
+		defaultVersions:         make(map[string]string),
 		pipelineResultStorages:  make([]interfaces.Storage, 0),
 		pipelineCatalogueLoader: pipelineCatalogueLoader,
+		cataloguePath:           _config.Pipeline.Catalogue,
 	}
 
-	pipelines, err := pipelineCatalogueLoader.LoadCatalogue(
-		_config.Pipeline.Catalogue,
-	)
-	if err != nil {
+	if _, err := registry.ReloadCatalogue(); err != nil {
 		return nil, err
 	}
 
-	for _, pipeline := range pipelines {
-		registry.Add(pipeline)
-	}
-
 	return registry, nil
 }
 
-func (pr *PipelineRegistry) Add(p interfaces.Pipeline) {
+// ValidatePipelineSchema validates the Pipeline definition against the Pipelines validation schema
+func ValidatePipelineSchema(p interfaces.Pipeline) error {
 	_config := config.GetConfig()
 	registrySchema := _config.Pipeline.SchemaPtr
 	pipelineSchemaLoader := gojsonschema.NewStringLoader(p.GetSchemaString())
 	validationResult, err := registrySchema.Validate(pipelineSchemaLoader)
 
 	if err != nil {
-		panic(err)
+		return err
 	}
 	if !validationResult.Valid() {
 		errStr := fmt.Sprintf("Pipeline schema is invalid for pipeline: %s", p.GetSlug())
 		for _, err := range validationResult.Errors() {
 			errStr += fmt.Sprintf("\n- %s", err)
 		}
-		panic(errStr)
+		return errors.New(errStr)
+	}
+
+	return nil
+}
+
+// Add registers the version of the Pipeline, the Pipelines invalid for the validation schema are not registered
+func (pr *PipelineRegistry) Add(p interfaces.Pipeline) error {
+	if err := ValidatePipelineSchema(p); err != nil {
+		return err
+	}
+
+	pr.Lock()
+	defer pr.Unlock()
+
+	if _, ok := pr.pipelineVersions[p.GetSlug()]; !ok {
+		pr.pipelineVersions[p.GetSlug()] = make(map[string]interfaces.Pipeline)
+	}
+	pr.pipelineVersions[p.GetSlug()][p.GetVersion()] = p
+
+	pipelines := pr.copyPipelines()
+	pipelines[p.GetSlug()] = pr.getDefaultVersion(p.GetSlug())
+	pr.Pipelines = pipelines
+
+	return nil
+}
+
+// ErrPipelineVersionNotFound is returned when the Pipeline has no such version
+var ErrPipelineVersionNotFound = errors.New("pipeline version not found")
+
+// getDefaultVersion returns the version of the Pipeline set as default or the latest one.
+// The registry must be locked by the caller
+func (pr *PipelineRegistry) getDefaultVersion(slug string) interfaces.Pipeline {
+	versions := pr.pipelineVersions[slug]
+	if pipeline, ok := versions[pr.defaultVersions[slug]]; ok {
+		return pipeline
+	}
+
+	var latest interfaces.Pipeline
+	for _, pipeline := range versions {
+		if latest == nil || helpers.CompareVersions(pipeline.GetVersion(), latest.GetVersion()) > 0 {
+			latest = pipeline
+		}
+	}
+
+	return latest
+}
+
+// GetVersion returns the version of the Pipeline or the default one if the version is empty
+func (pr *PipelineRegistry) GetVersion(slug string, version string) interfaces.Pipeline {
+	pr.Lock()
+	defer pr.Unlock()
+
+	if version == "" {
+		return pr.Pipelines[slug]
+	}
+
+	return pr.pipelineVersions[slug][version]
+}
+
+// GetVersions returns the versions of the Pipeline ordered from the oldest
+func (pr *PipelineRegistry) GetVersions(slug string) []interfaces.Pipeline {
+	pr.Lock()
+	defer pr.Unlock()
+
+	versions := make([]interfaces.Pipeline, 0, len(pr.pipelineVersions[slug]))
+	for _, pipeline := range pr.pipelineVersions[slug] {
+		versions = append(versions, pipeline)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return helpers.CompareVersions(versions[i].GetVersion(), versions[j].GetVersion()) < 0
+	})
+
+	return versions
+}
+
+// SetDefaultVersion sets the version new processings of the Pipeline are started with.
+// The default version is kept across catalogue reloads while the version exists
+func (pr *PipelineRegistry) SetDefaultVersion(slug string, version string) error {
+	pr.Lock()
+	defer pr.Unlock()
+
+	pipeline, ok := pr.pipelineVersions[slug][version]
+	if !ok {
+		return fmt.Errorf("%w: %s version %s", ErrPipelineVersionNotFound, slug, version)
+	}
+
+	pr.defaultVersions[slug] = version
+
+	// The map is swapped as on the catalogue reload, so it is never changed while being read
+	pipelines := pr.copyPipelines()
+	pipelines[slug] = pipeline
+	pr.Pipelines = pipelines
+
+	return nil
+}
+
+func (pr *PipelineRegistry) SetCataloguePath(cataloguePath string) {
+	pr.Lock()
+	defer pr.Unlock()
+
+	pr.cataloguePath = cataloguePath
+}
+
+func (pr *PipelineRegistry) GetCataloguePath() string {
+	pr.Lock()
+	defer pr.Unlock()
+
+	return pr.cataloguePath
+}
+
+func (pr *PipelineRegistry) GetPipelineCatalogueLoader() interfaces.PipelineCatalogueLoader {
+	return pr.pipelineCatalogueLoader
+}
+
+// ReloadCatalogue loads the catalogue and swaps the registered Pipelines at once.
+// Invalid files, even the ones which can not be parsed, keep the Pipeline version they defined before.
+// Running processings keep the definition they were started with
+func (pr *PipelineRegistry) ReloadCatalogue() (schemas.PipelineCatalogueReloadSchema, error) {
+	pr.catalogueReloadLock.Lock()
+	defer pr.catalogueReloadLock.Unlock()
+
+	logger := config.GetLogger()
+	cataloguePath := pr.GetCataloguePath()
+
+	report := schemas.PipelineCatalogueReloadSchema{
+		Date:      time.Now().UTC(),
+		Pipelines: make([]string, 0),
+		Removed:   make([]string, 0),
+		Errors:    make([]schemas.PipelineCatalogueErrorSchema, 0),
+	}
+
+	// Version is taken before loading, so changes made meanwhile are reloaded next time
+	version, err := pr.pipelineCatalogueLoader.GetCatalogueVersion(cataloguePath)
+	if err != nil {
+		logger.Errorf("Failed to get Pipelines catalogue %s version: %s", cataloguePath, err)
+		return report, err
+	}
+	report.Version = version
+
+	pipelines, pipelineFiles, fileErrors, err := pr.pipelineCatalogueLoader.LoadCatalogue(cataloguePath)
+	if err != nil {
+		logger.Errorf("Failed to load Pipelines catalogue %s: %s", cataloguePath, err)
+		return report, err
+	}
+
+	pr.Lock()
+	previousVersions := pr.pipelineVersions
+	previousFiles := pr.catalogueFiles
+	pr.Unlock()
+
+	for _, fileError := range fileErrors {
+		logger.Errorf("Pipelines catalogue file %s is invalid: %s", fileError.File, fileError.Error)
+
+		previousPipeline, ok := previousFiles[fileError.File]
+		if !ok {
+			continue
+		}
+		slug, version := previousPipeline.GetSlug(), previousPipeline.GetVersion()
+		if _, ok := pipelines[slug][version]; ok {
+			continue
+		}
+		if _, ok := pipelines[slug]; !ok {
+			pipelines[slug] = make(map[string]interfaces.Pipeline)
+		}
+		pipelines[slug][version] = previousPipeline
+		pipelineFiles[fileError.File] = previousPipeline
+	}
+	report.Errors = fileErrors
+
+	for slug := range pipelines {
+		report.Pipelines = append(report.Pipelines, slug)
+	}
+	for slug := range previousVersions {
+		if _, ok := pipelines[slug]; !ok {
+			report.Removed = append(report.Removed, slug)
+		}
+	}
+	sort.Strings(report.Pipelines)
+	sort.Strings(report.Removed)
+
+	pr.Lock()
+	pr.pipelineVersions = pipelines
+	pr.catalogueFiles = pipelineFiles
+	pr.Pipelines = make(map[string]interfaces.Pipeline, len(pipelines))
+	for slug := range pipelines {
+		pr.Pipelines[slug] = pr.getDefaultVersion(slug)
 	}
+	pr.catalogueReload = report
+	pr.Unlock()
+
+	logger.Infof(
+		"Pipelines catalogue %s loaded: %d pipelines, %d removed, %d invalid files",
+		cataloguePath,
+		len(report.Pipelines),
+		len(report.Removed),
+		len(report.Errors),
+	)
 
+	return report, nil
+}
+
+// GetCatalogueReload returns the result of the last catalogue reload
+func (pr *PipelineRegistry) GetCatalogueReload() schemas.PipelineCatalogueReloadSchema {
 	pr.Lock()
 	defer pr.Unlock()
 
-	pr.Pipelines[p.GetSlug()] = p
+	return pr.catalogueReload
 }
 
 func (pr *PipelineRegistry) SetPipelineResultStorages(storages []interfaces.Storage) {
@@ -111,11 +327,23 @@ func (pr *PipelineRegistry) Get(slug string) interfaces.Pipeline {
 	return pr.Pipelines[slug]
 }
 
+// GetAll returns the copy of the registered Pipelines, safe to range over while the registry changes
 func (pr *PipelineRegistry) GetAll() map[string]interfaces.Pipeline {
 	pr.Lock()
 	defer pr.Unlock()
 
-	return pr.Pipelines
+	return pr.copyPipelines()
+}
+
+// copyPipelines returns the copy of the registered Pipelines.
+// The registry must be locked by the caller
+func (pr *PipelineRegistry) copyPipelines() map[string]interfaces.Pipeline {
+	pipelines := make(map[string]interfaces.Pipeline, len(pr.Pipelines))
+	for slug, pipeline := range pr.Pipelines {
+		pipelines[slug] = pipeline
+	}
+
+	return pipelines
 }
 
 func (pr *PipelineRegistry) Delete(slug string) {
@@ -123,6 +351,13 @@ func (pr *PipelineRegistry) Delete(slug string) {
 	defer pr.Unlock()
 
 	delete(pr.Pipelines, slug)
+	delete(pr.pipelineVersions, slug)
+	delete(pr.defaultVersions, slug)
+	for file, pipeline := range pr.catalogueFiles {
+		if pipeline.GetSlug() == slug {
+			delete(pr.catalogueFiles, file)
+		}
+	}
 }
 
 func (pr *PipelineRegistry) DeleteAll() {
@@ -132,6 +367,9 @@ func (pr *PipelineRegistry) DeleteAll() {
 	for slug := range pr.Pipelines {
 		delete(pr.Pipelines, slug)
 	}
+	pr.pipelineVersions = make(map[string]map[string]interfaces.Pipeline)
+	pr.defaultVersions = make(map[string]string)
+	pr.catalogueFiles = make(map[string]interfaces.Pipeline)
 }
 
 func (pr *PipelineRegistry) Shutdown(ctx context.Context) error {
@@ -144,37 +382,190 @@ func (pr *PipelineRegistry) GetProcessingsStatus(p interfaces.Pipeline) map[uuid
 	)
 }
 
-func (pr *PipelineRegistry) GetProcessingDetailsByLogId(p interfaces.Pipeline, pipelineId uuid.UUID, logId uuid.UUID) interfaces.PipelineProcessingDetails {
-	return p.GetProcessingDetailsByLogId(pipelineId, logId, pr.GetPipelineResultStorages())
+func (pr *PipelineRegistry) QueryProcessingsStatus(
+	p interfaces.Pipeline,
+	query schemas.PipelineProcessingsQuerySchema,
+) ([]interfaces.PipelineProcessingStatus, int) {
+	return p.QueryProcessingsStatus(query, pr.GetPipelineResultStorages())
 }
 
-func (pr *PipelineRegistry) GetProcessingDetails(p interfaces.Pipeline, pipelineId uuid.UUID) []interfaces.PipelineProcessingDetails {
-	return p.GetProcessingDetails(pipelineId, pr.GetPipelineResultStorages())
+// QueryProcessingsUsage sums the usage of the Pipeline processings matching the query
+func (pr *PipelineRegistry) QueryProcessingsUsage(
+	p interfaces.Pipeline,
+	query schemas.PipelineProcessingsQuerySchema,
+) schemas.PipelineUsageSchema {
+	return p.QueryProcessingsUsage(query, pr.GetPipelineResultStorages())
 }
 
-func (pr *PipelineRegistry) StartPipeline(
+// getDailyCost returns the cost of the Pipeline processings of the current UTC day
+func (pr *PipelineRegistry) getDailyCost(slug string) float64 {
+	return GetUsageRegistry().GetDailyCost(slug, func(from time.Time) schemas.PipelineUsageSchema {
+		pipeline := pr.Get(slug)
+		if pipeline == nil {
+			return schemas.PipelineUsageSchema{PipelineSlug: slug}
+		}
+
+		query := schemas.NewPipelineProcessingsQuerySchema()
+		query.From = &from
+		return pr.QueryProcessingsUsage(pipeline, query)
+	})
+}
+
+// reserveBudget reserves the estimated cost of the processing to be started.
+// It returns ErrBudgetExceeded when the Pipeline or all the Pipelines spent the daily budget.
+// The reservation of a processing which fails to start has to be released
+func (pr *PipelineRegistry) reserveBudget(slug string, processingId uuid.UUID) error {
+	costs := config.GetConfig().Costs
+	if costs.Budgets.Pipelines[slug] <= 0 && costs.Budgets.Daily <= 0 {
+		return nil
+	}
+
+	return GetUsageRegistry().Reserve(slug, processingId, func() error {
+		return pr.checkBudget(slug)
+	})
+}
+
+// startWithBudget starts the processing within the reserved budget
+func (pr *PipelineRegistry) startWithBudget(
+	pipeline interfaces.Pipeline,
 	data schemas.PipelineStartInputSchema,
 ) (uuid.UUID, error) {
-	pipeline := pr.Get(data.Pipeline.Slug)
-	if pipeline == nil {
-		return uuid.UUID{}, fmt.Errorf("pipeline with slug %s not found", data.Pipeline.Slug)
+	processingId := data.GetProcessingID()
+	if err := pr.reserveBudget(pipeline.GetSlug(), processingId); err != nil {
+		return uuid.UUID{}, err
 	}
 
-	return pipeline.Process(
+	if _, err := pipeline.Process(
 		pr.GetWorkerRegistry(),
 		pr.GetBlockRegistry(),
 		pr.GetProcessingRegistry(),
 		data,
 		pr.GetPipelineResultStorages(),
-	)
+	); err != nil {
+		GetUsageRegistry().Release(pipeline.GetSlug(), processingId)
+		return uuid.UUID{}, err
+	}
+
+	return processingId, nil
+}
+
+// checkBudget returns ErrBudgetExceeded when the Pipeline or all the Pipelines spent the daily budget
+func (pr *PipelineRegistry) checkBudget(slug string) error {
+	costs := config.GetConfig().Costs
+
+	if budget := costs.Budgets.Pipelines[slug]; budget > 0 {
+		if cost := pr.getDailyCost(slug); cost >= budget {
+			return fmt.Errorf(
+				"%w: pipeline %s spent %.4f of %.4f %s today",
+				ErrBudgetExceeded,
+				slug,
+				cost,
+				budget,
+				costs.Currency,
+			)
+		}
+	}
+
+	if costs.Budgets.Daily > 0 {
+		cost := 0.0
+		for pipelineSlug := range pr.GetAll() {
+			cost += pr.getDailyCost(pipelineSlug)
+		}
+		if cost >= costs.Budgets.Daily {
+			return fmt.Errorf(
+				"%w: pipelines spent %.4f of %.4f %s today",
+				ErrBudgetExceeded,
+				cost,
+				costs.Budgets.Daily,
+				costs.Currency,
+			)
+		}
+	}
+
+	return nil
+}
+
+// ReindexProcessings rebuilds the Processing Index of the Pipeline from the storages.
+// It returns the number of indexed logs
+func (pr *PipelineRegistry) ReindexProcessings(p interfaces.Pipeline) (int, error) {
+	return GetProcessingIndex().Rebuild(p.GetSlug(), pr.GetPipelineResultStorages())
+}
+
+func (pr *PipelineRegistry) GetProcessingDetailsByLogId(p interfaces.Pipeline, pipelineId uuid.UUID, logId uuid.UUID) interfaces.PipelineProcessingDetails {
+	return p.GetProcessingDetailsByLogId(pipelineId, logId, pr.GetPipelineResultStorages())
+}
+
+func (pr *PipelineRegistry) GetProcessingDetails(p interfaces.Pipeline, pipelineId uuid.UUID) []interfaces.PipelineProcessingDetails {
+	return p.GetProcessingDetails(pipelineId, pr.GetPipelineResultStorages())
+}
+
+// getPipelineVersion returns the version of the Pipeline or the default one if the version is empty
+func (pr *PipelineRegistry) getPipelineVersion(slug string, version string) (interfaces.Pipeline, error) {
+	if pr.Get(slug) == nil {
+		return nil, fmt.Errorf("pipeline with slug %s not found", slug)
+	}
+
+	pipeline := pr.GetVersion(slug, version)
+	if pipeline == nil {
+		return nil, fmt.Errorf("%w: %s version %s", ErrPipelineVersionNotFound, slug, version)
+	}
+
+	return pipeline, nil
 }
 
+// restoreProcessingInput sets the Pipeline version the processing is pinned to
+// and its input parameters from the processing logs unless they are set explicitly.
+// Processings without a recorded version use the default one
+func (pr *PipelineRegistry) restoreProcessingInput(
+	processingId uuid.UUID,
+	data *schemas.PipelineStartInputSchema,
+) {
+	if processingId == uuid.Nil || (data.Pipeline.Version != "" && data.Pipeline.Input != nil) {
+		return
+	}
+
+	storage, logFiles, err := pr.findProcessingLogs(data.Pipeline.Slug, processingId)
+	if err != nil {
+		return
+	}
+
+	processingLogs := parseProcessingLogs(storage, logFiles)
+	if data.Pipeline.Version == "" {
+		data.Pipeline.Version = processingLogs.pipelineVersion
+	}
+	if data.Pipeline.Input == nil {
+		data.Pipeline.Input = processingLogs.pipelineInput
+	}
+}
+
+// StartPipeline starts a new processing with the input parameters validated
+// against the input schema of the Pipeline
+func (pr *PipelineRegistry) StartPipeline(
+	data schemas.PipelineStartInputSchema,
+) (uuid.UUID, error) {
+	pipeline, err := pr.getPipelineVersion(data.Pipeline.Slug, data.Pipeline.Version)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	data.Pipeline.Input, err = pipeline.ValidateInput(data.Pipeline.Input)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return pr.startWithBudget(pipeline, data)
+}
+
+// ResumePipeline resumes the processing with the Pipeline version and input parameters
+// it was started with
 func (pr *PipelineRegistry) ResumePipeline(
 	data schemas.PipelineStartInputSchema,
 ) (uuid.UUID, error) {
-	pipeline := pr.Get(data.Pipeline.Slug)
-	if pipeline == nil {
-		return uuid.UUID{}, fmt.Errorf("pipeline with slug %s not found", data.Pipeline.Slug)
+	pr.restoreProcessingInput(data.Pipeline.ProcessingID, &data)
+
+	pipeline, err := pr.getPipelineVersion(data.Pipeline.Slug, data.Pipeline.Version)
+	if err != nil {
+		return uuid.UUID{}, err
 	}
 
 	return pipeline.Process(
@@ -186,6 +577,585 @@ func (pr *PipelineRegistry) ResumePipeline(
 	)
 }
 
+// ErrProcessingNotFound is returned when none of the storages has the processing
+var ErrProcessingNotFound = errors.New("processing not found")
+
+// ErrProcessingNotFinished is returned for the processings which are running or waiting for the moderation
+var ErrProcessingNotFinished = errors.New("processing is not finished")
+
+// ForkPipeline starts a new processing from the given block.
+// Outputs of the blocks before it are copied from the source processing.
+// The new processing uses the Pipeline version and input parameters of the source processing
+func (pr *PipelineRegistry) ForkPipeline(
+	sourceProcessingId uuid.UUID,
+	data schemas.PipelineStartInputSchema,
+) (uuid.UUID, error) {
+	pr.restoreProcessingInput(sourceProcessingId, &data)
+
+	pipeline, err := pr.getPipelineVersion(data.Pipeline.Slug, data.Pipeline.Version)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	// Fail before copying the outputs, the budget is reserved when the processing starts
+	if err := pr.checkBudget(pipeline.GetSlug()); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	previousBlockSlugs := make([]string, 0)
+	blockFound := false
+	for _, block := range pipeline.GetBlocks() {
+		if block.GetSlug() == data.Block.Slug {
+			blockFound = true
+			break
+		}
+		previousBlockSlugs = append(previousBlockSlugs, block.GetSlug())
+	}
+	if !blockFound {
+		return uuid.UUID{}, fmt.Errorf(
+			"block with slug %s not found in pipeline %s",
+			data.Block.Slug,
+			pipeline.GetSlug(),
+		)
+	}
+
+	processingId := uuid.New()
+	if err := pr.copyProcessingOutputs(
+		pipeline.GetSlug(),
+		sourceProcessingId,
+		processingId,
+		previousBlockSlugs,
+	); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	data.Pipeline.ProcessingID = processingId
+	data.Pipeline.ParentProcessingID = sourceProcessingId
+
+	return pr.startWithBudget(pipeline, data)
+}
+
+// copyProcessingOutputs copies outputs of the blocks between processings in every storage
+func (pr *PipelineRegistry) copyProcessingOutputs(
+	pipelineSlug string,
+	sourceProcessingId uuid.UUID,
+	destinationProcessingId uuid.UUID,
+	blockSlugs []string,
+) error {
+	sourceFound := false
+
+	for _, storage := range pr.GetPipelineResultStorages() {
+		sourcePath := path.Join(pipelineSlug, sourceProcessingId.String())
+		objects, err := storage.ListObjects(storage.NewStorageLocation(sourcePath))
+		if err != nil {
+			continue
+		}
+		for _, object := range objects {
+			if strings.Contains(filepath.ToSlash(object.GetFilePath()), sourcePath+"/") {
+				sourceFound = true
+				break
+			}
+		}
+
+		for _, blockSlug := range blockSlugs {
+			blockPath := path.Join(sourcePath, blockSlug)
+			objects, err := storage.ListObjects(storage.NewStorageLocation(blockPath))
+			if err != nil {
+				continue
+			}
+
+			// Outputs are copied with the content type of their metadata
+			contentTypes := make(map[int]string)
+			for _, object := range objects {
+				objectPath := filepath.ToSlash(object.GetFilePath())
+				var outputIndex int
+				if strings.Contains(objectPath, blockPath+"/") &&
+					METADATA_FILE_REGEX.MatchString(path.Base(objectPath)) {
+					if _, err := fmt.Sscanf(path.Base(objectPath), METADATA_FILE_TEMPLATE, &outputIndex); err == nil {
+						contentTypes[outputIndex] = loadOutputMetadata(storage, object).ContentType
+					}
+				}
+			}
+
+			for _, object := range objects {
+				objectPath := filepath.ToSlash(object.GetFilePath())
+				if !strings.Contains(objectPath, blockPath+"/") ||
+					(!OUTPUT_FILE_REGEX.MatchString(path.Base(objectPath)) &&
+						!METADATA_FILE_REGEX.MatchString(path.Base(objectPath))) {
+					continue
+				}
+
+				data, err := storage.GetObjectBytes(object)
+				if err != nil {
+					return err
+				}
+
+				destination := storage.NewStorageLocation(
+					path.Join(
+						pipelineSlug,
+						destinationProcessingId.String(),
+						blockSlug,
+						path.Base(objectPath),
+					),
+				)
+				var outputIndex int
+				contentTypeStorage, ok := storage.(interfaces.ContentTypeStorage)
+				if _, scanErr := fmt.Sscanf(path.Base(objectPath), OUTPUT_FILE_TEMPLATE, &outputIndex); ok && scanErr == nil {
+					_, err = contentTypeStorage.PutObjectBytesWithContentType(destination, data, contentTypes[outputIndex])
+				} else {
+					_, err = storage.PutObjectBytes(destination, data)
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if !sourceFound {
+		return fmt.Errorf("%w: %s", ErrProcessingNotFound, sourceProcessingId)
+	}
+
+	return nil
+}
+
+// RetryPipeline resumes the processing from the first failed or incomplete block.
+// The block is resumed with the input it was originally started with
+func (pr *PipelineRegistry) RetryPipeline(
+	pipelineSlug string,
+	processingId uuid.UUID,
+	data schemas.PipelineRetryInputSchema,
+) (schemas.PipelineRetryOutputSchema, error) {
+	result := schemas.PipelineRetryOutputSchema{
+		ProcessingID: processingId,
+	}
+
+	if pr.Get(pipelineSlug) == nil {
+		return result, fmt.Errorf("pipeline with slug %s not found", pipelineSlug)
+	}
+
+	processingPath := path.Join(pipelineSlug, processingId.String())
+
+	storage, logFiles, err := pr.findProcessingLogs(pipelineSlug, processingId)
+	if err != nil {
+		return result, err
+	}
+	if len(logFiles) == 0 {
+		return result, fmt.Errorf("processing %s is not finished", processingId)
+	}
+
+	processingLogs := parseProcessingLogs(storage, logFiles)
+	failedIndexes := processingLogs.failedIndexes
+
+	// The processing is retried with the Pipeline version it was started with
+	pipeline, err := pr.getPipelineVersion(pipelineSlug, processingLogs.pipelineVersion)
+	if err != nil {
+		return result, err
+	}
+
+	var retryBlock interfaces.ProcessableBlockData
+	for _, block := range pipeline.GetBlocks() {
+		if len(failedIndexes[block.GetSlug()]) > 0 ||
+			!hasProcessingOutputs(storage, path.Join(processingPath, block.GetSlug())) {
+			retryBlock = block
+			break
+		}
+	}
+	if retryBlock == nil {
+		return result, fmt.Errorf("processing %s has no failed or incomplete blocks", processingId)
+	}
+
+	inputData := schemas.PipelineStartInputSchema{
+		Pipeline: schemas.PipelineInputSchema{
+			Slug:         pipelineSlug,
+			ProcessingID: processingId,
+			Input:        processingLogs.pipelineInput,
+		},
+		Block: schemas.BlockInputSchema{
+			Slug:        retryBlock.GetSlug(),
+			Input:       loadProcessingBlockInput(storage, path.Join(processingPath, retryBlock.GetSlug())),
+			TargetIndex: -1,
+		},
+		Callback: data.Callback,
+	}
+	if data.FailedIndexesOnly {
+		inputData.Block.TargetIndexes = failedIndexes[retryBlock.GetSlug()]
+	}
+
+	if _, err := pr.startWithBudget(pipeline, inputData); err != nil {
+		return result, err
+	}
+
+	result.BlockSlug = retryBlock.GetSlug()
+	result.TargetIndexes = inputData.Block.TargetIndexes
+
+	return result, nil
+}
+
+// findProcessingLogs returns the first storage with the processing and the processing logs in it
+func (pr *PipelineRegistry) findProcessingLogs(
+	pipelineSlug string,
+	processingId uuid.UUID,
+) (interfaces.Storage, []interfaces.StorageLocation, error) {
+	processingPath := path.Join(pipelineSlug, processingId.String())
+
+	for _, storage := range pr.GetPipelineResultStorages() {
+		objects, err := storage.ListObjects(storage.NewStorageLocation(processingPath))
+		if err != nil {
+			continue
+		}
+
+		processingFound := false
+		logFiles := make([]interfaces.StorageLocation, 0)
+		for _, object := range objects {
+			objectPath := filepath.ToSlash(object.GetFilePath())
+			if !strings.Contains(objectPath, processingPath+"/") {
+				continue
+			}
+			processingFound = true
+
+			if LOG_FILE_REGEX.MatchString(path.Base(objectPath)) {
+				logFiles = append(logFiles, object)
+			}
+		}
+
+		if processingFound {
+			return storage, logFiles, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("%w: %s", ErrProcessingNotFound, processingId)
+}
+
+// processingLogs is the state of the processing recorded in its logs
+type processingLogs struct {
+	// The block input indexes which failed and were not processed afterwards
+	failedIndexes map[string][]int
+	// The Pipeline version the processing is pinned to
+	pipelineVersion string
+	// The Pipeline input parameters without files
+	pipelineInput map[string]interface{}
+}
+
+// parseProcessingLogs reads the state of the processing from its logs
+func parseProcessingLogs(
+	storage interfaces.Storage,
+	logFiles []interfaces.StorageLocation,
+) processingLogs {
+	// Logs are named log_<unix timestamp>
+	sort.Slice(logFiles, func(i, j int) bool {
+		return getProcessingFileTimestamp(logFiles[i], "log_") < getProcessingFileTimestamp(logFiles[j], "log_")
+	})
+
+	failed := make(map[string]map[int]bool)
+	pipelineVersion := ""
+	var pipelineInput map[string]interface{}
+
+	for _, logFile := range logFiles {
+		logBuffer, err := storage.GetObjectBytes(logFile)
+		if err != nil {
+			continue
+		}
+
+		logDetails := struct {
+			LogData []map[string]interface{} `json:"log_data"`
+		}{}
+		if err := json.Unmarshal(logBuffer.Bytes(), &logDetails); err != nil {
+			continue
+		}
+
+		for _, logLine := range logDetails.LogData {
+			message, _ := logLine["message"].(string)
+
+			if matches := PIPELINE_VERSION_LOG_REGEX.FindStringSubmatch(message); len(matches) == 2 {
+				pipelineVersion = matches[1]
+				continue
+			}
+
+			if matches := PIPELINE_INPUT_LOG_REGEX.FindStringSubmatch(message); len(matches) == 2 {
+				input := make(map[string]interface{})
+				if err := json.Unmarshal([]byte(matches[1]), &input); err == nil {
+					pipelineInput = input
+				}
+				continue
+			}
+
+			if matches := BLOCK_INPUT_FAILED_LOG_REGEX.FindStringSubmatch(message); len(matches) == 3 {
+				index, _ := strconv.Atoi(matches[2])
+				if _, ok := failed[matches[1]]; !ok {
+					failed[matches[1]] = make(map[int]bool)
+				}
+				failed[matches[1]][index] = true
+				continue
+			}
+
+			if matches := BLOCK_INPUT_COMPLETED_LOG_REGEX.FindStringSubmatch(message); len(matches) == 3 {
+				index, _ := strconv.Atoi(matches[2])
+				delete(failed[matches[1]], index)
+			}
+		}
+	}
+
+	failedIndexes := make(map[string][]int)
+	for blockSlug, indexes := range failed {
+		for index := range indexes {
+			failedIndexes[blockSlug] = append(failedIndexes[blockSlug], index)
+		}
+		sort.Ints(failedIndexes[blockSlug])
+	}
+
+	return processingLogs{
+		failedIndexes:   failedIndexes,
+		pipelineVersion: pipelineVersion,
+		pipelineInput:   pipelineInput,
+	}
+}
+
+// getProcessingFileTimestamp returns the timestamp of the `<prefix><timestamp>` file name
+func getProcessingFileTimestamp(file interfaces.StorageLocation, prefix string) int64 {
+	timestamp, _ := strconv.ParseInt(
+		strings.TrimPrefix(
+			strings.TrimSuffix(path.Base(file.GetFilePath()), path.Ext(file.GetFilePath())),
+			prefix,
+		),
+		10,
+		64,
+	)
+
+	return timestamp
+}
+
+// loadProcessingBlockInput returns the input the block of the processing was last started with
+// by the API callers. Unlike the logged input, it keeps the credentials the logs mask
+func loadProcessingBlockInput(storage interfaces.Storage, blockPath string) map[string]interface{} {
+	objects, err := storage.ListObjects(storage.NewStorageLocation(blockPath))
+	if err != nil {
+		return nil
+	}
+
+	var inputFile interfaces.StorageLocation
+	for _, object := range objects {
+		objectPath := filepath.ToSlash(object.GetFilePath())
+		if !strings.Contains(objectPath, blockPath+"/") || !INPUT_FILE_REGEX.MatchString(path.Base(objectPath)) {
+			continue
+		}
+		if inputFile == nil ||
+			getProcessingFileTimestamp(object, "input_") > getProcessingFileTimestamp(inputFile, "input_") {
+			inputFile = object
+		}
+	}
+	if inputFile == nil {
+		return nil
+	}
+
+	inputBuffer, err := storage.GetObjectBytes(inputFile)
+	if err != nil {
+		config.GetLogger().Errorf("Failed to load block input %s: %s", inputFile.GetFilePath(), err)
+		return nil
+	}
+	input := make(map[string]interface{})
+	if err := json.Unmarshal(inputBuffer.Bytes(), &input); err != nil {
+		config.GetLogger().Errorf("Failed to load block input %s: %s", inputFile.GetFilePath(), err)
+		return nil
+	}
+
+	return input
+}
+
+// hasProcessingOutputs checks if the block of the processing saved any output
+func hasProcessingOutputs(storage interfaces.Storage, blockPath string) bool {
+	objects, err := storage.ListObjects(storage.NewStorageLocation(blockPath))
+	if err != nil {
+		return false
+	}
+
+	for _, object := range objects {
+		objectPath := filepath.ToSlash(object.GetFilePath())
+		if strings.Contains(objectPath, blockPath+"/") &&
+			OUTPUT_FILE_REGEX.MatchString(path.Base(objectPath)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DeleteProcessing removes all artifacts of the processing in every storage.
+// It returns the deleted objects prefixed with the storage name
+func (pr *PipelineRegistry) DeleteProcessing(
+	pipelineSlug string,
+	processingId uuid.UUID,
+) ([]string, error) {
+	processingPath := path.Join(pipelineSlug, processingId.String())
+
+	deletedObjects := make([]string, 0)
+	if processing := pr.GetProcessingRegistry().Get(processingId.String()); processing != nil {
+		switch processing.GetStatus() {
+		case interfaces.ProcessingStatusPending, interfaces.ProcessingStatusRunning, interfaces.ProcessingStatusRetry:
+			return deletedObjects, fmt.Errorf("%w: %s", ErrProcessingNotFinished, processingId)
+		}
+	}
+
+	storagesObjects := make(map[interfaces.Storage][]interfaces.StorageLocation)
+	var (
+		statusLocation  interfaces.StorageLocation
+		statusTimestamp int64
+	)
+	for _, storage := range pr.GetPipelineResultStorages() {
+		objects := listStorageObjectsRecursive(storage, processingPath)
+		if len(objects) == 0 {
+			continue
+		}
+		storagesObjects[storage] = objects
+
+		for _, object := range objects {
+			key := GetStorageObjectKey(storage, object)
+			matches := PROCESSING_STATUS_FILE_REGEX.FindStringSubmatch(path.Base(key))
+			if len(matches) != 2 || path.Dir(key) != processingPath {
+				continue
+			}
+			if timestamp, err := strconv.ParseInt(matches[1], 10, 64); err == nil && timestamp >= statusTimestamp {
+				statusTimestamp = timestamp
+				statusLocation = object
+			}
+		}
+	}
+
+	if len(storagesObjects) == 0 {
+		return deletedObjects, fmt.Errorf("%w: %s", ErrProcessingNotFound, processingId)
+	}
+
+	// Outputs of the processings which are not finished yet are still needed to resume them, as for the retention
+	isTerminal, err := isTerminalStatus(statusLocation)
+	if err != nil {
+		return deletedObjects, err
+	}
+	if !isTerminal {
+		return deletedObjects, fmt.Errorf("%w: %s", ErrProcessingNotFinished, processingId)
+	}
+
+	for _, storage := range pr.GetPipelineResultStorages() {
+		objects, ok := storagesObjects[storage]
+		if !ok {
+			continue
+		}
+
+		if err := removeIndexedProcessing(storage, pipelineSlug, processingId); err != nil {
+			return deletedObjects, err
+		}
+
+		deleted, err := deleteProcessingObjects(storage, processingPath, objects)
+		deletedObjects = append(deletedObjects, deleted...)
+		if err != nil {
+			return deletedObjects, err
+		}
+
+		// Local storages keep the processing directory
+		processingLocation := storage.NewStorageLocation(processingPath)
+		if storage.LocationExists(processingLocation) {
+			if err := deleteProcessingObject(storage, processingPath, processingLocation); err != nil {
+				return deletedObjects, err
+			}
+		}
+	}
+
+	return deletedObjects, nil
+}
+
+// removeIndexedProcessing removes the processing from the Processing Index
+// before its logs & statuses are deleted
+func removeIndexedProcessing(storage interfaces.Storage, pipelineSlug string, processingId uuid.UUID) error {
+	processingIndex := GetProcessingIndex()
+	if !processingIndex.IsEnabled() {
+		return nil
+	}
+
+	return processingIndex.DeleteProcessing(storage, pipelineSlug, processingId)
+}
+
+// GetStorageObjectKey returns the object path relative to the storage root
+func GetStorageObjectKey(storage interfaces.Storage, object interfaces.StorageLocation) string {
+	if object.GetLocalDirectory() == "" {
+		return object.GetFileName()
+	}
+
+	key, err := filepath.Rel(storage.GetStorageDirectory(), object.GetFilePath())
+	if err != nil {
+		return ""
+	}
+
+	return filepath.ToSlash(key)
+}
+
+// listStorageObjectsRecursive returns all objects under the prefix.
+// Nested objects are returned before their directories
+func listStorageObjectsRecursive(storage interfaces.Storage, prefix string) []interfaces.StorageLocation {
+	result := make([]interfaces.StorageLocation, 0)
+
+	objects, err := storage.ListObjects(storage.NewStorageLocation(prefix))
+	if err != nil {
+		return result
+	}
+
+	for _, object := range objects {
+		key := GetStorageObjectKey(storage, object)
+		if !strings.HasPrefix(key, prefix+"/") {
+			continue
+		}
+
+		// Local storages list a single directory, so descend into the nested ones
+		if !strings.Contains(strings.TrimPrefix(key, prefix+"/"), "/") {
+			result = append(result, listStorageObjectsRecursive(storage, key)...)
+		}
+		result = append(result, object)
+	}
+
+	return result
+}
+
+// deleteProcessingObject deletes the object only if it is inside the processing path
+func deleteProcessingObject(
+	storage interfaces.Storage,
+	processingPath string,
+	object interfaces.StorageLocation,
+) error {
+	key := GetStorageObjectKey(storage, object)
+
+	if strings.Count(processingPath, "/") != 1 ||
+		path.Clean(processingPath) != processingPath ||
+		strings.HasPrefix(processingPath, ".") ||
+		strings.HasPrefix(processingPath, "/") {
+		return fmt.Errorf("refusing to delete objects of invalid processing path %s", processingPath)
+	}
+	if path.Clean(key) != key ||
+		(key != processingPath && !strings.HasPrefix(key, processingPath+"/")) {
+		return fmt.Errorf("refusing to delete %s outside of %s", key, processingPath)
+	}
+
+	return storage.DeleteObject(object)
+}
+
+// deleteProcessingObjects deletes the objects of the processing and returns
+// the deleted ones prefixed with the storage name
+func deleteProcessingObjects(
+	storage interfaces.Storage,
+	processingPath string,
+	objects []interfaces.StorageLocation,
+) ([]string, error) {
+	deleted := make([]string, 0, len(objects))
+	for _, object := range objects {
+		if err := deleteProcessingObject(storage, processingPath, object); err != nil {
+			return deleted, err
+		}
+		deleted = append(
+			deleted,
+			fmt.Sprintf("%s:%s", storage.GetStorageName(), GetStorageObjectKey(storage, object)),
+		)
+	}
+
+	return deleted, nil
+}
+
 func (pr *PipelineRegistry) GetWorkerRegistry() interfaces.WorkerRegistry {
 	return pr.workerRegistry
 }
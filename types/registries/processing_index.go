@@ -0,0 +1,921 @@
+package registries
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/interfaces"
+)
+
+const (
+	PROCESSING_INDEX_OPEN_TIMEOUT = time.Second
+
+	// Fixed width, so the keys of the date bucket are ordered by the finish date
+	PROCESSING_INDEX_DATE_FORMAT = "2006-01-02T15:04:05.000000000Z"
+)
+
+var (
+	// Bucket with the time every Pipeline was indexed at by storage
+	processingIndexPipelinesBucket = []byte("_pipelines")
+	// Bucket of the Pipeline with the entry keys by finish date
+	processingIndexDateBucket = []byte("_date_finished")
+
+	processingIndexFileRegex = regexp.MustCompile(`^(log|status)_(\d+)$`)
+
+	ErrProcessingIndexDisabled = errors.New("processing index is disabled")
+)
+
+var (
+	onceProcessingIndex     sync.Once
+	processingIndexInstance *ProcessingIndex
+)
+
+// GetProcessingIndex returns the Processing Index opened at the configured path.
+// Disabled or failed to open index falls back to the storages listing
+func GetProcessingIndex(forceNewInstance ...bool) *ProcessingIndex {
+	newProcessingIndex := func() *ProcessingIndex {
+		indexConfig := config.GetConfig().ProcessingIndex
+		if !indexConfig.Enabled {
+			return &ProcessingIndex{}
+		}
+
+		index, err := NewProcessingIndex(indexConfig.Path)
+		if err != nil {
+			config.GetLogger().Errorf("Failed to open processing index %s: %s", indexConfig.Path, err)
+			return &ProcessingIndex{}
+		}
+
+		return index
+	}
+
+	if len(forceNewInstance) > 0 && forceNewInstance[0] {
+		if processingIndexInstance != nil {
+			processingIndexInstance.Close()
+		}
+		newInstance := newProcessingIndex()
+		processingIndexInstance = newInstance
+		onceProcessingIndex = sync.Once{}
+		return newInstance
+	}
+
+	onceProcessingIndex.Do(func() {
+		processingIndexInstance = newProcessingIndex()
+	})
+
+	return processingIndexInstance
+}
+
+// ProcessingIndexEntry is a single log & status of the processing saved in the storage
+type ProcessingIndexEntry struct {
+	PipelineSlug string          `json:"pipeline_slug"`
+	ProcessingId uuid.UUID       `json:"id"`
+	LogId        uuid.UUID       `json:"log_id"`
+	Storage      string          `json:"storage"`
+	IsStopped    bool            `json:"is_stopped"`
+	IsCompleted  bool            `json:"is_completed"`
+	IsError      bool            `json:"is_error"`
+	DateFinished time.Time       `json:"date_finished"`
+	LogPath      string          `json:"log_path"`
+	StatusPath   string          `json:"status_path"`
+	Status       json.RawMessage `json:"status"`
+}
+
+// NewProcessingIndexEntry builds the entry from the status file content
+func NewProcessingIndexEntry(
+	pipelineSlug string,
+	storageName string,
+	logPath string,
+	statusPath string,
+	statusContent []byte,
+) (ProcessingIndexEntry, error) {
+	entry := ProcessingIndexEntry{}
+	if err := json.Unmarshal(statusContent, &entry); err != nil {
+		return entry, err
+	}
+
+	entry.PipelineSlug = pipelineSlug
+	entry.Storage = storageName
+	entry.LogPath = logPath
+	entry.StatusPath = statusPath
+	entry.Status = json.RawMessage(statusContent)
+
+	return entry, nil
+}
+
+func (e ProcessingIndexEntry) getKey() []byte {
+	return []byte(fmt.Sprintf("%s/%s", e.ProcessingId, e.LogId))
+}
+
+func (e ProcessingIndexEntry) getDateKey() []byte {
+	return []byte(fmt.Sprintf("%s/%s", formatProcessingIndexDate(e.DateFinished), e.getKey()))
+}
+
+func formatProcessingIndexDate(date time.Time) string {
+	return date.UTC().Format(PROCESSING_INDEX_DATE_FORMAT)
+}
+
+// GetStatus returns the processing status name of the entry
+func (e ProcessingIndexEntry) GetStatus() string {
+	switch {
+	case e.IsError:
+		return schemas.PROCESSING_STATUS_FAILED
+	case e.IsStopped:
+		return schemas.PROCESSING_STATUS_STOPPED
+	case e.IsCompleted:
+		return schemas.PROCESSING_STATUS_COMPLETED
+	default:
+		return schemas.PROCESSING_STATUS_INCOMPLETE
+	}
+}
+
+// ProcessingIndex keeps processing logs & statuses by storage, Pipeline, processing and log ID
+// so they are found without listing the storages
+type ProcessingIndex struct {
+	sync.Mutex
+
+	db *bolt.DB
+}
+
+func NewProcessingIndex(indexPath string) (*ProcessingIndex, error) {
+	if err := os.MkdirAll(filepath.Dir(indexPath), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(indexPath, 0600, &bolt.Options{Timeout: PROCESSING_INDEX_OPEN_TIMEOUT})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProcessingIndex{db: db}, nil
+}
+
+// getStorageBucketName identifies the storage, so changing the storage root
+// never mixes processings of different roots
+func getStorageBucketName(storageName string, storageDirectory string) []byte {
+	return []byte(fmt.Sprintf("%s:%s", storageName, storageDirectory))
+}
+
+func (i *ProcessingIndex) getDB() (*bolt.DB, error) {
+	i.Lock()
+	defer i.Unlock()
+
+	if i.db == nil {
+		return nil, ErrProcessingIndexDisabled
+	}
+
+	return i.db, nil
+}
+
+func (i *ProcessingIndex) IsEnabled() bool {
+	_, err := i.getDB()
+	return err == nil
+}
+
+func (i *ProcessingIndex) Close() error {
+	i.Lock()
+	defer i.Unlock()
+
+	if i.db == nil {
+		return nil
+	}
+
+	err := i.db.Close()
+	i.db = nil
+
+	return err
+}
+
+// Add saves the entry of the storage to the index
+func (i *ProcessingIndex) Add(storage interfaces.Storage, entry ProcessingIndexEntry) error {
+	db, err := i.getDB()
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := createProcessingIndexPipelineBucket(tx, storage, entry.PipelineSlug)
+		if err != nil {
+			return err
+		}
+
+		return putProcessingIndexEntry(bucket, entry)
+	})
+}
+
+func createProcessingIndexPipelineBucket(
+	tx *bolt.Tx,
+	storage interfaces.Storage,
+	pipelineSlug string,
+) (*bolt.Bucket, error) {
+	storageBucket, err := tx.CreateBucketIfNotExists(
+		getStorageBucketName(storage.GetStorageName(), storage.GetStorageDirectory()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return storageBucket.CreateBucketIfNotExists([]byte(pipelineSlug))
+}
+
+func getProcessingIndexPipelineBucket(
+	tx *bolt.Tx,
+	storage interfaces.Storage,
+	pipelineSlug string,
+) *bolt.Bucket {
+	storageBucket := tx.Bucket(
+		getStorageBucketName(storage.GetStorageName(), storage.GetStorageDirectory()),
+	)
+	if storageBucket == nil {
+		return nil
+	}
+
+	return storageBucket.Bucket([]byte(pipelineSlug))
+}
+
+// putProcessingIndexEntry saves the entry and its key by finish date,
+// replacing the finish date of the entry saved before
+func putProcessingIndexEntry(bucket *bolt.Bucket, entry ProcessingIndexEntry) error {
+	dateBucket, err := bucket.CreateBucketIfNotExists(processingIndexDateBucket)
+	if err != nil {
+		return err
+	}
+
+	if err := deleteProcessingIndexEntry(bucket, entry.getKey()); err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := bucket.Put(entry.getKey(), value); err != nil {
+		return err
+	}
+
+	return dateBucket.Put(entry.getDateKey(), entry.getKey())
+}
+
+// deleteProcessingIndexEntry deletes the entry and its key by finish date
+func deleteProcessingIndexEntry(bucket *bolt.Bucket, key []byte) error {
+	value := bucket.Get(key)
+	if value == nil {
+		return nil
+	}
+
+	entry := ProcessingIndexEntry{}
+	if err := json.Unmarshal(value, &entry); err == nil {
+		if dateBucket := bucket.Bucket(processingIndexDateBucket); dateBucket != nil {
+			if err := dateBucket.Delete(entry.getDateKey()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return bucket.Delete(key)
+}
+
+// markProcessingIndexIndexed saves the time the Pipeline was indexed at from the storage
+func markProcessingIndexIndexed(tx *bolt.Tx, storage interfaces.Storage, pipelineSlug string) error {
+	pipelinesBucket, err := tx.CreateBucketIfNotExists(processingIndexPipelinesBucket)
+	if err != nil {
+		return err
+	}
+	indexedStorageBucket, err := pipelinesBucket.CreateBucketIfNotExists(
+		getStorageBucketName(storage.GetStorageName(), storage.GetStorageDirectory()),
+	)
+	if err != nil {
+		return err
+	}
+	indexedAt, _ := time.Now().UTC().MarshalText()
+
+	return indexedStorageBucket.Put([]byte(pipelineSlug), indexedAt)
+}
+
+// IsIndexed checks if the Pipeline processings were indexed from all the storages
+func (i *ProcessingIndex) IsIndexed(pipelineSlug string, storages []interfaces.Storage) bool {
+	return !i.getIndexedAt(pipelineSlug, storages).IsZero()
+}
+
+// getIndexedAt returns the time the Pipeline was indexed at from the storages, the earliest one.
+// Zero time means some storage was never indexed
+func (i *ProcessingIndex) getIndexedAt(pipelineSlug string, storages []interfaces.Storage) time.Time {
+	db, err := i.getDB()
+	if err != nil {
+		return time.Time{}
+	}
+
+	var indexedAt time.Time
+	db.View(func(tx *bolt.Tx) error {
+		pipelinesBucket := tx.Bucket(processingIndexPipelinesBucket)
+		for _, storage := range storages {
+			var storageBucket *bolt.Bucket
+			if pipelinesBucket != nil {
+				storageBucket = pipelinesBucket.Bucket(
+					getStorageBucketName(storage.GetStorageName(), storage.GetStorageDirectory()),
+				)
+			}
+			if storageBucket == nil {
+				indexedAt = time.Time{}
+				return nil
+			}
+
+			storageIndexedAt := time.Time{}
+			if value := storageBucket.Get([]byte(pipelineSlug)); value == nil || storageIndexedAt.UnmarshalText(value) != nil {
+				indexedAt = time.Time{}
+				return nil
+			}
+			if indexedAt.IsZero() || storageIndexedAt.Before(indexedAt) {
+				indexedAt = storageIndexedAt
+			}
+		}
+		return nil
+	})
+
+	return indexedAt
+}
+
+// forEachEntry calls the handler for the Pipeline entries of the storages with the key prefix
+func (i *ProcessingIndex) forEachEntry(
+	pipelineSlug string,
+	storages []interfaces.Storage,
+	prefix []byte,
+	handler func(ProcessingIndexEntry),
+) error {
+	db, err := i.getDB()
+	if err != nil {
+		return err
+	}
+
+	return db.View(func(tx *bolt.Tx) error {
+		for _, storage := range storages {
+			bucket := getProcessingIndexPipelineBucket(tx, storage, pipelineSlug)
+			if bucket == nil {
+				continue
+			}
+
+			cursor := bucket.Cursor()
+			for key, value := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+				entry := ProcessingIndexEntry{}
+				// Nested buckets have nil values
+				if value == nil {
+					continue
+				}
+				if err := json.Unmarshal(value, &entry); err == nil {
+					handler(entry)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// GetProcessingEntries returns entries of the processing ordered by finish date
+func (i *ProcessingIndex) GetProcessingEntries(
+	pipelineSlug string,
+	processingId uuid.UUID,
+	storages []interfaces.Storage,
+) []ProcessingIndexEntry {
+	entries := make([]ProcessingIndexEntry, 0)
+
+	i.forEachEntry(
+		pipelineSlug,
+		storages,
+		[]byte(processingId.String()+"/"),
+		func(entry ProcessingIndexEntry) {
+			entries = append(entries, entry)
+		},
+	)
+
+	sort.SliceStable(entries, func(a, b int) bool {
+		return entries[a].DateFinished.Before(entries[b].DateFinished)
+	})
+
+	return entries
+}
+
+// Query returns a page of the Pipeline entries matching the query and the total number of matches.
+// The entries are read in the order of the finish date, only the entries of the page are loaded
+// unless they are filtered by status
+func (i *ProcessingIndex) Query(
+	pipelineSlug string,
+	query schemas.PipelineProcessingsQuerySchema,
+	storages []interfaces.Storage,
+) ([]ProcessingIndexEntry, int, error) {
+	page := make([]ProcessingIndexEntry, 0)
+	total := 0
+
+	db, err := i.getDB()
+	if err != nil {
+		return page, total, err
+	}
+
+	descending := query.Sort != schemas.PROCESSINGS_SORT_DATE_FINISHED
+
+	err = db.View(func(tx *bolt.Tx) error {
+		cursors := make([]*processingIndexDateCursor, 0, len(storages))
+		for _, storage := range storages {
+			bucket := getProcessingIndexPipelineBucket(tx, storage, pipelineSlug)
+			if bucket == nil || bucket.Bucket(processingIndexDateBucket) == nil {
+				continue
+			}
+			cursors = append(cursors, newProcessingIndexDateCursor(bucket, query.From, query.To, descending))
+		}
+
+		for {
+			// The entries of the storages are merged by finish date
+			var next *processingIndexDateCursor
+			for _, cursor := range cursors {
+				if !cursor.isValid() {
+					continue
+				}
+				if next == nil {
+					next = cursor
+					continue
+				}
+				comparison := bytes.Compare(cursor.key, next.key)
+				if (!descending && comparison < 0) || (descending && comparison > 0) {
+					next = cursor
+				}
+			}
+			if next == nil {
+				return nil
+			}
+
+			value := next.bucket.Get(next.value)
+			next.advance()
+			if value == nil {
+				continue
+			}
+
+			inPage := total >= query.Offset && (query.Limit <= 0 || len(page) < query.Limit)
+			if query.Status == "" && !inPage {
+				total++
+				continue
+			}
+
+			entry := ProcessingIndexEntry{}
+			if err := json.Unmarshal(value, &entry); err != nil {
+				continue
+			}
+			if query.Status != "" && entry.GetStatus() != query.Status {
+				continue
+			}
+			if inPage {
+				page = append(page, entry)
+			}
+			total++
+		}
+	})
+
+	return page, total, err
+}
+
+// processingIndexDateCursor iterates the keys of the Pipeline entries by finish date within the range
+type processingIndexDateCursor struct {
+	bucket     *bolt.Bucket
+	cursor     *bolt.Cursor
+	descending bool
+	from       []byte
+	to         []byte
+
+	key   []byte
+	value []byte
+}
+
+func newProcessingIndexDateCursor(
+	bucket *bolt.Bucket,
+	from *time.Time,
+	to *time.Time,
+	descending bool,
+) *processingIndexDateCursor {
+	c := &processingIndexDateCursor{
+		bucket:     bucket,
+		cursor:     bucket.Bucket(processingIndexDateBucket).Cursor(),
+		descending: descending,
+	}
+	if from != nil {
+		c.from = []byte(formatProcessingIndexDate(*from))
+	}
+	if to != nil {
+		c.to = []byte(formatProcessingIndexDate(*to))
+	}
+
+	switch {
+	case !descending && c.from != nil:
+		c.key, c.value = c.cursor.Seek(c.from)
+	case !descending:
+		c.key, c.value = c.cursor.First()
+	case c.to != nil:
+		// The first key after all the keys finished at the "to" date
+		upper := append(append([]byte{}, c.to...), '/'+1)
+		if c.key, c.value = c.cursor.Seek(upper); c.key == nil {
+			c.key, c.value = c.cursor.Last()
+		} else {
+			c.key, c.value = c.cursor.Prev()
+		}
+	default:
+		c.key, c.value = c.cursor.Last()
+	}
+
+	return c
+}
+
+func (c *processingIndexDateCursor) isValid() bool {
+	if c.key == nil || len(c.key) < len(PROCESSING_INDEX_DATE_FORMAT) {
+		return false
+	}
+
+	date := c.key[:len(PROCESSING_INDEX_DATE_FORMAT)]
+	if c.from != nil && bytes.Compare(date, c.from) < 0 {
+		return false
+	}
+	if c.to != nil && bytes.Compare(date, c.to) > 0 {
+		return false
+	}
+
+	return true
+}
+
+func (c *processingIndexDateCursor) advance() {
+	if c.descending {
+		c.key, c.value = c.cursor.Prev()
+	} else {
+		c.key, c.value = c.cursor.Next()
+	}
+}
+
+// QueryProcessingIndexEntries filters, sorts and paginates the entries.
+// It returns the page and the total number of matching entries
+func QueryProcessingIndexEntries(
+	entries []ProcessingIndexEntry,
+	query schemas.PipelineProcessingsQuerySchema,
+) ([]ProcessingIndexEntry, int) {
+	matches := make([]ProcessingIndexEntry, 0, len(entries))
+	for _, entry := range entries {
+		if query.Status != "" && entry.GetStatus() != query.Status {
+			continue
+		}
+		if query.From != nil && entry.DateFinished.Before(*query.From) {
+			continue
+		}
+		if query.To != nil && entry.DateFinished.After(*query.To) {
+			continue
+		}
+		matches = append(matches, entry)
+	}
+
+	descending := query.Sort != schemas.PROCESSINGS_SORT_DATE_FINISHED
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].DateFinished.Equal(matches[b].DateFinished) {
+			return string(matches[a].getKey()) < string(matches[b].getKey())
+		}
+		if descending {
+			return matches[a].DateFinished.After(matches[b].DateFinished)
+		}
+		return matches[a].DateFinished.Before(matches[b].DateFinished)
+	})
+
+	total := len(matches)
+	if query.Offset >= total {
+		return make([]ProcessingIndexEntry, 0), total
+	}
+	end := total
+	if query.Limit > 0 && query.Offset+query.Limit < total {
+		end = query.Offset + query.Limit
+	}
+
+	return matches[query.Offset:end], total
+}
+
+// DeleteProcessing removes all entries of the processing in the storage
+func (i *ProcessingIndex) DeleteProcessing(
+	storage interfaces.Storage,
+	pipelineSlug string,
+	processingId uuid.UUID,
+) error {
+	db, err := i.getDB()
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := getProcessingIndexPipelineBucket(tx, storage, pipelineSlug)
+		if bucket == nil {
+			return nil
+		}
+
+		return deleteProcessingIndexProcessing(bucket, processingId)
+	})
+}
+
+// deleteProcessingIndexProcessing deletes all entries of the processing from the Pipeline bucket
+func deleteProcessingIndexProcessing(bucket *bolt.Bucket, processingId uuid.UUID) error {
+	prefix := []byte(processingId.String() + "/")
+
+	keys := make([][]byte, 0)
+	cursor := bucket.Cursor()
+	for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+		keys = append(keys, append([]byte{}, key...))
+	}
+	for _, key := range keys {
+		if err := deleteProcessingIndexEntry(bucket, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Rebuild replaces the Pipeline entries with the logs & statuses found in the storages.
+// It returns the number of indexed entries
+func (i *ProcessingIndex) Rebuild(pipelineSlug string, storages []interfaces.Storage) (int, error) {
+	db, err := i.getDB()
+	if err != nil {
+		return 0, err
+	}
+
+	indexed := 0
+	for _, storage := range storages {
+		entries, err := ListProcessingIndexEntries(storage, pipelineSlug)
+		if err != nil {
+			return indexed, err
+		}
+
+		err = db.Update(func(tx *bolt.Tx) error {
+			storageBucket, err := tx.CreateBucketIfNotExists(
+				getStorageBucketName(storage.GetStorageName(), storage.GetStorageDirectory()),
+			)
+			if err != nil {
+				return err
+			}
+			if storageBucket.Bucket([]byte(pipelineSlug)) != nil {
+				if err := storageBucket.DeleteBucket([]byte(pipelineSlug)); err != nil {
+					return err
+				}
+			}
+			bucket, err := storageBucket.CreateBucket([]byte(pipelineSlug))
+			if err != nil {
+				return err
+			}
+
+			for _, entry := range entries {
+				if err := putProcessingIndexEntry(bucket, entry); err != nil {
+					return err
+				}
+			}
+
+			return markProcessingIndexIndexed(tx, storage, pipelineSlug)
+		})
+		if err != nil {
+			return indexed, err
+		}
+		indexed += len(entries)
+	}
+
+	return indexed, nil
+}
+
+// ListProcessingIndexEntries reads the logs & statuses of all the Pipeline processings in the storage.
+// Only the Pipeline and the processing directories are listed, not the block outputs
+func ListProcessingIndexEntries(storage interfaces.Storage, pipelineSlug string) ([]ProcessingIndexEntry, error) {
+	entries := make([]ProcessingIndexEntry, 0)
+
+	processingKeys, err := listStorageDirectory(storage, pipelineSlug)
+	if err != nil {
+		return entries, err
+	}
+
+	for _, processingKey := range processingKeys {
+		if _, err := uuid.Parse(path.Base(processingKey)); err != nil {
+			continue
+		}
+		entries = append(entries, scanProcessingIndexEntries(storage, pipelineSlug, processingKey, nil)...)
+	}
+
+	return entries, nil
+}
+
+// Refresh indexes the Pipeline logs & statuses saved to the storages since the last refresh,
+// e.g. by the other workers, and removes the processings deleted from the storages.
+// Only the Pipeline and the processing directories are listed and only the new statuses are read,
+// the completed processings are not listed again. It returns the number of indexed entries
+func (i *ProcessingIndex) Refresh(pipelineSlug string, storages []interfaces.Storage) (int, error) {
+	db, err := i.getDB()
+	if err != nil {
+		return 0, err
+	}
+
+	indexed := 0
+	errs := make([]error, 0)
+	for _, storage := range storages {
+		processingKeys, err := listStorageDirectory(storage, pipelineSlug)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s storage: %w", storage.GetStorageName(), err))
+			continue
+		}
+
+		indexedProcessings := i.getIndexedProcessings(storage, pipelineSlug)
+
+		listed := make(map[uuid.UUID]bool)
+		entries := make([]ProcessingIndexEntry, 0)
+		for _, processingKey := range processingKeys {
+			processingId, err := uuid.Parse(path.Base(processingKey))
+			if err != nil {
+				continue
+			}
+			listed[processingId] = true
+
+			indexedProcessing, ok := indexedProcessings[processingId]
+			if ok && indexedProcessing.isCompleted() {
+				continue
+			}
+			entries = append(
+				entries,
+				scanProcessingIndexEntries(storage, pipelineSlug, processingKey, indexedProcessing.statusPaths)...,
+			)
+		}
+
+		err = db.Update(func(tx *bolt.Tx) error {
+			bucket, err := createProcessingIndexPipelineBucket(tx, storage, pipelineSlug)
+			if err != nil {
+				return err
+			}
+
+			for _, entry := range entries {
+				if err := putProcessingIndexEntry(bucket, entry); err != nil {
+					return err
+				}
+			}
+			for processingId := range indexedProcessings {
+				if listed[processingId] {
+					continue
+				}
+				if err := deleteProcessingIndexProcessing(bucket, processingId); err != nil {
+					return err
+				}
+			}
+
+			return markProcessingIndexIndexed(tx, storage, pipelineSlug)
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		indexed += len(entries)
+	}
+
+	return indexed, errors.Join(errs...)
+}
+
+// indexedProcessing keeps the status paths and the latest entry of the indexed processing
+type indexedProcessing struct {
+	statusPaths map[string]bool
+	latest      ProcessingIndexEntry
+}
+
+// isCompleted reports if the latest status of the processing is completed.
+// Failed, stopped and unfinished processings may be retried or resumed with new logs
+func (p indexedProcessing) isCompleted() bool {
+	return p.latest.GetStatus() == schemas.PROCESSING_STATUS_COMPLETED
+}
+
+// getIndexedProcessings returns the processings of the Pipeline indexed from the storage
+func (i *ProcessingIndex) getIndexedProcessings(
+	storage interfaces.Storage,
+	pipelineSlug string,
+) map[uuid.UUID]indexedProcessing {
+	processings := make(map[uuid.UUID]indexedProcessing)
+
+	i.forEachEntry(
+		pipelineSlug,
+		[]interfaces.Storage{storage},
+		nil,
+		func(entry ProcessingIndexEntry) {
+			processing, ok := processings[entry.ProcessingId]
+			if !ok {
+				processing = indexedProcessing{statusPaths: make(map[string]bool)}
+			}
+			processing.statusPaths[entry.StatusPath] = true
+			if !ok || entry.DateFinished.After(processing.latest.DateFinished) {
+				processing.latest = entry
+			}
+			processings[entry.ProcessingId] = processing
+		},
+	)
+
+	return processings
+}
+
+// scanProcessingIndexEntries reads the logs & statuses of the processing directory in the storage,
+// except the already indexed statuses
+func scanProcessingIndexEntries(
+	storage interfaces.Storage,
+	pipelineSlug string,
+	processingKey string,
+	indexedStatusPaths map[string]bool,
+) []ProcessingIndexEntry {
+	entries := make([]ProcessingIndexEntry, 0)
+
+	keys, err := listStorageDirectory(storage, processingKey)
+	if err != nil {
+		return entries
+	}
+
+	// Logs & statuses of the same processing run share the timestamp suffix
+	logPaths := make(map[string]string)
+	statusPaths := make(map[string]string)
+	for _, key := range keys {
+		fileName := path.Base(key)
+		matches := processingIndexFileRegex.FindStringSubmatch(strings.TrimSuffix(fileName, path.Ext(fileName)))
+		if len(matches) != 3 {
+			continue
+		}
+
+		switch matches[1] {
+		case "log":
+			logPaths[matches[2]] = key
+		case "status":
+			statusPaths[matches[2]] = key
+		}
+	}
+
+	for timestamp, statusPath := range statusPaths {
+		if indexedStatusPaths[statusPath] {
+			continue
+		}
+
+		statusContent, err := storage.GetObjectBytes(storage.NewStorageLocation(statusPath))
+		if err != nil {
+			continue
+		}
+
+		entry, err := NewProcessingIndexEntry(
+			pipelineSlug,
+			storage.GetStorageName(),
+			logPaths[timestamp],
+			statusPath,
+			statusContent.Bytes(),
+		)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// listStorageDirectory returns the keys of the files and the directories directly inside the prefix,
+// the empty prefix is the storage root. Storages which can not list a single directory list the nested
+// objects, which are grouped by directory
+func listStorageDirectory(storage interfaces.Storage, prefix string) ([]string, error) {
+	var objects []interfaces.StorageLocation
+	var err error
+
+	if directoryStorage, ok := storage.(interfaces.DirectoryStorage); ok {
+		objects, err = directoryStorage.ListDirectory(storage.NewStorageLocation(prefix))
+	} else {
+		objects, err = storage.ListObjects(storage.NewStorageLocation(prefix))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(objects))
+	listed := make(map[string]bool)
+	for _, object := range objects {
+		key := strings.TrimSuffix(GetStorageObjectKey(storage, object), "/")
+		if prefix != "" {
+			if !strings.HasPrefix(key, prefix+"/") {
+				continue
+			}
+			key = strings.TrimPrefix(key, prefix+"/")
+		}
+		if key == "" || key == "." {
+			continue
+		}
+
+		childKey := path.Join(prefix, strings.SplitN(key, "/", 2)[0])
+		if !listed[childKey] {
+			listed[childKey] = true
+			keys = append(keys, childKey)
+		}
+	}
+
+	return keys, nil
+}
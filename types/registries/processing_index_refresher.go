@@ -0,0 +1,109 @@
+package registries
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/interfaces"
+)
+
+// ProcessingIndexRefresher periodically indexes the processings saved to the storages
+// by the other workers, so the status requests never list the storages
+type ProcessingIndexRefresher struct {
+	sync.Mutex
+
+	pipelineRegistry interfaces.PipelineRegistry
+	refreshInterval  time.Duration
+
+	started  bool
+	stopOnce sync.Once
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Ensure ProcessingIndexRefresher implements the ProcessingIndexRefresher
+var _ interfaces.ProcessingIndexRefresher = (*ProcessingIndexRefresher)(nil)
+
+func NewProcessingIndexRefresher(pipelineRegistry interfaces.PipelineRegistry) *ProcessingIndexRefresher {
+	refreshInterval := config.GetConfig().ProcessingIndex.RefreshInterval
+	if refreshInterval <= 0 {
+		refreshInterval = config.DEFAULT_PROCESSING_INDEX_REFRESH
+	}
+
+	return &ProcessingIndexRefresher{
+		pipelineRegistry: pipelineRegistry,
+		refreshInterval:  refreshInterval,
+		stopChan:         make(chan struct{}),
+	}
+}
+
+// Start refreshes the Processing Index periodically until Shutdown is called
+func (r *ProcessingIndexRefresher) Start() {
+	r.Lock()
+	if r.started {
+		r.Unlock()
+		return
+	}
+	r.started = true
+	r.Unlock()
+
+	logger := config.GetLogger()
+	logger.Infof("Starting Processing Index refresh with interval %s", r.refreshInterval)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			r.Refresh()
+
+			select {
+			case <-r.stopChan:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (r *ProcessingIndexRefresher) Shutdown(ctx context.Context) error {
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Refresh indexes the processings of every registered Pipeline saved to the storages
+// since the last refresh and removes the deleted ones
+func (r *ProcessingIndexRefresher) Refresh() {
+	processingIndex := GetProcessingIndex()
+	if !processingIndex.IsEnabled() {
+		return
+	}
+
+	logger := config.GetLogger()
+	storages := r.pipelineRegistry.GetPipelineResultStorages()
+
+	for pipelineSlug := range r.pipelineRegistry.GetAll() {
+		if _, err := processingIndex.Refresh(pipelineSlug, storages); err != nil {
+			logger.Errorf("Failed to refresh processings index of Pipeline %s: %s", pipelineSlug, err)
+		}
+	}
+}
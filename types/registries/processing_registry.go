@@ -41,6 +41,7 @@ type ProcessingRegistry struct {
 	Processing                 map[uuid.UUID]interfaces.Processing
 	processingCompletedChannel chan interfaces.Processing
 	notificationChannel        chan interfaces.Processing // Channel for external notifications
+	readersWg                  sync.WaitGroup
 }
 
 // Ensure ProcessingRegistry implements the ProcessingRegistry
@@ -54,6 +55,7 @@ func NewProcessingRegistry() *ProcessingRegistry {
 	}
 
 	for i := 0; i < numReaders; i++ {
+		registry.readersWg.Add(1)
 		go registry.processCompleted()
 	}
 
@@ -61,6 +63,7 @@ func NewProcessingRegistry() *ProcessingRegistry {
 }
 
 func (pr *ProcessingRegistry) processCompleted() {
+	defer pr.readersWg.Done()
 
 	for processing := range pr.processingCompletedChannel {
 		// Copy the reference outside the lock
@@ -146,6 +149,9 @@ func (pr *ProcessingRegistry) Shutdown(ctx context.Context) error {
 		shutdownWg.Wait()
 		close(pr.processingCompletedChannel)
 		close(done)
+
+		// Readers may still be forwarding notifications
+		pr.readersWg.Wait()
 		if pr.notificationChannel != nil {
 			close(pr.notificationChannel)
 		}
@@ -0,0 +1,417 @@
+package registries
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/interfaces"
+)
+
+var PROCESSING_KEY_REGEX = regexp.MustCompile(
+	`^[^/]+/([a-f0-9]{8}-[a-f0-9]{4}-4[a-f0-9]{3}-[89aAbB][a-f0-9]{3}-[a-f0-9]{12})(/|$)`,
+)
+var PROCESSING_FINISHED_FILE_REGEX = regexp.MustCompile(`^(?:log|status)_(\d+)`)
+var PROCESSING_STATUS_FILE_REGEX = regexp.MustCompile(`^status_(\d+)`)
+
+// retentionProcessing keeps the objects of a processing found in the storages
+type retentionProcessing struct {
+	pipelineSlug string
+	processingId uuid.UUID
+	dateFinished time.Time
+	objects      map[interfaces.Storage][]interfaces.StorageLocation
+
+	// The latest status of the processing
+	statusTimestamp int64
+	statusLocation  interfaces.StorageLocation
+}
+
+// isTerminal reports if the latest status of the processing is completed, stopped or failed.
+// Processings waiting for the moderation or transferred to another worker have statuses too
+func (p *retentionProcessing) isTerminal() (bool, error) {
+	return isTerminalStatus(p.statusLocation)
+}
+
+// isTerminalStatus reports if the status is completed, stopped or failed. Missing status is not terminal
+func isTerminalStatus(statusLocation interfaces.StorageLocation) (bool, error) {
+	if statusLocation == nil {
+		return false, nil
+	}
+
+	statusBuffer, err := statusLocation.GetObjectBytes()
+	if err != nil {
+		return false, err
+	}
+
+	status := struct {
+		IsStopped   bool `json:"is_stopped"`
+		IsCompleted bool `json:"is_completed"`
+		IsError     bool `json:"is_error"`
+	}{}
+	if err := json.Unmarshal(statusBuffer.Bytes(), &status); err != nil {
+		return false, err
+	}
+
+	return status.IsCompleted || status.IsStopped || status.IsError, nil
+}
+
+// RetentionManager periodically deletes expired processing artifacts
+// according to the retention policy
+type RetentionManager struct {
+	sync.Mutex
+
+	pipelineRegistry interfaces.PipelineRegistry
+	policy           config.RetentionConfig
+
+	started  bool
+	stopOnce sync.Once
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Ensure RetentionManager implements the RetentionManager
+var _ interfaces.RetentionManager = (*RetentionManager)(nil)
+
+func NewRetentionManager(pipelineRegistry interfaces.PipelineRegistry) *RetentionManager {
+	policy := config.GetConfig().Retention
+	if policy.CheckInterval <= 0 {
+		policy.CheckInterval = config.DEFAULT_RETENTION_CHECK_INTERVAL
+	}
+
+	return &RetentionManager{
+		pipelineRegistry: pipelineRegistry,
+		policy:           policy,
+		stopChan:         make(chan struct{}),
+	}
+}
+
+func (m *RetentionManager) SetPolicy(policy config.RetentionConfig) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.policy = policy
+}
+
+func (m *RetentionManager) GetPolicy() config.RetentionConfig {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.policy
+}
+
+// Start applies the retention policy periodically until Shutdown is called
+func (m *RetentionManager) Start() {
+	m.Lock()
+	if m.started {
+		m.Unlock()
+		return
+	}
+	m.started = true
+	m.Unlock()
+
+	policy := m.GetPolicy()
+
+	logger := config.GetLogger()
+	logger.Infof(
+		"Starting retention of processings with check interval %s ( dry run: %t )",
+		policy.CheckInterval,
+		policy.DryRun,
+	)
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(policy.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			m.Apply(time.Now(), policy.DryRun)
+
+			select {
+			case <-m.stopChan:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (m *RetentionManager) Shutdown(ctx context.Context) error {
+	m.stopOnce.Do(func() {
+		close(m.stopChan)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Apply deletes processing artifacts which are expired at the given time.
+// In dry run the report lists the objects which would be deleted
+func (m *RetentionManager) Apply(now time.Time, dryRun bool) schemas.RetentionReportSchema {
+	logger := config.GetLogger()
+	policy := m.GetPolicy()
+
+	// Whole processings are kept as long as the intermediate outputs unless configured
+	keepFinalOutputsDays := policy.KeepFinalOutputsDays
+	if keepFinalOutputsDays <= 0 {
+		keepFinalOutputsDays = policy.KeepDays
+	}
+
+	report := schemas.RetentionReportSchema{
+		DryRun:      dryRun,
+		Date:        now.UTC(),
+		Processings: make([]schemas.RetentionReportProcessingSchema, 0),
+	}
+
+	pipelines := m.pipelineRegistry.GetAll()
+	for _, pipelineSlug := range m.getPipelineSlugs(pipelines) {
+		pipeline := pipelines[pipelineSlug]
+		processings := m.getProcessings(pipelineSlug)
+
+		// Latest processings first
+		sort.Slice(processings, func(i, j int) bool {
+			return processings[i].dateFinished.After(processings[j].dateFinished)
+		})
+
+		finalBlockSlug := ""
+		if pipeline != nil {
+			if blocks := pipeline.GetBlocks(); len(blocks) > 0 {
+				finalBlockSlug = blocks[len(blocks)-1].GetSlug()
+			}
+		}
+
+		for i, processing := range processings {
+			if i < policy.KeepLatest {
+				continue
+			}
+
+			age := now.Sub(processing.dateFinished)
+			action := ""
+			switch {
+			case keepFinalOutputsDays > 0 && age > time.Duration(keepFinalOutputsDays)*24*time.Hour:
+				action = schemas.RETENTION_ACTION_PURGE
+			case policy.KeepDays > 0 && age > time.Duration(policy.KeepDays)*24*time.Hour:
+				// The final block of the Pipelines removed from the catalogue is not known,
+				// their processings are purged as a whole only
+				if pipeline == nil {
+					continue
+				}
+				action = schemas.RETENTION_ACTION_PURGE_INTERMEDIATE
+			default:
+				continue
+			}
+
+			// Outputs of the processings which are not finished yet are still needed to resume them
+			if isTerminal, err := processing.isTerminal(); err != nil || !isTerminal {
+				if err != nil {
+					logger.Warnf(
+						"Retention skips processing %s of Pipeline %s with unreadable status: %s",
+						processing.processingId,
+						processing.pipelineSlug,
+						err,
+					)
+				}
+				continue
+			}
+
+			processingReport, applyErrors := m.applyAction(processing, action, finalBlockSlug, dryRun)
+			for _, err := range applyErrors {
+				logger.Errorf(
+					"Retention of processing %s of Pipeline %s failed: %s",
+					processing.processingId,
+					processing.pipelineSlug,
+					err,
+				)
+				report.Errors = append(report.Errors, err.Error())
+			}
+			if len(processingReport.Objects) > 0 {
+				report.Processings = append(report.Processings, processingReport)
+			}
+		}
+	}
+
+	logger.Infof(
+		"Retention of processings applied to %d processings ( dry run: %t )",
+		len(report.Processings),
+		dryRun,
+	)
+
+	return report
+}
+
+func (m *RetentionManager) applyAction(
+	processing *retentionProcessing,
+	action string,
+	finalBlockSlug string,
+	dryRun bool,
+) (schemas.RetentionReportProcessingSchema, []error) {
+	processingReport := schemas.RetentionReportProcessingSchema{
+		PipelineSlug: processing.pipelineSlug,
+		ProcessingID: processing.processingId,
+		DateFinished: processing.dateFinished,
+		Action:       action,
+		Objects:      make([]string, 0),
+	}
+	applyErrors := make([]error, 0)
+
+	processingPath := path.Join(processing.pipelineSlug, processing.processingId.String())
+
+	for storage, objects := range processing.objects {
+		expiredObjects := make([]interfaces.StorageLocation, 0)
+		for _, object := range objects {
+			if action == schemas.RETENTION_ACTION_PURGE_INTERMEDIATE {
+				// Keep logs, statuses, webhook deliveries and the final block outputs
+				relativeKey := strings.TrimPrefix(GetStorageObjectKey(storage, object), processingPath+"/")
+				blockSlug, _, _ := strings.Cut(relativeKey, "/")
+				if blockSlug == finalBlockSlug ||
+					PROCESSING_FINISHED_FILE_REGEX.MatchString(relativeKey) ||
+					WEBHOOKS_FILE_REGEX.MatchString(relativeKey) {
+					continue
+				}
+			}
+			expiredObjects = append(expiredObjects, object)
+		}
+
+		if dryRun {
+			for _, object := range expiredObjects {
+				processingReport.Objects = append(
+					processingReport.Objects,
+					fmt.Sprintf("%s:%s", storage.GetStorageName(), GetStorageObjectKey(storage, object)),
+				)
+			}
+			continue
+		}
+
+		if action == schemas.RETENTION_ACTION_PURGE {
+			if err := removeIndexedProcessing(storage, processing.pipelineSlug, processing.processingId); err != nil {
+				applyErrors = append(applyErrors, err)
+				continue
+			}
+		}
+
+		deleted, err := deleteProcessingObjects(storage, processingPath, expiredObjects)
+		processingReport.Objects = append(processingReport.Objects, deleted...)
+		if err != nil {
+			applyErrors = append(applyErrors, err)
+			continue
+		}
+
+		if action == schemas.RETENTION_ACTION_PURGE {
+			// Local storages keep the processing directory
+			processingLocation := storage.NewStorageLocation(processingPath)
+			if storage.LocationExists(processingLocation) {
+				if err := deleteProcessingObject(storage, processingPath, processingLocation); err != nil {
+					applyErrors = append(applyErrors, err)
+				}
+			}
+		}
+	}
+
+	sort.Strings(processingReport.Objects)
+
+	return processingReport, applyErrors
+}
+
+// getPipelineSlugs returns the slugs of the registered Pipelines and of the top level directories of the storages,
+// so the artifacts of the Pipelines removed from the catalogue expire too
+func (m *RetentionManager) getPipelineSlugs(pipelines map[string]interfaces.Pipeline) []string {
+	slugs := make(map[string]bool, len(pipelines))
+	for slug := range pipelines {
+		slugs[slug] = true
+	}
+
+	logger := config.GetLogger()
+	for _, storage := range m.pipelineRegistry.GetPipelineResultStorages() {
+		keys, err := listStorageDirectory(storage, "")
+		if err != nil {
+			logger.Warnf("Retention failed to list the pipelines of storage %s: %s", storage.GetStorageName(), err)
+			continue
+		}
+		for _, key := range keys {
+			slugs[key] = true
+		}
+	}
+
+	result := make([]string, 0, len(slugs))
+	for slug := range slugs {
+		result = append(result, slug)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// getProcessings returns processings of the Pipeline with logs or statuses found in the storages.
+// Processings without them are still running and never returned
+func (m *RetentionManager) getProcessings(pipelineSlug string) []*retentionProcessing {
+	processings := make(map[uuid.UUID]*retentionProcessing)
+
+	for _, storage := range m.pipelineRegistry.GetPipelineResultStorages() {
+		for _, object := range listStorageObjectsRecursive(storage, pipelineSlug) {
+			key := GetStorageObjectKey(storage, object)
+
+			matches := PROCESSING_KEY_REGEX.FindStringSubmatch(key)
+			if len(matches) != 3 || matches[2] == "" {
+				// Processing directory itself is deleted separately
+				continue
+			}
+			processingId := uuid.MustParse(matches[1])
+
+			processing, ok := processings[processingId]
+			if !ok {
+				processing = &retentionProcessing{
+					pipelineSlug: pipelineSlug,
+					processingId: processingId,
+					objects:      make(map[interfaces.Storage][]interfaces.StorageLocation),
+				}
+				processings[processingId] = processing
+			}
+			processing.objects[storage] = append(processing.objects[storage], object)
+
+			// Logs and statuses are named <type>_<unix timestamp>
+			if finishedMatches := PROCESSING_FINISHED_FILE_REGEX.FindStringSubmatch(path.Base(key)); len(finishedMatches) == 2 &&
+				path.Dir(key) == path.Join(pipelineSlug, processingId.String()) {
+				if timestamp, err := strconv.ParseInt(finishedMatches[1], 10, 64); err == nil {
+					if dateFinished := time.Unix(timestamp, 0); dateFinished.After(processing.dateFinished) {
+						processing.dateFinished = dateFinished
+					}
+					if PROCESSING_STATUS_FILE_REGEX.MatchString(path.Base(key)) && timestamp >= processing.statusTimestamp {
+						processing.statusTimestamp = timestamp
+						processing.statusLocation = object
+					}
+				}
+			}
+		}
+	}
+
+	result := make([]*retentionProcessing, 0, len(processings))
+	for _, processing := range processings {
+		if !processing.dateFinished.IsZero() {
+			result = append(result, processing)
+		}
+	}
+
+	return result
+}
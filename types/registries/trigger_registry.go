@@ -0,0 +1,637 @@
+package registries
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/interfaces"
+)
+
+const (
+	TRIGGERS_STORAGE_PREFIX          = "_triggers"
+	TRIGGERS_PREFERRED_CLAIM_STORAGE = "minio"
+)
+
+var TRIGGER_CLAIM_FILE_REGEX = regexp.MustCompile(`^run_(\d+)`)
+
+// PipelineTriggerState keeps the schedule state of a single Pipeline Trigger
+//
+// swagger:model
+type PipelineTriggerState struct {
+	lock sync.Mutex
+
+	PipelineSlug     string                     `json:"pipeline_slug"`
+	Trigger          interfaces.PipelineTrigger `json:"trigger"`
+	LastRun          time.Time                  `json:"last_run,omitempty"`
+	NextRun          time.Time                  `json:"next_run,omitempty"`
+	LastProcessingId uuid.UUID                  `json:"last_processing_id,omitempty"`
+	LastError        string                     `json:"last_error,omitempty"`
+}
+
+// Ensure PipelineTriggerState implements the PipelineTriggerState
+var _ interfaces.PipelineTriggerState = (*PipelineTriggerState)(nil)
+
+func NewPipelineTriggerState(
+	pipelineSlug string,
+	trigger interfaces.PipelineTrigger,
+) *PipelineTriggerState {
+	return &PipelineTriggerState{
+		PipelineSlug: pipelineSlug,
+		Trigger:      trigger,
+	}
+}
+
+func GetPipelineTriggerStateId(pipelineSlug string, triggerSlug string) string {
+	return fmt.Sprintf("%s:%s", pipelineSlug, triggerSlug)
+}
+
+func (s *PipelineTriggerState) GetId() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return GetPipelineTriggerStateId(s.PipelineSlug, s.Trigger.GetSlug())
+}
+
+func (s *PipelineTriggerState) GetPipelineSlug() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.PipelineSlug
+}
+
+func (s *PipelineTriggerState) GetTrigger() interfaces.PipelineTrigger {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.Trigger
+}
+
+func (s *PipelineTriggerState) GetLastRun() time.Time {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.LastRun
+}
+
+func (s *PipelineTriggerState) GetNextRun() time.Time {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.NextRun
+}
+
+func (s *PipelineTriggerState) GetLastProcessingId() uuid.UUID {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.LastProcessingId
+}
+
+func (s *PipelineTriggerState) SetNextRun(nextRun time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.NextRun = nextRun
+}
+
+func (s *PipelineTriggerState) MarshalJSON() ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var lastRun, nextRun *time.Time
+	if !s.LastRun.IsZero() {
+		lastRun = &s.LastRun
+	}
+	if !s.NextRun.IsZero() {
+		nextRun = &s.NextRun
+	}
+
+	var lastProcessingId *uuid.UUID
+	if s.LastProcessingId != uuid.Nil {
+		lastProcessingId = &s.LastProcessingId
+	}
+
+	return json.Marshal(&struct {
+		Id               string                     `json:"id"`
+		PipelineSlug     string                     `json:"pipeline_slug"`
+		Trigger          interfaces.PipelineTrigger `json:"trigger"`
+		Enabled          bool                       `json:"enabled"`
+		LastRun          *time.Time                 `json:"last_run,omitempty"`
+		NextRun          *time.Time                 `json:"next_run,omitempty"`
+		LastProcessingId *uuid.UUID                 `json:"last_processing_id,omitempty"`
+		LastError        string                     `json:"last_error,omitempty"`
+	}{
+		Id:               GetPipelineTriggerStateId(s.PipelineSlug, s.Trigger.GetSlug()),
+		PipelineSlug:     s.PipelineSlug,
+		Trigger:          s.Trigger,
+		Enabled:          s.Trigger.IsEnabled(),
+		LastRun:          lastRun,
+		NextRun:          nextRun,
+		LastProcessingId: lastProcessingId,
+		LastError:        s.LastError,
+	})
+}
+
+// TriggerRegistry is a registry for scheduled Pipeline Triggers.
+// Several workers may share the same Pipelines Catalogue, so every scheduled run
+// is claimed in the shared storage first and only the claiming worker starts the Pipeline.
+type TriggerRegistry struct {
+	sync.Mutex
+
+	Id       uuid.UUID
+	Triggers map[string]*PipelineTriggerState
+
+	pipelineRegistry interfaces.PipelineRegistry
+	checkInterval    time.Duration
+
+	started  bool
+	stopOnce sync.Once
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Ensure TriggerRegistry implements the TriggerRegistry
+var _ interfaces.TriggerRegistry = (*TriggerRegistry)(nil)
+
+func NewTriggerRegistry(pipelineRegistry interfaces.PipelineRegistry) *TriggerRegistry {
+	_config := config.GetConfig()
+
+	checkInterval := _config.Triggers.CheckInterval
+	if checkInterval <= 0 {
+		checkInterval = config.DEFAULT_TRIGGERS_CHECK_INTERVAL
+	}
+
+	registry := &TriggerRegistry{
+		Id:               uuid.New(),
+		Triggers:         make(map[string]*PipelineTriggerState),
+		pipelineRegistry: pipelineRegistry,
+		checkInterval:    checkInterval,
+		stopChan:         make(chan struct{}),
+	}
+
+	registry.SyncTriggers()
+
+	return registry
+}
+
+func (r *TriggerRegistry) Add(state interfaces.PipelineTriggerState) {
+	r.Lock()
+	defer r.Unlock()
+
+	triggerState, ok := state.(*PipelineTriggerState)
+	if !ok {
+		triggerState = NewPipelineTriggerState(
+			state.GetPipelineSlug(),
+			state.GetTrigger(),
+		)
+		triggerState.LastRun = state.GetLastRun()
+		triggerState.NextRun = state.GetNextRun()
+		triggerState.LastProcessingId = state.GetLastProcessingId()
+	}
+
+	r.Triggers[triggerState.GetId()] = triggerState
+}
+
+func (r *TriggerRegistry) Get(id string) interfaces.PipelineTriggerState {
+	r.Lock()
+	defer r.Unlock()
+
+	if state, ok := r.Triggers[id]; ok {
+		return state
+	}
+
+	return nil
+}
+
+func (r *TriggerRegistry) GetAll() map[string]interfaces.PipelineTriggerState {
+	r.Lock()
+	defer r.Unlock()
+
+	states := make(map[string]interfaces.PipelineTriggerState, len(r.Triggers))
+	for id, state := range r.Triggers {
+		states[id] = state
+	}
+
+	return states
+}
+
+func (r *TriggerRegistry) Delete(id string) {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.Triggers, id)
+}
+
+func (r *TriggerRegistry) DeleteAll() {
+	r.Lock()
+	defer r.Unlock()
+
+	for id := range r.Triggers {
+		delete(r.Triggers, id)
+	}
+}
+
+func (r *TriggerRegistry) Shutdown(ctx context.Context) error {
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// GetPipelineTriggers returns the Triggers states of the Pipeline sorted by Trigger slug
+func (r *TriggerRegistry) GetPipelineTriggers(pipelineSlug string) []interfaces.PipelineTriggerState {
+	r.Lock()
+	defer r.Unlock()
+
+	states := make([]interfaces.PipelineTriggerState, 0)
+	for _, state := range r.Triggers {
+		if state.GetPipelineSlug() == pipelineSlug {
+			states = append(states, state)
+		}
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].GetId() < states[j].GetId()
+	})
+
+	return states
+}
+
+// Start runs the scheduler loop until Shutdown is called
+func (r *TriggerRegistry) Start() {
+	r.Lock()
+	if r.started {
+		r.Unlock()
+		return
+	}
+	r.started = true
+	r.Unlock()
+
+	logger := config.GetLogger()
+	logger.Infof("Starting Pipeline Triggers scheduler with check interval %s", r.checkInterval)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.checkInterval)
+		defer ticker.Stop()
+
+		for {
+			r.SyncTriggers()
+			r.CheckTriggers(time.Now())
+
+			select {
+			case <-r.stopChan:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// SyncTriggers adds Triggers of registered Pipelines and removes Triggers
+// which are not defined anymore
+func (r *TriggerRegistry) SyncTriggers() {
+	now := time.Now()
+	known := make(map[string]bool)
+
+	for _, pipeline := range r.pipelineRegistry.GetAll() {
+		for _, trigger := range pipeline.GetTriggers() {
+			id := GetPipelineTriggerStateId(pipeline.GetSlug(), trigger.GetSlug())
+			known[id] = true
+
+			r.Lock()
+			state, exists := r.Triggers[id]
+			r.Unlock()
+
+			if exists && state.GetTrigger() == trigger {
+				continue
+			}
+
+			if !exists {
+				state = NewPipelineTriggerState(pipeline.GetSlug(), trigger)
+				state.LastRun = r.getLastClaimedRun(pipeline.GetSlug(), trigger.GetSlug())
+			}
+
+			state.lock.Lock()
+			state.Trigger = trigger
+			state.NextRun = time.Time{}
+			if trigger.IsEnabled() {
+				after := now
+				if state.LastRun.After(after) {
+					after = state.LastRun
+				}
+				if nextRun, err := trigger.GetNextRun(after); err == nil {
+					state.NextRun = nextRun
+				} else {
+					state.LastError = err.Error()
+				}
+			}
+			state.lock.Unlock()
+
+			r.Lock()
+			r.Triggers[id] = state
+			r.Unlock()
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	for id := range r.Triggers {
+		if !known[id] {
+			delete(r.Triggers, id)
+		}
+	}
+}
+
+// CheckTriggers fires every enabled Trigger which is due at the given time
+func (r *TriggerRegistry) CheckTriggers(now time.Time) {
+	r.Lock()
+	dueStates := make([]*PipelineTriggerState, 0)
+	for _, state := range r.Triggers {
+		nextRun := state.GetNextRun()
+		if !nextRun.IsZero() && !now.Before(nextRun) {
+			dueStates = append(dueStates, state)
+		}
+	}
+	r.Unlock()
+
+	for _, state := range dueStates {
+		r.fireTrigger(state, now)
+	}
+}
+
+func (r *TriggerRegistry) fireTrigger(state *PipelineTriggerState, now time.Time) {
+	logger := config.GetLogger()
+
+	pipelineSlug := state.GetPipelineSlug()
+	trigger := state.GetTrigger()
+	scheduledAt := state.GetNextRun()
+
+	nextRun, err := trigger.GetNextRun(scheduledAt)
+	if err == nil && !nextRun.After(now) {
+		// Skip the runs missed while the worker was busy or stopped
+		nextRun, err = trigger.GetNextRun(now)
+	}
+	if err != nil {
+		nextRun = time.Time{}
+	}
+
+	claimed, err := r.claimRun(pipelineSlug, trigger.GetSlug(), scheduledAt)
+	if err != nil {
+		logger.Errorf(
+			"Failed to claim Trigger [%s] of Pipeline %s scheduled at %s: %s",
+			trigger.GetSlug(),
+			pipelineSlug,
+			scheduledAt,
+			err,
+		)
+
+		state.lock.Lock()
+		state.NextRun = nextRun
+		state.LastError = err.Error()
+		state.lock.Unlock()
+		return
+	}
+
+	if !claimed {
+		logger.Infof(
+			"Trigger [%s] of Pipeline %s scheduled at %s is claimed by another worker",
+			trigger.GetSlug(),
+			pipelineSlug,
+			scheduledAt,
+		)
+
+		state.lock.Lock()
+		state.LastRun = scheduledAt
+		state.NextRun = nextRun
+		state.lock.Unlock()
+		return
+	}
+
+	processingId, err := r.pipelineRegistry.StartPipeline(
+		schemas.PipelineStartInputSchema{
+			Pipeline: schemas.PipelineInputSchema{
+				Slug:  pipelineSlug,
+				Input: trigger.GetPipelineInput(),
+			},
+			Block: schemas.BlockInputSchema{
+				Slug:        trigger.GetBlockSlug(),
+				Input:       trigger.GetInput(),
+				TargetIndex: -1,
+			},
+		},
+	)
+
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	state.LastRun = scheduledAt
+	state.NextRun = nextRun
+	state.LastError = ""
+
+	if err != nil {
+		logger.Errorf(
+			"Failed to start Pipeline %s by Trigger [%s]: %s",
+			pipelineSlug,
+			trigger.GetSlug(),
+			err,
+		)
+		state.LastError = err.Error()
+		return
+	}
+
+	logger.Infof(
+		"Started Pipeline %s by Trigger [%s] with processing ID %s",
+		pipelineSlug,
+		trigger.GetSlug(),
+		processingId,
+	)
+	state.LastProcessingId = processingId
+}
+
+// getClaimStorage returns the storage to claim runs in: the shared one if configured,
+// otherwise the only storage of the single worker
+func (r *TriggerRegistry) getClaimStorage() interfaces.Storage {
+	storages := r.pipelineRegistry.GetPipelineResultStorages()
+	for _, storage := range storages {
+		if storage.GetStorageName() == TRIGGERS_PREFERRED_CLAIM_STORAGE {
+			return storage
+		}
+	}
+
+	if len(storages) == 0 {
+		return nil
+	}
+
+	return storages[0]
+}
+
+func (r *TriggerRegistry) getClaimDirectory(pipelineSlug string, triggerSlug string) string {
+	return fmt.Sprintf("%s/%s/%s", TRIGGERS_STORAGE_PREFIX, pipelineSlug, triggerSlug)
+}
+
+func (r *TriggerRegistry) listClaims(
+	storage interfaces.Storage,
+	pipelineSlug string,
+	triggerSlug string,
+) (map[int64]interfaces.StorageLocation, error) {
+	claims := make(map[int64]interfaces.StorageLocation)
+
+	claimDirectory := r.getClaimDirectory(pipelineSlug, triggerSlug)
+	objects, err := storage.ListObjects(storage.NewStorageLocation(claimDirectory))
+	if err != nil {
+		return claims, err
+	}
+
+	for _, object := range objects {
+		filePath := filepath.ToSlash(object.GetFilePath())
+		if !strings.Contains(filePath, claimDirectory+"/") {
+			continue
+		}
+
+		matches := TRIGGER_CLAIM_FILE_REGEX.FindStringSubmatch(filepath.Base(filePath))
+		if len(matches) != 2 {
+			continue
+		}
+
+		if scheduledAt, err := strconv.ParseInt(matches[1], 10, 64); err == nil {
+			claims[scheduledAt] = object
+		}
+	}
+
+	return claims, nil
+}
+
+func (r *TriggerRegistry) getLastClaimedRun(pipelineSlug string, triggerSlug string) time.Time {
+	storage := r.getClaimStorage()
+	if storage == nil {
+		return time.Time{}
+	}
+
+	claims, err := r.listClaims(storage, pipelineSlug, triggerSlug)
+	if err != nil {
+		return time.Time{}
+	}
+
+	var lastRun int64
+	for scheduledAt := range claims {
+		if scheduledAt > lastRun {
+			lastRun = scheduledAt
+		}
+	}
+
+	if lastRun == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(lastRun, 0)
+}
+
+// claimRun creates a claim file for the scheduled run in the shared storage atomically.
+// It returns false if the run was already claimed by another worker, and an error
+// if the shared storage is unavailable, so no worker fires the run unclaimed.
+func (r *TriggerRegistry) claimRun(
+	pipelineSlug string,
+	triggerSlug string,
+	scheduledAt time.Time,
+) (bool, error) {
+	storage := r.getClaimStorage()
+	if storage == nil {
+		// No storage - nothing to coordinate with
+		return true, nil
+	}
+
+	exclusiveStorage, ok := storage.(interfaces.ExclusiveStorage)
+	if !ok {
+		return false, fmt.Errorf("storage %s does not support exclusive claims", storage.GetStorageName())
+	}
+
+	claimContent, err := json.Marshal(
+		map[string]interface{}{
+			"worker_id":    r.Id,
+			"scheduled_at": scheduledAt.Unix(),
+			"claimed_at":   time.Now().Unix(),
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	_, claimed, err := exclusiveStorage.CreateObjectBytes(
+		storage.NewStorageLocation(
+			fmt.Sprintf(
+				"%s/run_%d.json",
+				r.getClaimDirectory(pipelineSlug, triggerSlug),
+				scheduledAt.Unix(),
+			),
+		),
+		bytes.NewBuffer(claimContent),
+	)
+	if err != nil {
+		return false, fmt.Errorf("unable to claim trigger run in %s storage: %w", storage.GetStorageName(), err)
+	}
+
+	if claimed {
+		r.deleteClaimsBefore(storage, pipelineSlug, triggerSlug, scheduledAt)
+	}
+
+	return claimed, nil
+}
+
+// deleteClaimsBefore deletes the claims of the runs before the scheduled one,
+// the last claim is enough to restore the last run of the Trigger
+func (r *TriggerRegistry) deleteClaimsBefore(
+	storage interfaces.Storage,
+	pipelineSlug string,
+	triggerSlug string,
+	scheduledAt time.Time,
+) {
+	claims, err := r.listClaims(storage, pipelineSlug, triggerSlug)
+	if err != nil {
+		return
+	}
+
+	for claimScheduledAt, claimLocation := range claims {
+		if claimScheduledAt >= scheduledAt.Unix() {
+			continue
+		}
+
+		if err := storage.DeleteObject(claimLocation); err != nil {
+			config.GetLogger().Warnf(
+				"Failed to delete the claim %s of Trigger [%s] of Pipeline %s: %s",
+				claimLocation.GetFilePath(),
+				triggerSlug,
+				pipelineSlug,
+				err,
+			)
+		}
+	}
+}
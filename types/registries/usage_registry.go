@@ -0,0 +1,282 @@
+package registries
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"data-pipelines-worker/api/schemas"
+	"data-pipelines-worker/types/config"
+)
+
+// Processing log message with the usage of the paid API calls of a block input.
+// The usage is saved with the processing status, the message is informational
+const PROCESSING_USAGE_LOG_TEMPLATE = "Processing usage for block %s with index %d: %s"
+
+// ErrBudgetExceeded is returned when the processing is not started because of the spent budget
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+var (
+	usageRegistry     *UsageRegistry
+	onceUsageRegistry sync.Once
+
+	usageTokensCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "data_pipelines_usage_tokens_total",
+			Help: "Tokens of the chat completions by pipeline, model and type ( prompt or completion )",
+		},
+		[]string{"pipeline", "model", "type"},
+	)
+	usageCharactersCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "data_pipelines_usage_characters_total",
+			Help: "Characters of the speech synthesis by pipeline and model",
+		},
+		[]string{"pipeline", "model"},
+	)
+	usageSecondsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "data_pipelines_usage_audio_seconds_total",
+			Help: "Seconds of the transcribed audio by pipeline and model",
+		},
+		[]string{"pipeline", "model"},
+	)
+	usageImagesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "data_pipelines_usage_images_total",
+			Help: "Generated images by pipeline and model",
+		},
+		[]string{"pipeline", "model"},
+	)
+	usageCostCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "data_pipelines_usage_cost_total",
+			Help: "Cost of the paid API calls by pipeline and model",
+		},
+		[]string{"pipeline", "model"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		usageTokensCounter,
+		usageCharactersCounter,
+		usageSecondsCounter,
+		usageImagesCounter,
+		usageCostCounter,
+	)
+}
+
+// UsageRegistry keeps the cost of the Pipelines processings of the current UTC day for the budgets.
+// The cost of the saved processings ( of all workers sharing the storages ) is loaded from the storages
+// and refreshed periodically, the cost of the running processings of the worker is added to it.
+// A started processing counts with its estimated cost until it spends more
+type UsageRegistry struct {
+	sync.Mutex
+
+	// Serializes the budget checks with the reservations of the started processings
+	reserveLock sync.Mutex
+
+	refreshInterval time.Duration
+	estimatedCost   float64
+
+	day        time.Time
+	savedCosts map[string]float64
+	// Number of the saved processings with usage
+	savedProcessings map[string]int
+	// Time the cost of the saved processings of the Pipeline was loaded at
+	loadedAt map[string]time.Time
+	// Time the log of a processing of the worker was saved at last
+	savedAt map[string]time.Time
+	// Cost of the processings of the worker which logs are not saved yet
+	runningCosts map[string]map[uuid.UUID]float64
+	// Estimated cost of the started processings of the worker which logs are not saved yet
+	reservedCosts map[string]map[uuid.UUID]float64
+}
+
+func GetUsageRegistry(forceNewInstance ...bool) *UsageRegistry {
+	if len(forceNewInstance) > 0 && forceNewInstance[0] {
+		usageRegistry = NewUsageRegistry()
+		onceUsageRegistry = sync.Once{}
+		return usageRegistry
+	}
+
+	onceUsageRegistry.Do(func() {
+		usageRegistry = NewUsageRegistry()
+	})
+
+	return usageRegistry
+}
+
+func NewUsageRegistry() *UsageRegistry {
+	return &UsageRegistry{
+		refreshInterval:  config.GetConfig().Costs.Budgets.RefreshInterval,
+		estimatedCost:    config.GetConfig().Costs.Budgets.EstimatedCost,
+		day:              getUsageDay(time.Now()),
+		savedCosts:       make(map[string]float64),
+		savedProcessings: make(map[string]int),
+		loadedAt:         make(map[string]time.Time),
+		savedAt:          make(map[string]time.Time),
+		runningCosts:     make(map[string]map[uuid.UUID]float64),
+		reservedCosts:    make(map[string]map[uuid.UUID]float64),
+	}
+}
+
+func getUsageDay(date time.Time) time.Time {
+	return date.UTC().Truncate(24 * time.Hour)
+}
+
+// rotate resets the costs on the next UTC day
+func (r *UsageRegistry) rotate() {
+	if day := getUsageDay(time.Now()); !day.Equal(r.day) {
+		r.day = day
+		r.savedCosts = make(map[string]float64)
+		r.savedProcessings = make(map[string]int)
+		r.loadedAt = make(map[string]time.Time)
+		r.savedAt = make(map[string]time.Time)
+		r.runningCosts = make(map[string]map[uuid.UUID]float64)
+		r.reservedCosts = make(map[string]map[uuid.UUID]float64)
+	}
+}
+
+// SetRefreshInterval sets the interval the cost saved by all workers is read again from the storages after
+func (r *UsageRegistry) SetRefreshInterval(refreshInterval time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.refreshInterval = refreshInterval
+}
+
+// SetEstimatedCost sets the least cost reserved for a started processing
+func (r *UsageRegistry) SetEstimatedCost(estimatedCost float64) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.estimatedCost = estimatedCost
+}
+
+// DailyCostLoader returns the usage of the Pipeline processings saved since the date
+type DailyCostLoader func(from time.Time) schemas.PipelineUsageSchema
+
+// load reads the cost of the processings saved today when it is older than the refresh interval
+func (r *UsageRegistry) load(pipelineSlug string, loader DailyCostLoader) {
+	r.Lock()
+	r.rotate()
+	refreshInterval := r.refreshInterval
+	day := r.day
+	loadedAt, loaded := r.loadedAt[pipelineSlug]
+	// Cost loaded before the log of the worker processing was saved misses it
+	loaded = loaded && loadedAt.After(r.savedAt[pipelineSlug])
+	r.Unlock()
+
+	if loader == nil || (loaded && (refreshInterval <= 0 || time.Since(loadedAt) < refreshInterval)) {
+		return
+	}
+
+	loadedAt = time.Now()
+	savedUsage := loader(day)
+
+	r.Lock()
+	defer r.Unlock()
+
+	if r.day.Equal(day) && r.loadedAt[pipelineSlug].Before(loadedAt) && r.savedAt[pipelineSlug].Before(loadedAt) {
+		r.savedCosts[pipelineSlug] = savedUsage.Usage.Cost
+		r.savedProcessings[pipelineSlug] = savedUsage.Processings
+		r.loadedAt[pipelineSlug] = loadedAt
+	}
+}
+
+// Add accounts the usage of a running processing of the Pipeline and exports it to Prometheus
+func (r *UsageRegistry) Add(pipelineSlug string, processingId uuid.UUID, usage schemas.ProcessingUsageSchema) {
+	r.Lock()
+	r.rotate()
+	if _, ok := r.runningCosts[pipelineSlug]; !ok {
+		r.runningCosts[pipelineSlug] = make(map[uuid.UUID]float64)
+	}
+	r.runningCosts[pipelineSlug][processingId] += usage.Cost
+	r.Unlock()
+
+	for model, modelUsage := range usage.Models {
+		usageTokensCounter.WithLabelValues(pipelineSlug, model, "prompt").Add(float64(modelUsage.PromptTokens))
+		usageTokensCounter.WithLabelValues(pipelineSlug, model, "completion").Add(float64(modelUsage.CompletionTokens))
+		usageCharactersCounter.WithLabelValues(pipelineSlug, model).Add(float64(modelUsage.Characters))
+		usageSecondsCounter.WithLabelValues(pipelineSlug, model).Add(modelUsage.Seconds)
+		usageImagesCounter.WithLabelValues(pipelineSlug, model).Add(float64(modelUsage.Images))
+		usageCostCounter.WithLabelValues(pipelineSlug, model).Add(modelUsage.Cost)
+	}
+}
+
+// Reserve runs the budget check and reserves the estimated cost of the processing when it passes.
+// Concurrent starts are checked one by one, each seeing the reservations of the ones before.
+// The reservation is released when the processing log is saved or with Release
+func (r *UsageRegistry) Reserve(pipelineSlug string, processingId uuid.UUID, check func() error) error {
+	r.reserveLock.Lock()
+	defer r.reserveLock.Unlock()
+
+	if err := check(); err != nil {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.rotate()
+	// The average cost of the processings saved today, at least the configured estimate
+	estimatedCost := r.estimatedCost
+	if processings := r.savedProcessings[pipelineSlug]; processings > 0 {
+		estimatedCost = max(estimatedCost, r.savedCosts[pipelineSlug]/float64(processings))
+	}
+	if _, ok := r.reservedCosts[pipelineSlug]; !ok {
+		r.reservedCosts[pipelineSlug] = make(map[uuid.UUID]float64)
+	}
+	r.reservedCosts[pipelineSlug][processingId] = estimatedCost
+
+	return nil
+}
+
+// Release removes the reservation of the processing which did not start
+func (r *UsageRegistry) Release(pipelineSlug string, processingId uuid.UUID) {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.reservedCosts[pipelineSlug], processingId)
+}
+
+// SetSaved moves the cost of the processing with the saved log to the saved cost,
+// which is loaded from the storages on the next GetDailyCost
+func (r *UsageRegistry) SetSaved(pipelineSlug string, processingId uuid.UUID) {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.reservedCosts[pipelineSlug], processingId)
+	if _, ok := r.runningCosts[pipelineSlug][processingId]; !ok {
+		return
+	}
+
+	delete(r.runningCosts[pipelineSlug], processingId)
+	r.savedAt[pipelineSlug] = time.Now()
+}
+
+// GetDailyCost returns the cost of the Pipeline processings of the current UTC day
+func (r *UsageRegistry) GetDailyCost(pipelineSlug string, loader DailyCostLoader) float64 {
+	r.load(pipelineSlug, loader)
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.rotate()
+	cost := r.savedCosts[pipelineSlug]
+	for processingId, runningCost := range r.runningCosts[pipelineSlug] {
+		cost += max(runningCost, r.reservedCosts[pipelineSlug][processingId])
+	}
+	for processingId, reservedCost := range r.reservedCosts[pipelineSlug] {
+		if _, ok := r.runningCosts[pipelineSlug][processingId]; !ok {
+			cost += reservedCost
+		}
+	}
+
+	return cost
+}
@@ -0,0 +1,140 @@
+package registries
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/interfaces"
+)
+
+// WasmPluginsWatcher reloads the WebAssembly plugins when the `.wasm` files
+// of the plugins directory change
+type WasmPluginsWatcher struct {
+	sync.Mutex
+
+	blockRegistry interfaces.BlockRegistry
+	directory     string
+	watch         bool
+	reloadDelay   time.Duration
+
+	started  bool
+	stopOnce sync.Once
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Ensure WasmPluginsWatcher implements the WasmPluginsWatcher
+var _ interfaces.WasmPluginsWatcher = (*WasmPluginsWatcher)(nil)
+
+func NewWasmPluginsWatcher(blockRegistry interfaces.BlockRegistry) *WasmPluginsWatcher {
+	wasmConfig := config.GetConfig().Plugins.Wasm.WithDefaults()
+
+	return &WasmPluginsWatcher{
+		blockRegistry: blockRegistry,
+		directory:     wasmConfig.Directory,
+		watch:         wasmConfig.Watch,
+		reloadDelay:   wasmConfig.ReloadDelay,
+		stopChan:      make(chan struct{}),
+	}
+}
+
+func (w *WasmPluginsWatcher) SetWatch(watch bool) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.watch = watch
+}
+
+func (w *WasmPluginsWatcher) SetReloadDelay(reloadDelay time.Duration) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.reloadDelay = reloadDelay
+}
+
+// Start reloads the plugins on the changes of the directory until Shutdown is called
+func (w *WasmPluginsWatcher) Start() {
+	w.Lock()
+	if w.started {
+		w.Unlock()
+		return
+	}
+	w.started = true
+	watch := w.watch && w.directory != ""
+	reloadDelay := w.reloadDelay
+	w.Unlock()
+
+	if !watch {
+		return
+	}
+
+	logger := config.GetLogger()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = watcher.Add(w.directory)
+	}
+	if err != nil {
+		if watcher != nil {
+			watcher.Close()
+		}
+		logger.Errorf("Failed to watch WebAssembly plugins %s: %s", w.directory, err)
+		return
+	}
+	logger.Infof("Watching WebAssembly plugins %s", w.directory)
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer watcher.Close()
+
+		// Modules are copied in several writes, so changes are collected before the reload
+		reloadTimer := time.NewTimer(reloadDelay)
+		reloadTimer.Stop()
+		defer reloadTimer.Stop()
+
+		for {
+			select {
+			case <-w.stopChan:
+				return
+			case event := <-watcher.Events:
+				if filepath.Ext(event.Name) != ".wasm" {
+					continue
+				}
+				if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+					continue
+				}
+				reloadTimer.Reset(reloadDelay)
+			case err := <-watcher.Errors:
+				logger.Errorf("WebAssembly plugins watcher error: %s", err)
+			case <-reloadTimer.C:
+				logger.Info("Reloading changed WebAssembly plugins")
+				w.blockRegistry.ReloadWasmPlugins()
+			}
+		}
+	}()
+}
+
+func (w *WasmPluginsWatcher) Shutdown(ctx context.Context) error {
+	w.stopOnce.Do(func() {
+		close(w.stopChan)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"data-pipelines-worker/api/schemas"
 	"data-pipelines-worker/types/config"
+	"data-pipelines-worker/types/helpers"
 	"data-pipelines-worker/types/interfaces"
 )
 
@@ -162,10 +163,14 @@ func (wr *WorkerRegistry) GetWorkersWithPipeline(
 	return workersWithPipeline
 }
 
+// GetWorkersWithBlocksAvailable returns the workers with an available version of the block.
+// The `id@version` references require a version compatible with the pinned one
 func (wr *WorkerRegistry) GetWorkersWithBlocksAvailable(
 	workers map[string]interfaces.Worker,
-	blockId string,
+	blockReference string,
 ) map[string]interfaces.Worker {
+	blockId, version := helpers.ParseBlockReference(blockReference)
+
 	workersWithBlocksAvailable := make(map[string]interfaces.Worker)
 	for id, worker := range workers {
 		workerBlocks, err := wr.GetWorkerBlocks(worker)
@@ -177,10 +182,7 @@ func (wr *WorkerRegistry) GetWorkersWithBlocksAvailable(
 				continue
 			}
 			if workerBlockMap, ok := workerBlock.(map[string]interface{}); ok {
-				if _, ok := workerBlockMap["available"]; !ok {
-					continue
-				}
-				if workerBlockMap["available"].(bool) {
+				if isWorkerBlockVersionAvailable(workerBlockMap, version) {
 					workersWithBlocksAvailable[id] = worker
 				}
 			}
@@ -190,6 +192,29 @@ func (wr *WorkerRegistry) GetWorkersWithBlocksAvailable(
 	return workersWithBlocksAvailable
 }
 
+// isWorkerBlockVersionAvailable looks for an available version matching the pinned one in the `versions` of the block,
+// the blocks of the workers without versions are the only version
+func isWorkerBlockVersionAvailable(workerBlock map[string]interface{}, pinnedVersion string) bool {
+	versions, ok := workerBlock["versions"].([]interface{})
+	if !ok {
+		versions = []interface{}{workerBlock}
+	}
+
+	for _, version := range versions {
+		versionMap, ok := version.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		available, _ := versionMap["available"].(bool)
+		blockVersion, _ := versionMap["version"].(string)
+		if available && helpers.MatchVersion(pinnedVersion, blockVersion) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (wr *WorkerRegistry) GetValidWorkers(
 	pipelineSlug string,
 	blockId string,
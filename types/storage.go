@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/minio/minio-go/v7"
@@ -127,7 +128,16 @@ func (s *LocalStorage) PutObjectBytes(
 	destination interfaces.StorageLocation,
 	content *bytes.Buffer,
 ) (interfaces.StorageLocation, error) {
-	mimeType, err := helpers.DetectMimeTypeFromBuffer(*content)
+	return s.PutObjectBytesWithContentType(destination, content, "")
+}
+
+// PutObjectBytesWithContentType writes the content with the file extension of the content type
+func (s *LocalStorage) PutObjectBytesWithContentType(
+	destination interfaces.StorageLocation,
+	content *bytes.Buffer,
+	contentType string,
+) (interfaces.StorageLocation, error) {
+	mimeType, err := helpers.GetMimeType(contentType, *content)
 	if err != nil {
 		return s.NewStorageLocation(""), err
 	}
@@ -169,6 +179,31 @@ func (s *LocalStorage) PutObjectBytes(
 	return destinationWithExtension, nil
 }
 
+// CreateObjectBytes writes the content to the file only if it does not exist yet
+func (s *LocalStorage) CreateObjectBytes(
+	destination interfaces.StorageLocation,
+	content *bytes.Buffer,
+) (interfaces.StorageLocation, bool, error) {
+	if err := os.MkdirAll(filepath.Dir(destination.GetFilePath()), os.ModePerm); err != nil {
+		return s.NewStorageLocation(""), false, err
+	}
+
+	file, err := os.OpenFile(destination.GetFilePath(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return destination, false, nil
+	}
+	if err != nil {
+		return s.NewStorageLocation(""), false, err
+	}
+	defer file.Close()
+
+	if _, err := content.WriteTo(file); err != nil {
+		return s.NewStorageLocation(""), false, err
+	}
+
+	return destination, true, nil
+}
+
 func (s *LocalStorage) GetObject(
 	source interfaces.StorageLocation,
 	destination interfaces.StorageLocation,
@@ -192,9 +227,27 @@ func (s *LocalStorage) GetObjectBytes(source interfaces.StorageLocation) (*bytes
 	return buffer, nil
 }
 
+// DeleteObject deletes a file or an empty directory.
+// Locations outside of the storage root and the root itself are never deleted
 func (s *LocalStorage) DeleteObject(location interfaces.StorageLocation) error {
-	// TODO: Add sanity checks
-	return os.Remove(location.GetFilePath())
+	root, err := filepath.Abs(s.GetStorageDirectory())
+	if err != nil {
+		return err
+	}
+	filePath, err := filepath.Abs(location.GetFilePath())
+	if err != nil {
+		return err
+	}
+
+	relativePath, err := filepath.Rel(root, filePath)
+	if err != nil ||
+		relativePath == "." ||
+		relativePath == ".." ||
+		strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("refusing to delete %s outside of the storage directory %s", filePath, root)
+	}
+
+	return os.Remove(filePath)
 }
 
 func (s *LocalStorage) LocationExists(location interfaces.StorageLocation) bool {
@@ -202,6 +255,16 @@ func (s *LocalStorage) LocationExists(location interfaces.StorageLocation) bool
 	return err == nil
 }
 
+// GetObjectVersion returns the modification time and size of the file
+func (s *LocalStorage) GetObjectVersion(location interfaces.StorageLocation) (string, error) {
+	info, err := os.Stat(location.GetFilePath())
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()), nil
+}
+
 func (s *LocalStorage) Shutdown() {}
 
 type MINIOStorage struct {
@@ -209,7 +272,7 @@ type MINIOStorage struct {
 
 	name         string
 	bucket       string
-	localStorage interfaces.Storage // Some operations requires local storage
+	localStorage *LocalStorage // Some operations requires local storage
 }
 
 func NewMINIOStorage() *MINIOStorage {
@@ -270,9 +333,44 @@ func (s *MINIOStorage) ListObjects(location interfaces.StorageLocation) ([]inter
 	return objects, nil
 }
 
+// ListDirectory lists the objects and the prefixes directly inside the location,
+// so the outputs of the nested directories are not listed
+func (s *MINIOStorage) ListDirectory(location interfaces.StorageLocation) ([]interfaces.StorageLocation, error) {
+	objects := make([]interfaces.StorageLocation, 0)
+
+	prefix := strings.TrimSuffix(location.GetFileName(), "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	for object := range s.Client.ListObjects(
+		context.Background(),
+		s.GetStorageDirectory(),
+		minio.ListObjectsOptions{
+			Prefix:    prefix,
+			Recursive: false,
+		},
+	) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		objects = append(objects, s.NewStorageLocation(object.Key))
+	}
+
+	return objects, nil
+}
+
 func (s *MINIOStorage) PutObject(
 	source interfaces.StorageLocation,
 	destination interfaces.StorageLocation,
+) (interfaces.StorageLocation, error) {
+	return s.putObject(source, destination, "")
+}
+
+func (s *MINIOStorage) putObject(
+	source interfaces.StorageLocation,
+	destination interfaces.StorageLocation,
+	contentType string,
 ) (interfaces.StorageLocation, error) {
 	// Get the file extension of Source
 	fileName := destination.GetFileName()
@@ -282,7 +380,7 @@ func (s *MINIOStorage) PutObject(
 		if err != nil {
 			return s.NewStorageLocation(""), err
 		}
-		mimeType, err := helpers.DetectMimeTypeFromBuffer(*content)
+		mimeType, err := helpers.GetMimeType(contentType, *content)
 		if err != nil {
 			return s.NewStorageLocation(""), err
 		}
@@ -297,7 +395,7 @@ func (s *MINIOStorage) PutObject(
 		s.GetStorageDirectory(),
 		destinationWithExtension.GetFileName(),
 		filepath.Join(source.GetLocalDirectory(), source.GetFileName()),
-		minio.PutObjectOptions{},
+		minio.PutObjectOptions{ContentType: contentType},
 	)
 	if err != nil {
 		return s.NewStorageLocation(""), err
@@ -309,16 +407,56 @@ func (s *MINIOStorage) PutObject(
 func (s *MINIOStorage) PutObjectBytes(
 	destination interfaces.StorageLocation,
 	content *bytes.Buffer,
+) (interfaces.StorageLocation, error) {
+	return s.PutObjectBytesWithContentType(destination, content, "")
+}
+
+// PutObjectBytesWithContentType uploads the content with the content type and its file extension
+func (s *MINIOStorage) PutObjectBytesWithContentType(
+	destination interfaces.StorageLocation,
+	content *bytes.Buffer,
+	contentType string,
 ) (interfaces.StorageLocation, error) {
 	localStorageLocation := s.localStorage.NewStorageLocation(uuid.NewString())
 	defer s.localStorage.DeleteObject(localStorageLocation)
 
-	localStorage, err := s.localStorage.PutObjectBytes(localStorageLocation, content)
+	localStorage, err := s.localStorage.PutObjectBytesWithContentType(
+		localStorageLocation,
+		content,
+		contentType,
+	)
 	if err != nil {
 		return s.NewStorageLocation(""), err
 	}
 
-	return s.PutObject(localStorage, destination)
+	return s.putObject(localStorage, destination, contentType)
+}
+
+// CreateObjectBytes uploads the content only if the object does not exist yet,
+// the If-None-Match precondition makes the check and the upload atomic
+func (s *MINIOStorage) CreateObjectBytes(
+	destination interfaces.StorageLocation,
+	content *bytes.Buffer,
+) (interfaces.StorageLocation, bool, error) {
+	options := minio.PutObjectOptions{}
+	options.SetMatchETagExcept("*")
+
+	_, err := s.Client.PutObject(
+		context.Background(),
+		s.GetStorageDirectory(),
+		destination.GetFileName(),
+		bytes.NewReader(content.Bytes()),
+		int64(content.Len()),
+		options,
+	)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "PreconditionFailed" {
+			return destination, false, nil
+		}
+		return s.NewStorageLocation(""), false, err
+	}
+
+	return destination, true, nil
 }
 
 func (s *MINIOStorage) GetObject(
@@ -365,4 +503,19 @@ func (s *MINIOStorage) LocationExists(location interfaces.StorageLocation) bool
 	return err == nil
 }
 
+// GetObjectVersion returns the ETag of the object
+func (s *MINIOStorage) GetObjectVersion(location interfaces.StorageLocation) (string, error) {
+	info, err := s.Client.StatObject(
+		context.Background(),
+		s.GetStorageDirectory(),
+		location.GetFileName(),
+		minio.StatObjectOptions{},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return info.ETag, nil
+}
+
 func (s *MINIOStorage) Shutdown() {}